@@ -0,0 +1,195 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/kalikim/x-cli/config"
+)
+
+// Names of the accessibility checks, as accessibility.skip takes them.
+const (
+	accessibilityAltText   = "alt_text"
+	accessibilitySubtitles = "subtitles"
+	accessibilityHashtags  = "hashtags"
+)
+
+const (
+	// maxAltTextLength is X's limit on image descriptions.
+	maxAltTextLength = 1000
+
+	// Single-case hashtags this long are checked against the spell
+	// checker's dictionary, so #programming passes and #opensource does
+	// not. Without a dictionary only longer ones are flagged.
+	minCamelCaseHashtag          = 8
+	minCamelCaseHashtagNoChecker = 12
+)
+
+// validateAltText checks --alt-text against the media it describes.
+func validateAltText(altText, image string) error {
+	if image == "" {
+		return invalidInput(errors.New("--alt-text needs --image"))
+	}
+	head, err := readHead(image)
+	if err != nil {
+		return invalidInput(fmt.Errorf("media: %w", err))
+	}
+	if !strings.HasPrefix(detectMime(image, head), "image/") {
+		return invalidInput(errors.New("--alt-text describes images and GIFs; give videos --subtitles instead"))
+	}
+	if n := utf8.RuneCountInString(altText); n > maxAltTextLength {
+		return invalidInput(fmt.Errorf("--alt-text is %d characters, over X's %d limit", n, maxAltTextLength))
+	}
+	return nil
+}
+
+// setAltText sets the description screen readers announce for the uploaded
+// image mediaID.
+func setAltText(client *http.Client, cfg config.Config, mediaID, altText string) error {
+	var req struct {
+		MediaID string `json:"media_id"`
+		AltText struct {
+			Text string `json:"text"`
+		} `json:"alt_text"`
+	}
+	req.MediaID = mediaID
+	req.AltText.Text = altText
+
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("encoding alt text request: %w", err)
+	}
+	status, respBody, err := sendSigned(client, cfg, http.MethodPost, mediaMetadataEndpoint, payload, "application/json")
+	if err != nil {
+		return fmt.Errorf("setting alt text: %w", err)
+	}
+	if status >= 300 {
+		return fmt.Errorf("setting alt text: %w", newAPIError(status, respBody))
+	}
+	return nil
+}
+
+// checkAccessibility looks for an image without alt text, a video without
+// subtitles and hashtags written in one case, which screen readers read as
+// one long word. Problems are returned as warnings, or as an error when the
+// level is "error" and force is not set.
+func checkAccessibility(cfg config.Config, texts []ruleText, image string, media mediaOptions, force bool) ([]string, error) {
+	level := cfg.Accessibility.Level
+	switch level {
+	case "", "warn", "error":
+	case "off":
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("invalid accessibility level %q (use warn, error or off)", level)
+	}
+	skip := map[string]bool{}
+	for _, name := range cfg.Accessibility.Skip {
+		switch name {
+		case accessibilityAltText, accessibilitySubtitles, accessibilityHashtags:
+			skip[name] = true
+		default:
+			return nil, fmt.Errorf("invalid accessibility check %q in skip (use %s, %s or %s)", name, accessibilityAltText, accessibilitySubtitles, accessibilityHashtags)
+		}
+	}
+
+	var found []string
+	if image != "" {
+		head, err := readHead(image)
+		if err != nil {
+			return nil, fmt.Errorf("reading media: %w", err)
+		}
+		mimeType := detectMime(image, head)
+		if !skip[accessibilityAltText] && strings.HasPrefix(mimeType, "image/") && strings.TrimSpace(media.AltText) == "" {
+			found = append(found, "the image has no alt text for screen readers (add --alt-text)")
+		}
+		if !skip[accessibilitySubtitles] && strings.HasPrefix(mimeType, "video/") && media.Subtitles == nil {
+			found = append(found, "the video has no captions for deaf and hard-of-hearing viewers (add --subtitles)")
+		}
+	}
+	if !skip[accessibilityHashtags] {
+		for _, t := range texts {
+			for _, tag := range singleCaseHashtags(cfg, t.text) {
+				v := fmt.Sprintf("screen readers cannot split %s into words; capitalize each one, e.g. #LikeThis", tag)
+				if t.name != "" {
+					v = t.name + ": " + v
+				}
+				found = append(found, v)
+			}
+		}
+	}
+
+	if len(found) > 0 && level == "error" && !force {
+		return nil, invalidInput(errors.New("tweet fails accessibility checks: " + strings.Join(found, "; ") + " (use --force to post anyway)"))
+	}
+	return found, nil
+}
+
+// singleCaseHashtags returns the hashtags in text that look like several
+// words run together in one case, such as #throwbackthursday. Tags that are
+// one dictionary word, or listed in spellcheck_words, are left alone.
+func singleCaseHashtags(cfg config.Config, text string) []string {
+	withoutLinks := countURLPattern.ReplaceAllString(text, " ")
+
+	var candidates []string
+	seen := map[string]bool{}
+	for _, tag := range hashtagPattern.FindAllString(withoutLinks, -1) {
+		// Digits and underscores already break a tag into parts.
+		word := strings.TrimRightFunc(strings.TrimPrefix(tag, "#"), unicode.IsPunct)
+		if strings.ContainsFunc(word, func(r rune) bool { return unicode.IsDigit(r) || r == '_' }) {
+			continue
+		}
+		letters, upper, lower := 0, 0, 0
+		for _, r := range word {
+			if unicode.IsLetter(r) {
+				letters++
+			}
+			if unicode.IsUpper(r) {
+				upper++
+			}
+			if unicode.IsLower(r) {
+				lower++
+			}
+		}
+		if letters < minCamelCaseHashtag || (upper > 0 && lower > 0) || seen[strings.ToLower(word)] {
+			continue
+		}
+		seen[strings.ToLower(word)] = true
+		candidates = append(candidates, word)
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	lowered := make([]string, len(candidates))
+	for i, word := range candidates {
+		lowered[i] = strings.ToLower(word)
+	}
+	unknown, err := spellcheck(cfg, strings.Join(lowered, " "))
+	if err != nil {
+		// Without a dictionary, only flag tags too long to be one word.
+		var tags []string
+		for _, word := range candidates {
+			if utf8.RuneCountInString(word) >= minCamelCaseHashtagNoChecker {
+				tags = append(tags, "#"+word)
+			}
+		}
+		return tags
+	}
+
+	isUnknown := map[string]bool{}
+	for _, w := range unknown {
+		isUnknown[strings.ToLower(w)] = true
+	}
+	var tags []string
+	for _, word := range candidates {
+		if isUnknown[strings.ToLower(word)] {
+			tags = append(tags, "#"+word)
+		}
+	}
+	return tags
+}
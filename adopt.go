@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/kalikim/x-cli/config"
+	"github.com/spf13/cobra"
+)
+
+// adoptPageSize is the largest page the user tweets endpoint returns.
+const adoptPageSize = 100
+
+func newSchedulerAdoptCmd(queue *string) *cobra.Command {
+	var since, labelSpec, campaign string
+	var limit int
+	var assumeYes bool
+
+	cmd := &cobra.Command{
+		Use:   "adopt",
+		Short: "Record tweets posted outside x-cli in the history, with labels",
+		Long: "Scan the account's recent tweets for ones missing from the history, such as\n" +
+			"tweets posted from the app, and record them so stats, reshare suggestions\n" +
+			"and history search cover them. Each tweet is shown in turn to attach labels\n" +
+			"or skip it; --yes adopts them all with --label and --campaign.",
+		Example: `  x-cli scheduler adopt --since 30d
+  x-cli scheduler adopt --since 7d --label launch --campaign spring --yes
+  x-cli scheduler adopt --queue work`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			window, err := parsePeriod(since)
+			if err != nil {
+				return fmt.Errorf("--since: %w", err)
+			}
+			if limit <= 0 {
+				return invalidInput(fmt.Errorf("--limit must be positive, got %d", limit))
+			}
+			labels, err := parseLabels(labelSpec)
+			if err != nil {
+				return err
+			}
+			if err := validateCampaignName(campaign); err != nil {
+				return err
+			}
+			if !assumeYes && !isTerminal(os.Stdin) {
+				return invalidInput(errors.New("adopt asks about each tweet; use --yes to adopt them all without a terminal"))
+			}
+
+			cfg, err := configForQueue(config.LoadConfig(), *queue)
+			if err != nil {
+				return err
+			}
+			if err := cfg.Validate(); err != nil {
+				return err
+			}
+
+			candidates, err := findAdoptableTweets(apiClient(), cfg, time.Now().Add(-window), limit)
+			if err != nil {
+				return err
+			}
+			if len(candidates) == 0 {
+				say("📭", "Every tweet from the last %s is already in the history", since)
+				return nil
+			}
+
+			entries := make([]historyEntry, 0, len(candidates))
+			for _, t := range candidates {
+				entries = append(entries, historyEntry{TweetID: t.ID, Text: t.Text, PostedAt: t.CreatedAt, Queue: *queue, Labels: labels, Campaign: campaign})
+			}
+			if !assumeYes {
+				entries = reviewAdoptions(entries)
+			}
+			if len(entries) == 0 {
+				say("📭", "No tweets adopted")
+				return nil
+			}
+
+			if err := adoptTweets(entries); err != nil {
+				return fmt.Errorf("updating history: %w", err)
+			}
+			say("✅", "Adopted %d tweet(s) into %s", len(entries), storeLocation(historyFile))
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&since, "since", "30d", "How far back to scan, e.g. 7d or 4w")
+	cmd.Flags().IntVar(&limit, "limit", 200, "Scan at most this many recent tweets")
+	cmd.Flags().StringVar(&labelSpec, "label", "", "Comma-separated labels for every adopted tweet")
+	cmd.Flags().StringVar(&campaign, "campaign", "", "Campaign for every adopted tweet")
+	cmd.Flags().BoolVarP(&assumeYes, "yes", "y", false, "Adopt every tweet found without asking")
+	return cmd
+}
+
+// findAdoptableTweets returns the account's tweets since cutoff, at most
+// limit of them and oldest first, that the history does not have yet.
+// Retweets are left out: they are not the account's own content.
+func findAdoptableTweets(client *http.Client, cfg config.Config, cutoff time.Time, limit int) ([]timelineTweet, error) {
+	entries, err := loadHistory()
+	if err != nil {
+		return nil, fmt.Errorf("loading history: %w", err)
+	}
+	known := map[string]bool{}
+	for _, e := range entries {
+		known[e.TweetID] = true
+	}
+
+	userID, err := accountUserID(client, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var found []timelineTweet
+	scanned, pageToken := 0, ""
+	for scanned < limit {
+		page, err := fetchTimeline(client, cfg, userTweetsEndpoint, userID, pageToken, "", adoptPageSize)
+		if err != nil {
+			return nil, fmt.Errorf("fetching recent tweets: %w", err)
+		}
+		for _, t := range page.Tweets {
+			if scanned == limit || t.CreatedAt.Before(cutoff) {
+				return reverseTweets(found), nil
+			}
+			scanned++
+			if known[t.ID] || strings.HasPrefix(t.Text, "RT @") {
+				continue
+			}
+			t.Text = html.UnescapeString(t.Text)
+			found = append(found, t)
+		}
+		if page.NextToken == "" {
+			break
+		}
+		pageToken = page.NextToken
+	}
+	return reverseTweets(found), nil
+}
+
+func reverseTweets(tweets []timelineTweet) []timelineTweet {
+	for i, j := 0, len(tweets)-1; i < j; i, j = i+1, j-1 {
+		tweets[i], tweets[j] = tweets[j], tweets[i]
+	}
+	return tweets
+}
+
+// reviewAdoptions shows each tweet and asks for its labels, returning the
+// entries to adopt. Labels typed for a tweet are added to the --label ones.
+func reviewAdoptions(entries []historyEntry) []historyEntry {
+	in := bufio.NewReader(os.Stdin)
+	var kept []historyEntry
+
+	for i, entry := range entries {
+		fmt.Println()
+		say("📥", "[%d/%d] Posted %s · %s", i+1, len(entries), entry.PostedAt.Local().Format("2006-01-02 15:04"), tweetURL("", entry.TweetID))
+		for _, line := range strings.Split(entry.Text, "\n") {
+			fmt.Println("  " + line)
+		}
+
+		for answered := false; !answered; {
+			fmt.Print("Labels to add, [s]kip or [q]uit (Enter adopts as is): ")
+			answer, err := readInboxLine(in)
+			if err != nil {
+				fmt.Println()
+				return kept
+			}
+
+			switch strings.ToLower(answer) {
+			case "s", "skip":
+				answered = true
+			case "q", "quit":
+				return kept
+			default:
+				labels, err := parseLabels(answer)
+				if err != nil {
+					say("⚠️", "%v", err)
+					continue
+				}
+				entry.Labels = append([]string(nil), entry.Labels...)
+				for _, label := range labels {
+					if !hasLabels(entry.Labels, []string{label}) {
+						entry.Labels = append(entry.Labels, label)
+					}
+				}
+				kept = append(kept, entry)
+				answered = true
+			}
+		}
+	}
+	return kept
+}
+
+// adoptTweets appends entries to the history, skipping any recorded in the
+// meantime.
+func adoptTweets(entries []historyEntry) error {
+	return updateHistory(func(history []historyEntry) []historyEntry {
+		known := map[string]bool{}
+		for _, e := range history {
+			known[e.TweetID] = true
+		}
+		for _, e := range entries {
+			if !known[e.TweetID] {
+				history = append(history, e)
+			}
+		}
+		return history
+	})
+}
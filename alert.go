@@ -0,0 +1,346 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kalikim/x-cli/config"
+	"github.com/spf13/cobra"
+)
+
+const (
+	alertsFile           = "alerts.json"
+	defaultAlertInterval = "15m"
+	// minAlertInterval keeps saved searches from eating the search rate
+	// limit, which is shared with every other search the account makes.
+	minAlertInterval = 5 * time.Minute
+	alertSearchSize  = 100
+	// alertPreviewTweets is how many matches a notification's text quotes.
+	alertPreviewTweets = 3
+)
+
+// meOperator matches search operators naming the account itself, which X
+// search does not understand.
+var meOperator = regexp.MustCompile(`\b(from|to):me\b|@me\b`)
+
+// savedAlert is a saved search the scheduler daemon runs periodically,
+// pushing new matches to its notification target.
+type savedAlert struct {
+	ID        string       `json:"id"`
+	Query     string       `json:"query"`
+	Notify    notifyTarget `json:"notify"`
+	Every     string       `json:"every"`
+	CreatedAt time.Time    `json:"created_at"`
+	LastRun   time.Time    `json:"last_run,omitempty"`
+	// SinceID is the newest match already notified.
+	SinceID string `json:"since_id,omitempty"`
+}
+
+func newAlertCmd() *cobra.Command {
+	alertCmd := &cobra.Command{
+		Use:   "alert",
+		Short: "Watch saved searches and get notified of new matches",
+	}
+
+	var notify, webhook, every string
+
+	addCmd := &cobra.Command{
+		Use:   "add <query>",
+		Short: "Save a search for the scheduler daemon to run periodically",
+		Long: "Save a search for the scheduler daemon to run periodically. New matches are\n" +
+			"pushed to a webhook as JSON or shown as a desktop notification. The query uses\n" +
+			"X search syntax; from:me, to:me and @me stand for your own account.",
+		Example: `  x-cli alert add "brandname -from:me" --notify webhook --webhook https://example.com/hook
+  x-cli alert add "#launch" --notify desktop --every 1h`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			query := strings.TrimSpace(args[0])
+			if query == "" {
+				return invalidInput(errors.New("the search query cannot be empty"))
+			}
+			target, err := parseNotifyTarget(notify, webhook)
+			if err != nil {
+				return err
+			}
+			period, err := parsePeriod(every)
+			if err != nil {
+				return fmt.Errorf("--every: %w", err)
+			}
+			if period < minAlertInterval {
+				return invalidInput(fmt.Errorf("--every must be at least 5m, got %s", every))
+			}
+
+			if meOperator.MatchString(query) {
+				cfg := config.LoadConfig()
+				if err := cfg.Validate(); err != nil {
+					return err
+				}
+				username, err := accountUsername(apiClient(), cfg)
+				if err != nil {
+					return err
+				}
+				query = expandMeOperators(query, username)
+			}
+
+			var alert savedAlert
+			err = updateAlerts(func(alerts []savedAlert) ([]savedAlert, error) {
+				alert = savedAlert{
+					ID:        nextAlertID(alerts),
+					Query:     query,
+					Notify:    target,
+					Every:     every,
+					CreatedAt: time.Now(),
+				}
+				return append(alerts, alert), nil
+			})
+			if err != nil {
+				return err
+			}
+
+			say("🔔", "Alert %s saved: %q every %s via %s", alert.ID, alert.Query, alert.Every, alert.Notify)
+			say("💡", "The scheduler daemon runs it; matches posted from now on are reported")
+			return nil
+		},
+	}
+	addCmd.Flags().StringVar(&notify, "notify", notifyWebhook, "Where to send matches: webhook, slack or desktop")
+	addCmd.Flags().StringVar(&webhook, "webhook", "", "Webhook URL for --notify webhook or slack")
+	addCmd.Flags().StringVar(&every, "every", defaultAlertInterval, "How often the daemon runs the search (at least 5m)")
+
+	var listOutput string
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List saved alerts",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			alerts, err := loadAlerts()
+			if err != nil {
+				return fmt.Errorf("loading alerts: %w", err)
+			}
+			if len(alerts) == 0 && listOutput == "table" {
+				say("📭", "No alerts saved (use 'x-cli alert add')")
+				return nil
+			}
+
+			rows := make([][]any, 0, len(alerts))
+			for _, a := range alerts {
+				lastRun := "never"
+				if !a.LastRun.IsZero() {
+					lastRun = a.LastRun.Local().Format("2006-01-02 15:04")
+				}
+				rows = append(rows, []any{a.ID, a.Query, a.Every, a.Notify.String(), lastRun})
+			}
+			return writeRows(listOutput, []string{"id", "query", "every", "notify", "last_run"}, rows)
+		},
+	}
+	listCmd.Flags().StringVarP(&listOutput, "output", "o", "table", "Output format: table, json, yaml or tsv")
+
+	removeCmd := &cobra.Command{
+		Use:   "remove <id>",
+		Short: "Delete a saved alert",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := updateAlerts(func(alerts []savedAlert) ([]savedAlert, error) {
+				for i := range alerts {
+					if alerts[i].ID == args[0] {
+						return append(alerts[:i], alerts[i+1:]...), nil
+					}
+				}
+				return nil, invalidInput(fmt.Errorf("no alert with ID %s (see 'x-cli alert list')", args[0]))
+			})
+			if err != nil {
+				return err
+			}
+			say("🗑️", "Removed alert %s", args[0])
+			return nil
+		},
+	}
+
+	runCmd := &cobra.Command{
+		Use:   "run",
+		Short: "Run every saved alert now instead of waiting for the daemon",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg := config.LoadConfig()
+			if err := cfg.Validate(); err != nil {
+				return err
+			}
+			alerts, err := loadAlerts()
+			if err != nil {
+				return fmt.Errorf("loading alerts: %w", err)
+			}
+			if len(alerts) == 0 {
+				say("📭", "No alerts saved (use 'x-cli alert add')")
+				return nil
+			}
+
+			client := apiClient()
+			failed := 0
+			for _, alert := range alerts {
+				if err := runAlert(client, cfg, alert, time.Now()); err != nil {
+					say("❌", "Alert %s: %v", alert.ID, err)
+					failed++
+				}
+			}
+			if failed > 0 {
+				return fmt.Errorf("%d of %d alert(s) failed", failed, len(alerts))
+			}
+			return nil
+		},
+	}
+
+	alertCmd.AddCommand(addCmd, listCmd, removeCmd, runCmd)
+	return alertCmd
+}
+
+// expandMeOperators replaces from:me, to:me and @me with the account's
+// handle.
+func expandMeOperators(query, username string) string {
+	return meOperator.ReplaceAllStringFunc(query, func(op string) string {
+		if op == "@me" {
+			return "@" + username
+		}
+		return strings.TrimSuffix(op, "me") + username
+	})
+}
+
+func nextAlertID(alerts []savedAlert) string {
+	highest := 0
+	for _, a := range alerts {
+		if n, err := strconv.Atoi(a.ID); err == nil && n > highest {
+			highest = n
+		}
+	}
+	return strconv.Itoa(highest + 1)
+}
+
+func loadAlerts() ([]savedAlert, error) {
+	data, err := os.ReadFile(alertsFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var alerts []savedAlert
+	if err := json.Unmarshal(data, &alerts); err != nil {
+		return nil, err
+	}
+	return alerts, nil
+}
+
+// updateAlerts applies fn to the saved alerts under the store lock.
+func updateAlerts(fn func([]savedAlert) ([]savedAlert, error)) error {
+	return withFileLock(alertsFile, func() error {
+		alerts, err := loadAlerts()
+		if err != nil {
+			return fmt.Errorf("loading alerts: %w", err)
+		}
+
+		if alerts, err = fn(alerts); err != nil {
+			return err
+		}
+
+		data, err := json.MarshalIndent(alerts, "", "  ")
+		if err != nil {
+			return err
+		}
+		return writeFileAtomic(alertsFile, data, 0600)
+	})
+}
+
+// runDueAlerts runs every saved alert whose period has elapsed.
+func runDueAlerts(client *http.Client, cfg config.Config, now time.Time) {
+	alerts, err := loadAlerts()
+	if err != nil {
+		log.Printf("Error loading alerts: %v", err)
+		return
+	}
+
+	for _, alert := range alerts {
+		period, err := parsePeriod(alert.Every)
+		if err != nil {
+			log.Printf("Error in alert %s: %v", alert.ID, err)
+			continue
+		}
+		if !alert.LastRun.IsZero() && now.Sub(alert.LastRun) < period {
+			continue
+		}
+		if err := runAlert(client, cfg, alert, now); err != nil {
+			log.Printf("Error running alert %s: %v", alert.ID, err)
+		}
+	}
+}
+
+// runAlert searches for matches newer than the alert's last notified one
+// and pushes them. The alert only moves forward once the notification is
+// delivered, so a failed delivery is retried on the next run.
+func runAlert(client *http.Client, cfg config.Config, alert savedAlert, now time.Time) error {
+	page, err := searchRecent(client, cfg, alert.Query, alert.SinceID, alert.CreatedAt, alertSearchSize)
+	if err != nil {
+		return fmt.Errorf("searching: %w", err)
+	}
+
+	var matches []timelineTweet
+	sinceID := alert.SinceID
+	for _, t := range page.Tweets {
+		if !newerID(t.ID, alert.SinceID) {
+			continue
+		}
+		matches = append(matches, t)
+		if newerID(t.ID, sinceID) {
+			sinceID = t.ID
+		}
+	}
+
+	if len(matches) > 0 {
+		ev := newHookEvent(cfg, eventAlertMatched, alertNotification(alert, matches))
+		ev.Alert = &hookAlert{ID: alert.ID, Query: alert.Query}
+		if err := sendNotification(client, alert.Notify, ev); err != nil {
+			return fmt.Errorf("notifying: %w", err)
+		}
+		say("🔔", "Alert %s: %d new match(es) for %q", alert.ID, len(matches), alert.Query)
+	}
+
+	return updateAlerts(func(alerts []savedAlert) ([]savedAlert, error) {
+		for i := range alerts {
+			if alerts[i].ID == alert.ID {
+				alerts[i].LastRun = now
+				alerts[i].SinceID = sinceID
+			}
+		}
+		return alerts, nil
+	})
+}
+
+// alertNotification summarizes matches, newest first, quoting the first few.
+func alertNotification(alert savedAlert, matches []timelineTweet) notification {
+	n := notification{Title: "x-cli alert: " + alert.Query}
+
+	lines := []string{fmt.Sprintf("%d new match(es)", len(matches))}
+	for i, t := range matches {
+		if i < alertPreviewTweets {
+			lines = append(lines, fmt.Sprintf("@%s: %s", t.Username, truncateText(strings.ReplaceAll(t.Text, "\n", " "), 100)))
+		}
+		n.Tweets = append(n.Tweets, notificationTweet{
+			ID:        t.ID,
+			Text:      t.Text,
+			Username:  t.Username,
+			URL:       tweetURL(t.Username, t.ID),
+			CreatedAt: &t.CreatedAt,
+		})
+	}
+	if len(matches) > alertPreviewTweets {
+		lines = append(lines, fmt.Sprintf("…and %d more", len(matches)-alertPreviewTweets))
+	}
+	n.Text = strings.Join(lines, "\n")
+	return n
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/kalikim/x-cli/config"
+	"github.com/spf13/cobra"
+)
+
+const (
+	githubAPIBase         = "https://api.github.com"
+	defaultGitHubTemplate = "🚀 {{.Tag}} is out!\n\n{{.Changelog}}\n\n{{.URL}}"
+)
+
+type githubRelease struct {
+	TagName string        `json:"tag_name"`
+	Name    string        `json:"name"`
+	Body    string        `json:"body"`
+	HTMLURL string        `json:"html_url"`
+	Assets  []githubAsset `json:"assets"`
+}
+
+type githubAsset struct {
+	Name        string `json:"name"`
+	DownloadURL string `json:"browser_download_url"`
+}
+
+func newAnnounceCmd() *cobra.Command {
+	announceCmd := &cobra.Command{
+		Use:   "announce",
+		Short: "Announce releases from external sources",
+	}
+
+	var tmpl, scheduleAt string
+	var thread, dryRun, noFooter, force bool
+
+	githubCmd := &cobra.Command{
+		Use:   "github-release owner/repo tag",
+		Short: "Announce a GitHub release using its release notes",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client := apiClient()
+
+			release, err := fetchGitHubRelease(client, args[0], args[1])
+			if err != nil {
+				return err
+			}
+
+			info := releaseInfo{
+				Tag:       release.TagName,
+				Name:      release.Name,
+				URL:       release.HTMLURL,
+				Changelog: plainReleaseNotes(release.Body),
+			}
+
+			cfg := config.LoadConfig()
+
+			footer := ""
+			if !noFooter {
+				if footer, err = renderFooter(cfg); err != nil {
+					return err
+				}
+			}
+
+			var segments []string
+			if thread {
+				text, err := renderReleaseText(tmpl, info)
+				if err != nil {
+					return err
+				}
+				segments = splitThread(appendFooter(text, footer), maxTweetLength)
+			} else {
+				text, err := renderReleaseTweet(tmpl, info, footer)
+				if err != nil {
+					return err
+				}
+				segments = []string{text}
+			}
+
+			if dryRun {
+				fmt.Println(strings.Join(segments, "\n---\n"))
+				return nil
+			}
+
+			if err := checkPostTexts(cfg, threadRuleTexts(segments), force); err != nil {
+				return err
+			}
+
+			if err := checkWritable(cfg); err != nil {
+				return err
+			}
+			if scheduleAt != "" {
+				return handleScheduledTweet(cfg, "", scheduledTweet{Text: segments[0], Thread: segments[1:]}, scheduleAt, force, false)
+			}
+
+			if err := cfg.Validate(); err != nil {
+				return err
+			}
+
+			ids, err := publishThread(client, cfg, segments, "", mediaOptions{}, tweetExtras{})
+			if err != nil {
+				return err
+			}
+
+			if len(ids) > 1 {
+				say("✅", "Release %s announced in a %d-tweet thread!", info.Tag, len(ids))
+			} else {
+				say("✅", "Release %s announced!", info.Tag)
+			}
+			return nil
+		},
+	}
+
+	githubCmd.Flags().StringVar(&tmpl, "template", defaultGitHubTemplate, "Tweet template ({{.Tag}}, {{.Name}}, {{.URL}}, {{.Changelog}})")
+	githubCmd.Flags().BoolVar(&thread, "thread", false, "Post the full release notes as a thread instead of an excerpt")
+	githubCmd.Flags().StringVarP(&scheduleAt, "schedule", "s", "", "Schedule the announcement instead of posting now")
+	githubCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print the rendered tweet(s) without posting")
+	githubCmd.Flags().BoolVar(&noFooter, "no-footer", false, "Do not append the configured footer")
+	githubCmd.Flags().BoolVar(&force, "force", false, "Post even if the text matches banned content, breaks content rules or the schedule is too close to another tweet")
+
+	announceCmd.AddCommand(githubCmd)
+	return announceCmd
+}
+
+// fetchGitHubRelease looks up a release by tag, or the latest release when tag
+// is empty. GITHUB_TOKEN is used when set, which is required for private
+// repositories and raises rate limits.
+func fetchGitHubRelease(client *http.Client, repo, tag string) (githubRelease, error) {
+	var release githubRelease
+
+	owner, name, ok := strings.Cut(repo, "/")
+	if !ok || owner == "" || name == "" || strings.Contains(name, "/") {
+		return release, fmt.Errorf("invalid repository %q (expected owner/repo)", repo)
+	}
+
+	endpoint := fmt.Sprintf("%s/repos/%s/%s/releases/tags/%s", githubAPIBase, owner, name, tag)
+	if tag == "" {
+		endpoint = fmt.Sprintf("%s/repos/%s/%s/releases/latest", githubAPIBase, owner, name)
+	}
+	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		return release, fmt.Errorf("creating request: %w", err)
+	}
+
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if token := strings.TrimSpace(os.Getenv("GITHUB_TOKEN")); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return release, fmt.Errorf("fetching release: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return release, fmt.Errorf("reading release response: %w", err)
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		if tag == "" {
+			return release, fmt.Errorf("no published releases found in %s", repo)
+		}
+		return release, fmt.Errorf("release %s not found in %s", tag, repo)
+	}
+	if resp.StatusCode >= 300 {
+		return release, fmt.Errorf("github API error (%d): %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	if err := json.Unmarshal(body, &release); err != nil {
+		return release, fmt.Errorf("decoding release: %w", err)
+	}
+	if release.TagName == "" {
+		return release, errors.New("github API returned a release without a tag")
+	}
+
+	return release, nil
+}
+
+var (
+	markdownHeading = regexp.MustCompile(`(?m)^#{1,6}\s*`)
+	markdownBullet  = regexp.MustCompile(`(?m)^\s*[*-]\s+`)
+	markdownEmph    = regexp.MustCompile(`\*\*([^*]+)\*\*|__([^_]+)__`)
+	markdownLink    = regexp.MustCompile(`\[([^\]]+)\]\(([^)]+)\)`)
+)
+
+// plainReleaseNotes converts the Markdown of a release body into plain text
+// that reads well in a tweet.
+func plainReleaseNotes(body string) string {
+	body = strings.ReplaceAll(body, "\r\n", "\n")
+	body = markdownHeading.ReplaceAllString(body, "")
+	body = markdownBullet.ReplaceAllString(body, "• ")
+	body = markdownEmph.ReplaceAllString(body, "$1$2")
+	body = markdownLink.ReplaceAllString(body, "$1")
+	return strings.TrimSpace(body)
+}
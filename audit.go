@@ -0,0 +1,110 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kalikim/x-cli/config"
+)
+
+// auditEntry is one line of the audit log. It never contains the payload or
+// credentials, only a hash of what was sent.
+type auditEntry struct {
+	Time        time.Time `json:"time"`
+	Method      string    `json:"method"`
+	Endpoint    string    `json:"endpoint"`
+	UserID      string    `json:"user_id,omitempty"`
+	PayloadHash string    `json:"payload_sha256"`
+	Status      int       `json:"status"`
+	Error       string    `json:"error,omitempty"`
+}
+
+func auditLogPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".x-cli", "audit.ndjson"), nil
+}
+
+// recordAudit appends a write API call to the audit log when audit_log is
+// enabled. Failures are reported but never stop the call itself.
+func recordAudit(cfg config.Config, method, endpoint string, payload []byte, status int, callErr error) {
+	if !cfg.AuditLog {
+		return
+	}
+
+	sum := sha256.Sum256(payload)
+	entry := auditEntry{
+		Time:        time.Now().UTC(),
+		Method:      method,
+		Endpoint:    auditEndpoint(endpoint),
+		UserID:      accessTokenUserID(cfg.AccessToken),
+		PayloadHash: hex.EncodeToString(sum[:]),
+		Status:      status,
+	}
+	if callErr != nil {
+		entry.Error = callErr.Error()
+	}
+
+	if err := appendAudit(entry); err != nil {
+		log.Print(decorate("⚠️", fmt.Sprintf("Failed to write audit log: %v", err)))
+	}
+}
+
+func appendAudit(entry auditEntry) error {
+	path, err := auditLogPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+// auditEndpoint drops the query string, which may carry request data.
+func auditEndpoint(endpoint string) string {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return endpoint
+	}
+	u.RawQuery = ""
+	return u.String()
+}
+
+// accessTokenUserID returns the numeric account ID that prefixes OAuth 1.0a
+// access tokens; the rest of the token is secret and never logged.
+func accessTokenUserID(token string) string {
+	id, _, found := strings.Cut(token, "-")
+	if !found {
+		return ""
+	}
+	for _, r := range id {
+		if r < '0' || r > '9' {
+			return ""
+		}
+	}
+	return id
+}
@@ -0,0 +1,359 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kalikim/x-cli/config"
+	"github.com/spf13/cobra"
+)
+
+// Batch operations.
+const (
+	batchPost     = "post"
+	batchSchedule = "schedule"
+	batchDelete   = "delete"
+)
+
+// batchOp is one operation read by `batch`. ID is echoed back so callers
+// can match results to requests.
+type batchOp struct {
+	ID          string   `json:"id,omitempty"`
+	Op          string   `json:"op"`
+	Text        string   `json:"text,omitempty"`
+	Image       string   `json:"image,omitempty"`
+	At          string   `json:"at,omitempty"`
+	Queue       string   `json:"queue,omitempty"`
+	Profile     string   `json:"profile,omitempty"`
+	Labels      []string `json:"labels,omitempty"`
+	Campaign    string   `json:"campaign,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	NoFooter    bool     `json:"no_footer,omitempty"`
+	Force       bool     `json:"force,omitempty"`
+	TweetID     string   `json:"tweet_id,omitempty"`
+	ScheduledID string   `json:"scheduled_id,omitempty"`
+}
+
+// batchResult is written for every operation, in input order.
+type batchResult struct {
+	ID           string     `json:"id,omitempty"`
+	Op           string     `json:"op,omitempty"`
+	OK           bool       `json:"ok"`
+	TweetID      string     `json:"tweet_id,omitempty"`
+	URL          string     `json:"url,omitempty"`
+	ScheduledID  string     `json:"scheduled_id,omitempty"`
+	ScheduledFor *time.Time `json:"scheduled_for,omitempty"`
+	Error        string     `json:"error,omitempty"`
+	ExitCode     int        `json:"exit_code,omitempty"`
+}
+
+func newBatchCmd() *cobra.Command {
+	var resume bool
+	cmd := &cobra.Command{
+		Use:   "batch <file|->",
+		Short: "Run post, schedule and delete operations from a JSON stream",
+		Long: "Read JSON objects, one operation each, from a file or stdin (-) and write\n" +
+			"one JSON result per line to stdout as each completes. Progress messages go\n" +
+			"to stderr. Operations:\n\n" +
+			"  {\"op\": \"post\", \"text\": \"...\", \"image\": \"...\"}\n" +
+			"  {\"op\": \"schedule\", \"text\": \"...\", \"at\": \"2025-07-01 09:00\"}\n" +
+			"  {\"op\": \"delete\", \"tweet_id\": \"...\"}        delete a posted tweet\n" +
+			"  {\"op\": \"delete\", \"scheduled_id\": \"...\"}    cancel a scheduled tweet\n\n" +
+			"Every operation also accepts id (echoed in its result), queue, profile,\n" +
+			"labels, campaign, tags, no_footer and force.\n\n" +
+			"When reading a file, progress is saved in ~/.x-cli/checkpoints/ after every\n" +
+			"operation. If X rate-limits the batch it stops; run the same command with\n" +
+			"--resume to skip the operations already done.",
+		Example: `  echo '{"id":"1","op":"post","text":"Hello"}' | x-cli batch -
+  x-cli batch ops.ndjson > results.ndjson
+  x-cli batch ops.ndjson --resume >> results.ndjson`,
+		Args: cobra.ExactArgs(1),
+		// Failed operations are reported in the results; usage text on
+		// stderr would only clutter a worker's logs.
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			in := os.Stdin
+			var cp *checkpoint
+			if args[0] != "-" {
+				f, err := os.Open(args[0])
+				if err != nil {
+					return invalidInput(err)
+				}
+				defer f.Close()
+				in = f
+				job, err := batchJob(f)
+				if err != nil {
+					return err
+				}
+				if cp, err = batchCheckpoint(job, resume); err != nil {
+					return err
+				}
+			} else if resume {
+				return invalidInput(errors.New("--resume needs a file; a batch read from stdin is not checkpointed"))
+			}
+
+			// Results own stdout; everything the operations print goes to
+			// stderr so the output stays one JSON object per line.
+			out := os.Stdout
+			os.Stdout = os.Stderr
+			defer func() { os.Stdout = out }()
+
+			return runBatch(in, out, cp)
+		},
+	}
+	cmd.Flags().BoolVar(&resume, "resume", false, "Skip the operations a rate-limited or interrupted run of this file already did")
+	return cmd
+}
+
+// batchJob names the checkpoint of a batch file. The file's size and
+// modification time are part of it, so an edited file starts over.
+func batchJob(f *os.File) (string, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	path, err := filepath.Abs(f.Name())
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%d\x00%d", path, info.Size(), info.ModTime().UnixNano())))
+	return "batch-" + hex.EncodeToString(sum[:8]), nil
+}
+
+// batchCheckpoint returns the checkpoint a batch continues from: the saved
+// one with --resume, otherwise a fresh one.
+func batchCheckpoint(job string, resume bool) (*checkpoint, error) {
+	saved, err := loadCheckpoint(job)
+	if err != nil {
+		return nil, fmt.Errorf("loading batch checkpoint: %w", err)
+	}
+	switch {
+	case saved != nil && resume:
+		fmt.Fprintln(os.Stderr, decorate("⏩", fmt.Sprintf("Resuming the batch after %d operation(s)", saved.Completed)))
+		return saved, nil
+	case saved != nil:
+		fmt.Fprintln(os.Stderr, decorate("⚠️", fmt.Sprintf("Starting over; an interrupted batch of %d operation(s) is replaced (use --resume to continue it)", saved.Completed)))
+	case resume:
+		fmt.Fprintln(os.Stderr, decorate("📭", "No interrupted batch to resume; starting from the beginning"))
+	}
+	return &checkpoint{Job: job}, nil
+}
+
+// runBatch performs each operation read from in and writes its result to
+// out. A malformed stream stops the batch; failed operations do not. With
+// a checkpoint, operations it records as done are skipped, progress is
+// saved after each operation and a rate limit stops the batch so it can be
+// resumed.
+func runBatch(in io.Reader, out io.Writer, cp *checkpoint) error {
+	cfg := config.LoadConfig()
+	client := apiClient()
+	enc := json.NewEncoder(out)
+	dec := json.NewDecoder(in)
+
+	total, failed := 0, 0
+	for {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err == io.EOF {
+			break
+		} else if err != nil {
+			enc.Encode(batchResult{Error: fmt.Sprintf("reading operation %d: %v", total+1, err), ExitCode: exitCode(invalidInput(err))})
+			return invalidInput(fmt.Errorf("reading operation %d: %w", total+1, err))
+		}
+		total++
+		if cp != nil && total <= cp.Completed {
+			continue
+		}
+
+		result, err := runBatchOp(client, cfg, raw)
+		if cp != nil && rateLimited(err) {
+			// Leave this operation undone so --resume retries it.
+			if err := cp.save("", total-1, nil); err != nil {
+				return fmt.Errorf("saving batch checkpoint: %w", err)
+			}
+			fmt.Fprintln(os.Stderr, decorate("💾", fmt.Sprintf("Rate limited at operation %d; run the same command with --resume once the limit resets to continue", total)))
+			return fmt.Errorf("operation %d: %w", total, err)
+		}
+		if !result.OK {
+			failed++
+		}
+		if err := enc.Encode(result); err != nil {
+			return fmt.Errorf("writing result: %w", err)
+		}
+		if cp != nil {
+			if err := cp.save("", total, nil); err != nil {
+				return fmt.Errorf("saving batch checkpoint: %w", err)
+			}
+		}
+	}
+
+	if cp != nil {
+		if err := clearCheckpoint(cp.Job); err != nil {
+			log.Print(decorate("⚠️", fmt.Sprintf("Failed to remove the batch checkpoint: %v", err)))
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d operation(s) failed", failed, total)
+	}
+	return nil
+}
+
+// runBatchOp decodes and performs one operation. The error is the one its
+// failed result reports.
+func runBatchOp(client *http.Client, cfg config.Config, raw json.RawMessage) (batchResult, error) {
+	var op batchOp
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&op); err != nil {
+		// Echo the ID of an operation that is valid JSON but the wrong shape.
+		json.Unmarshal(raw, &op)
+		err = invalidInput(fmt.Errorf("invalid operation: %w", err))
+		return batchFailure(op, err), err
+	}
+
+	result, err := performBatchOp(client, cfg, op)
+	if err != nil {
+		return batchFailure(op, err), err
+	}
+	result.ID, result.Op, result.OK = op.ID, op.Op, true
+	return result, nil
+}
+
+func batchFailure(op batchOp, err error) batchResult {
+	return batchResult{ID: op.ID, Op: op.Op, Error: err.Error(), ExitCode: exitCode(err)}
+}
+
+func performBatchOp(client *http.Client, cfg config.Config, op batchOp) (batchResult, error) {
+	if err := validateQueueName(op.Queue); err != nil {
+		return batchResult{}, err
+	}
+	cfg, err := configForTweet(cfg, op.Queue, op.Profile)
+	if err != nil {
+		return batchResult{}, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return batchResult{}, err
+	}
+
+	switch op.Op {
+	case batchPost, batchSchedule:
+		if err := checkWritable(cfg); err != nil {
+			return batchResult{}, err
+		}
+		tweet, err := batchTweet(cfg, op)
+		if err != nil {
+			return batchResult{}, err
+		}
+
+		if op.Op == batchSchedule {
+			if op.At == "" {
+				return batchResult{}, invalidInput(errors.New("schedule needs at"))
+			}
+			tweet, err := scheduleTweet(cfg, op.Queue, tweet, op.At, op.Force, false)
+			if err != nil {
+				return batchResult{}, err
+			}
+			return batchResult{ScheduledID: tweet.ID, ScheduledFor: &tweet.ScheduleTime}, nil
+		}
+
+		if op.At != "" {
+			return batchResult{}, invalidInput(errors.New("at only applies to schedule"))
+		}
+		tweetID, err := publishTweet(client, cfg, tweet.Text, tweet.Image, mediaOptions{}, tweetExtras{})
+		if err != nil {
+			return batchResult{}, err
+		}
+		if err := tagHistory([]string{tweetID}, tweet.Labels, tweet.Campaign); err != nil {
+			say("⚠️", "Failed to tag tweet in history: %v", err)
+		}
+		return batchResult{TweetID: tweetID, URL: tweetURL("", tweetID)}, nil
+
+	case batchDelete:
+		switch {
+		case (op.TweetID == "") == (op.ScheduledID == ""):
+			return batchResult{}, invalidInput(errors.New("delete needs either tweet_id or scheduled_id"))
+		case op.ScheduledID != "":
+			if err := checkAllowed(cfg, actionCancel); err != nil {
+				return batchResult{}, err
+			}
+			if err := cancelScheduledTweet(op.Queue, op.ScheduledID); err != nil {
+				return batchResult{}, err
+			}
+			return batchResult{ScheduledID: op.ScheduledID}, nil
+		}
+		if !tweetIDPattern.MatchString(op.TweetID) {
+			return batchResult{}, invalidInput(fmt.Errorf("invalid tweet_id %q", op.TweetID))
+		}
+		if err := checkWritable(cfg); err != nil {
+			return batchResult{}, err
+		}
+		if err := deleteTweet(client, cfg, op.TweetID); err != nil {
+			return batchResult{}, err
+		}
+		return batchResult{TweetID: op.TweetID}, nil
+	}
+
+	return batchResult{}, invalidInput(fmt.Errorf("unknown op %q (use post, schedule or delete)", op.Op))
+}
+
+// batchTweet checks a post or schedule operation the way the root command
+// checks its flags, minus the interactive lint and spell checks.
+func batchTweet(cfg config.Config, op batchOp) (scheduledTweet, error) {
+	text := strings.TrimSpace(op.Text)
+	if text == "" {
+		return scheduledTweet{}, invalidInput(errors.New("tweet text cannot be empty"))
+	}
+	if len(op.Tags) > 0 {
+		tags, err := hashtagSetTags(cfg, strings.Join(op.Tags, ","))
+		if err != nil {
+			return scheduledTweet{}, err
+		}
+		if text, err = applyHashtags(text, tags); err != nil {
+			return scheduledTweet{}, err
+		}
+	}
+	if !op.NoFooter {
+		withFooter, err := applyFooter(cfg, text)
+		if err != nil {
+			return scheduledTweet{}, err
+		}
+		text = withFooter
+	}
+	if err := validateTweetText(text); err != nil {
+		return scheduledTweet{}, err
+	}
+	if op.Image != "" {
+		if err := validateMedia(op.Image); err != nil {
+			return scheduledTweet{}, err
+		}
+	}
+	labels, err := parseLabels(strings.Join(op.Labels, ","))
+	if err != nil {
+		return scheduledTweet{}, err
+	}
+	if err := validateCampaignName(op.Campaign); err != nil {
+		return scheduledTweet{}, err
+	}
+
+	if !op.Force {
+		if err := checkBannedContent(cfg, text); err != nil {
+			return scheduledTweet{}, err
+		}
+	}
+	if _, err := checkContentRules(cfg, []ruleText{{"", text}}, op.Force); err != nil {
+		return scheduledTweet{}, err
+	}
+
+	return scheduledTweet{Text: text, Image: op.Image, Profile: op.Profile, Labels: labels, Campaign: op.Campaign}, nil
+}
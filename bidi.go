@@ -0,0 +1,80 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Unicode bidirectional isolates: text between them is laid out on its own,
+// in the direction of its first strong character.
+const (
+	firstStrongIsolate    = "\u2068"
+	popDirectionalIsolate = "\u2069"
+)
+
+// rtlScripts are the scripts written right to left that tweets commonly
+// use.
+var rtlScripts = []*unicode.RangeTable{unicode.Arabic, unicode.Hebrew, unicode.Syriac, unicode.Thaana, unicode.Nko}
+
+// isRTLText reports whether the first letter of text belongs to a
+// right-to-left script, which makes it the text's direction.
+func isRTLText(text string) bool {
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		for _, script := range rtlScripts {
+			if unicode.Is(script, r) {
+				return true
+			}
+		}
+		return false
+	}
+	return false
+}
+
+// isBidiControl reports whether r is an invisible character that changes
+// the direction of the text around it.
+func isBidiControl(r rune) bool {
+	return r == '\u061c' || r == '\u200e' || r == '\u200f' ||
+		(r >= '\u202a' && r <= '\u202e') || (r >= '\u2066' && r <= '\u2069')
+}
+
+// countBidiControls returns how many direction control characters text has.
+func countBidiControls(text string) int {
+	n := 0
+	for _, r := range text {
+		if isBidiControl(r) {
+			n++
+		}
+	}
+	return n
+}
+
+// isolateBidi prepares text for a line of terminal output. Right-to-left
+// text, and text with direction controls, is wrapped in an isolate so a
+// terminal that lays out Arabic or Hebrew keeps it, and any override left
+// open inside it, from reordering the rest of the line. Other text is
+// returned unchanged. The isolates are for display only; never post them.
+func isolateBidi(text string) string {
+	if !isRTLText(text) && !strings.ContainsFunc(text, isBidiControl) {
+		return text
+	}
+	return firstStrongIsolate + text + popDirectionalIsolate
+}
+
+// completeRunes drops a multi-byte character that has only partly arrived
+// from the end of buf, as happens while a terminal delivers Arabic or
+// Hebrew input one byte at a time.
+func completeRunes(buf []byte) []byte {
+	for i := len(buf) - 1; i >= 0 && i >= len(buf)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(buf[i]) {
+			if !utf8.FullRune(buf[i:]) {
+				return buf[:i]
+			}
+			break
+		}
+	}
+	return buf
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+)
+
+// openBrowser opens url in the default browser. $BROWSER, when set, is used
+// instead of the platform's opener.
+func openBrowser(url string) error {
+	var name string
+	var args []string
+
+	switch {
+	case os.Getenv("BROWSER") != "":
+		name = os.Getenv("BROWSER")
+	case runtime.GOOS == "darwin":
+		name = "open"
+	case runtime.GOOS == "windows":
+		name, args = "rundll32", []string{"url.dll,FileProtocolHandler"}
+	default:
+		name = "xdg-open"
+	}
+
+	bin, err := exec.LookPath(name)
+	if err != nil {
+		return fmt.Errorf("no browser opener found (%s); open %s manually", name, url)
+	}
+
+	cmd := exec.Command(bin, append(args, url)...)
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("starting %s: %w", name, err)
+	}
+	// The opener hands off to the browser and exits; don't leave a zombie.
+	go cmd.Wait()
+	return nil
+}
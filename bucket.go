@@ -0,0 +1,361 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kalikim/x-cli/config"
+)
+
+// Storage backends.
+const (
+	storageS3  = "s3"
+	storageGCS = "gcs"
+)
+
+const bucketTimeout = 30 * time.Second
+
+// bucketStore reads and writes stores as objects over the S3 API, which
+// Google Cloud Storage also speaks with HMAC keys. Requests are signed
+// with AWS Signature Version 4.
+type bucketStore struct {
+	backend      string
+	endpoint     *url.URL
+	bucket       string
+	prefix       string
+	region       string
+	accessKey    string
+	secretKey    string
+	sessionToken string
+	client       *http.Client
+}
+
+func newBucketStore(s config.Storage) (*bucketStore, error) {
+	b := &bucketStore{
+		backend:   s.Backend,
+		bucket:    s.Bucket,
+		prefix:    strings.Trim(s.Prefix, "/"),
+		region:    s.Region,
+		accessKey: firstNonEmpty(os.Getenv("XCLI_STORAGE_ACCESS_KEY_ID"), s.AccessKeyID),
+		secretKey: firstNonEmpty(os.Getenv("XCLI_STORAGE_SECRET_ACCESS_KEY"), s.SecretAccessKey),
+		client:    &http.Client{Timeout: bucketTimeout},
+	}
+	if b.bucket == "" {
+		return nil, errors.New("bucket is not set")
+	}
+
+	endpoint := s.Endpoint
+	switch s.Backend {
+	case storageS3:
+		if b.accessKey == "" && b.secretKey == "" {
+			b.accessKey = os.Getenv("AWS_ACCESS_KEY_ID")
+			b.secretKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+			b.sessionToken = os.Getenv("AWS_SESSION_TOKEN")
+		}
+		if b.region == "" {
+			b.region = firstNonEmpty(os.Getenv("AWS_REGION"), os.Getenv("AWS_DEFAULT_REGION"), "us-east-1")
+		}
+		if endpoint == "" {
+			endpoint = "https://s3." + b.region + ".amazonaws.com"
+		}
+	case storageGCS:
+		if b.region == "" {
+			b.region = "auto"
+		}
+		if endpoint == "" {
+			endpoint = "https://storage.googleapis.com"
+		}
+	default:
+		return nil, fmt.Errorf("unknown backend %q (use s3 or gcs)", s.Backend)
+	}
+	if b.accessKey == "" || b.secretKey == "" {
+		return nil, errors.New("no access key; set access_key_id and secret_access_key, or XCLI_STORAGE_ACCESS_KEY_ID and XCLI_STORAGE_SECRET_ACCESS_KEY")
+	}
+
+	u, err := url.Parse(strings.TrimRight(endpoint, "/"))
+	if err != nil || u.Host == "" || (u.Scheme != "https" && u.Scheme != "http") {
+		return nil, fmt.Errorf("invalid endpoint %q", endpoint)
+	}
+	b.endpoint = u
+	return b, nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func (b *bucketStore) key(name string) string {
+	if b.prefix == "" {
+		return name
+	}
+	return b.prefix + "/" + name
+}
+
+func (b *bucketStore) url(name string) string {
+	scheme := b.backend
+	if b.backend == storageGCS {
+		scheme = "gs"
+	}
+	return scheme + "://" + b.bucket + "/" + b.key(name)
+}
+
+// get returns the store name and its version, which putIf uses to detect
+// concurrent changes.
+func (b *bucketStore) get(name string) ([]byte, string, error) {
+	resp, body, err := b.do(http.MethodGet, b.key(name), nil, nil, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, "", &fs.PathError{Op: "read", Path: b.url(name), Err: fs.ErrNotExist}
+	default:
+		return nil, "", bucketError(resp, body)
+	}
+
+	if b.backend == storageGCS {
+		return body, resp.Header.Get("x-goog-generation"), nil
+	}
+	return body, resp.Header.Get("ETag"), nil
+}
+
+// put overwrites the store name.
+func (b *bucketStore) put(name string, data []byte) error {
+	return b.write(name, data, nil)
+}
+
+// putIf writes the store name only if it is still at version, as returned
+// by get; an empty version means the store must not exist yet. Otherwise
+// it fails with errStoreConflict.
+func (b *bucketStore) putIf(name string, data []byte, version string) error {
+	header := http.Header{}
+	switch {
+	case b.backend == storageGCS && version == "":
+		header.Set("x-goog-if-generation-match", "0")
+	case b.backend == storageGCS:
+		header.Set("x-goog-if-generation-match", version)
+	case version == "":
+		header.Set("If-None-Match", "*")
+	default:
+		header.Set("If-Match", version)
+	}
+	return b.write(name, data, header)
+}
+
+func (b *bucketStore) write(name string, data []byte, header http.Header) error {
+	if header == nil {
+		header = http.Header{}
+	}
+	header.Set("Content-Type", "application/json")
+	resp, body, err := b.do(http.MethodPut, b.key(name), nil, data, header)
+	if err != nil {
+		return err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusCreated, http.StatusNoContent:
+		return nil
+	case http.StatusPreconditionFailed, http.StatusConflict:
+		return fmt.Errorf("writing %s: %w", b.url(name), errStoreConflict)
+	}
+	return bucketError(resp, body)
+}
+
+// list returns the names of the stores under the prefix.
+func (b *bucketStore) list() ([]string, error) {
+	prefix := ""
+	if b.prefix != "" {
+		prefix = b.prefix + "/"
+	}
+
+	var names []string
+	marker := ""
+	for {
+		query := url.Values{"prefix": {prefix}}
+		if marker != "" {
+			query.Set("marker", marker)
+		}
+		resp, body, err := b.do(http.MethodGet, "", query, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			return nil, bucketError(resp, body)
+		}
+
+		var result struct {
+			IsTruncated bool `xml:"IsTruncated"`
+			Contents    []struct {
+				Key string `xml:"Key"`
+			} `xml:"Contents"`
+		}
+		if err := xml.Unmarshal(body, &result); err != nil {
+			return nil, fmt.Errorf("listing %s: %w", b.bucket, err)
+		}
+		for _, c := range result.Contents {
+			name := strings.TrimPrefix(c.Key, prefix)
+			if !strings.Contains(name, "/") {
+				names = append(names, name)
+			}
+			marker = c.Key
+		}
+		if !result.IsTruncated || len(result.Contents) == 0 {
+			return names, nil
+		}
+	}
+}
+
+// do sends a signed request for key ("" for the bucket itself) and returns
+// the response with its body read.
+func (b *bucketStore) do(method, key string, query url.Values, body []byte, header http.Header) (*http.Response, []byte, error) {
+	u := *b.endpoint
+	u.Path = "/" + b.bucket
+	if key != "" {
+		u.Path += "/" + key
+	}
+	u.RawPath = awsEscape(u.Path, false)
+	u.RawQuery = canonicalQuery(query)
+
+	req, err := http.NewRequest(method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, nil, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	b.sign(req, body, time.Now().UTC())
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp, data, nil
+}
+
+// sign adds an AWS Signature Version 4 Authorization header covering every
+// header set on req.
+func (b *bucketStore) sign(req *http.Request, body []byte, now time.Time) {
+	payloadHash := sha256Hex(body)
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", now.Format("20060102T150405Z"))
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if b.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", b.sessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		awsEscape(req.URL.Path, false),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + b.region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + now.Format("20060102T150405Z") + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	signingKey := hmacSHA256([]byte("AWS4"+b.secretKey), date)
+	for _, part := range []string{b.region, "s3", "aws4_request"} {
+		signingKey = hmacSHA256(signingKey, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		b.accessKey, scope, signedHeaders, signature))
+}
+
+// canonicalQuery encodes query sorted by key, as signing requires.
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, k := range keys {
+		for _, v := range query[k] {
+			parts = append(parts, awsEscape(k, true)+"="+awsEscape(v, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// awsEscape percent-encodes everything but unreserved characters, and "/"
+// unless escapeSlash is set.
+func awsEscape(s string, escapeSlash bool) string {
+	var buf strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '-', c == '_', c == '.', c == '~':
+			buf.WriteByte(c)
+		case c == '/' && !escapeSlash:
+			buf.WriteByte(c)
+		default:
+			fmt.Fprintf(&buf, "%%%02X", c)
+		}
+	}
+	return buf.String()
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// bucketError turns an S3 XML error response into an error.
+func bucketError(resp *http.Response, body []byte) error {
+	var e struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	if xml.Unmarshal(body, &e) == nil && e.Code != "" {
+		return fmt.Errorf("storage returned %s: %s: %s", resp.Status, e.Code, e.Message)
+	}
+	return fmt.Errorf("storage returned %s", resp.Status)
+}
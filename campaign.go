@@ -0,0 +1,248 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/kalikim/x-cli/config"
+	"github.com/spf13/cobra"
+)
+
+// campaignSummary counts a campaign's tweets by state.
+type campaignSummary struct {
+	Name    string
+	Posted  int
+	Pending int
+	Paused  int
+	Failed  int
+}
+
+func validateCampaignName(name string) error {
+	if name != "" && !labelPattern.MatchString(name) {
+		return invalidInput(fmt.Errorf("invalid campaign name %q (use lowercase letters, digits, '-' and '_')", name))
+	}
+	return nil
+}
+
+func newCampaignCmd() *cobra.Command {
+	campaignCmd := &cobra.Command{
+		Use:   "campaign",
+		Short: "Manage groups of tweets created with --campaign",
+	}
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List campaigns with their tweet counts",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			summaries, err := summarizeCampaigns(config.LoadConfig())
+			if err != nil {
+				return err
+			}
+			if len(summaries) == 0 {
+				say("📭", "No campaigns found")
+				return nil
+			}
+
+			for _, s := range summaries {
+				printCampaignSummary(s)
+			}
+			return nil
+		},
+	}
+
+	statusCmd := &cobra.Command{
+		Use:   "status <name>",
+		Short: "Show posted, pending, paused and failed counts for a campaign",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			summaries, err := summarizeCampaigns(config.LoadConfig())
+			if err != nil {
+				return err
+			}
+
+			for _, s := range summaries {
+				if s.Name == args[0] {
+					printCampaignSummary(s)
+					return nil
+				}
+			}
+			return invalidInput(fmt.Errorf("campaign %q not found", args[0]))
+		},
+	}
+
+	var pause bool
+
+	cancelCmd := &cobra.Command{
+		Use:   "cancel <name>",
+		Short: "Remove (or with --pause, pause) every scheduled tweet of a campaign",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			n, err := updateCampaignTweets(config.LoadConfig(), args[0], actionCancel, func(tweet *scheduledTweet) bool {
+				if pause {
+					tweet.Paused = true
+					return true
+				}
+				return false
+			})
+			if err != nil {
+				return err
+			}
+
+			if pause {
+				say("⏸️", "Paused %d scheduled tweet(s) in campaign %s", n, args[0])
+			} else {
+				say("🗑️", "Cancelled %d scheduled tweet(s) in campaign %s", n, args[0])
+			}
+			return nil
+		},
+	}
+	cancelCmd.Flags().BoolVar(&pause, "pause", false, "Pause the tweets instead of removing them")
+
+	resumeCmd := &cobra.Command{
+		Use:   "resume <name>",
+		Short: "Resume every paused tweet of a campaign",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			n, err := updateCampaignTweets(config.LoadConfig(), args[0], actionSchedule, func(tweet *scheduledTweet) bool {
+				tweet.Paused = false
+				return true
+			})
+			if err != nil {
+				return err
+			}
+
+			say("▶️", "Resumed %d scheduled tweet(s) in campaign %s", n, args[0])
+			return nil
+		},
+	}
+
+	campaignCmd.AddCommand(listCmd, statusCmd, cancelCmd, resumeCmd)
+	return campaignCmd
+}
+
+// summarizeCampaigns counts the tweets of every campaign found in the
+// history and the scheduler queues, sorted by name.
+func summarizeCampaigns(cfg config.Config) ([]campaignSummary, error) {
+	byName := map[string]*campaignSummary{}
+	get := func(name string) *campaignSummary {
+		if byName[name] == nil {
+			byName[name] = &campaignSummary{Name: name}
+		}
+		return byName[name]
+	}
+
+	entries, err := loadHistory()
+	if err != nil {
+		return nil, fmt.Errorf("loading history: %w", err)
+	}
+	for _, entry := range entries {
+		if entry.Campaign != "" {
+			get(entry.Campaign).Posted++
+		}
+	}
+
+	tweets, err := loadAllScheduledTweets(cfg)
+	if err != nil {
+		return nil, err
+	}
+	for _, tweet := range tweets {
+		if tweet.Campaign == "" {
+			continue
+		}
+		s := get(tweet.Campaign)
+		switch {
+		case tweet.LastError != "":
+			s.Failed++
+		case tweet.Paused:
+			s.Paused++
+		default:
+			s.Pending++
+		}
+	}
+
+	var summaries []campaignSummary
+	for _, s := range byName {
+		summaries = append(summaries, *s)
+	}
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].Name < summaries[j].Name })
+	return summaries, nil
+}
+
+func printCampaignSummary(s campaignSummary) {
+	say("📣", "%s — posted: %d, pending: %d, paused: %d, failed: %d", s.Name, s.Posted, s.Pending, s.Paused, s.Failed)
+}
+
+// updateCampaignTweets applies fn to every scheduled tweet of the campaign
+// across all queues. Tweets for which fn returns false are removed. Every
+// tweet's profile must allow action and be writable before any is changed.
+// It returns how many tweets were affected.
+func updateCampaignTweets(cfg config.Config, campaign, action string, fn func(*scheduledTweet) bool) (int, error) {
+	if err := validateCampaignName(campaign); err != nil {
+		return 0, err
+	}
+
+	// Leave queues without campaign tweets untouched on disk.
+	var queues []string
+	for _, queue := range knownQueues(cfg) {
+		tweets, err := loadScheduledTweets(queue)
+		if err != nil {
+			return 0, fmt.Errorf("queue %s: %w", queueLabel(queue), err)
+		}
+		matched := filterScheduledByCampaign(tweets, campaign)
+		if len(matched) == 0 {
+			continue
+		}
+		for _, tweet := range matched {
+			qcfg, err := configForTweet(cfg, queue, tweet.Profile)
+			if err != nil {
+				return 0, fmt.Errorf("queue %s: %w", queueLabel(queue), err)
+			}
+			if err := checkAllowed(qcfg, action); err != nil {
+				return 0, err
+			}
+			if err := checkWritable(qcfg); err != nil {
+				return 0, err
+			}
+		}
+		queues = append(queues, queue)
+	}
+
+	total := 0
+	for _, queue := range queues {
+		err := updateScheduledTweets(queue, func(tweets []scheduledTweet) ([]scheduledTweet, error) {
+			var kept []scheduledTweet
+			for _, tweet := range tweets {
+				if tweet.Campaign != campaign {
+					kept = append(kept, tweet)
+					continue
+				}
+				total++
+				if fn(&tweet) {
+					kept = append(kept, tweet)
+				} else {
+					removeScheduledTweetMedia(tweet)
+				}
+			}
+			return kept, nil
+		})
+		if err != nil {
+			return total, fmt.Errorf("queue %s: %w", queueLabel(queue), err)
+		}
+	}
+
+	if total == 0 {
+		return 0, invalidInput(errors.New("no scheduled tweets in campaign " + campaign))
+	}
+	return total, nil
+}
+
+func filterScheduledByCampaign(tweets []scheduledTweet, campaign string) []scheduledTweet {
+	var kept []scheduledTweet
+	for _, tweet := range tweets {
+		if tweet.Campaign == campaign {
+			kept = append(kept, tweet)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// cardFetchLimit caps how much of a page is read; card metadata lives in the
+// document head.
+const cardFetchLimit = 1 << 20
+
+var (
+	metaTagPattern  = regexp.MustCompile(`(?is)<meta\s[^>]*>`)
+	attrPattern     = regexp.MustCompile(`(?is)([a-z:_-]+)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s>]+))`)
+	titleTagPattern = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+)
+
+// cardPreview is the link preview X is expected to render for a URL.
+type cardPreview struct {
+	URL         string
+	Card        string
+	Title       string
+	Description string
+	Image       string
+	ImageAlt    string
+	Site        string
+	Warnings    []string
+}
+
+func newCardCheckCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "card-check <url>",
+		Short: "Show the link preview card X will render for a URL",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client := apiClient()
+
+			preview, err := fetchCardPreview(client, args[0])
+			if err != nil {
+				return err
+			}
+
+			printCardPreview(preview)
+			return nil
+		},
+	}
+}
+
+func fetchCardPreview(client *http.Client, rawURL string) (cardPreview, error) {
+	preview := cardPreview{URL: rawURL}
+
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "https://" + rawURL
+		preview.URL = rawURL
+	}
+
+	req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+	if err != nil {
+		return preview, fmt.Errorf("creating request: %w", err)
+	}
+	// Many sites only serve card metadata to X's crawler.
+	req.Header.Set("User-Agent", "Twitterbot/1.0")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return preview, fmt.Errorf("fetching %s: %w", rawURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return preview, fmt.Errorf("fetching %s: HTTP %d", rawURL, resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, cardFetchLimit))
+	if err != nil {
+		return preview, fmt.Errorf("reading page: %w", err)
+	}
+
+	meta := parseMetaTags(string(body))
+	first := func(keys ...string) string {
+		for _, k := range keys {
+			if v := meta[k]; v != "" {
+				return v
+			}
+		}
+		return ""
+	}
+
+	preview.Card = first("twitter:card")
+	preview.Title = first("twitter:title", "og:title")
+	preview.Description = first("twitter:description", "og:description", "description")
+	preview.Image = first("twitter:image", "twitter:image:src", "og:image", "og:image:url")
+	preview.ImageAlt = first("twitter:image:alt", "og:image:alt")
+	preview.Site = first("twitter:site")
+
+	if preview.Title == "" {
+		if m := titleTagPattern.FindStringSubmatch(string(body)); m != nil {
+			preview.Title = strings.TrimSpace(html.UnescapeString(m[1]))
+		}
+	}
+
+	if preview.Card == "" {
+		preview.Warnings = append(preview.Warnings, "no twitter:card tag; X falls back to a summary card if og: tags are present")
+		if preview.Title != "" {
+			preview.Card = "summary"
+		}
+	}
+	if preview.Title == "" {
+		preview.Warnings = append(preview.Warnings, "no title found; X will not render a card")
+	}
+	if preview.Description == "" {
+		preview.Warnings = append(preview.Warnings, "no description found")
+	}
+
+	if preview.Image == "" {
+		preview.Warnings = append(preview.Warnings, "no image found; the card will be text-only")
+	} else {
+		base := resp.Request.URL
+		if ref, err := url.Parse(preview.Image); err == nil {
+			if !ref.IsAbs() {
+				preview.Warnings = append(preview.Warnings, "image URL is relative; X requires an absolute URL")
+			}
+			preview.Image = base.ResolveReference(ref).String()
+		}
+		if warning := checkCardImage(client, preview.Image); warning != "" {
+			preview.Warnings = append(preview.Warnings, warning)
+		}
+		if preview.ImageAlt == "" {
+			preview.Warnings = append(preview.Warnings, "image has no alt text (twitter:image:alt)")
+		}
+	}
+
+	return preview, nil
+}
+
+// parseMetaTags collects name/property → content pairs from <meta> tags.
+// Keys are lowercased; the first occurrence of a key wins.
+func parseMetaTags(page string) map[string]string {
+	meta := map[string]string{}
+
+	for _, tag := range metaTagPattern.FindAllString(page, -1) {
+		attrs := map[string]string{}
+		for _, m := range attrPattern.FindAllStringSubmatch(tag, -1) {
+			attrs[strings.ToLower(m[1])] = m[2] + m[3] + m[4]
+		}
+
+		key := attrs["property"]
+		if key == "" {
+			key = attrs["name"]
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		if key == "" {
+			continue
+		}
+
+		if _, exists := meta[key]; !exists {
+			meta[key] = strings.TrimSpace(html.UnescapeString(attrs["content"]))
+		}
+	}
+
+	return meta
+}
+
+// checkCardImage verifies that the card image is reachable and is an image,
+// returning a warning or "".
+func checkCardImage(client *http.Client, imageURL string) string {
+	req, err := http.NewRequest(http.MethodHead, imageURL, nil)
+	if err != nil {
+		return fmt.Sprintf("image URL is invalid: %v", err)
+	}
+	req.Header.Set("User-Agent", "Twitterbot/1.0")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Sprintf("image could not be fetched: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return fmt.Sprintf("image returned HTTP %d", resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "" && !strings.HasPrefix(ct, "image/") {
+		return fmt.Sprintf("image has content type %q", ct)
+	}
+	if resp.ContentLength > 5<<20 {
+		return fmt.Sprintf("image is %d bytes; X ignores card images over 5MB", resp.ContentLength)
+	}
+
+	return ""
+}
+
+func printCardPreview(p cardPreview) {
+	orNone := func(s string) string {
+		if s == "" {
+			return "(none)"
+		}
+		return s
+	}
+
+	say("🔗", "URL: %s", p.URL)
+	fmt.Printf("Card: %s\n", orNone(p.Card))
+	fmt.Printf("Title: %s\n", orNone(p.Title))
+	fmt.Printf("Description: %s\n", orNone(p.Description))
+	fmt.Printf("Image: %s\n", orNone(p.Image))
+	if p.ImageAlt != "" {
+		fmt.Printf("Image alt: %s\n", p.ImageAlt)
+	}
+	if p.Site != "" {
+		fmt.Printf("Site: %s\n", p.Site)
+	}
+
+	if len(p.Warnings) == 0 {
+		say("✅", "Card metadata looks good")
+		return
+	}
+
+	fmt.Println()
+	for _, w := range p.Warnings {
+		say("⚠️", "%s", w)
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// checkpoint records how far a paged bulk job got, so --resume can carry on
+// after a rate limit or crash instead of starting over. Items holds what the
+// job has collected so far, for jobs that write their output at the end.
+type checkpoint struct {
+	Job       string          `json:"job"`
+	Cursor    string          `json:"cursor"`
+	Completed int             `json:"completed"`
+	UpdatedAt time.Time       `json:"updated_at"`
+	Items     json.RawMessage `json:"items,omitempty"`
+}
+
+func checkpointPath(job string) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".x-cli", "checkpoints", job+".json"), nil
+}
+
+// loadCheckpoint returns the saved checkpoint of job, or nil when the job
+// has none.
+func loadCheckpoint(job string) (*checkpoint, error) {
+	path, err := checkpointPath(job)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var cp checkpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	return &cp, nil
+}
+
+// save records that the job finished completed items, has collected items
+// and continues from cursor.
+func (cp *checkpoint) save(cursor string, completed int, items any) error {
+	raw, err := json.Marshal(items)
+	if err != nil {
+		return err
+	}
+	cp.Cursor, cp.Completed, cp.UpdatedAt, cp.Items = cursor, completed, time.Now().UTC(), raw
+
+	path, err := checkpointPath(cp.Job)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data, 0600)
+}
+
+// clearCheckpoint removes the checkpoint of a finished job.
+func clearCheckpoint(job string) error {
+	path, err := checkpointPath(job)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// rateLimited reports whether err is X refusing a request for exceeding a
+// rate limit.
+func rateLimited(err error) bool {
+	var apiErr *apiError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusTooManyRequests
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// readClipboardText returns the text currently on the system clipboard using
+// the first available platform tool.
+func readClipboardText() (string, error) {
+	var tools [][]string
+	switch runtime.GOOS {
+	case "darwin":
+		tools = [][]string{{"pbpaste"}}
+	case "windows":
+		tools = [][]string{{"powershell", "-NoProfile", "-Command", "Get-Clipboard -Raw"}}
+	default:
+		if os.Getenv("WAYLAND_DISPLAY") != "" {
+			tools = append(tools, []string{"wl-paste", "--no-newline"})
+		}
+		tools = append(tools,
+			[]string{"xclip", "-selection", "clipboard", "-o"},
+			[]string{"xsel", "--clipboard", "--output"},
+		)
+	}
+
+	var names []string
+	for _, tool := range tools {
+		names = append(names, tool[0])
+
+		bin, err := exec.LookPath(tool[0])
+		if err != nil {
+			continue
+		}
+
+		out, err := exec.Command(bin, tool[1:]...).Output()
+		if err != nil {
+			return "", fmt.Errorf("%s failed: %w", tool[0], err)
+		}
+
+		text := strings.TrimSpace(string(out))
+		if text == "" {
+			return "", errors.New("clipboard is empty")
+		}
+		return text, nil
+	}
+
+	return "", fmt.Errorf("no clipboard tool found; install one of: %s", strings.Join(names, ", "))
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	clockOffsetFile   = "clock_offset.json"
+	clockOffsetMaxAge = 24 * time.Hour
+)
+
+// clockOffset is how far the X API's clock is ahead of ours, applied to
+// OAuth timestamps. It is learned from "timestamp out of bounds" rejections.
+var (
+	clockOffset     atomic.Int64
+	clockOffsetOnce sync.Once
+)
+
+type savedClockOffset struct {
+	Offset     time.Duration `json:"offset_ns"`
+	MeasuredAt time.Time     `json:"measured_at"`
+}
+
+func clockOffsetPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".x-cli", clockOffsetFile), nil
+}
+
+// oauthNow returns the current time corrected by the cached clock offset.
+func oauthNow() time.Time {
+	clockOffsetOnce.Do(func() {
+		path, err := clockOffsetPath()
+		if err != nil {
+			return
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return
+		}
+		var saved savedClockOffset
+		if json.Unmarshal(data, &saved) == nil && time.Since(saved.MeasuredAt) < clockOffsetMaxAge {
+			clockOffset.Store(int64(saved.Offset))
+		}
+	})
+
+	return time.Now().Add(time.Duration(clockOffset.Load()))
+}
+
+// isTimestampRejected reports whether X refused a request because its OAuth
+// timestamp was too far from the server clock (v1.1 error code 135).
+func isTimestampRejected(status int, body []byte) bool {
+	if status != http.StatusUnauthorized {
+		return false
+	}
+	lower := strings.ToLower(string(body))
+	return strings.Contains(lower, "timestamp out of bounds") || strings.Contains(lower, `"code":135`)
+}
+
+// adjustClock derives the clock offset from a response Date header and caches
+// it. It returns false when the header is unusable or the offset is already
+// known, in which case re-signing would not help.
+func adjustClock(dateHeader string) bool {
+	serverTime, err := http.ParseTime(dateHeader)
+	if err != nil {
+		return false
+	}
+
+	offset := time.Until(serverTime).Round(time.Second)
+	if old := time.Duration(clockOffset.Swap(int64(offset))); (offset - old).Abs() < 2*time.Second {
+		return false
+	}
+
+	if err := saveClockOffset(offset); err != nil {
+		say("⚠️", "Failed to save the clock offset: %v", err)
+	}
+	return true
+}
+
+// saveClockOffset caches offset so later runs sign correctly from their
+// first request.
+func saveClockOffset(offset time.Duration) error {
+	path, err := clockOffsetPath()
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(savedClockOffset{Offset: offset, MeasuredAt: time.Now()})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data, 0600)
+}
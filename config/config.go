@@ -7,6 +7,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 )
 
@@ -15,26 +16,413 @@ type Config struct {
 	APISecret    string `json:"api_secret"`
 	AccessToken  string `json:"access_token"`
 	AccessSecret string `json:"access_secret"`
+
+	// SecretsFile is an age- or SOPS-encrypted JSON file holding the
+	// credentials, decrypted on every run, so the config file itself can
+	// live in a dotfile repo. Its values replace those above.
+	SecretsFile string `json:"secrets_file,omitempty"`
+
+	// CredentialsFrom fetches the credentials from a secrets manager:
+	// vault://<mount>/<path> or aws-sm://<secret-id>. They are kept in
+	// memory for one run; CredentialsCacheTTL, such as "5m", also caches
+	// them on disk for that long.
+	CredentialsFrom     string `json:"credentials_from,omitempty"`
+	CredentialsCacheTTL string `json:"credentials_cache_ttl,omitempty"`
+
+	// SpellcheckDictionary is a word list or hunspell .dic file used for
+	// spell checking when no hunspell/aspell binary is installed.
+	SpellcheckDictionary string `json:"spellcheck_dictionary,omitempty"`
+	// SpellcheckWords are extra words (names, jargon) never flagged.
+	SpellcheckWords []string `json:"spellcheck_words,omitempty"`
+	// SpellcheckStrict refuses tweets with possible misspellings instead
+	// of only warning about them.
+	SpellcheckStrict bool `json:"spellcheck_strict,omitempty"`
+
+	// BannedWords are matched case-insensitively as whole words and
+	// BannedPatterns as regular expressions; matching tweets are refused
+	// unless forced.
+	BannedWords    []string `json:"banned_words,omitempty"`
+	BannedPatterns []string `json:"banned_patterns,omitempty"`
+
+	// ContentRules flags text likely to be throttled or rejected. Profiles
+	// may carry their own rules, e.g. hard errors for brand accounts.
+	ContentRules ContentRules `json:"content_rules,omitempty"`
+
+	// Accessibility checks tweets for images without alt text, videos
+	// without subtitles and hashtags screen readers cannot split into words.
+	Accessibility Accessibility `json:"accessibility,omitempty"`
+
+	// Polish runs the --polish typography pass on every tweet: curly
+	// quotes, em dashes, single spaces and composed accented letters.
+	Polish bool `json:"polish,omitempty"`
+
+	// HashtagSets names groups of hashtags, such as
+	// "golang": "#golang #programming", appended with --tags golang.
+	HashtagSets map[string]string `json:"hashtag_sets,omitempty"`
+
+	// Footer is a text/template appended to every tweet, e.g.
+	// " — posted via x-cli" or campaign hashtags.
+	Footer string `json:"footer,omitempty"`
+
+	// Profiles holds credentials for additional accounts, keyed by name.
+	// The top-level credentials form the default profile.
+	Profiles map[string]Profile `json:"profiles,omitempty"`
+	// Queues configures named scheduler queues.
+	Queues map[string]Queue `json:"queues,omitempty"`
+
+	// ReadOnly refuses every write to X (posts, deletes, likes, DMs, media
+	// uploads), for analytics-only tokens and for trying scripts against
+	// production credentials. It applies to every profile.
+	ReadOnly bool `json:"read_only,omitempty"`
+
+	// Allow lists the actions the default profile may take, such as
+	// "schedule" and "cancel"; see the allow setting of Profile.
+	Allow []string `json:"allow,omitempty"`
+
+	// ProfileName is the profile selected by ForProfile; empty for the
+	// default credentials.
+	ProfileName string `json:"-"`
+
+	// AuditLog appends every write API call to ~/.x-cli/audit.ndjson.
+	AuditLog bool `json:"audit_log,omitempty"`
+
+	// QuietHours is a local time window such as "23:00-07:00" during which
+	// the daemon holds due tweets. Queues may override it.
+	QuietHours string `json:"quiet_hours,omitempty"`
+
+	// MinSpacing is the shortest gap allowed between two scheduled tweets
+	// from the same account, as a Go duration. "0" disables the check.
+	MinSpacing string `json:"min_spacing,omitempty"`
+
+	// LinkCheck requests every link before posting: "off" (the default),
+	// "warn" or "error" to refuse tweets with dead links.
+	LinkCheck string `json:"link_check,omitempty"`
+
+	// KeepEXIF uploads images with their metadata (GPS position, camera
+	// details) instead of stripping it.
+	KeepEXIF bool `json:"keep_exif,omitempty"`
+
+	// UploadRate limits media upload bandwidth, e.g. "500KB/s". Empty means
+	// unlimited; --upload-rate overrides it.
+	UploadRate string `json:"upload_rate,omitempty"`
+
+	// MediaHooks maps a media type such as "image/png", "image/*" or "*" to
+	// a shell command run on a copy of each file before upload, e.g. to
+	// compress it or add a watermark. The copy's path is appended as the
+	// last argument and the command changes the file in place.
+	MediaHooks map[string]string `json:"media_hooks,omitempty"`
+
+	// Watermark stamps a logo on every JPEG and PNG image uploaded;
+	// --watermark overrides it for one tweet.
+	Watermark Watermark `json:"watermark,omitempty"`
+
+	// CaptionOverlay styles the text --caption-overlay draws on images; a
+	// Text here is drawn on every JPEG and PNG image uploaded.
+	CaptionOverlay CaptionOverlay `json:"caption_overlay,omitempty"`
+
+	// UsageStats records each command run and the duration of each API
+	// call in ~/.x-cli/usage.ndjson for `x-cli stats usage`. Nothing is
+	// sent anywhere.
+	UsageStats bool `json:"usage_stats,omitempty"`
+
+	// UserAgentSuffix is appended to x-cli's User-Agent on every request,
+	// e.g. a team name some enterprise proxies require.
+	UserAgentSuffix string `json:"user_agent_suffix,omitempty"`
+
+	// Daemon tunes how often the scheduler daemon polls and retries.
+	Daemon Daemon `json:"daemon,omitempty"`
+
+	// QueueHealth sets when `scheduler list` and the daemon warn that a
+	// queue needs attention.
+	QueueHealth QueueHealth `json:"queue_health,omitempty"`
+
+	// EmailDigest has the scheduler daemon email a summary of posted,
+	// failed and upcoming tweets.
+	EmailDigest EmailDigest `json:"email_digest,omitempty"`
+
+	// SlackWebhook is a Slack incoming webhook URL. When set, the daemon
+	// reports each scheduled tweet posted or failing there, and
+	// --notify-slack does the same for immediate posts.
+	SlackWebhook string `json:"slack_webhook,omitempty"`
+
+	// DiscordWebhook is a Discord channel webhook URL every posted tweet is
+	// mirrored to, with its link and image.
+	DiscordWebhook string `json:"discord_webhook,omitempty"`
+
+	// Webhooks receive a JSON event for each tweet posted or failing; see
+	// `x-cli hooks schema`.
+	Webhooks []Webhook `json:"webhooks,omitempty"`
+
+	// Storage keeps the scheduler queues and history in a bucket instead
+	// of the working directory, so several machines share them.
+	Storage Storage `json:"storage,omitempty"`
+}
+
+// Profile is a set of OAuth 1.0a user credentials for one account.
+type Profile struct {
+	APIKey       string `json:"api_key"`
+	APISecret    string `json:"api_secret"`
+	AccessToken  string `json:"access_token"`
+	AccessSecret string `json:"access_secret"`
+
+	// CredentialsFrom fetches this profile's credentials like the
+	// top-level setting.
+	CredentialsFrom string `json:"credentials_from,omitempty"`
+
+	// ReadOnly refuses writes with this profile only.
+	ReadOnly bool `json:"read_only,omitempty"`
+
+	// Allow, when set, lists the only actions this profile may take: post,
+	// schedule, delete, cancel, like, retweet and dm. It guards shared
+	// brand accounts, e.g. letting interns schedule but not post directly.
+	Allow []string `json:"allow,omitempty"`
+
+	// ContentRules, when set, replaces the top-level rules for this profile.
+	ContentRules *ContentRules `json:"content_rules,omitempty"`
+}
+
+// ContentRules holds the limits checked before posting. Zero values use the
+// built-in defaults and negative values disable a rule. Level is "warn"
+// (the default) or "error".
+type ContentRules struct {
+	MaxHashtags      int     `json:"max_hashtags,omitempty"`
+	MaxMentions      int     `json:"max_mentions,omitempty"`
+	MaxCapsRatio     float64 `json:"max_caps_ratio,omitempty"`
+	MaxRepeatedLinks int     `json:"max_repeated_links,omitempty"`
+	Level            string  `json:"level,omitempty"`
+}
+
+// Accessibility configures the accessibility checks. Level is "warn" (the
+// default), "error" or "off"; Skip names checks to leave out: "alt_text",
+// "subtitles" or "hashtags".
+type Accessibility struct {
+	Level string   `json:"level,omitempty"`
+	Skip  []string `json:"skip,omitempty"`
+}
+
+// Queue ties a named scheduler queue to a profile and optional daily cap.
+// QuietHours replaces the global window for this queue; "off" disables it.
+type Queue struct {
+	Profile    string `json:"profile,omitempty"`
+	MaxPerDay  int    `json:"max_per_day,omitempty"`
+	QuietHours string `json:"quiet_hours,omitempty"`
+}
+
+// Daemon holds scheduler daemon timings as Go durations such as "45s" or
+// "5m". Empty values use the daemon's defaults.
+type Daemon struct {
+	Interval        string `json:"interval,omitempty"`
+	RetryBackoff    string `json:"retry_backoff,omitempty"`
+	MaxRetryBackoff string `json:"max_retry_backoff,omitempty"`
+	StartupJitter   string `json:"startup_jitter,omitempty"`
+	// MaxMedianLateness is the median posting delay that triggers a
+	// warning; "0" turns the warning off.
+	MaxMedianLateness string `json:"max_median_lateness,omitempty"`
+	// StopTimeout bounds how long a stop signal waits for the current
+	// post; empty waits until it is done.
+	StopTimeout string `json:"stop_timeout,omitempty"`
+	// NoticeBefore has the daemon announce each scheduled tweet this long
+	// before posting it, with the command that cancels it. The notice goes
+	// to the Slack webhook and to webhooks receiving tweet.upcoming events;
+	// empty sends none.
+	NoticeBefore string `json:"notice_before,omitempty"`
+	// NoticeDesktop also shows the notice as a desktop notification.
+	NoticeDesktop bool `json:"notice_desktop,omitempty"`
+}
+
+// QueueHealth holds the thresholds of the queue warnings. MaxSize is how
+// many tweets a queue may hold; zero uses the default and a negative value
+// disables the warning. MaxFailing is how long a tweet may keep failing, as
+// a Go duration; empty uses the default and "0" disables it.
+type QueueHealth struct {
+	MaxSize    int    `json:"max_size,omitempty"`
+	MaxFailing string `json:"max_failing,omitempty"`
+}
+
+// Webhook is an endpoint for x-cli events, such as an IFTTT or Zapier
+// catch hook. Events lists the event types it receives; empty means all.
+type Webhook struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events,omitempty"`
+}
+
+// Watermark is a logo composited onto images before upload. It is off
+// while Image is empty. Position is top-left, top-right, bottom-left,
+// bottom-right (the default) or center; Opacity runs from 0 to 1 and
+// defaults to 1.
+type Watermark struct {
+	Image    string  `json:"image,omitempty"`
+	Position string  `json:"position,omitempty"`
+	Opacity  float64 `json:"opacity,omitempty"`
+}
+
+// CaptionOverlay is text drawn across images before upload in the built-in
+// pixel font. It is off while Text is empty. Font is regular or bold (the
+// default); Size is small, medium (the default) or large; Position is top,
+// center or bottom (the default). Color is the text's #rrggbb, white by
+// default, and Background the band behind it as #rrggbb or #rrggbbaa, or
+// "none"; it defaults to translucent black.
+type CaptionOverlay struct {
+	Text       string `json:"text,omitempty"`
+	Font       string `json:"font,omitempty"`
+	Size       string `json:"size,omitempty"`
+	Position   string `json:"position,omitempty"`
+	Color      string `json:"color,omitempty"`
+	Background string `json:"background,omitempty"`
+}
+
+// Storage selects an object-storage bucket for the scheduler and history
+// stores. Backend is "s3" or "gcs"; empty keeps local files. Endpoint
+// overrides the service URL for S3-compatible stores such as MinIO or R2.
+// The access keys default to XCLI_STORAGE_ACCESS_KEY_ID and
+// XCLI_STORAGE_SECRET_ACCESS_KEY, then to the usual AWS variables for s3;
+// gcs uses HMAC keys.
+type Storage struct {
+	Backend         string `json:"backend,omitempty"`
+	Bucket          string `json:"bucket,omitempty"`
+	Prefix          string `json:"prefix,omitempty"`
+	Region          string `json:"region,omitempty"`
+	Endpoint        string `json:"endpoint,omitempty"`
+	AccessKeyID     string `json:"access_key_id,omitempty"`
+	SecretAccessKey string `json:"secret_access_key,omitempty"`
+}
+
+// EmailDigest holds the SMTP settings and timing of the daemon's digest
+// email. The digest is off while To is empty. Every is "1d" (the default)
+// or a number of days or weeks such as "1w"; At is the local time it goes
+// out, "08:00" by default.
+type EmailDigest struct {
+	SMTPHost string   `json:"smtp_host,omitempty"`
+	SMTPPort int      `json:"smtp_port,omitempty"`
+	Username string   `json:"username,omitempty"`
+	Password string   `json:"password,omitempty"`
+	From     string   `json:"from,omitempty"`
+	To       []string `json:"to,omitempty"`
+	Every    string   `json:"every,omitempty"`
+	At       string   `json:"at,omitempty"`
 }
 
 var errConfigNotFound = errors.New("config file not found")
 
+// Warn reports non-fatal problems found while loading the config. Callers
+// may replace it to match their own output style.
+var Warn = func(msg string) {
+	log.Print("⚠️ " + msg)
+}
+
+// CredentialFallback, when set, fills any credential still empty after the
+// config file and environment are read. Offline mode uses it so no real
+// credentials are needed.
+var CredentialFallback string
+
+// Loaded, when set, is called with every config LoadConfig returns, once
+// credentials have been resolved.
+var Loaded func(Config)
+
+// EnvOnly ignores config files, for containers configured entirely through
+// the environment. The config file's JSON may then be passed in XCLI_CONFIG.
+var EnvOnly bool
+
+// envConfigName names XCLI_CONFIG in messages about its contents.
+const envConfigName = "$XCLI_CONFIG"
+
+// ErrMissingCredentials is returned by Validate when any credential is unset.
+var ErrMissingCredentials = errors.New("missing credentials")
+
 func LoadConfig() Config {
-	cfg, err := readConfigFile()
+	cfg, path, err := readConfigFile()
 	switch {
 	case err == nil:
-		// file loaded successfully
+		for _, p := range lastProblems {
+			Warn(p.String() + "; the setting is ignored")
+		}
+		if cfg.SecretsFile != "" {
+			if err := applySecretsFile(&cfg, path); err != nil {
+				// Recorded so a missing-credentials error names the cause.
+				lastProblems = append(lastProblems, Problem{File: path, Message: "secrets_file: " + err.Error()})
+				Warn(fmt.Sprintf("Failed to load secrets file: %v", err))
+			}
+		}
+		if err := applyCredentialProviders(&cfg); err != nil {
+			lastProblems = append(lastProblems, Problem{File: path, Message: "credentials_from: " + err.Error()})
+			Warn(fmt.Sprintf("Failed to fetch credentials: %v", err))
+		}
 	case errors.Is(err, errConfigNotFound):
-		log.Printf("⚠️ No config file found, relying on environment variables")
+		if !EnvOnly {
+			Warn("No config file found, relying on environment variables")
+		}
 	default:
-		log.Printf("⚠️ Failed to read config file: %v", err)
+		Warn(fmt.Sprintf("Failed to read config file: %v", err))
 	}
 
 	applyEnvOverrides(&cfg)
+	if CredentialFallback != "" {
+		for _, field := range []*string{&cfg.APIKey, &cfg.APISecret, &cfg.AccessToken, &cfg.AccessSecret} {
+			if strings.TrimSpace(*field) == "" {
+				*field = CredentialFallback
+			}
+		}
+	}
+	if Loaded != nil {
+		Loaded(cfg)
+	}
+
+	return cfg
+}
 
+// Peek reads the config file like LoadConfig but stays silent when it is
+// missing or broken, for settings needed before a command loads its config.
+func Peek() Config {
+	cfg, _, _ := readConfigFile()
 	return cfg
 }
 
+// Secrets lists the non-empty credentials, passwords and webhook URLs in c
+// and its profiles, for masking in logs.
+func (c Config) Secrets() []string {
+	values := []string{c.APIKey, c.APISecret, c.AccessToken, c.AccessSecret,
+		c.EmailDigest.Password, c.SlackWebhook, c.DiscordWebhook, c.Storage.SecretAccessKey}
+	for _, p := range c.Profiles {
+		values = append(values, p.APIKey, p.APISecret, p.AccessToken, p.AccessSecret)
+	}
+	for _, w := range c.Webhooks {
+		values = append(values, w.URL)
+	}
+
+	var secrets []string
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			secrets = append(secrets, v)
+		}
+	}
+	return secrets
+}
+
+// ForProfile returns a copy of c using the credentials of the named profile.
+// An empty name selects the default (top-level) credentials.
+func (c Config) ForProfile(name string) (Config, error) {
+	if name == "" {
+		return c, nil
+	}
+
+	p, ok := c.Profiles[name]
+	if !ok {
+		return c, fmt.Errorf("unknown profile %q", name)
+	}
+
+	c.APIKey = p.APIKey
+	c.APISecret = p.APISecret
+	c.AccessToken = p.AccessToken
+	c.AccessSecret = p.AccessSecret
+	c.ReadOnly = c.ReadOnly || p.ReadOnly
+	c.Allow = p.Allow
+	c.ProfileName = name
+	if p.ContentRules != nil {
+		c.ContentRules = *p.ContentRules
+	}
+	return c, nil
+}
+
 func (c Config) Validate() error {
 	var missing []string
 
@@ -52,32 +440,73 @@ func (c Config) Validate() error {
 	}
 
 	if len(missing) > 0 {
-		return fmt.Errorf("missing credentials: %s", strings.Join(missing, ", "))
+		err := fmt.Errorf("%w: %s", ErrMissingCredentials, strings.Join(missing, ", "))
+		// A broken file or a misspelled or mistyped top-level key is the
+		// likely cause; say so.
+		var notes []string
+		for _, p := range lastProblems {
+			if !strings.ContainsAny(p.Key, ".[") {
+				notes = append(notes, p.String())
+			}
+		}
+		if len(notes) > 0 {
+			err = fmt.Errorf("%w (%s)", err, strings.Join(notes, "; "))
+		}
+		return err
 	}
 
 	return nil
 }
 
-func readConfigFile() (Config, error) {
+// readConfigFile reads the first config file found and returns its path.
+func readConfigFile() (Config, string, error) {
 	var cfg Config
 
+	if EnvOnly {
+		data := strings.TrimSpace(os.Getenv("XCLI_CONFIG"))
+		if data == "" {
+			return cfg, "", errConfigNotFound
+		}
+		return parseConfig(envConfigName, []byte(data))
+	}
+
 	for _, path := range candidatePaths() {
 		data, err := os.ReadFile(path)
 		if err != nil {
 			if errors.Is(err, os.ErrNotExist) {
 				continue
 			}
-			return cfg, fmt.Errorf("reading %s: %w", path, err)
+			return cfg, path, fmt.Errorf("reading %s: %w", path, err)
 		}
+		return parseConfig(path, data)
+	}
 
-		if err := json.Unmarshal(data, &cfg); err != nil {
-			return cfg, fmt.Errorf("parsing %s: %w", path, err)
-		}
+	return cfg, "", errConfigNotFound
+}
+
+// parseConfig decodes the config file at path, recording its problems.
+func parseConfig(path string, data []byte) (Config, string, error) {
+	var cfg Config
+
+	problems, err := CheckFile(path, data)
+	if err != nil {
+		lastProblems = []Problem{{File: path, Message: "not valid JSON, " + err.Error()}}
+		return cfg, path, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	lastProblems = problems
 
-		return cfg, nil
+	// Values of the wrong type were reported above; the rest still apply.
+	var typeErr *json.UnmarshalTypeError
+	if err := json.Unmarshal(data, &cfg); err != nil && !errors.As(err, &typeErr) {
+		return cfg, path, fmt.Errorf("parsing %s: %w", path, err)
 	}
 
-	return cfg, errConfigNotFound
+	return cfg, path, nil
+}
+
+// Paths lists the config file locations in the order they are searched.
+func Paths() []string {
+	return candidatePaths()
 }
 
 func candidatePaths() []string {
@@ -107,4 +536,16 @@ func applyEnvOverrides(cfg *Config) {
 	if v := strings.TrimSpace(os.Getenv("TWITTER_ACCESS_SECRET")); v != "" {
 		cfg.AccessSecret = v
 	}
+	if v, err := strconv.ParseBool(strings.TrimSpace(os.Getenv("XCLI_READ_ONLY"))); err == nil && v {
+		cfg.ReadOnly = true
+	}
+	if v := os.Getenv("XCLI_SMTP_PASSWORD"); v != "" {
+		cfg.EmailDigest.Password = v
+	}
+	if v := strings.TrimSpace(os.Getenv("XCLI_SLACK_WEBHOOK")); v != "" {
+		cfg.SlackWebhook = v
+	}
+	if v := strings.TrimSpace(os.Getenv("XCLI_DISCORD_WEBHOOK")); v != "" {
+		cfg.DiscordWebhook = v
+	}
 }
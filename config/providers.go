@@ -0,0 +1,309 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// credentialsCacheFile holds fetched credentials in plain text between
+// runs. It is only written when credentials_cache_ttl is set.
+const credentialsCacheFile = "credentials_cache.json"
+
+// credentialProvider fetches the secret at ref, a URI without its scheme,
+// as a JSON object.
+type credentialProvider func(ref string) (json.RawMessage, error)
+
+// credentialProviders maps credentials_from URI schemes to providers.
+var credentialProviders = map[string]credentialProvider{
+	"vault":  fetchVaultSecret,
+	"aws-sm": fetchAWSSecret,
+}
+
+// fetchedCredentials holds secrets fetched by this process, so a secret
+// shared by several profiles is fetched once.
+var fetchedCredentials = map[string]json.RawMessage{}
+
+// cachedCredentials is one secret in the credentials cache.
+type cachedCredentials struct {
+	Secret    json.RawMessage `json:"secret"`
+	FetchedAt time.Time       `json:"fetched_at"`
+}
+
+// applyCredentialProviders fetches the credentials_from secrets of cfg and
+// its profiles and fills in their values. Secrets are only kept in memory
+// unless credentials_cache_ttl opts in to the disk cache.
+func applyCredentialProviders(cfg *Config) error {
+	var ttl time.Duration
+	if cfg.CredentialsCacheTTL != "" {
+		d, err := time.ParseDuration(cfg.CredentialsCacheTTL)
+		if err != nil || d < 0 {
+			return fmt.Errorf("invalid credentials_cache_ttl %q (use a duration such as \"10m\", or \"0\" to disable)", cfg.CredentialsCacheTTL)
+		}
+		ttl = d
+	}
+	if ttl == 0 && (cfg.CredentialsFrom != "" || hasProfileCredentialsFrom(cfg)) {
+		removeCredentialsCache()
+	}
+
+	// A failing provider leaves the credentials it would have filled empty;
+	// the others still apply.
+	var errs []string
+	if cfg.CredentialsFrom != "" {
+		if s, err := fetchCredentials(cfg.CredentialsFrom, ttl); err != nil {
+			errs = append(errs, err.Error())
+		} else {
+			s.apply(cfg)
+		}
+	}
+
+	for name, p := range cfg.Profiles {
+		if p.CredentialsFrom == "" {
+			continue
+		}
+		s, err := fetchCredentials(p.CredentialsFrom, ttl)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("profile %q: %v", name, err))
+			continue
+		}
+		setIfSet(&p.APIKey, s.APIKey)
+		setIfSet(&p.APISecret, s.APISecret)
+		setIfSet(&p.AccessToken, s.AccessToken)
+		setIfSet(&p.AccessSecret, s.AccessSecret)
+		cfg.Profiles[name] = p
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func hasProfileCredentialsFrom(cfg *Config) bool {
+	for _, p := range cfg.Profiles {
+		if p.CredentialsFrom != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// fetchCredentials returns the secret at uri, from the disk cache when ttl
+// is set and it was fetched less than ttl ago.
+func fetchCredentials(uri string, ttl time.Duration) (secrets, error) {
+	scheme, ref, ok := strings.Cut(uri, "://")
+	provider, known := credentialProviders[scheme]
+	if !ok || !known || ref == "" {
+		return secrets{}, fmt.Errorf("invalid credentials_from %q (use vault://<path> or aws-sm://<secret-id>)", uri)
+	}
+
+	raw, ok := fetchedCredentials[uri]
+	if !ok && ttl > 0 {
+		raw, ok = readCredentialsCache(uri, ttl)
+	}
+	if !ok {
+		var err error
+		if raw, err = provider(ref); err != nil {
+			return secrets{}, fmt.Errorf("fetching %s: %w", uri, err)
+		}
+		if ttl > 0 {
+			if err := writeCredentialsCache(uri, raw); err != nil {
+				Warn(fmt.Sprintf("Failed to cache credentials: %v", err))
+			}
+		}
+	}
+	fetchedCredentials[uri] = raw
+
+	// The secret may hold values for other tools; only known keys are used.
+	var s secrets
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return secrets{}, fmt.Errorf("parsing %s: %w", uri, err)
+	}
+	return s, nil
+}
+
+func credentialsCachePath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".x-cli", credentialsCacheFile), nil
+}
+
+// removeCredentialsCache deletes a disk cache left from when it was enabled,
+// so secrets don't stay on disk after opting out.
+func removeCredentialsCache() {
+	path, err := credentialsCachePath()
+	if err != nil {
+		return
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		Warn(fmt.Sprintf("Failed to remove the credentials cache: %v", err))
+	}
+}
+
+func loadCredentialsCache(path string) map[string]cachedCredentials {
+	cache := map[string]cachedCredentials{}
+	if data, err := os.ReadFile(path); err == nil {
+		// A broken cache is refilled from the providers.
+		json.Unmarshal(data, &cache)
+	}
+	return cache
+}
+
+func readCredentialsCache(uri string, ttl time.Duration) (json.RawMessage, bool) {
+	path, err := credentialsCachePath()
+	if err != nil {
+		return nil, false
+	}
+	entry, ok := loadCredentialsCache(path)[uri]
+	if !ok || time.Since(entry.FetchedAt) >= ttl {
+		return nil, false
+	}
+	return entry.Secret, true
+}
+
+// writeCredentialsCache stores secret for uri, dropping entries too old to
+// be used by any reasonable TTL.
+func writeCredentialsCache(uri string, secret json.RawMessage) error {
+	path, err := credentialsCachePath()
+	if err != nil {
+		return err
+	}
+	cache := loadCredentialsCache(path)
+	for key, entry := range cache {
+		if time.Since(entry.FetchedAt) > 24*time.Hour {
+			delete(cache, key)
+		}
+	}
+	cache[uri] = cachedCredentials{Secret: secret, FetchedAt: time.Now()}
+
+	data, err := json.MarshalIndent(cache, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+credentialsCacheFile+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// fetchVaultSecret reads a KV secret such as "secret/x-cli" from the Vault
+// server at VAULT_ADDR with VAULT_TOKEN (or ~/.vault-token). KV version 2
+// is tried first, then version 1.
+func fetchVaultSecret(ref string) (json.RawMessage, error) {
+	addr := strings.TrimRight(strings.TrimSpace(os.Getenv("VAULT_ADDR")), "/")
+	if addr == "" {
+		return nil, errors.New("VAULT_ADDR is not set")
+	}
+	token := strings.TrimSpace(os.Getenv("VAULT_TOKEN"))
+	if token == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			data, _ := os.ReadFile(filepath.Join(home, ".vault-token"))
+			token = strings.TrimSpace(string(data))
+		}
+	}
+	if token == "" {
+		return nil, errors.New("VAULT_TOKEN is not set and there is no ~/.vault-token")
+	}
+
+	ref = strings.Trim(ref, "/")
+	mount, path, ok := strings.Cut(ref, "/")
+	if !ok {
+		return nil, fmt.Errorf("vault path %q needs a mount and a secret, such as secret/x-cli", ref)
+	}
+
+	var v2 struct {
+		Data struct {
+			Data json.RawMessage `json:"data"`
+		} `json:"data"`
+	}
+	err := vaultGet(addr+"/v1/"+mount+"/data/"+path, token, &v2)
+	if err == nil {
+		return v2.Data.Data, nil
+	}
+	if !errors.Is(err, errVaultNotFound) {
+		return nil, err
+	}
+
+	var v1 struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := vaultGet(addr+"/v1/"+ref, token, &v1); err != nil {
+		if errors.Is(err, errVaultNotFound) {
+			return nil, fmt.Errorf("no secret at %s", ref)
+		}
+		return nil, err
+	}
+	return v1.Data, nil
+}
+
+var errVaultNotFound = errors.New("not found")
+
+func vaultGet(endpoint, token string, v any) error {
+	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Vault-Token", token)
+	if ns := strings.TrimSpace(os.Getenv("VAULT_NAMESPACE")); ns != "" {
+		req.Header.Set("X-Vault-Namespace", ns)
+	}
+
+	resp, err := (&http.Client{Timeout: 10 * time.Second}).Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return errVaultNotFound
+	case resp.StatusCode != http.StatusOK:
+		var vaultErr struct {
+			Errors []string `json:"errors"`
+		}
+		if json.Unmarshal(body, &vaultErr) == nil && len(vaultErr.Errors) > 0 {
+			return fmt.Errorf("vault returned %s: %s", resp.Status, strings.Join(vaultErr.Errors, "; "))
+		}
+		return fmt.Errorf("vault returned %s", resp.Status)
+	}
+	return json.Unmarshal(body, v)
+}
+
+// fetchAWSSecret reads a Secrets Manager secret by name or ARN with the aws
+// CLI, which finds credentials and region the usual way: environment,
+// profiles, SSO, or the runner's role.
+func fetchAWSSecret(ref string) (json.RawMessage, error) {
+	out, err := runSecretsTool(nil, "aws", "secretsmanager", "get-secret-value",
+		"--secret-id", ref, "--query", "SecretString", "--output", "text")
+	if err != nil {
+		return nil, err
+	}
+	secret := json.RawMessage(strings.TrimSpace(string(out)))
+	if !json.Valid(secret) || !strings.HasPrefix(string(secret), "{") {
+		return nil, errors.New("the secret is not a JSON object of key/value pairs")
+	}
+	return secret, nil
+}
@@ -0,0 +1,249 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Problem is a mistake found in a config file: a key x-cli does not know or
+// a value of the wrong type. Either way the setting is ignored, which would
+// otherwise only show up later as, say, missing credentials.
+type Problem struct {
+	File    string
+	Key     string
+	Message string
+}
+
+func (p Problem) String() string {
+	if p.Key == "" {
+		return p.File + ": " + p.Message
+	}
+	return fmt.Sprintf("%s: %s: %s", p.File, p.Key, p.Message)
+}
+
+// lastProblems holds the problems of the config file read last.
+var lastProblems []Problem
+
+// Problems returns the problems found in the config file read last.
+func Problems() []Problem {
+	return lastProblems
+}
+
+// CheckFile compares a config file's contents with the Config structure.
+// A syntax error is returned as an error with its line and column.
+func CheckFile(file string, data []byte) ([]Problem, error) {
+	var doc any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		var syntax *json.SyntaxError
+		if errors.As(err, &syntax) {
+			line, col := position(data, syntax.Offset)
+			return nil, fmt.Errorf("line %d, column %d: %w", line, col, err)
+		}
+		return nil, err
+	}
+
+	c := checker{file: file}
+	c.check(doc, reflect.TypeOf(Config{}), "")
+	return c.problems, nil
+}
+
+type checker struct {
+	file     string
+	problems []Problem
+}
+
+func (c *checker) add(key, format string, args ...any) {
+	c.problems = append(c.problems, Problem{File: c.file, Key: key, Message: fmt.Sprintf(format, args...)})
+}
+
+// check walks value, decoded from JSON, alongside the Go type it fills.
+func (c *checker) check(value any, t reflect.Type, key string) {
+	if value == nil {
+		return
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		obj, ok := value.(map[string]any)
+		if !ok {
+			c.add(key, "expected an object, got %s", describeJSON(value))
+			return
+		}
+		fields := jsonFields(t)
+		for _, name := range sortedKeys(obj) {
+			field, ok := lookupField(fields, name)
+			if !ok {
+				msg := fmt.Sprintf("unknown key %q", name)
+				if suggestion := closest(name, fields); suggestion != "" {
+					msg += fmt.Sprintf(" (did you mean %q?)", suggestion)
+				}
+				c.problems = append(c.problems, Problem{File: c.file, Key: joinKey(key, name), Message: msg})
+				continue
+			}
+			c.check(obj[name], field.Type, joinKey(key, name))
+		}
+
+	case reflect.Map:
+		obj, ok := value.(map[string]any)
+		if !ok {
+			c.add(key, "expected an object, got %s", describeJSON(value))
+			return
+		}
+		for _, name := range sortedKeys(obj) {
+			c.check(obj[name], t.Elem(), joinKey(key, name))
+		}
+
+	case reflect.Slice:
+		list, ok := value.([]any)
+		if !ok {
+			c.add(key, "expected a list, got %s", describeJSON(value))
+			return
+		}
+		for i, item := range list {
+			c.check(item, t.Elem(), fmt.Sprintf("%s[%d]", key, i))
+		}
+
+	case reflect.String:
+		if _, ok := value.(string); !ok {
+			c.add(key, "expected a string, got %s", describeJSON(value))
+		}
+
+	case reflect.Bool:
+		if _, ok := value.(bool); !ok {
+			c.add(key, "expected true or false, got %s", describeJSON(value))
+		}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, ok := value.(float64)
+		if !ok {
+			c.add(key, "expected a whole number, got %s", describeJSON(value))
+		} else if n != float64(int64(n)) {
+			c.add(key, "expected a whole number, got %v", n)
+		}
+
+	case reflect.Float32, reflect.Float64:
+		if _, ok := value.(float64); !ok {
+			c.add(key, "expected a number, got %s", describeJSON(value))
+		}
+	}
+}
+
+// jsonFields maps the JSON names of t's fields to the fields.
+func jsonFields(t reflect.Type) map[string]reflect.StructField {
+	fields := map[string]reflect.StructField{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[name] = f
+	}
+	return fields
+}
+
+// lookupField finds name the way encoding/json does: exactly, else
+// ignoring case.
+func lookupField(fields map[string]reflect.StructField, name string) (reflect.StructField, bool) {
+	if f, ok := fields[name]; ok {
+		return f, true
+	}
+	for known, f := range fields {
+		if strings.EqualFold(known, name) {
+			return f, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// closest returns the known key most like name, if any is close enough to
+// be a typo.
+func closest(name string, fields map[string]reflect.StructField) string {
+	best, bestDistance := "", 3
+	for known := range fields {
+		d := editDistance(strings.ToLower(name), known)
+		if d < bestDistance || (d == bestDistance && best != "" && known < best) {
+			best, bestDistance = known, d
+		}
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+func describeJSON(value any) string {
+	switch v := value.(type) {
+	case string:
+		return fmt.Sprintf("the string %q", v)
+	case float64:
+		return fmt.Sprintf("the number %v", v)
+	case bool:
+		return fmt.Sprintf("%v", v)
+	case []any:
+		return "a list"
+	case map[string]any:
+		return "an object"
+	}
+	return "null"
+}
+
+func joinKey(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "." + name
+}
+
+func sortedKeys(obj map[string]any) []string {
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// position converts a byte offset into a 1-based line and column.
+func position(data []byte, offset int64) (int, int) {
+	line, col := 1, 1
+	for i := 0; i < int(offset) && i < len(data); i++ {
+		if data[i] == '\n' {
+			line, col = line+1, 1
+		} else {
+			col++
+		}
+	}
+	return line, col
+}
@@ -0,0 +1,148 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Age file headers, binary and ASCII-armored.
+const (
+	ageHeader      = "age-encryption.org/v1\n"
+	ageArmorHeader = "-----BEGIN AGE ENCRYPTED FILE-----"
+)
+
+// secrets is the decrypted content of a secrets file: the credentials that
+// would otherwise sit in the config file in plain text.
+type secrets struct {
+	APIKey       string `json:"api_key,omitempty"`
+	APISecret    string `json:"api_secret,omitempty"`
+	AccessToken  string `json:"access_token,omitempty"`
+	AccessSecret string `json:"access_secret,omitempty"`
+
+	// Profiles holds credentials for the profiles of the same name in the
+	// config file, or adds profiles that only need credentials.
+	Profiles map[string]secrets `json:"profiles,omitempty"`
+
+	SMTPPassword   string `json:"smtp_password,omitempty"`
+	SlackWebhook   string `json:"slack_webhook,omitempty"`
+	DiscordWebhook string `json:"discord_webhook,omitempty"`
+}
+
+// applySecretsFile decrypts cfg.SecretsFile and fills cfg with its values.
+// A relative path is taken from the directory of the config file at
+// configPath.
+func applySecretsFile(cfg *Config, configPath string) error {
+	path := secretsPath(cfg.SecretsFile, configPath)
+	data, err := decryptSecretsFile(path)
+	if err != nil {
+		return err
+	}
+
+	var s secrets
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&s); err != nil {
+		return fmt.Errorf("parsing decrypted %s: %w", path, err)
+	}
+
+	s.apply(cfg)
+	return nil
+}
+
+// apply fills cfg with the values set in s.
+func (s secrets) apply(cfg *Config) {
+	setIfSet(&cfg.APIKey, s.APIKey)
+	setIfSet(&cfg.APISecret, s.APISecret)
+	setIfSet(&cfg.AccessToken, s.AccessToken)
+	setIfSet(&cfg.AccessSecret, s.AccessSecret)
+	setIfSet(&cfg.EmailDigest.Password, s.SMTPPassword)
+	setIfSet(&cfg.SlackWebhook, s.SlackWebhook)
+	setIfSet(&cfg.DiscordWebhook, s.DiscordWebhook)
+
+	for name, ps := range s.Profiles {
+		if cfg.Profiles == nil {
+			cfg.Profiles = map[string]Profile{}
+		}
+		p := cfg.Profiles[name]
+		setIfSet(&p.APIKey, ps.APIKey)
+		setIfSet(&p.APISecret, ps.APISecret)
+		setIfSet(&p.AccessToken, ps.AccessToken)
+		setIfSet(&p.AccessSecret, ps.AccessSecret)
+		cfg.Profiles[name] = p
+	}
+}
+
+func setIfSet(field *string, value string) {
+	if value = strings.TrimSpace(value); value != "" {
+		*field = value
+	}
+}
+
+func secretsPath(path, configPath string) string {
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, rest)
+		}
+	}
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(filepath.Dir(configPath), path)
+}
+
+// decryptSecretsFile decrypts an age file with the age binary, or anything
+// else with sops, which finds its own keys (SOPS_AGE_KEY_FILE, cloud KMS,
+// PGP).
+func decryptSecretsFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading secrets file: %w", err)
+	}
+
+	if bytes.HasPrefix(data, []byte(ageHeader)) || bytes.HasPrefix(bytes.TrimSpace(data), []byte(ageArmorHeader)) {
+		return decryptAge(path)
+	}
+	return runSecretsTool(nil, "sops", "--decrypt", "--output-type", "json", path)
+}
+
+// decryptAge decrypts path with the identity in XCLI_AGE_KEY, or in the
+// file named by XCLI_AGE_KEY_FILE.
+func decryptAge(path string) ([]byte, error) {
+	if key := strings.TrimSpace(os.Getenv("XCLI_AGE_KEY")); key != "" {
+		return runSecretsTool(strings.NewReader(key+"\n"), "age", "--decrypt", "-i", "-", path)
+	}
+	if keyFile := strings.TrimSpace(os.Getenv("XCLI_AGE_KEY_FILE")); keyFile != "" {
+		return runSecretsTool(nil, "age", "--decrypt", "-i", keyFile, path)
+	}
+	return nil, fmt.Errorf("%s is age-encrypted; set XCLI_AGE_KEY or XCLI_AGE_KEY_FILE to its identity", path)
+}
+
+// runSecretsTool runs an external secrets tool and returns its output, or
+// its error message.
+func runSecretsTool(stdin *strings.Reader, name string, args ...string) ([]byte, error) {
+	if _, err := exec.LookPath(name); err != nil {
+		return nil, fmt.Errorf("%s is not installed; x-cli needs it to read your credentials", name)
+	}
+
+	cmd := exec.Command(name, args...)
+	if stdin != nil {
+		cmd.Stdin = stdin
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			err = errors.New(msg)
+		}
+		return nil, fmt.Errorf("running %s: %w", name, err)
+	}
+	return out, nil
+}
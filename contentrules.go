@@ -0,0 +1,197 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/kalikim/x-cli/config"
+)
+
+// Defaults for rules left at zero in the config.
+const (
+	defaultMaxHashtags      = 3
+	defaultMaxMentions      = 5
+	defaultMaxCapsRatio     = 0.6
+	defaultMaxRepeatedLinks = 1
+
+	// minCapsLetters keeps short texts such as "NASA FTW" from tripping the
+	// caps ratio.
+	minCapsLetters = 20
+)
+
+// contentRuleViolations lists the ways text breaks the configured content
+// rules: too many hashtags or mentions, mostly capital letters, or the same
+// link more than once.
+func contentRuleViolations(rules config.ContentRules, text string) []string {
+	var violations []string
+
+	// Links may contain '#' and '@', so they are left out of the counts.
+	withoutLinks := countURLPattern.ReplaceAllString(text, " ")
+
+	if limit := ruleLimit(rules.MaxHashtags, defaultMaxHashtags); limit >= 0 {
+		hashtags := 0
+		for _, tag := range hashtagPattern.FindAllString(withoutLinks, -1) {
+			if len(tag) > 1 {
+				hashtags++
+			}
+		}
+		if hashtags > limit {
+			violations = append(violations, fmt.Sprintf("%d hashtags (limit %d)", hashtags, limit))
+		}
+	}
+
+	if limit := ruleLimit(rules.MaxMentions, defaultMaxMentions); limit >= 0 {
+		if mentions := len(mentionPattern.FindAllString(withoutLinks, -1)); mentions > limit {
+			violations = append(violations, fmt.Sprintf("%d mentions (limit %d)", mentions, limit))
+		}
+	}
+
+	if limit := rules.MaxCapsRatio; limit >= 0 {
+		if limit == 0 {
+			limit = defaultMaxCapsRatio
+		}
+		if ratio, ok := capsRatio(withoutLinks); ok && ratio > limit {
+			violations = append(violations, fmt.Sprintf("%.0f%% of letters are capitals (limit %.0f%%)", ratio*100, limit*100))
+		}
+	}
+
+	if limit := ruleLimit(rules.MaxRepeatedLinks, defaultMaxRepeatedLinks); limit >= 0 {
+		counts := map[string]int{}
+		var links []string
+		for _, link := range countURLPattern.FindAllString(text, -1) {
+			key := linkKey(link)
+			if counts[key] == 0 {
+				links = append(links, link)
+			}
+			counts[key]++
+		}
+		for _, link := range links {
+			if n := counts[linkKey(link)]; n > limit {
+				violations = append(violations, fmt.Sprintf("%s appears %d times (limit %d)", link, n, limit))
+			}
+		}
+	}
+
+	return violations
+}
+
+// linkKey normalizes a link so trailing punctuation, a trailing slash or
+// case differences do not hide a repeat.
+func linkKey(link string) string {
+	link = strings.TrimRight(link, ".,;:!?)\"'")
+	return strings.TrimRight(strings.ToLower(link), "/")
+}
+
+// ruleLimit resolves a configured limit: zero selects the default and a
+// negative value (returned as -1) disables the rule.
+func ruleLimit(configured, fallback int) int {
+	switch {
+	case configured < 0:
+		return -1
+	case configured == 0:
+		return fallback
+	}
+	return configured
+}
+
+// capsRatio returns the share of capital letters in text, ignoring mentions
+// and hashtags. It reports false when there are too few letters to judge.
+func capsRatio(text string) (float64, bool) {
+	upper, letters := 0, 0
+	for _, field := range strings.Fields(text) {
+		if strings.HasPrefix(field, "@") || strings.HasPrefix(field, "#") {
+			continue
+		}
+		for _, r := range field {
+			if !unicode.IsLetter(r) || (!unicode.IsUpper(r) && !unicode.IsLower(r)) {
+				continue
+			}
+			letters++
+			if unicode.IsUpper(r) {
+				upper++
+			}
+		}
+	}
+
+	if letters < minCapsLetters {
+		return 0, false
+	}
+	return float64(upper) / float64(letters), true
+}
+
+// ruleText is one tweet checked against the content rules. Name prefixes its
+// violations, e.g. "variant B".
+type ruleText struct {
+	name, text string
+}
+
+// checkContentRules applies the content rules to each text. Violations are
+// returned as warnings, or as an error when the rules' level is "error" and
+// force is not set.
+func checkContentRules(cfg config.Config, texts []ruleText, force bool) ([]string, error) {
+	level := cfg.ContentRules.Level
+	switch level {
+	case "", "warn", "error":
+	default:
+		return nil, fmt.Errorf("invalid content_rules level %q (use warn or error)", level)
+	}
+
+	var found []string
+	for _, t := range texts {
+		for _, v := range contentRuleViolations(cfg.ContentRules, t.text) {
+			if t.name != "" {
+				v = t.name + ": " + v
+			}
+			found = append(found, v)
+		}
+	}
+
+	if len(found) > 0 && level == "error" && !force {
+		return nil, invalidInput(errors.New("tweet breaks content rules: " + strings.Join(found, "; ") + " (use --force to post anyway)"))
+	}
+	return found, nil
+}
+
+// checkPostTexts runs the text checks every posting path shares with the
+// root command: each text must fit in a tweet, and together they must pass
+// the banned-content guard and the content rules. Rule warnings are printed.
+// force skips the guard and lets rule errors through as warnings.
+func checkPostTexts(cfg config.Config, texts []ruleText, force bool) error {
+	var joined []string
+	for _, t := range texts {
+		if err := validateTweetText(t.text); err != nil {
+			if t.name != "" {
+				return fmt.Errorf("%s: %w", t.name, err)
+			}
+			return err
+		}
+		joined = append(joined, t.text)
+	}
+	if !force {
+		if err := checkBannedContent(cfg, strings.Join(joined, "\n")); err != nil {
+			return err
+		}
+	}
+	warnings, err := checkContentRules(cfg, texts, force)
+	if err != nil {
+		return err
+	}
+	for _, warning := range warnings {
+		say("⚠️", "%s", warning)
+	}
+	return nil
+}
+
+// threadRuleTexts names the segments of a thread for checkPostTexts.
+func threadRuleTexts(segments []string) []ruleText {
+	texts := make([]ruleText, len(segments))
+	for i, segment := range segments {
+		texts[i] = ruleText{text: segment}
+		if len(segments) > 1 {
+			texts[i].name = fmt.Sprintf("tweet %d", i+1)
+		}
+	}
+	return texts
+}
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/kalikim/x-cli/config"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// controlSocketFile is the scheduler daemon's control socket, next to
+	// the stores it manages.
+	controlSocketFile = "scheduler_daemon.sock"
+	controlTimeout    = 10 * time.Second
+)
+
+// Control operations.
+const (
+	controlEnqueue = "enqueue"
+	controlCancel  = "cancel"
+	controlPostNow = "post-now"
+	controlStatus  = "status"
+)
+
+// errNoDaemon means no daemon answers on the control socket; callers fall
+// back to editing the stores themselves.
+var errNoDaemon = errors.New("no scheduler daemon is listening")
+
+// controlRequest is one line sent to the daemon's control socket.
+type controlRequest struct {
+	Op    string          `json:"op"`
+	Queue string          `json:"queue,omitempty"`
+	ID    string          `json:"id,omitempty"`
+	Tweet *scheduledTweet `json:"tweet,omitempty"`
+}
+
+// controlResponse is the daemon's one-line answer.
+type controlResponse struct {
+	OK     bool                 `json:"ok"`
+	Error  string               `json:"error,omitempty"`
+	Status *controlStatusReport `json:"status,omitempty"`
+}
+
+// controlStatusReport describes the running daemon.
+type controlStatusReport struct {
+	PID       int           `json:"pid"`
+	StartedAt time.Time     `json:"started_at"`
+	LastCheck time.Time     `json:"last_check"`
+	Interval  time.Duration `json:"interval"`
+	Paused    bool          `json:"paused"`
+	Pending   int           `json:"pending"`
+	NextDue   *time.Time    `json:"next_due,omitempty"`
+	Posted    int           `json:"posted"`
+	Failed    int           `json:"failed"`
+}
+
+// controlServer answers requests on the control socket while the daemon
+// runs. Changes wake the daemon's loop so they take effect at once.
+type controlServer struct {
+	cfg      config.Config
+	listener net.Listener
+	wake     chan<- struct{}
+
+	mu     sync.Mutex
+	status controlStatusReport
+}
+
+// startControlServer listens on controlSocketFile. The daemon holds its
+// lock, so a socket file left behind by a crashed daemon is removed first.
+func startControlServer(cfg config.Config, wake chan<- struct{}) (*controlServer, error) {
+	if err := os.Remove(controlSocketFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	listener, err := net.Listen("unix", controlSocketFile)
+	if err != nil {
+		return nil, err
+	}
+	// Anyone who can connect can schedule tweets.
+	if err := os.Chmod(controlSocketFile, 0600); err != nil {
+		listener.Close()
+		return nil, err
+	}
+
+	s := &controlServer{cfg: cfg, listener: listener, wake: wake}
+	go s.serve()
+	return s, nil
+}
+
+// update records the daemon's state for status requests.
+func (s *controlServer) update(status daemonStatus, session *daemonSession) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status.PID = status.PID
+	s.status.StartedAt = status.StartedAt
+	s.status.LastCheck = status.LastCheck
+	s.status.Interval = status.Interval
+	s.status.Posted = session.posted
+	s.status.Failed = session.failed
+}
+
+// Close stops listening and removes the socket file.
+func (s *controlServer) Close() error {
+	return s.listener.Close()
+}
+
+func (s *controlServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				log.Printf("Error accepting control connection: %v", err)
+			}
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *controlServer) handle(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(controlTimeout))
+
+	var req controlRequest
+	if err := json.NewDecoder(bufio.NewReader(conn)).Decode(&req); err != nil {
+		json.NewEncoder(conn).Encode(controlResponse{Error: fmt.Sprintf("reading request: %v", err)})
+		return
+	}
+
+	resp := controlResponse{OK: true}
+	if err := s.perform(req, &resp); err != nil {
+		resp = controlResponse{Error: err.Error()}
+	}
+	if err := json.NewEncoder(conn).Encode(resp); err != nil {
+		log.Printf("Error answering control request: %v", err)
+	}
+}
+
+func (s *controlServer) perform(req controlRequest, resp *controlResponse) error {
+	switch req.Op {
+	case controlEnqueue:
+		if req.Tweet == nil || req.Tweet.ID == "" {
+			return errors.New("enqueue needs a tweet with an ID")
+		}
+		if err := validateQueueName(req.Queue); err != nil {
+			return err
+		}
+		if err := storeScheduledTweet(req.Queue, *req.Tweet); err != nil {
+			return err
+		}
+		say("📥", "Scheduled tweet %s received for %s", req.Tweet.ID, req.Tweet.ScheduleTime.Format("2006-01-02 15:04:05"))
+		notify(s.wake)
+		return nil
+
+	case controlCancel:
+		if err := validateQueueName(req.Queue); err != nil {
+			return err
+		}
+		if _, err := removeScheduledTweet(req.Queue, req.ID); err != nil {
+			return err
+		}
+		say("🗑️", "Scheduled tweet %s cancelled", req.ID)
+		notify(s.wake)
+		return nil
+
+	case controlPostNow:
+		if err := validateQueueName(req.Queue); err != nil {
+			return err
+		}
+		if err := markPostNow(req.Queue, req.ID); err != nil {
+			return err
+		}
+		say("📤", "Scheduled tweet %s to be posted now on request", req.ID)
+		notify(s.wake)
+		return nil
+
+	case controlStatus:
+		report, err := s.report(time.Now())
+		if err != nil {
+			return err
+		}
+		resp.Status = &report
+		return nil
+	}
+	return fmt.Errorf("unknown control operation %q", req.Op)
+}
+
+// report combines the daemon's state with the queues as they are now.
+func (s *controlServer) report(now time.Time) (controlStatusReport, error) {
+	s.mu.Lock()
+	report := s.status
+	s.mu.Unlock()
+
+	state, err := loadSchedulerState()
+	if err != nil {
+		return report, fmt.Errorf("loading scheduler state: %w", err)
+	}
+	report.Paused = state.Paused
+
+	tweets, err := loadAllScheduledTweets(s.cfg)
+	if err != nil {
+		return report, fmt.Errorf("loading scheduled tweets: %w", err)
+	}
+	var due []time.Time
+	for _, tweet := range tweets {
+		if !tweet.Paused {
+			due = append(due, tweet.ScheduleTime)
+		}
+	}
+	report.Pending = len(due)
+	if len(due) > 0 {
+		sort.Slice(due, func(i, j int) bool { return due[i].Before(due[j]) })
+		report.NextDue = &due[0]
+	}
+	return report, nil
+}
+
+// sendControl sends req to the daemon running in this directory. It returns
+// errNoDaemon when none is listening.
+func sendControl(req controlRequest) (controlResponse, error) {
+	conn, err := net.DialTimeout("unix", controlSocketFile, time.Second)
+	if err != nil {
+		return controlResponse{}, errNoDaemon
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(controlTimeout))
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return controlResponse{}, fmt.Errorf("sending to the scheduler daemon: %w", err)
+	}
+	var resp controlResponse
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return controlResponse{}, fmt.Errorf("reading the scheduler daemon's answer: %w", err)
+	}
+	if !resp.OK {
+		return resp, errors.New(resp.Error)
+	}
+	return resp, nil
+}
+
+func newSchedulerStatusCmd() *cobra.Command {
+	var output string
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "Ask the running scheduler daemon for its state",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if output != "text" && output != "json" {
+				return invalidInput(fmt.Errorf("invalid --output %q (use text or json)", output))
+			}
+
+			resp, err := sendControl(controlRequest{Op: controlStatus})
+			if err != nil && !errors.Is(err, errNoDaemon) {
+				return err
+			}
+			if output == "json" {
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+				return enc.Encode(map[string]any{"running": resp.Status != nil, "status": resp.Status})
+			}
+
+			if resp.Status == nil {
+				fmt.Println("Daemon:  " + describeDaemonStatus(time.Now()))
+				return nil
+			}
+			s := resp.Status
+			now := time.Now()
+			fmt.Printf("Daemon:  running (PID %d, up %s, last check %s ago)\n", s.PID, now.Sub(s.StartedAt).Round(time.Second), now.Sub(s.LastCheck).Round(time.Second))
+			fmt.Printf("Paused:  %s\n", map[bool]string{true: "yes", false: "no"}[s.Paused])
+			next := ""
+			if s.NextDue != nil {
+				next = fmt.Sprintf(" (next due %s)", s.NextDue.Local().Format("2006-01-02 15:04:05"))
+			}
+			fmt.Printf("Pending: %d%s\n", s.Pending, next)
+			fmt.Printf("Session: %d posted, %d failed\n", s.Posted, s.Failed)
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&output, "output", "o", "text", "Output format: text or json")
+	return cmd
+}
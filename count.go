@@ -0,0 +1,87 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/kalikim/x-cli/config"
+	"github.com/spf13/cobra"
+)
+
+func newCountCmd() *cobra.Command {
+	var text, lang string
+	var noShortcodes, withFooter bool
+
+	countCmd := &cobra.Command{
+		Use:   "count",
+		Short: "Show the weighted length of a tweet and what it is made of",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			text = strings.TrimSpace(text)
+			if text == "" {
+				return invalidInput(errors.New("text flag cannot be empty"))
+			}
+			if !noShortcodes {
+				text = expandShortcodes(text)
+			}
+			if withFooter {
+				withFooterText, err := applyFooter(config.LoadConfig(), text)
+				if err != nil {
+					return err
+				}
+				text = withFooterText
+			}
+
+			if lang != "" && !tweetLangPattern.MatchString(lang) {
+				return invalidInput(fmt.Errorf("invalid --lang %q (use a code such as en, ja or pt-BR)", lang))
+			}
+			printLengthBreakdown(measureTweet(text), text, lang)
+			return validateTweetText(text)
+		},
+	}
+
+	countCmd.Flags().StringVarP(&text, "text", "t", "", "Tweet text")
+	countCmd.Flags().BoolVar(&noShortcodes, "no-shortcodes", false, "Do not expand :shortcode: emoji before counting")
+	countCmd.Flags().BoolVar(&withFooter, "footer", false, "Include the configured footer in the count")
+	countCmd.Flags().StringVar(&lang, "lang", "", "Language of the text, e.g. ja (default: detected)")
+	countCmd.MarkFlagRequired("text")
+
+	return countCmd
+}
+
+// printLengthBreakdown shows how the length adds up. For Chinese, Japanese
+// and Korean the remaining room is also given in their characters, which
+// count as 2 each; lang is detected from the text when empty.
+func printLengthBreakdown(b lengthBreakdown, text, lang string) {
+	remaining := maxTweetLength - b.Total
+
+	if remaining >= 0 {
+		say("📏", "%d/%d characters (%d remaining)", b.Total, maxTweetLength, remaining)
+	} else {
+		say("⚠️", "%d/%d characters (%d over the limit)", b.Total, maxTweetLength, -remaining)
+	}
+
+	source := "given"
+	if lang == "" {
+		lang, source = detectLanguage(text), "detected"
+	}
+	if lang != "" {
+		fmt.Printf("Language: %s (%s)", lang, source)
+		if isCJKLanguage(lang) && remaining > 0 {
+			fmt.Printf(", room for %d more CJK characters", remaining/2)
+		}
+		fmt.Println()
+	}
+
+	fmt.Printf("URLs:  %d × %d = %d\n", len(b.URLs), tcoLength, len(b.URLs)*tcoLength)
+	for _, u := range b.URLs {
+		fmt.Printf("  %s\n", u)
+	}
+	fmt.Printf("Emoji: %d × 2 = %d", len(b.Emoji), len(b.Emoji)*2)
+	if len(b.Emoji) > 0 {
+		fmt.Printf("  %s", strings.Join(b.Emoji, " "))
+	}
+	fmt.Println()
+	fmt.Printf("CJK:   %d × 2 = %d\n", b.Wide, b.Wide*2)
+	fmt.Printf("Other: %d × 1 = %d\n", b.Other, b.Other)
+}
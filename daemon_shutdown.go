@@ -0,0 +1,92 @@
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// repeatSignalGrace is how soon after the first signal a repeat is treated
+// as the same request.
+const repeatSignalGrace = time.Second
+
+// daemonSession tracks one run of the scheduler daemon so it can stop
+// cleanly: the first SIGINT or SIGTERM lets the current post finish, a
+// second one, or the stop timeout running out, exits at once.
+type daemonSession struct {
+	started  time.Time
+	stopping chan struct{}
+	posted   int
+	failed   int
+
+	// verbose prints how late each tweet went out.
+	verbose bool
+	latency latencyTracker
+
+	// manual is set for `scheduler post`, which posts only the tweets
+	// marked PostNow and leaves the rest to the daemon.
+	manual bool
+	// queueWarned holds the queue warnings already reported, by key.
+	queueWarned map[string]bool
+}
+
+func newDaemonSession(verbose bool, maxLateness, stopTimeout time.Duration) *daemonSession {
+	s := &daemonSession{
+		started:  time.Now(),
+		stopping: make(chan struct{}),
+		verbose:  verbose,
+		latency:  latencyTracker{threshold: maxLateness},
+	}
+
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		say("🛑", "Stopping after the current post (press Ctrl+C again to quit now)")
+		close(s.stopping)
+		first := time.Now()
+		if stopTimeout > 0 {
+			time.AfterFunc(stopTimeout, func() {
+				say("⚠️", "The current post did not finish within %s; exiting", stopTimeout)
+				os.Exit(exitFailure)
+			})
+		}
+
+		// Tools like timeout signal both the process and its group, so a
+		// repeat right after the first is not a second request.
+		for range signals {
+			if time.Since(first) > repeatSignalGrace {
+				os.Exit(exitFailure)
+			}
+		}
+	}()
+
+	return s
+}
+
+// stopped reports whether a shutdown was requested; no new tweet is started
+// once it returns true.
+func (s *daemonSession) stopped() bool {
+	select {
+	case <-s.stopping:
+		return true
+	default:
+		return false
+	}
+}
+
+// recordLateness notes how long after its scheduled time a tweet went out.
+func (s *daemonSession) recordLateness(id string, lateness time.Duration) {
+	if s.verbose {
+		say("⏱️", "Tweet %s posted %s after its scheduled time", id, formatLateness(lateness))
+	}
+	s.latency.record(lateness)
+}
+
+func (s *daemonSession) printSummary() {
+	say("👋", "Scheduler daemon stopped after %s: %d tweet(s) posted, %d failed attempt(s)", time.Since(s.started).Round(time.Second), s.posted, s.failed)
+	if len(s.latency.recent) > 0 {
+		say("⏱️", "Median lateness of the last %d post(s): %s", len(s.latency.recent), formatLateness(s.latency.median()))
+	}
+}
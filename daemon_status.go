@@ -0,0 +1,218 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	daemonStatusFile = "scheduler_daemon.json"
+	daemonLockFile   = "scheduler_daemon.lock"
+)
+
+// daemonStatus is a heartbeat written by the scheduler daemon on every check
+// so other commands can tell whether it is alive.
+type daemonStatus struct {
+	PID       int       `json:"pid"`
+	StartedAt time.Time `json:"started_at"`
+	LastCheck time.Time `json:"last_check"`
+	// Interval is the daemon's poll interval; older daemons did not record
+	// it and always used the default.
+	Interval time.Duration `json:"interval,omitempty"`
+	// StoppedAt is set when the daemon shuts down cleanly.
+	StoppedAt *time.Time `json:"stopped_at,omitempty"`
+}
+
+// staleAfter is how long the heartbeat may go unrefreshed: two polls.
+func (s daemonStatus) staleAfter() time.Duration {
+	if s.Interval <= 0 {
+		return 2 * defaultDaemonInterval
+	}
+	return 2 * s.Interval
+}
+
+func writeDaemonStatus(status daemonStatus) error {
+	data, err := json.MarshalIndent(status, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(daemonStatusFile, data, 0644)
+}
+
+func loadDaemonStatus() (daemonStatus, error) {
+	var status daemonStatus
+
+	data, err := os.ReadFile(daemonStatusFile)
+	if err != nil {
+		return status, err
+	}
+
+	if err := json.Unmarshal(data, &status); err != nil {
+		return status, err
+	}
+
+	return status, nil
+}
+
+// describeDaemonStatus summarizes the daemon heartbeat for display. A daemon
+// that has not checked in for two poll intervals is reported as stopped.
+func describeDaemonStatus(now time.Time) string {
+	status, err := loadDaemonStatus()
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "not running"
+		}
+		return "unknown (" + err.Error() + ")"
+	}
+
+	if status.StoppedAt != nil {
+		return "stopped at " + status.StoppedAt.Format("2006-01-02 15:04:05")
+	}
+
+	age := now.Sub(status.LastCheck).Round(time.Second)
+	if age > status.staleAfter() {
+		return "stopped (last seen " + status.LastCheck.Format("2006-01-02 15:04:05") + ")"
+	}
+
+	return "running (last check " + age.String() + " ago)"
+}
+
+// daemonAlive reports whether the heartbeat shows a live daemon, optionally
+// requiring it to belong to pid.
+func daemonAlive(pid int, now time.Time) bool {
+	status, err := loadDaemonStatus()
+	if err != nil {
+		return false
+	}
+	if pid != 0 && status.PID != pid {
+		return false
+	}
+	if status.StoppedAt != nil {
+		return false
+	}
+
+	return now.Sub(status.LastCheck) <= status.staleAfter()
+}
+
+// acquireDaemonLock makes sure only one scheduler daemon works on the stores
+// in this directory; a second one would post every due tweet twice.
+func acquireDaemonLock() (func(), error) {
+	unlock, err := tryLockFile(daemonLockFile)
+	if errors.Is(err, errLockHeld) {
+		holder := "another process"
+		if pid := lockHolder(daemonLockFile); pid != 0 {
+			holder = fmt.Sprintf("PID %d", pid)
+		}
+		return nil, fmt.Errorf("a scheduler daemon is already running in this directory (%s); stop it before starting another", holder)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return unlock, nil
+}
+
+// statusLine renders a single, continuously updated line describing the
+// daemon's state while it waits between checks. It is only active when
+// stdout is a terminal.
+type statusLine struct {
+	enabled bool
+	last    string
+}
+
+func newStatusLine() *statusLine {
+	return &statusLine{enabled: isTerminal(os.Stdout) && os.Getenv("TERM") != "dumb"}
+}
+
+// recordResult remembers the outcome of the latest post for display.
+func (l *statusLine) recordResult(result string) {
+	l.last = time.Now().Format("15:04:05") + " " + result
+}
+
+// wait sleeps for d, or until wake or stop fires, redrawing the status line
+// every second when enabled.
+func (l *statusLine) wait(d time.Duration, tweets []scheduledTweet, paused bool, wake, stop <-chan struct{}) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	if !l.enabled {
+		select {
+		case <-timer.C:
+		case <-wake:
+		case <-stop:
+		}
+		return
+	}
+
+	deadline := time.Now().Add(d)
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+wait:
+	for {
+		fmt.Print("\r\x1b[K" + l.render(time.Now(), deadline, tweets, paused))
+		select {
+		case <-ticker.C:
+		case <-timer.C:
+			break wait
+		case <-wake:
+			break wait
+		case <-stop:
+			break wait
+		}
+	}
+
+	// Clear the line so regular output starts on a clean row.
+	fmt.Print("\r\x1b[K")
+}
+
+func (l *statusLine) render(now, nextCheck time.Time, tweets []scheduledTweet, paused bool) string {
+	parts := []string{decorate("🟢", "alive")}
+	if paused {
+		parts[0] = decorate("⏸️", "paused")
+	}
+
+	var next *scheduledTweet
+	for i := range tweets {
+		if tweets[i].Paused {
+			continue
+		}
+		if next == nil || tweets[i].ScheduleTime.Before(next.ScheduleTime) {
+			next = &tweets[i]
+		}
+	}
+
+	if next == nil {
+		parts = append(parts, "queue empty")
+	} else {
+		wait := next.ScheduleTime.Sub(now).Round(time.Second)
+		if wait < 0 {
+			wait = 0
+		}
+		parts = append(parts, fmt.Sprintf("next %s in %s (%d queued)", truncateText(next.Text, 24), formatCountdown(wait), len(tweets)))
+	}
+
+	parts = append(parts, fmt.Sprintf("check in %s", nextCheck.Sub(now).Round(time.Second)))
+
+	if l.last != "" {
+		parts = append(parts, "last: "+l.last)
+	}
+
+	return strings.Join(parts, " · ")
+}
+
+// formatCountdown renders d compactly, switching to days for long waits.
+func formatCountdown(d time.Duration) string {
+	if d < 24*time.Hour {
+		return d.String()
+	}
+
+	days := d / (24 * time.Hour)
+	hours := (d % (24 * time.Hour)) / time.Hour
+	return fmt.Sprintf("%dd%dh", days, hours)
+}
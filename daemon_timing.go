@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/kalikim/x-cli/config"
+)
+
+const (
+	defaultDaemonInterval  = 30 * time.Second
+	defaultMaxRetryBackoff = 15 * time.Minute
+)
+
+// daemonTiming controls how often the daemon checks the queues, how long it
+// waits before retrying a failed tweet, and how long it waits at startup.
+type daemonTiming struct {
+	Interval        time.Duration
+	RetryBackoff    time.Duration
+	MaxRetryBackoff time.Duration
+	StartupJitter   time.Duration
+	// MaxMedianLateness is the median lateness that triggers a warning;
+	// zero disables it.
+	MaxMedianLateness time.Duration
+	// StopTimeout is how long a stop signal waits for the current post
+	// before the daemon exits anyway; zero waits as long as it takes.
+	StopTimeout time.Duration
+	// NoticeBefore is how long before posting a tweet is announced; zero
+	// sends no notice.
+	NoticeBefore time.Duration
+}
+
+// daemonTimingFlags are the daemon command's overrides for config.Daemon.
+type daemonTimingFlags struct {
+	interval, retryBackoff, maxRetryBackoff, jitter, maxLateness, stopTimeout, noticeBefore string
+}
+
+// resolveDaemonTiming combines defaults, the config file and flags, in
+// increasing order of precedence.
+func resolveDaemonTiming(cfg config.Daemon, flags daemonTimingFlags) (daemonTiming, error) {
+	timing := daemonTiming{
+		Interval:          defaultDaemonInterval,
+		MaxRetryBackoff:   defaultMaxRetryBackoff,
+		MaxMedianLateness: defaultMaxMedianLateness,
+	}
+
+	fields := []struct {
+		name       string
+		fromConfig string
+		fromFlag   string
+		target     *time.Duration
+		positive   bool
+	}{
+		{"interval", cfg.Interval, flags.interval, &timing.Interval, true},
+		{"retry backoff", cfg.RetryBackoff, flags.retryBackoff, &timing.RetryBackoff, true},
+		{"max retry backoff", cfg.MaxRetryBackoff, flags.maxRetryBackoff, &timing.MaxRetryBackoff, true},
+		{"startup jitter", cfg.StartupJitter, flags.jitter, &timing.StartupJitter, false},
+		{"max median lateness", cfg.MaxMedianLateness, flags.maxLateness, &timing.MaxMedianLateness, false},
+		{"stop timeout", cfg.StopTimeout, flags.stopTimeout, &timing.StopTimeout, false},
+		{"notice before", cfg.NoticeBefore, flags.noticeBefore, &timing.NoticeBefore, false},
+	}
+
+	for _, f := range fields {
+		value := f.fromConfig
+		if f.fromFlag != "" {
+			value = f.fromFlag
+		}
+		if value == "" {
+			continue
+		}
+
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return timing, invalidInput(fmt.Errorf("invalid %s %q (use e.g. 45s or 5m)", f.name, value))
+		}
+		if d < 0 || (f.positive && d == 0) {
+			return timing, invalidInput(fmt.Errorf("%s must be positive, got %q", f.name, value))
+		}
+		*f.target = d
+	}
+
+	if timing.RetryBackoff == 0 {
+		timing.RetryBackoff = timing.Interval
+	}
+	if timing.MaxRetryBackoff < timing.RetryBackoff {
+		timing.MaxRetryBackoff = timing.RetryBackoff
+	}
+
+	return timing, nil
+}
+
+// startupDelay picks the random wait before the first check.
+func (t daemonTiming) startupDelay() time.Duration {
+	if t.StartupJitter <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(t.StartupJitter)))
+}
+
+// retryBackoff spaces out retries of tweets that keep failing, doubling the
+// wait after each consecutive failure.
+type retryBackoff struct {
+	base, max time.Duration
+	retries   map[string]retryState
+}
+
+type retryState struct {
+	failures int
+	next     time.Time
+}
+
+func newRetryBackoff(timing daemonTiming) *retryBackoff {
+	return &retryBackoff{base: timing.RetryBackoff, max: timing.MaxRetryBackoff, retries: map[string]retryState{}}
+}
+
+// ready reports whether the tweet may be attempted at now.
+func (r *retryBackoff) ready(id string, now time.Time) bool {
+	state, ok := r.retries[id]
+	return !ok || !now.Before(state.next)
+}
+
+// failed records a failed attempt and returns the wait before the next one.
+func (r *retryBackoff) failed(id string, now time.Time) time.Duration {
+	state := r.retries[id]
+	state.failures++
+
+	wait := r.base
+	for i := 1; i < state.failures && wait < r.max; i++ {
+		wait *= 2
+	}
+	if wait > r.max {
+		wait = r.max
+	}
+
+	state.next = now.Add(wait)
+	r.retries[id] = state
+	return wait
+}
+
+// succeeded forgets the tweet's failures.
+func (r *retryBackoff) succeeded(id string) {
+	delete(r.retries, id)
+}
@@ -0,0 +1,400 @@
+package main
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kalikim/x-cli/config"
+	"github.com/spf13/cobra"
+)
+
+const (
+	digestStateFile    = "digest_state.json"
+	defaultDigestEvery = "1d"
+	defaultDigestAt    = "08:00"
+	defaultSMTPPort    = 587
+	// digestSlack lets a digest go out a little early, so one sent late
+	// because the daemon was down does not push every later one back.
+	digestSlack = time.Hour
+)
+
+// digestState remembers when the last digest went out.
+type digestState struct {
+	LastSent time.Time `json:"last_sent"`
+}
+
+// digestTweet is one line of a digest section.
+type digestTweet struct {
+	Time  time.Time
+	Queue string
+	Text  string
+	Link  string
+	Error string
+}
+
+// digest summarizes the scheduler's activity over a period.
+type digest struct {
+	Since    time.Time
+	Until    time.Time
+	Posted   []digestTweet
+	Failed   []digestTweet
+	Upcoming []digestTweet
+}
+
+func newDigestCmd() *cobra.Command {
+	digestCmd := &cobra.Command{
+		Use:   "digest",
+		Short: "Preview or send the scheduler's email digest",
+		Long: "The scheduler daemon emails a digest of posted, failing and upcoming tweets\n" +
+			"when email_digest is configured. These commands show or send it right away.",
+	}
+
+	previewCmd := &cobra.Command{
+		Use:   "preview",
+		Short: "Print the digest the daemon would send now",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg := config.LoadConfig()
+			d, err := buildDigest(cfg, time.Now())
+			if err != nil {
+				return err
+			}
+			fmt.Println("Subject: " + d.subject())
+			fmt.Println()
+			fmt.Print(d.body())
+			return nil
+		},
+	}
+
+	sendCmd := &cobra.Command{
+		Use:   "send",
+		Short: "Email the digest now",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg := config.LoadConfig()
+			if err := validateEmailDigest(cfg.EmailDigest); err != nil {
+				return err
+			}
+			if len(cfg.EmailDigest.To) == 0 {
+				return invalidInput(errors.New("email_digest.to is empty in the config"))
+			}
+			now := time.Now()
+			if err := sendDigest(cfg, now); err != nil {
+				return err
+			}
+			say("📧", "Digest sent to %s", strings.Join(cfg.EmailDigest.To, ", "))
+			return nil
+		},
+	}
+
+	digestCmd.AddCommand(previewCmd, sendCmd)
+	return digestCmd
+}
+
+// validateEmailDigest checks the settings a digest needs to go out.
+func validateEmailDigest(d config.EmailDigest) error {
+	if len(d.To) == 0 {
+		return nil
+	}
+	if d.SMTPHost == "" {
+		return invalidInput(errors.New("email_digest.smtp_host is required"))
+	}
+	if _, err := mail.ParseAddress(d.From); err != nil {
+		return invalidInput(fmt.Errorf("invalid email_digest.from %q: %w", d.From, err))
+	}
+	for _, to := range d.To {
+		if _, err := mail.ParseAddress(to); err != nil {
+			return invalidInput(fmt.Errorf("invalid email_digest.to address %q: %w", to, err))
+		}
+	}
+	if _, err := digestPeriod(d); err != nil {
+		return err
+	}
+	if _, err := time.Parse("15:04", digestAt(d)); err != nil {
+		return invalidInput(fmt.Errorf("invalid email_digest.at %q (use HH:MM)", d.At))
+	}
+	return nil
+}
+
+func digestPeriod(d config.EmailDigest) (time.Duration, error) {
+	every := d.Every
+	if every == "" {
+		every = defaultDigestEvery
+	}
+	period, err := parsePeriod(every)
+	if err != nil {
+		return 0, fmt.Errorf("email_digest.every: %w", err)
+	}
+	return period, nil
+}
+
+func digestAt(d config.EmailDigest) string {
+	if d.At == "" {
+		return defaultDigestAt
+	}
+	return d.At
+}
+
+// digestDue reports whether a digest should go out at now: once the day's
+// send time has passed and a period has gone by since the last one.
+func digestDue(d config.EmailDigest, state digestState, now time.Time) (bool, error) {
+	period, err := digestPeriod(d)
+	if err != nil {
+		return false, err
+	}
+	at, err := time.Parse("15:04", digestAt(d))
+	if err != nil {
+		return false, invalidInput(fmt.Errorf("invalid email_digest.at %q (use HH:MM)", d.At))
+	}
+
+	local := now.Local()
+	sendAt := time.Date(local.Year(), local.Month(), local.Day(), at.Hour(), at.Minute(), 0, 0, time.Local)
+	if local.Before(sendAt) {
+		return false, nil
+	}
+	return state.LastSent.IsZero() || now.Sub(state.LastSent) >= period-digestSlack, nil
+}
+
+// runDueDigest emails the digest when it is due.
+func runDueDigest(cfg config.Config, now time.Time) {
+	if len(cfg.EmailDigest.To) == 0 {
+		return
+	}
+
+	state, err := loadDigestState()
+	if err != nil {
+		log.Printf("Error loading digest state: %v", err)
+		return
+	}
+	due, err := digestDue(cfg.EmailDigest, state, now)
+	if err != nil {
+		log.Printf("Error in email digest settings: %v", err)
+		return
+	}
+	if !due {
+		return
+	}
+
+	if err := sendDigest(cfg, now); err != nil {
+		log.Printf("Error sending email digest: %v", err)
+		return
+	}
+	say("📧", "Digest emailed to %s", strings.Join(cfg.EmailDigest.To, ", "))
+}
+
+// sendDigest builds and emails the digest covering the time since the last
+// one, then records it as sent.
+func sendDigest(cfg config.Config, now time.Time) error {
+	d, err := buildDigest(cfg, now)
+	if err != nil {
+		return err
+	}
+	if err := sendEmail(cfg.EmailDigest, d.subject(), d.body()); err != nil {
+		return fmt.Errorf("sending digest: %w", err)
+	}
+	return saveDigestState(digestState{LastSent: now})
+}
+
+// buildDigest collects tweets posted since the last digest (or over one
+// period for the first), tweets whose last attempt failed, and tweets due
+// within the next period.
+func buildDigest(cfg config.Config, now time.Time) (digest, error) {
+	period, err := digestPeriod(cfg.EmailDigest)
+	if err != nil {
+		return digest{}, err
+	}
+	state, err := loadDigestState()
+	if err != nil {
+		return digest{}, fmt.Errorf("loading digest state: %w", err)
+	}
+
+	d := digest{Since: now.Add(-period), Until: now}
+	if !state.LastSent.IsZero() {
+		d.Since = state.LastSent
+	}
+
+	entries, err := loadHistory()
+	if err != nil {
+		return digest{}, fmt.Errorf("loading history: %w", err)
+	}
+	for _, entry := range entries {
+		if entry.TweetID == "" || entry.PostedAt.Before(d.Since) || entry.PostedAt.After(now) {
+			continue
+		}
+		d.Posted = append(d.Posted, digestTweet{
+			Time:  entry.PostedAt,
+			Queue: entry.Queue,
+			Text:  entry.Text,
+			Link:  tweetURL("", entry.TweetID),
+		})
+	}
+
+	for _, queue := range knownQueues(cfg) {
+		tweets, err := loadScheduledTweets(queue)
+		if err != nil {
+			return digest{}, fmt.Errorf("queue %s: %w", queueLabel(queue), err)
+		}
+		for _, tweet := range tweets {
+			item := digestTweet{Time: tweet.ScheduleTime, Queue: queue, Text: tweet.Text, Error: tweet.LastError}
+			switch {
+			case tweet.LastError != "":
+				d.Failed = append(d.Failed, item)
+			case !tweet.Paused && tweet.ScheduleTime.Before(now.Add(period)):
+				d.Upcoming = append(d.Upcoming, item)
+			}
+		}
+	}
+
+	for _, list := range [][]digestTweet{d.Posted, d.Failed, d.Upcoming} {
+		sort.SliceStable(list, func(i, j int) bool { return list[i].Time.Before(list[j].Time) })
+	}
+	return d, nil
+}
+
+func (d digest) subject() string {
+	return fmt.Sprintf("x-cli digest: %d posted, %d failing, %d upcoming (%s)",
+		len(d.Posted), len(d.Failed), len(d.Upcoming), d.Until.Local().Format("Jan 2"))
+}
+
+func (d digest) body() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Scheduled tweets from %s to %s.\n",
+		d.Since.Local().Format("Mon Jan 2 15:04"), d.Until.Local().Format("Mon Jan 2 15:04"))
+
+	section := func(title string, tweets []digestTweet, empty string) {
+		fmt.Fprintf(&b, "\n%s (%d)\n%s\n", title, len(tweets), strings.Repeat("-", len(title)+len(strconv.Itoa(len(tweets)))+3))
+		if len(tweets) == 0 {
+			fmt.Fprintf(&b, "%s\n", empty)
+		}
+		for _, t := range tweets {
+			fmt.Fprintf(&b, "%s  [%s]  %s\n", t.Time.Local().Format("Mon Jan 2 15:04"), queueLabel(t.Queue), truncateText(flattenCell(t.Text), 100))
+			if t.Link != "" {
+				fmt.Fprintf(&b, "    %s\n", t.Link)
+			}
+			if t.Error != "" {
+				fmt.Fprintf(&b, "    Error: %s\n", t.Error)
+			}
+		}
+	}
+	section("Posted", d.Posted, "Nothing was posted.")
+	section("Failing", d.Failed, "No failures.")
+	section("Upcoming", d.Upcoming, "Nothing is scheduled.")
+
+	b.WriteString("\n-- \nSent by the x-cli scheduler daemon.\n")
+	return b.String()
+}
+
+// sendEmail sends a plain-text message over SMTP: implicit TLS on port 465,
+// STARTTLS when the server offers it otherwise. Offline mode logs the
+// message instead.
+func sendEmail(settings config.EmailDigest, subject, body string) error {
+	port := settings.SMTPPort
+	if port == 0 {
+		port = defaultSMTPPort
+	}
+	addr := net.JoinHostPort(settings.SMTPHost, strconv.Itoa(port))
+
+	msg := strings.Join([]string{
+		"From: " + settings.From,
+		"To: " + strings.Join(settings.To, ", "),
+		"Subject: " + mime.QEncoding.Encode("utf-8", subject),
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=utf-8",
+		"",
+		strings.ReplaceAll(body, "\n", "\r\n"),
+	}, "\r\n")
+
+	if offlineMode {
+		return (&mockTransport{logFile: mockLogFile()}).record(mockRequest{
+			Time:   time.Now(),
+			Method: "SMTP",
+			URL:    "smtp://" + addr,
+			Body:   msg,
+		})
+	}
+
+	var client *smtp.Client
+	var err error
+	if port == 465 {
+		conn, err := tls.Dial("tcp", addr, &tls.Config{ServerName: settings.SMTPHost})
+		if err != nil {
+			return fmt.Errorf("connecting to %s: %w", addr, err)
+		}
+		client, err = smtp.NewClient(conn, settings.SMTPHost)
+		if err != nil {
+			return fmt.Errorf("connecting to %s: %w", addr, err)
+		}
+	} else {
+		if client, err = smtp.Dial(addr); err != nil {
+			return fmt.Errorf("connecting to %s: %w", addr, err)
+		}
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(&tls.Config{ServerName: settings.SMTPHost}); err != nil {
+				client.Close()
+				return fmt.Errorf("starting TLS: %w", err)
+			}
+		}
+	}
+	defer client.Close()
+
+	if settings.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", settings.Username, settings.Password, settings.SMTPHost)); err != nil {
+			return fmt.Errorf("authenticating: %w", err)
+		}
+	}
+	if err := client.Mail(settings.From); err != nil {
+		return err
+	}
+	for _, to := range settings.To {
+		if err := client.Rcpt(to); err != nil {
+			return fmt.Errorf("recipient %s: %w", to, err)
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write([]byte(msg)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+func loadDigestState() (digestState, error) {
+	var state digestState
+	data, err := os.ReadFile(digestStateFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return state, err
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return state, err
+	}
+	return state, nil
+}
+
+func saveDigestState(state digestState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(digestStateFile, data, 0644)
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kalikim/x-cli/config"
+)
+
+// discordMaxAttachment is the largest file a Discord webhook accepts on a
+// server without boosts.
+const discordMaxAttachment = 8 << 20
+
+// validateDiscordWebhook checks that rawURL looks like a Discord webhook.
+func validateDiscordWebhook(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "https" || u.Host == "" || !strings.Contains(u.Path, "/webhooks/") {
+		return fmt.Errorf("invalid discord_webhook %q (expected https://discord.com/api/webhooks/...)", rawURL)
+	}
+	return nil
+}
+
+// mirrorToDiscord posts a just-published tweet to the configured Discord
+// webhook, if any: its text and link, with the image attached when there is
+// one Discord accepts.
+func mirrorToDiscord(client *http.Client, cfg config.Config, tweetID, text, image string) error {
+	if cfg.DiscordWebhook == "" {
+		return nil
+	}
+	if err := validateDiscordWebhook(cfg.DiscordWebhook); err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(map[string]any{
+		"content": text + "\n" + tweetURL("", tweetID),
+		// Tweets often contain @handles; none of them should ping anyone.
+		"allowed_mentions": map[string]any{"parse": []string{}},
+	})
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	if err := writer.WriteField("payload_json", string(payload)); err != nil {
+		return err
+	}
+	if image != "" {
+		if err := attachDiscordImage(writer, image); err != nil {
+			return err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, cfg.DiscordWebhook, &buf)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("posting to Discord: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("Discord returned %s", resp.Status)
+	}
+	return nil
+}
+
+// attachDiscordImage adds image as the message's file. Videos and images
+// over discordMaxAttachment are left out; the tweet link still shows them.
+func attachDiscordImage(writer *multipart.Writer, image string) error {
+	data, err := os.ReadFile(image)
+	if err != nil {
+		return fmt.Errorf("reading media: %w", err)
+	}
+	mimeType, _, _ := strings.Cut(detectMime(image, data[:min(len(data), 512)]), ";")
+	if !strings.HasPrefix(mimeType, "image/") || len(data) > discordMaxAttachment {
+		return nil
+	}
+
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="files[0]"; filename=%q`, filepath.Base(image)))
+	header.Set("Content-Type", mimeType)
+	part, err := writer.CreatePart(header)
+	if err != nil {
+		return err
+	}
+	_, err = part.Write(data)
+	return err
+}
@@ -0,0 +1,444 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kalikim/x-cli/config"
+	"github.com/spf13/cobra"
+)
+
+const (
+	dmEventsEndpoint       = "https://api.twitter.com/2/dm_events"
+	dmConversationEndpoint = "https://api.twitter.com/2/dm_conversations/%s/messages"
+	dmWithEndpoint         = "https://api.twitter.com/2/dm_conversations/with/%s/dm_events"
+)
+
+// dmEvent is a direct message with its sender resolved.
+type dmEvent struct {
+	ID             string
+	ConversationID string
+	Text           string
+	CreatedAt      time.Time
+	SenderID       string
+	Username       string
+	Name           string
+}
+
+// dmExportColumns are the fields of an exported message, in CSV order.
+var dmExportColumns = []string{"id", "conversation_id", "created_at", "sender_id", "sender", "text"}
+
+// dmPage is one page of direct messages and the token of the next.
+type dmPage struct {
+	Messages  []dmEvent
+	NextToken string
+}
+
+func newDMCmd() *cobra.Command {
+	dmCmd := &cobra.Command{
+		Use:   "dm",
+		Short: "List and export direct messages",
+	}
+
+	var with, profile, listOutput string
+	var limit int
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List DM conversations, or the messages exchanged with one user",
+		Long: "List DM conversations with their latest message. With --with, list the\n" +
+			"messages exchanged with that user instead, oldest first.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, cfg, endpoint, err := dmSource(with, profile, limit)
+			if err != nil {
+				return err
+			}
+			messages, err := fetchAllDMEvents(client, cfg, endpoint, limit, nil)
+			if err != nil {
+				return fmt.Errorf("loading direct messages: %w", err)
+			}
+			if len(messages) == 0 && listOutput == "table" {
+				say("📭", "No direct messages found")
+				return nil
+			}
+
+			if with != "" {
+				rows := make([][]any, 0, len(messages))
+				for _, m := range messages {
+					rows = append(rows, []any{m.CreatedAt.Local().Format("2006-01-02 15:04"), dmSender(m), m.Text})
+				}
+				return writeRows(listOutput, []string{"time", "from", "text"}, rows)
+			}
+
+			userID, err := accountUserID(client, cfg)
+			if err != nil {
+				return err
+			}
+			return writeDMConversations(listOutput, messages, userID)
+		},
+	}
+	listCmd.Flags().StringVarP(&listOutput, "output", "o", "table", "Output format: table, json, yaml or tsv")
+
+	var to, format string
+	var resume bool
+	exportCmd := &cobra.Command{
+		Use:   "export",
+		Short: "Archive direct messages as JSON or CSV",
+		Long: "Archive direct messages as JSON or CSV, oldest first. X only returns\n" +
+			"messages from the last 30 days.\n\n" +
+			"Progress is saved in ~/.x-cli/checkpoints/ after every page. If X rate-limits\n" +
+			"the export or it is interrupted, run the same command with --resume to carry\n" +
+			"on from the last page instead of starting over.",
+		Example: `  x-cli dm export --to dms.json
+  x-cli dm export --with @jack --to jack.csv
+  x-cli dm export --to dms.json --limit 10000 --resume`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if format == "" {
+				format = "json"
+				if strings.EqualFold(filepath.Ext(to), ".csv") {
+					format = "csv"
+				}
+			}
+			if format != "json" && format != "csv" {
+				return invalidInput(fmt.Errorf("invalid --format %q (use json or csv)", format))
+			}
+
+			client, cfg, endpoint, err := dmSource(with, profile, limit)
+			if err != nil {
+				return err
+			}
+			cp, err := dmExportCheckpoint(dmExportJob(cfg, with), resume)
+			if err != nil {
+				return err
+			}
+			messages, err := fetchAllDMEvents(client, cfg, endpoint, limit, cp)
+			if err != nil {
+				if cp.Completed > 0 {
+					wait := ""
+					if rateLimited(err) {
+						wait = " once the rate limit resets"
+					}
+					fmt.Fprintln(os.Stderr, decorate("💾", fmt.Sprintf("Saved progress after %d message(s); run the same command with --resume%s to continue", cp.Completed, wait)))
+				}
+				return fmt.Errorf("loading direct messages: %w", err)
+			}
+
+			data, err := encodeDMExport(messages, format)
+			if err != nil {
+				return err
+			}
+			if to == "" {
+				if _, err := os.Stdout.Write(data); err != nil {
+					return err
+				}
+			} else {
+				if err := writeFileAtomic(to, data, 0600); err != nil {
+					return fmt.Errorf("writing %s: %w", to, err)
+				}
+				say("✅", "Exported %d message(s) to %s", len(messages), to)
+			}
+			if err := clearCheckpoint(cp.Job); err != nil {
+				log.Print(decorate("⚠️", fmt.Sprintf("Failed to remove the export checkpoint: %v", err)))
+			}
+			return nil
+		},
+	}
+	exportCmd.Flags().StringVar(&to, "to", "", "File to write (default stdout)")
+	exportCmd.Flags().StringVar(&format, "format", "", "json or csv (default from the --to extension, else json)")
+	exportCmd.Flags().BoolVar(&resume, "resume", false, "Continue an export that was rate-limited or interrupted")
+
+	for _, c := range []*cobra.Command{listCmd, exportCmd} {
+		c.Flags().StringVar(&with, "with", "", "Only the conversation with this @user")
+		c.Flags().StringVar(&profile, "profile", "", "Read the direct messages of this configured profile")
+		c.Flags().IntVar(&limit, "limit", 500, "Most messages to fetch")
+	}
+
+	dmCmd.AddCommand(listCmd, exportCmd)
+	return dmCmd
+}
+
+// dmSource loads the profile's config and resolves --with to the endpoint
+// to read messages from.
+func dmSource(with, profile string, limit int) (*http.Client, config.Config, string, error) {
+	if limit <= 0 {
+		return nil, config.Config{}, "", invalidInput(fmt.Errorf("--limit must be positive, got %d", limit))
+	}
+	cfg, err := config.LoadConfig().ForProfile(profile)
+	if err != nil {
+		return nil, config.Config{}, "", invalidInput(err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, config.Config{}, "", err
+	}
+	client := apiClient()
+
+	if with == "" {
+		return client, cfg, dmEventsEndpoint, nil
+	}
+	handle := strings.TrimPrefix(with, "@")
+	if !handlePattern.MatchString(handle) {
+		return nil, config.Config{}, "", invalidInput(fmt.Errorf("invalid --with %q (use @handle)", with))
+	}
+	found, err := resolveUsers(client, cfg, []string{handle})
+	if err != nil {
+		return nil, config.Config{}, "", fmt.Errorf("looking up @%s: %w", handle, err)
+	}
+	id := found[strings.ToLower(handle)]
+	if id == "" {
+		return nil, config.Config{}, "", fmt.Errorf("user @%s not found", handle)
+	}
+	return client, cfg, fmt.Sprintf(dmWithEndpoint, id), nil
+}
+
+// dmExportJob names the checkpoint of an export of cfg's direct messages,
+// all of them or only those exchanged with one user.
+func dmExportJob(cfg config.Config, with string) string {
+	target := "all"
+	if with != "" {
+		target = strings.ToLower(strings.TrimPrefix(with, "@"))
+	}
+	return "dm-export-" + rateLimitAccount(cfg) + "-" + target
+}
+
+// dmExportCheckpoint returns the checkpoint an export of job continues
+// from: the saved one with --resume, otherwise a fresh one.
+func dmExportCheckpoint(job string, resume bool) (*checkpoint, error) {
+	saved, err := loadCheckpoint(job)
+	if err != nil {
+		return nil, fmt.Errorf("loading export checkpoint: %w", err)
+	}
+	switch {
+	case saved != nil && resume:
+		fmt.Fprintln(os.Stderr, decorate("⏩", fmt.Sprintf("Resuming the export after %d message(s)", saved.Completed)))
+		return saved, nil
+	case saved != nil:
+		fmt.Fprintln(os.Stderr, decorate("⚠️", fmt.Sprintf("Starting over; an interrupted export of %d message(s) is replaced (use --resume to continue it)", saved.Completed)))
+	case resume:
+		fmt.Fprintln(os.Stderr, decorate("📭", "No interrupted export to resume; starting from the beginning"))
+	}
+	return &checkpoint{Job: job}, nil
+}
+
+// fetchAllDMEvents pages through endpoint until limit messages are loaded,
+// and returns them oldest first. With cp, it starts from cp's cursor and
+// messages and saves its progress there after every page, so an export
+// stopped by a rate limit or a crash can resume.
+func fetchAllDMEvents(client *http.Client, cfg config.Config, endpoint string, limit int, cp *checkpoint) ([]dmEvent, error) {
+	var messages []dmEvent
+	pageToken := ""
+	if cp != nil && cp.Cursor != "" {
+		if err := json.Unmarshal(cp.Items, &messages); err != nil {
+			return nil, fmt.Errorf("reading export checkpoint: %w", err)
+		}
+		pageToken = cp.Cursor
+	}
+	for len(messages) < limit {
+		page, err := fetchDMEvents(client, cfg, endpoint, pageToken, min(100, max(1, limit-len(messages))))
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, page.Messages...)
+		if page.NextToken == "" {
+			break
+		}
+		pageToken = page.NextToken
+		if cp != nil {
+			if err := cp.save(pageToken, len(messages), messages); err != nil {
+				return nil, fmt.Errorf("saving export checkpoint: %w", err)
+			}
+		}
+	}
+	if len(messages) > limit {
+		messages = messages[:limit]
+	}
+
+	sort.SliceStable(messages, func(i, j int) bool {
+		return messages[i].CreatedAt.Before(messages[j].CreatedAt)
+	})
+	return messages, nil
+}
+
+func dmSender(m dmEvent) string {
+	if m.Username != "" {
+		return "@" + m.Username
+	}
+	return m.SenderID
+}
+
+// writeDMConversations prints one row per conversation with its latest
+// message, most recent conversation first.
+func writeDMConversations(format string, messages []dmEvent, userID string) error {
+	type conversation struct {
+		id, with string
+		count    int
+		last     dmEvent
+	}
+	byID := map[string]*conversation{}
+	var order []*conversation
+	for _, m := range messages {
+		c := byID[m.ConversationID]
+		if c == nil {
+			c = &conversation{id: m.ConversationID}
+			byID[m.ConversationID] = c
+			order = append(order, c)
+		}
+		c.count++
+		c.last = m
+		if m.SenderID != userID {
+			c.with = dmSender(m)
+		}
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return order[i].last.CreatedAt.After(order[j].last.CreatedAt)
+	})
+
+	rows := make([][]any, 0, len(order))
+	for _, c := range order {
+		with := c.with
+		if with == "" {
+			// One-to-one conversation IDs are the two user IDs joined by "-".
+			for _, id := range strings.Split(c.id, "-") {
+				if id != userID {
+					with = id
+				}
+			}
+		}
+		rows = append(rows, []any{c.id, with, c.count, c.last.CreatedAt.Local().Format("2006-01-02 15:04"), c.last.Text})
+	}
+	return writeRows(format, []string{"conversation", "with", "messages", "last_at", "text"}, rows)
+}
+
+// encodeDMExport renders messages as a JSON array or a CSV table.
+func encodeDMExport(messages []dmEvent, format string) ([]byte, error) {
+	records := make([][]string, 0, len(messages))
+	for _, m := range messages {
+		records = append(records, []string{m.ID, m.ConversationID, m.CreatedAt.Format(time.RFC3339), m.SenderID, m.Username, m.Text})
+	}
+
+	if format == "csv" {
+		var buf bytes.Buffer
+		w := csv.NewWriter(&buf)
+		w.Write(dmExportColumns)
+		w.WriteAll(records)
+		return buf.Bytes(), w.Error()
+	}
+
+	objects := make([]map[string]string, 0, len(records))
+	for _, record := range records {
+		obj := map[string]string{}
+		for i, c := range dmExportColumns {
+			obj[c] = record[i]
+		}
+		objects = append(objects, obj)
+	}
+	data, err := json.MarshalIndent(objects, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// fetchDMEvents loads a page of direct messages from endpoint, newest first:
+// dmEventsEndpoint for all conversations, or a dmWithEndpoint URL for one.
+func fetchDMEvents(client *http.Client, cfg config.Config, endpoint, pageToken string, pageSize int) (dmPage, error) {
+	query := url.Values{}
+	query.Set("max_results", strconv.Itoa(pageSize))
+	query.Set("event_types", "MessageCreate")
+	query.Set("dm_event.fields", "created_at,sender_id,dm_conversation_id,text")
+	query.Set("expansions", "sender_id")
+	query.Set("user.fields", "username,name")
+	if pageToken != "" {
+		query.Set("pagination_token", pageToken)
+	}
+
+	body, err := signedGet(client, cfg, endpoint+"?"+query.Encode())
+	if err != nil {
+		return dmPage{}, err
+	}
+
+	var resp struct {
+		Data []struct {
+			ID             string    `json:"id"`
+			Text           string    `json:"text"`
+			CreatedAt      time.Time `json:"created_at"`
+			SenderID       string    `json:"sender_id"`
+			ConversationID string    `json:"dm_conversation_id"`
+		} `json:"data"`
+		Includes struct {
+			Users []struct {
+				ID       string `json:"id"`
+				Username string `json:"username"`
+				Name     string `json:"name"`
+			} `json:"users"`
+		} `json:"includes"`
+		Meta struct {
+			NextToken string `json:"next_token"`
+		} `json:"meta"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return dmPage{}, fmt.Errorf("decoding direct messages: %w", err)
+	}
+
+	users := map[string][2]string{}
+	for _, u := range resp.Includes.Users {
+		users[u.ID] = [2]string{u.Username, u.Name}
+	}
+
+	page := dmPage{NextToken: resp.Meta.NextToken}
+	for _, m := range resp.Data {
+		sender := users[m.SenderID]
+		page.Messages = append(page.Messages, dmEvent{
+			ID:             m.ID,
+			ConversationID: m.ConversationID,
+			Text:           m.Text,
+			CreatedAt:      m.CreatedAt,
+			SenderID:       m.SenderID,
+			Username:       sender[0],
+			Name:           sender[1],
+		})
+	}
+	return page, nil
+}
+
+// sendDM posts text to a direct message conversation and returns the new
+// message's event ID.
+func sendDM(client *http.Client, cfg config.Config, conversationID, text string) (string, error) {
+	if err := checkAllowed(cfg, actionDM); err != nil {
+		return "", err
+	}
+	body, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return "", err
+	}
+
+	status, respBody, err := sendSigned(client, cfg, http.MethodPost, fmt.Sprintf(dmConversationEndpoint, conversationID), body, "application/json")
+	if err != nil {
+		return "", fmt.Errorf("performing request: %w", err)
+	}
+	if status >= 300 {
+		return "", newAPIError(status, respBody)
+	}
+
+	var resp struct {
+		Data struct {
+			EventID string `json:"dm_event_id"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		return "", fmt.Errorf("decoding response: %w", err)
+	}
+	return resp.Data.EventID, nil
+}
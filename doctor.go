@@ -0,0 +1,313 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/kalikim/x-cli/config"
+	"github.com/spf13/cobra"
+)
+
+const usersMeEndpoint = "https://api.twitter.com/2/users/me"
+
+type checkStatus int
+
+const (
+	checkPass checkStatus = iota
+	checkWarn
+	checkFail
+)
+
+// checkResult is the outcome of one doctor check, with a suggested fix for
+// anything that is not a pass.
+type checkResult struct {
+	name   string
+	status checkStatus
+	detail string
+	fix    string
+}
+
+func newDoctorCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "doctor",
+		Short: "Diagnose configuration, credentials, clock and network problems",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client := apiClientWithTimeout(10 * time.Second)
+
+			var results []checkResult
+			results = append(results, checkConfigFile()...)
+
+			cfg := config.LoadConfig()
+			results = append(results, checkCredentials(client, cfg)...)
+			results = append(results, checkNetwork(client)...)
+			results = append(results, checkStores(cfg)...)
+
+			failed := 0
+			for _, r := range results {
+				switch r.status {
+				case checkPass:
+					say("✅", "%s: %s", r.name, r.detail)
+				case checkWarn:
+					say("⚠️", "%s: %s", r.name, r.detail)
+				case checkFail:
+					say("❌", "%s: %s", r.name, r.detail)
+					failed++
+				}
+				if r.fix != "" && r.status != checkPass {
+					fmt.Println("   " + decorate("💡", r.fix))
+				}
+			}
+
+			if failed > 0 {
+				return fmt.Errorf("%d check(s) failed", failed)
+			}
+			return nil
+		},
+	}
+}
+
+func checkConfigFile() []checkResult {
+	if config.EnvOnly {
+		data := strings.TrimSpace(os.Getenv("XCLI_CONFIG"))
+		if data == "" {
+			return []checkResult{{name: "Config file", status: checkPass, detail: "config files ignored; using environment variables only"}}
+		}
+		problems, err := config.CheckFile("$XCLI_CONFIG", []byte(data))
+		if err != nil {
+			return []checkResult{{name: "Config", status: checkFail, detail: fmt.Sprintf("XCLI_CONFIG is not valid JSON: %v", err),
+				fix: "Fix the syntax error; x-cli currently ignores it"}}
+		}
+		results := []checkResult{{name: "Config", status: checkPass, detail: "XCLI_CONFIG (config files ignored)"}}
+		for _, p := range problems {
+			results = append(results, checkResult{name: "Config key", status: checkWarn, detail: p.Key + ": " + p.Message,
+				fix: "Correct or remove the key in XCLI_CONFIG"})
+		}
+		return results
+	}
+
+	for _, path := range config.Paths() {
+		info, err := os.Stat(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return []checkResult{{name: "Config file", status: checkFail, detail: err.Error()}}
+		}
+
+		results := []checkResult{}
+
+		data, err := os.ReadFile(path)
+		var problems []config.Problem
+		if err == nil {
+			problems, err = config.CheckFile(path, data)
+		}
+		if err != nil {
+			results = append(results, checkResult{name: "Config file", status: checkFail,
+				detail: fmt.Sprintf("%s is not valid JSON: %v", path, err),
+				fix:    "Fix the syntax error; x-cli currently ignores this file"})
+		} else {
+			results = append(results, checkResult{name: "Config file", status: checkPass, detail: path})
+		}
+		for _, p := range problems {
+			results = append(results, checkResult{name: "Config key", status: checkWarn, detail: p.Key + ": " + p.Message,
+				fix: "Correct or remove the key in " + path})
+		}
+
+		if runtime.GOOS != "windows" && info.Mode().Perm()&0077 != 0 {
+			results = append(results, checkResult{name: "Config permissions", status: checkWarn,
+				detail: fmt.Sprintf("%s is readable by other users (%s)", path, info.Mode().Perm()),
+				fix:    "chmod 600 " + path})
+		}
+
+		return results
+	}
+
+	return []checkResult{{name: "Config file", status: checkWarn, detail: "no config file found; using environment variables",
+		fix: "Create ~/.x-cli/config.json (see README) or export the TWITTER_* variables"}}
+}
+
+// checkCredentials verifies every profile against the users/me endpoint and
+// uses the response's Date header to measure clock skew, which breaks OAuth
+// 1.0a signatures when it exceeds a few minutes.
+func checkCredentials(client *http.Client, cfg config.Config) []checkResult {
+	profiles := []string{""}
+	for name := range cfg.Profiles {
+		profiles = append(profiles, name)
+	}
+
+	var results []checkResult
+	var serverDate time.Time
+
+	for _, profile := range profiles {
+		name := "Credentials"
+		if profile != "" {
+			name += " (" + profile + ")"
+		}
+
+		pcfg, err := cfg.ForProfile(profile)
+		if err == nil {
+			err = pcfg.Validate()
+		}
+		if err != nil {
+			results = append(results, checkResult{name: name, status: checkFail, detail: err.Error(),
+				fix: "Set the missing values in config.json or the environment"})
+			continue
+		}
+
+		status, date, err := probeCredentials(client, pcfg)
+		if serverDate.IsZero() {
+			serverDate = date
+		}
+		switch {
+		case err != nil:
+			results = append(results, checkResult{name: name, status: checkFail, detail: err.Error(),
+				fix: "Check your network connection; credentials could not be verified"})
+		case status == http.StatusOK:
+			results = append(results, checkResult{name: name, status: checkPass, detail: "accepted by the X API"})
+		case status == http.StatusUnauthorized:
+			results = append(results, checkResult{name: name, status: checkFail, detail: "rejected by the X API (HTTP 401)",
+				fix: "Regenerate the access token and secret in the developer portal, or check the clock below"})
+		default:
+			results = append(results, checkResult{name: name, status: checkWarn, detail: fmt.Sprintf("X API answered HTTP %d", status),
+				fix: "Make sure the app has read and write access"})
+		}
+	}
+
+	results = append(results, checkClockSkew(serverDate))
+	return results
+}
+
+func probeCredentials(client *http.Client, cfg config.Config) (int, time.Time, error) {
+	req, err := http.NewRequest(http.MethodGet, usersMeEndpoint, nil)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	if err := signRequest(req, cfg); err != nil {
+		return 0, time.Time{}, err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	resp.Body.Close()
+
+	date, _ := http.ParseTime(resp.Header.Get("Date"))
+	return resp.StatusCode, date, nil
+}
+
+func checkClockSkew(serverDate time.Time) checkResult {
+	if serverDate.IsZero() {
+		return checkResult{name: "Clock", status: checkWarn, detail: "could not read the server time to measure skew"}
+	}
+
+	skew := time.Since(serverDate).Round(time.Second)
+	abs := skew
+	if abs < 0 {
+		abs = -abs
+	}
+
+	switch {
+	case abs > 5*time.Minute:
+		return checkResult{name: "Clock", status: checkFail, detail: fmt.Sprintf("local clock is off by %s; OAuth signatures will be rejected", skew),
+			fix: "Enable time synchronization (NTP), e.g. 'timedatectl set-ntp true'"}
+	case abs > 30*time.Second:
+		return checkResult{name: "Clock", status: checkWarn, detail: fmt.Sprintf("local clock is off by %s", skew),
+			fix: "Enable time synchronization (NTP) before the drift grows"}
+	}
+	return checkResult{name: "Clock", status: checkPass, detail: fmt.Sprintf("within %s of the X API", abs)}
+}
+
+func checkNetwork(client *http.Client) []checkResult {
+	var results []checkResult
+
+	for _, endpoint := range []string{tweetEndpoint, mediaUploadEndpoint} {
+		u, _ := url.Parse(endpoint)
+		name := "Network " + u.Host
+
+		via, fix := "", "Check DNS, firewall rules and HTTPS_PROXY"
+		if proxy, err := http.ProxyFromEnvironment(&http.Request{URL: u}); err == nil && proxy != nil {
+			via = " via proxy " + proxy.Host
+			fix = "Check that the proxy " + proxy.Host + " is reachable and allows " + u.Host
+		}
+
+		start := time.Now()
+		resp, err := client.Head("https://" + u.Host + "/")
+		if err != nil {
+			results = append(results, checkResult{name: name, status: checkFail, detail: "unreachable" + via + ": " + err.Error(), fix: fix})
+			continue
+		}
+		resp.Body.Close()
+
+		results = append(results, checkResult{name: name, status: checkPass,
+			detail: fmt.Sprintf("reachable%s in %s", via, time.Since(start).Round(time.Millisecond))})
+	}
+
+	return results
+}
+
+// checkStores verifies that every schedule file and the history parse, and
+// flags scheduled tweets that would fail when the daemon gets to them.
+func checkStores(cfg config.Config) []checkResult {
+	var results []checkResult
+
+	for _, queue := range knownQueues(cfg) {
+		name := "Schedule " + storeLocation(scheduleFile(queue))
+		tweets, err := loadScheduledTweets(queue)
+		if err != nil {
+			results = append(results, checkResult{name: name, status: checkFail, detail: err.Error(),
+				fix: "Restore the file from a backup or fix the JSON by hand"})
+			continue
+		}
+
+		var problems []string
+		seen := map[string]bool{}
+		for _, t := range tweets {
+			switch {
+			case t.ID == "":
+				problems = append(problems, "a tweet has no ID")
+			case seen[t.ID]:
+				problems = append(problems, "duplicate ID "+t.ID)
+			}
+			seen[t.ID] = true
+
+			if err := validateTweetText(t.Text); err != nil {
+				problems = append(problems, fmt.Sprintf("%s: %v", t.ID, err))
+			}
+			if t.Image != "" {
+				if _, err := os.Stat(t.Image); err != nil {
+					problems = append(problems, fmt.Sprintf("%s: image %s is missing", t.ID, t.Image))
+				}
+			}
+		}
+
+		if len(problems) > 0 {
+			for _, p := range problems {
+				results = append(results, checkResult{name: name, status: checkWarn, detail: p,
+					fix: "Cancel or fix the tweet with 'x-cli scheduler cancel'"})
+			}
+			continue
+		}
+		results = append(results, checkResult{name: name, status: checkPass, detail: fmt.Sprintf("%d tweet(s) OK", len(tweets))})
+	}
+
+	if _, err := loadHistory(); err != nil {
+		results = append(results, checkResult{name: "History " + storeLocation(historyFile), status: checkFail, detail: err.Error(),
+			fix: "Move the corrupt file aside; a new history will be started"})
+	} else {
+		results = append(results, checkResult{name: "History " + storeLocation(historyFile), status: checkPass, detail: "readable"})
+	}
+
+	if _, err := loadSchedulerState(); err != nil {
+		results = append(results, checkResult{name: "Scheduler state", status: checkFail, detail: err.Error(),
+			fix: "Delete " + schedulerStateFile + " and pause again if needed"})
+	}
+
+	return results
+}
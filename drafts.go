@@ -0,0 +1,179 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+const draftsFile = "drafts.json"
+
+// tweetDraft is unfinished tweet text, saved with 'draft save' or left in
+// the TUI composer on quit.
+type tweetDraft struct {
+	ID      string    `json:"id"`
+	Text    string    `json:"text"`
+	Image   string    `json:"image,omitempty"`
+	SavedAt time.Time `json:"saved_at"`
+}
+
+func newDraftCmd() *cobra.Command {
+	draftCmd := &cobra.Command{
+		Use:   "draft",
+		Short: "Keep unfinished tweets for later",
+		Long: "Keep unfinished tweets in " + draftsFile + " in the current directory. The TUI\n" +
+			"saves the text left in its composer when it quits and reopens it next time.\n" +
+			"Drafts are covered by 'history search'.",
+	}
+
+	var text, image string
+	saveCmd := &cobra.Command{
+		Use:     "save",
+		Short:   "Save a draft",
+		Example: `  x-cli draft save --text "Launch post, needs the final link"`,
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			text = strings.TrimSpace(text)
+			if text == "" {
+				return invalidInput(errors.New("text flag cannot be empty"))
+			}
+			if image != "" {
+				if err := validateMedia(image); err != nil {
+					return err
+				}
+			}
+			d := tweetDraft{ID: generateDraftID(), Text: text, Image: image, SavedAt: time.Now()}
+			if err := saveDraft(d); err != nil {
+				return err
+			}
+			say("📝", "Draft %s saved", d.ID)
+			return nil
+		},
+	}
+	saveCmd.Flags().StringVarP(&text, "text", "t", "", "Draft text")
+	saveCmd.Flags().StringVarP(&image, "image", "i", "", "Image to post with the draft")
+
+	var listOutput string
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List drafts, newest first",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			drafts, err := loadDrafts()
+			if err != nil {
+				return fmt.Errorf("loading drafts: %w", err)
+			}
+			if len(drafts) == 0 && listOutput == "table" {
+				say("📭", "No drafts saved (use 'x-cli draft save')")
+				return nil
+			}
+
+			rows := make([][]any, 0, len(drafts))
+			for _, d := range drafts {
+				rows = append(rows, []any{d.ID, d.SavedAt.Format("2006-01-02 15:04"), d.Image, d.Text})
+			}
+			return writeRows(listOutput, []string{"id", "saved", "image", "text"}, rows)
+		},
+	}
+	listCmd.Flags().StringVarP(&listOutput, "output", "o", "table", "Output format: table, json, yaml or tsv")
+
+	removeCmd := &cobra.Command{
+		Use:   "remove <id>",
+		Short: "Delete a draft",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			removed, err := removeDraft(args[0])
+			if err != nil {
+				return err
+			}
+			if !removed {
+				return invalidInput(fmt.Errorf("no draft %s (see 'x-cli draft list')", args[0]))
+			}
+			say("🗑️", "Removed draft %s", args[0])
+			return nil
+		},
+	}
+
+	draftCmd.AddCommand(saveCmd, listCmd, removeCmd)
+	return draftCmd
+}
+
+func generateDraftID() string {
+	return fmt.Sprintf("draft_%d", time.Now().UnixNano())
+}
+
+// loadDrafts returns the saved drafts, newest first.
+func loadDrafts() ([]tweetDraft, error) {
+	data, err := os.ReadFile(draftsFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return decodeDrafts(data)
+}
+
+func decodeDrafts(data []byte) ([]tweetDraft, error) {
+	if data == nil {
+		return nil, nil
+	}
+	var drafts []tweetDraft
+	if err := json.Unmarshal(data, &drafts); err != nil {
+		return nil, err
+	}
+	return drafts, nil
+}
+
+// updateDrafts applies fn to the saved drafts under the store lock.
+func updateDrafts(fn func([]tweetDraft) []tweetDraft) error {
+	return withFileLock(draftsFile, func() error {
+		drafts, err := loadDrafts()
+		if err != nil {
+			return fmt.Errorf("loading drafts: %w", err)
+		}
+
+		drafts = fn(drafts)
+		sort.SliceStable(drafts, func(i, j int) bool { return drafts[i].SavedAt.After(drafts[j].SavedAt) })
+
+		data, err := json.MarshalIndent(drafts, "", "  ")
+		if err != nil {
+			return err
+		}
+		return writeFileAtomic(draftsFile, data, 0644)
+	})
+}
+
+// saveDraft adds d, or replaces the draft with its ID.
+func saveDraft(d tweetDraft) error {
+	return updateDrafts(func(drafts []tweetDraft) []tweetDraft {
+		for i := range drafts {
+			if drafts[i].ID == d.ID {
+				drafts[i] = d
+				return drafts
+			}
+		}
+		return append(drafts, d)
+	})
+}
+
+// removeDraft deletes the draft id and reports whether it existed.
+func removeDraft(id string) (bool, error) {
+	removed := false
+	err := updateDrafts(func(drafts []tweetDraft) []tweetDraft {
+		for i := range drafts {
+			if drafts[i].ID == id {
+				removed = true
+				return append(drafts[:i], drafts[i+1:]...)
+			}
+		}
+		return drafts
+	})
+	return removed, err
+}
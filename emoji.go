@@ -0,0 +1,170 @@
+package main
+
+import "regexp"
+
+var shortcodePattern = regexp.MustCompile(`:([a-z0-9_+\-]+):`)
+
+// emojiShortcodes maps the commonly used GitHub/Slack style shortcodes to
+// their Unicode emoji.
+var emojiShortcodes = map[string]string{
+	"+1":                       "👍",
+	"-1":                       "👎",
+	"100":                      "💯",
+	"alarm_clock":              "⏰",
+	"angry":                    "😠",
+	"apple":                    "🍎",
+	"arrow_down":               "⬇️",
+	"arrow_left":               "⬅️",
+	"arrow_right":              "➡️",
+	"arrow_up":                 "⬆️",
+	"art":                      "🎨",
+	"balloon":                  "🎈",
+	"bangbang":                 "‼️",
+	"bell":                     "🔔",
+	"birthday":                 "🎂",
+	"blue_heart":               "💙",
+	"book":                     "📖",
+	"books":                    "📚",
+	"boom":                     "💥",
+	"bowtie":                   "🎀",
+	"brain":                    "🧠",
+	"briefcase":                "💼",
+	"bug":                      "🐛",
+	"bulb":                     "💡",
+	"calendar":                 "📆",
+	"camera":                   "📷",
+	"cat":                      "🐱",
+	"chart_with_upwards_trend": "📈",
+	"check":                    "✔️",
+	"checkered_flag":           "🏁",
+	"clap":                     "👏",
+	"clipboard":                "📋",
+	"clock":                    "🕐",
+	"cloud":                    "☁️",
+	"coffee":                   "☕",
+	"computer":                 "💻",
+	"confetti_ball":            "🎊",
+	"construction":             "🚧",
+	"cool":                     "🆒",
+	"cry":                      "😢",
+	"crystal_ball":             "🔮",
+	"dart":                     "🎯",
+	"dog":                      "🐶",
+	"earth_africa":             "🌍",
+	"earth_americas":           "🌎",
+	"earth_asia":               "🌏",
+	"email":                    "📧",
+	"eyes":                     "👀",
+	"facepalm":                 "🤦",
+	"fire":                     "🔥",
+	"flushed":                  "😳",
+	"gem":                      "💎",
+	"gift":                     "🎁",
+	"globe_with_meridians":     "🌐",
+	"grin":                     "😁",
+	"grinning":                 "😀",
+	"green_heart":              "💚",
+	"hammer":                   "🔨",
+	"hammer_and_wrench":        "🛠️",
+	"heart":                    "❤️",
+	"heart_eyes":               "😍",
+	"heavy_check_mark":         "✔️",
+	"hourglass":                "⌛",
+	"hugs":                     "🤗",
+	"hundred":                  "💯",
+	"information_source":       "ℹ️",
+	"joy":                      "😂",
+	"key":                      "🔑",
+	"laughing":                 "😆",
+	"link":                     "🔗",
+	"lock":                     "🔒",
+	"loudspeaker":              "📢",
+	"mag":                      "🔍",
+	"mega":                     "📣",
+	"memo":                     "📝",
+	"microphone":               "🎤",
+	"money_with_wings":         "💸",
+	"moneybag":                 "💰",
+	"moon":                     "🌙",
+	"muscle":                   "💪",
+	"musical_note":             "🎵",
+	"new":                      "🆕",
+	"newspaper":                "📰",
+	"ok":                       "🆗",
+	"ok_hand":                  "👌",
+	"package":                  "📦",
+	"paperclip":                "📎",
+	"party":                    "🥳",
+	"partying_face":            "🥳",
+	"pencil":                   "📝",
+	"pencil2":                  "✏️",
+	"pin":                      "📌",
+	"pizza":                    "🍕",
+	"point_down":               "👇",
+	"point_left":               "👈",
+	"point_right":              "👉",
+	"point_up":                 "☝️",
+	"pray":                     "🙏",
+	"pushpin":                  "📌",
+	"purple_heart":             "💜",
+	"question":                 "❓",
+	"raised_hands":             "🙌",
+	"recycle":                  "♻️",
+	"red_circle":               "🔴",
+	"robot":                    "🤖",
+	"rocket":                   "🚀",
+	"rofl":                     "🤣",
+	"rose":                     "🌹",
+	"rotating_light":           "🚨",
+	"runner":                   "🏃",
+	"sad":                      "😞",
+	"seedling":                 "🌱",
+	"shield":                   "🛡️",
+	"shrug":                    "🤷",
+	"smile":                    "😄",
+	"smiley":                   "😃",
+	"smirk":                    "😏",
+	"sob":                      "😭",
+	"sparkles":                 "✨",
+	"sparkling_heart":          "💖",
+	"speech_balloon":           "💬",
+	"star":                     "⭐",
+	"star2":                    "🌟",
+	"stopwatch":                "⏱️",
+	"sun":                      "☀️",
+	"sunglasses":               "😎",
+	"sunny":                    "☀️",
+	"sweat_smile":              "😅",
+	"tada":                     "🎉",
+	"thinking":                 "🤔",
+	"thought_balloon":          "💭",
+	"thumbsdown":               "👎",
+	"thumbsup":                 "👍",
+	"ticket":                   "🎫",
+	"tools":                    "🛠️",
+	"trophy":                   "🏆",
+	"tv":                       "📺",
+	"unlock":                   "🔓",
+	"v":                        "✌️",
+	"warning":                  "⚠️",
+	"wave":                     "👋",
+	"white_check_mark":         "✅",
+	"wink":                     "😉",
+	"world_map":                "🗺️",
+	"wrench":                   "🔧",
+	"x":                        "❌",
+	"yellow_heart":             "💛",
+	"zap":                      "⚡",
+	"zzz":                      "💤",
+}
+
+// expandShortcodes replaces known :shortcode: sequences in text with their
+// emoji, leaving unknown ones untouched.
+func expandShortcodes(text string) string {
+	return shortcodePattern.ReplaceAllStringFunc(text, func(match string) string {
+		if emoji, ok := emojiShortcodes[match[1:len(match)-1]]; ok {
+			return emoji
+		}
+		return match
+	})
+}
@@ -0,0 +1,338 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/kalikim/x-cli/config"
+	"github.com/spf13/cobra"
+)
+
+// hookSchemaVersion is bumped only when a field of hookEvent is removed,
+// renamed or changes meaning. Adding fields does not change it.
+const hookSchemaVersion = 1
+
+// Event types sent to webhooks.
+const (
+	eventTweetPosted   = "tweet.posted"
+	eventTweetFailed   = "tweet.failed"
+	eventTweetUpcoming = "tweet.upcoming"
+	eventAlertMatched  = "alert.matched"
+)
+
+var hookEventTypes = []string{eventTweetPosted, eventTweetFailed, eventTweetUpcoming, eventAlertMatched}
+
+// hookEvent is the JSON body POSTed to webhooks: the configured webhooks
+// for tweet events, and alert webhooks for matches. Title, Text and Tweets
+// are the notification summary, kept at the top level as alerts have always
+// sent them.
+type hookEvent struct {
+	SchemaVersion int                `json:"schema_version"`
+	ID            string             `json:"id"`
+	Type          string             `json:"type"`
+	OccurredAt    time.Time          `json:"occurred_at"`
+	Test          bool               `json:"test,omitempty"`
+	Account       hookAccount        `json:"account"`
+	Tweet         *notificationTweet `json:"tweet,omitempty"`
+	Error         string             `json:"error,omitempty"`
+	CancelCommand string             `json:"cancel_command,omitempty"`
+	Alert         *hookAlert         `json:"alert,omitempty"`
+	notification
+}
+
+// hookAccount identifies the account an event is about.
+type hookAccount struct {
+	ID string `json:"id"`
+}
+
+// hookAlert identifies the saved search behind an alert.matched event.
+type hookAlert struct {
+	ID    string `json:"id"`
+	Query string `json:"query"`
+}
+
+// hookEventSchema describes hookEvent as JSON Schema for downstream tools.
+const hookEventSchema = `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "$id": "https://github.com/kalikim/x-cli/hook-event-v1.json",
+  "title": "x-cli hook event",
+  "type": "object",
+  "required": ["schema_version", "id", "type", "occurred_at", "account", "title", "text"],
+  "properties": {
+    "schema_version": {"const": 1},
+    "id": {"type": "string", "description": "Unique per event; use it to drop duplicates"},
+    "type": {"enum": ["tweet.posted", "tweet.failed", "tweet.upcoming", "alert.matched"]},
+    "occurred_at": {"type": "string", "format": "date-time"},
+    "test": {"type": "boolean", "description": "Present and true for events sent by 'x-cli hooks test'"},
+    "account": {
+      "type": "object",
+      "required": ["id"],
+      "properties": {
+        "id": {"type": "string", "description": "User ID of the posting account; empty when unknown"}
+      }
+    },
+    "tweet": {"$ref": "#/$defs/tweet", "description": "The tweet posted, failing or about to be posted (tweet.* events)"},
+    "error": {"type": "string", "description": "Why posting failed (tweet.failed)"},
+    "cancel_command": {"type": "string", "description": "Command that cancels the tweet before it is posted (tweet.upcoming)"},
+    "alert": {
+      "type": "object",
+      "description": "The saved search that matched (alert.matched)",
+      "required": ["id", "query"],
+      "properties": {
+        "id": {"type": "string"},
+        "query": {"type": "string"}
+      }
+    },
+    "title": {"type": "string", "description": "Human-readable summary; wording may change"},
+    "text": {"type": "string", "description": "Human-readable details; wording may change"},
+    "tweets": {"type": "array", "items": {"$ref": "#/$defs/tweet"}, "description": "Matching tweets, newest first (alert.matched)"}
+  },
+  "$defs": {
+    "tweet": {
+      "type": "object",
+      "required": ["text"],
+      "properties": {
+        "id": {"type": "string", "description": "Absent until the tweet is posted"},
+        "text": {"type": "string"},
+        "username": {"type": "string"},
+        "url": {"type": "string", "format": "uri"},
+        "created_at": {"type": "string", "format": "date-time"},
+        "queue": {"type": "string", "description": "Scheduler queue; empty for the default queue"},
+        "scheduled_id": {"type": "string", "description": "ID of the scheduled tweet in x-cli"},
+        "scheduled_for": {"type": "string", "format": "date-time"},
+        "has_media": {"type": "boolean"},
+        "labels": {"type": "array", "items": {"type": "string"}},
+        "campaign": {"type": "string"}
+      }
+    }
+  }
+}
+`
+
+func newHooksCmd() *cobra.Command {
+	hooksCmd := &cobra.Command{
+		Use:   "hooks",
+		Short: "Inspect and test the events sent to webhooks",
+	}
+
+	schemaCmd := &cobra.Command{
+		Use:   "schema",
+		Short: "Print the JSON Schema of webhook events",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			fmt.Print(hookEventSchema)
+			return nil
+		},
+	}
+
+	var eventType, target string
+	testCmd := &cobra.Command{
+		Use:   "test",
+		Short: "Send a sample event to the configured webhooks",
+		Long: "Send a sample event, marked \"test\": true, to every configured webhook that\n" +
+			"subscribes to --event, or to --url. Use it to set up and check automations\n" +
+			"without posting.",
+		Example: `  x-cli hooks test
+  x-cli hooks test --event tweet.failed
+  x-cli hooks test --url https://hooks.zapier.com/hooks/catch/123/abc`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !slices.Contains(hookEventTypes, eventType) {
+				return invalidInput(fmt.Errorf("invalid --event %q (use %s)", eventType, strings.Join(hookEventTypes, ", ")))
+			}
+			cfg := config.LoadConfig()
+
+			var urls []string
+			if target != "" {
+				if err := validateWebhookURL(target); err != nil {
+					return invalidInput(err)
+				}
+				urls = []string{target}
+			} else {
+				if err := validateWebhooks(cfg.Webhooks); err != nil {
+					return err
+				}
+				for _, w := range cfg.Webhooks {
+					if webhookWants(w, eventType) {
+						urls = append(urls, w.URL)
+					}
+				}
+				if len(urls) == 0 {
+					return invalidInput(fmt.Errorf("no configured webhook receives %s events (add one under webhooks in the config, or use --url)", eventType))
+				}
+			}
+
+			ev := sampleHookEvent(cfg, eventType)
+			client := apiClient()
+			failed := 0
+			for _, u := range urls {
+				if err := postWebhook(client, u, ev); err != nil {
+					say("❌", "%s: %v", webhookHost(u), err)
+					failed++
+					continue
+				}
+				say("✅", "%s accepted the %s event", webhookHost(u), eventType)
+			}
+			if failed > 0 {
+				return fmt.Errorf("%d of %d webhook(s) failed", failed, len(urls))
+			}
+			return nil
+		},
+	}
+	testCmd.Flags().StringVar(&eventType, "event", eventTweetPosted, "Event type to send: "+strings.Join(hookEventTypes, ", "))
+	testCmd.Flags().StringVar(&target, "url", "", "Send to this URL instead of the configured webhooks")
+
+	hooksCmd.AddCommand(schemaCmd, testCmd)
+	return hooksCmd
+}
+
+// newHookEvent starts an event of type typ about cfg's account.
+func newHookEvent(cfg config.Config, typ string, n notification) hookEvent {
+	return hookEvent{
+		SchemaVersion: hookSchemaVersion,
+		ID:            fmt.Sprintf("evt_%d", time.Now().UnixNano()),
+		Type:          typ,
+		OccurredAt:    time.Now().UTC(),
+		Account:       hookAccount{ID: accessTokenUserID(cfg.AccessToken)},
+		notification:  n,
+	}
+}
+
+// tweetPostedEvent describes a tweet that was just posted.
+func tweetPostedEvent(cfg config.Config, tweet notificationTweet) hookEvent {
+	ev := newHookEvent(cfg, eventTweetPosted, notification{Title: "Tweet posted", Text: tweet.Text})
+	ev.Tweet = &tweet
+	return ev
+}
+
+// tweetFailedEvent describes a tweet that could not be posted.
+func tweetFailedEvent(cfg config.Config, tweet notificationTweet, err string) hookEvent {
+	ev := newHookEvent(cfg, eventTweetFailed, notification{Title: "Tweet failed", Text: err})
+	ev.Tweet = &tweet
+	ev.Error = err
+	return ev
+}
+
+// scheduledHookTweet is the event form of a scheduled tweet from queue,
+// posted as postedID (empty when it failed).
+func scheduledHookTweet(tweet scheduledTweet, queue, postedID string) notificationTweet {
+	t := notificationTweet{
+		Text:         tweet.postText(),
+		Queue:        queue,
+		ScheduledID:  tweet.ID,
+		HasMedia:     tweet.Image != "",
+		Labels:       tweet.Labels,
+		Campaign:     tweet.Campaign,
+		ScheduledFor: &tweet.ScheduleTime,
+	}
+	if postedID != "" {
+		now := time.Now().UTC()
+		t.ID, t.URL, t.CreatedAt = postedID, tweetURL("", postedID), &now
+	}
+	return t
+}
+
+// sampleHookEvent builds a realistic event of type typ for `hooks test`.
+func sampleHookEvent(cfg config.Config, typ string) hookEvent {
+	created := time.Now().UTC().Truncate(time.Second)
+	scheduled := created.Add(-time.Minute)
+	tweet := notificationTweet{
+		ID:           "1790000000000000000",
+		Text:         "This is a test event from x-cli",
+		URL:          tweetURL("", "1790000000000000000"),
+		CreatedAt:    &created,
+		Queue:        "",
+		ScheduledID:  "tweet_1700000000000000000",
+		ScheduledFor: &scheduled,
+		Labels:       []string{"test"},
+	}
+
+	var ev hookEvent
+	switch typ {
+	case eventTweetFailed:
+		tweet.ID, tweet.URL, tweet.CreatedAt = "", "", nil
+		ev = tweetFailedEvent(cfg, tweet, "X API error (status 403): sample failure")
+	case eventTweetUpcoming:
+		tweet.ID, tweet.URL, tweet.CreatedAt = "", "", nil
+		upcoming := created.Add(15 * time.Minute)
+		tweet.ScheduledFor = &upcoming
+		ev = newHookEvent(cfg, eventTweetUpcoming, notification{Title: "Posting in 15 minutes", Text: tweet.Text})
+		ev.Tweet = &tweet
+		ev.CancelCommand = cancelCommand("", tweet.ScheduledID)
+	case eventAlertMatched:
+		tweet.Username = "example"
+		tweet.URL = tweetURL("example", tweet.ID)
+		tweet.ScheduledID, tweet.ScheduledFor, tweet.Labels = "", nil, nil
+		alert := savedAlert{ID: "1", Query: "x-cli"}
+		ev = newHookEvent(cfg, eventAlertMatched, alertNotification(alert, []timelineTweet{{ID: tweet.ID, Text: tweet.Text, Username: tweet.Username, CreatedAt: created}}))
+		ev.Alert = &hookAlert{ID: alert.ID, Query: alert.Query}
+	default:
+		ev = tweetPostedEvent(cfg, tweet)
+	}
+	ev.Test = true
+	return ev
+}
+
+// emitEvent delivers ev to every configured webhook subscribed to its type.
+// Failures are logged; the event is not retried.
+func emitEvent(client *http.Client, cfg config.Config, ev hookEvent) {
+	for _, w := range cfg.Webhooks {
+		if !webhookWants(w, ev.Type) {
+			continue
+		}
+		if err := validateWebhookURL(w.URL); err != nil {
+			log.Printf("Error sending %s event: %v", ev.Type, err)
+			continue
+		}
+		if err := postWebhook(client, w.URL, ev); err != nil {
+			log.Printf("Error sending %s event to %s: %v", ev.Type, webhookHost(w.URL), err)
+		}
+	}
+}
+
+// webhookWants reports whether w subscribes to events of type typ; no
+// events listed means all of them.
+func webhookWants(w config.Webhook, typ string) bool {
+	return len(w.Events) == 0 || slices.Contains(w.Events, typ)
+}
+
+// validateWebhooks checks the configured webhooks' URLs and event types.
+func validateWebhooks(webhooks []config.Webhook) error {
+	for _, w := range webhooks {
+		if err := validateWebhookURL(w.URL); err != nil {
+			return invalidInput(err)
+		}
+		for _, typ := range w.Events {
+			if !slices.Contains(hookEventTypes, typ) {
+				return invalidInput(fmt.Errorf("webhook %s: unknown event %q (use %s)", webhookHost(w.URL), typ, strings.Join(hookEventTypes, ", ")))
+			}
+		}
+	}
+	return nil
+}
+
+func validateWebhookURL(rawURL string) error {
+	if rawURL == "" {
+		return errors.New("webhook URL is empty")
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return fmt.Errorf("invalid webhook URL %q", rawURL)
+	}
+	return nil
+}
+
+// webhookHost names a webhook without its path, which usually embeds a
+// secret.
+func webhookHost(rawURL string) string {
+	if u, err := url.Parse(rawURL); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return "webhook"
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+
+	"github.com/kalikim/x-cli/config"
+)
+
+// stripEXIFFlag is --strip-exif; stripEXIFFlagSet records whether it was
+// given, so the config's keep_exif only applies otherwise.
+var (
+	stripEXIFFlag    = true
+	stripEXIFFlagSet bool
+)
+
+var errMalformedImage = errors.New("malformed image")
+
+// exifOrientationTag is the one EXIF field kept: dropping it would show
+// phone photos rotated.
+const exifOrientationTag = 0x0112
+
+// shouldStripEXIF reports whether image metadata is removed before upload.
+func shouldStripEXIF(cfg config.Config) bool {
+	if stripEXIFFlagSet {
+		return stripEXIFFlag
+	}
+	return !cfg.KeepEXIF
+}
+
+// stripImageMetadata removes EXIF, XMP, IPTC and text metadata (GPS
+// position, camera and software details, timestamps) from JPEG, PNG and
+// WebP images. Other types are returned unchanged.
+func stripImageMetadata(data []byte, mimeType string) ([]byte, error) {
+	switch mimeType {
+	case "image/jpeg":
+		return stripJPEGMetadata(data)
+	case "image/png":
+		return stripPNGMetadata(data)
+	case "image/webp":
+		return stripWebPMetadata(data)
+	}
+	return data, nil
+}
+
+// stripJPEGMetadata drops the APP1 (EXIF, XMP), APP13 (IPTC) and comment
+// segments before the image data, re-adding a minimal EXIF block when the
+// photo has a non-default orientation.
+func stripJPEGMetadata(data []byte) ([]byte, error) {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil, errMalformedImage
+	}
+
+	out := []byte{0xFF, 0xD8}
+	orientation := uint16(0)
+	inserted := false
+	insertOrientation := func() {
+		if !inserted && orientation > 1 {
+			out = append(out, orientationSegment(orientation)...)
+		}
+		inserted = true
+	}
+
+	i := 2
+	for i+4 <= len(data) {
+		if data[i] != 0xFF {
+			return nil, errMalformedImage
+		}
+		marker := data[i+1]
+		if marker == 0xFF {
+			// Fill byte.
+			i++
+			continue
+		}
+		if marker == 0xDA {
+			// Start of scan: the rest is image data.
+			insertOrientation()
+			return append(out, data[i:]...), nil
+		}
+
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		end := i + 2 + length
+		if length < 2 || end > len(data) {
+			return nil, errMalformedImage
+		}
+		segment := data[i:end]
+		payload := data[i+4 : end]
+
+		switch {
+		case marker == 0xE1:
+			if o := exifOrientation(payload); o != 0 {
+				orientation = o
+			}
+		case marker == 0xED, marker == 0xFE:
+		case marker == 0xE0:
+			// JFIF must stay first; the orientation block follows it.
+			out = append(out, segment...)
+		default:
+			insertOrientation()
+			out = append(out, segment...)
+		}
+		i = end
+	}
+
+	return nil, errMalformedImage
+}
+
+// exifOrientation reads the Orientation tag from an APP1 EXIF payload, or
+// returns 0 when there is none.
+func exifOrientation(payload []byte) uint16 {
+	tiff, ok := bytes.CutPrefix(payload, []byte("Exif\x00\x00"))
+	if !ok || len(tiff) < 8 {
+		return 0
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0
+	}
+
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd+2 > len(tiff) {
+		return 0
+	}
+	count := int(order.Uint16(tiff[ifd:]))
+	for n := 0; n < count; n++ {
+		entry := ifd + 2 + n*12
+		if entry+12 > len(tiff) {
+			return 0
+		}
+		if order.Uint16(tiff[entry:]) == exifOrientationTag {
+			return order.Uint16(tiff[entry+8:])
+		}
+	}
+	return 0
+}
+
+// orientationSegment builds an APP1 segment whose EXIF holds only the
+// orientation.
+func orientationSegment(orientation uint16) []byte {
+	var exif []byte
+	exif = append(exif, "Exif\x00\x00MM\x00\x2A"...)
+	exif = binary.BigEndian.AppendUint32(exif, 8)                  // IFD0 offset
+	exif = binary.BigEndian.AppendUint16(exif, 1)                  // one entry
+	exif = binary.BigEndian.AppendUint16(exif, exifOrientationTag) // tag
+	exif = binary.BigEndian.AppendUint16(exif, 3)                  // SHORT
+	exif = binary.BigEndian.AppendUint32(exif, 1)                  // count
+	exif = binary.BigEndian.AppendUint16(exif, orientation)
+	exif = append(exif, 0, 0)                     // value padding
+	exif = binary.BigEndian.AppendUint32(exif, 0) // no next IFD
+
+	segment := []byte{0xFF, 0xE1}
+	segment = binary.BigEndian.AppendUint16(segment, uint16(len(exif)+2))
+	return append(segment, exif...)
+}
+
+// stripPNGMetadata drops the eXIf, text and timestamp chunks.
+func stripPNGMetadata(data []byte) ([]byte, error) {
+	const signature = "\x89PNG\r\n\x1a\n"
+	if !bytes.HasPrefix(data, []byte(signature)) {
+		return nil, errMalformedImage
+	}
+
+	out := []byte(signature)
+	for i := len(signature); i < len(data); {
+		if i+12 > len(data) {
+			return nil, errMalformedImage
+		}
+		end := i + 12 + int(binary.BigEndian.Uint32(data[i:]))
+		if end > len(data) || end < i {
+			return nil, errMalformedImage
+		}
+
+		switch string(data[i+4 : i+8]) {
+		case "eXIf", "tEXt", "zTXt", "iTXt", "tIME":
+		default:
+			out = append(out, data[i:end]...)
+		}
+		i = end
+	}
+	return out, nil
+}
+
+// stripWebPMetadata drops the EXIF and XMP chunks and clears their flags in
+// the extended header.
+func stripWebPMetadata(data []byte) ([]byte, error) {
+	if len(data) < 12 || string(data[:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return nil, errMalformedImage
+	}
+
+	out := append([]byte{}, data[:12]...)
+	for i := 12; i < len(data); {
+		if i+8 > len(data) {
+			return nil, errMalformedImage
+		}
+		size := int(binary.LittleEndian.Uint32(data[i+4:]))
+		end := i + 8 + size + size%2
+		if end > len(data) {
+			if i+8+size != len(data) {
+				return nil, errMalformedImage
+			}
+			end = len(data)
+		}
+
+		switch fourCC := string(data[i : i+4]); fourCC {
+		case "EXIF", "XMP ":
+		default:
+			start := len(out)
+			out = append(out, data[i:end]...)
+			if fourCC == "VP8X" && size > 0 {
+				out[start+8] &^= 0x08 | 0x04
+			}
+		}
+		i = end
+	}
+
+	binary.LittleEndian.PutUint32(out[4:], uint32(len(out)-8))
+	return out, nil
+}
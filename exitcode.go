@@ -0,0 +1,86 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/kalikim/x-cli/config"
+)
+
+// Exit codes let shell scripts branch on the kind of failure. Anything not
+// classified below exits with exitFailure.
+const (
+	exitFailure     = 1
+	exitValidation  = 2
+	exitAuth        = 3
+	exitRateLimited = 4
+	exitNetwork     = 5
+	exitDuplicate   = 6
+)
+
+// apiError is a non-2xx response from the X API.
+type apiError struct {
+	StatusCode int
+	Body       string
+}
+
+func newAPIError(statusCode int, body []byte) *apiError {
+	return &apiError{StatusCode: statusCode, Body: strings.TrimSpace(string(body))}
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("twitter API error (%d): %s", e.StatusCode, e.Body)
+}
+
+// duplicate reports whether X rejected the tweet as a repeat of a recent one.
+func (e *apiError) duplicate() bool {
+	return e.StatusCode == http.StatusForbidden && strings.Contains(strings.ToLower(e.Body), "duplicate")
+}
+
+// validationError marks a problem with the user's input rather than with
+// X or the network.
+type validationError struct {
+	err error
+}
+
+func (e validationError) Error() string { return e.err.Error() }
+func (e validationError) Unwrap() error { return e.err }
+
+func invalidInput(err error) error {
+	return validationError{err: err}
+}
+
+// exitCode maps err to the process exit status.
+func exitCode(err error) int {
+	var apiErr *apiError
+	if errors.As(err, &apiErr) {
+		switch {
+		case apiErr.duplicate():
+			return exitDuplicate
+		case apiErr.StatusCode == http.StatusUnauthorized, apiErr.StatusCode == http.StatusForbidden:
+			return exitAuth
+		case apiErr.StatusCode == http.StatusTooManyRequests:
+			return exitRateLimited
+		}
+		return exitFailure
+	}
+
+	var invalid validationError
+	if errors.As(err, &invalid) {
+		return exitValidation
+	}
+
+	if errors.Is(err, config.ErrMissingCredentials) {
+		return exitAuth
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return exitNetwork
+	}
+
+	return exitFailure
+}
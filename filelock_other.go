@@ -0,0 +1,68 @@
+//go:build !unix
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+const (
+	lockRetryInterval = 50 * time.Millisecond
+	lockWaitLimit     = 10 * time.Second
+	// lockStaleAfter is far longer than any read-modify-write cycle, so an
+	// older lock file was left behind by a crashed process.
+	lockStaleAfter = time.Minute
+)
+
+// lockFile creates path exclusively, waiting while another process holds it.
+func lockFile(path string) (func(), error) {
+	deadline := time.Now().Add(lockWaitLimit)
+
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			fmt.Fprintf(f, "%d\n", os.Getpid())
+			f.Close()
+			return func() { os.Remove(path) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("creating lock file: %w", err)
+		}
+
+		if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > lockStaleAfter {
+			os.Remove(path)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out waiting for %s; remove it if no other x-cli is running", path)
+		}
+		time.Sleep(lockRetryInterval)
+	}
+}
+
+// tryLockFile is like lockFile but returns errLockHeld instead of waiting.
+// Without kernel locks a crashed holder leaves its file behind, so the lock
+// is only honored while the daemon heartbeat it belongs to is fresh.
+func tryLockFile(path string) (func(), error) {
+	for attempt := 0; attempt < 2; attempt++ {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			fmt.Fprintf(f, "%d\n", os.Getpid())
+			f.Close()
+			return func() { os.Remove(path) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("creating lock file: %w", err)
+		}
+
+		if daemonAlive(lockHolder(path), time.Now()) {
+			return nil, errLockHeld
+		}
+		os.Remove(path)
+	}
+
+	return nil, errLockHeld
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// followUp is a reply scheduled relative to the moment its parent tweet is
+// posted. Delay is kept as text (e.g. "2h", "1d") for readability in the
+// schedule file.
+type followUp struct {
+	Text  string `json:"text"`
+	Delay string `json:"delay"`
+}
+
+// parseDelay parses a Go duration, additionally accepting a whole number of
+// days such as "2d".
+func parseDelay(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, invalidInput(fmt.Errorf("invalid delay %q", value))
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, invalidInput(fmt.Errorf("invalid delay %q (use e.g. 30m, 2h or 1d)", value))
+	}
+	if d <= 0 {
+		return 0, invalidInput(fmt.Errorf("delay must be positive, got %q", value))
+	}
+
+	return d, nil
+}
+
+// newFollowUpTweet builds the scheduler entry replying to parentID once the
+// follow-up's delay has elapsed after postedAt.
+func newFollowUpTweet(parentID string, fu followUp, postedAt time.Time) (scheduledTweet, error) {
+	delay, err := parseDelay(fu.Delay)
+	if err != nil {
+		return scheduledTweet{}, err
+	}
+
+	return scheduledTweet{
+		Text:         fu.Text,
+		ReplyTo:      parentID,
+		ScheduleTime: postedAt.Add(delay),
+		ID:           generateTweetID(),
+	}, nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+
+	"github.com/kalikim/x-cli/config"
+)
+
+// footerData is the data available to footer templates.
+type footerData struct {
+	Date    string
+	Weekday string
+}
+
+// renderFooter executes the configured footer template. It returns "" when no
+// footer is configured.
+func renderFooter(cfg config.Config) (string, error) {
+	if strings.TrimSpace(cfg.Footer) == "" {
+		return "", nil
+	}
+
+	t, err := template.New("footer").Parse(cfg.Footer)
+	if err != nil {
+		return "", fmt.Errorf("parsing footer template: %w", err)
+	}
+
+	now := time.Now()
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, footerData{Date: now.Format("2006-01-02"), Weekday: now.Weekday().String()}); err != nil {
+		return "", fmt.Errorf("rendering footer template: %w", err)
+	}
+
+	return strings.TrimRightFunc(buf.String(), unicode.IsSpace), nil
+}
+
+// appendFooter adds footer to text. Footers starting with whitespace are
+// appended verbatim; others go on their own paragraph. A footer already
+// present at the end of text is not added twice.
+func appendFooter(text, footer string) string {
+	if footer == "" || strings.HasSuffix(text, strings.TrimSpace(footer)) {
+		return text
+	}
+
+	if r := []rune(footer)[0]; unicode.IsSpace(r) {
+		return text + footer
+	}
+	return text + "\n\n" + footer
+}
+
+// applyFooter appends the configured footer to text and checks that the
+// combined tweet still fits.
+func applyFooter(cfg config.Config, text string) (string, error) {
+	footer, err := renderFooter(cfg)
+	if err != nil {
+		return "", err
+	}
+
+	combined := appendFooter(text, footer)
+	if footer != "" && tweetLength(combined) > maxTweetLength {
+		return "", fmt.Errorf("tweet text plus footer is %d characters, exceeding the %d character limit by %d (use --no-footer to omit the footer)",
+			tweetLength(combined), maxTweetLength, tweetLength(combined)-maxTweetLength)
+	}
+
+	return combined, nil
+}
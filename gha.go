@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/kalikim/x-cli/config"
+)
+
+// gha is set by --gha, which makes the output suit a GitHub Actions step:
+// secrets are masked, warnings and errors become annotations, and the
+// posted tweet is exposed as step outputs.
+var gha struct {
+	enabled bool
+	masked  map[string]bool
+
+	tweetID     string
+	scheduled   string
+	scheduledAt time.Time
+}
+
+func configureGHA() {
+	gha.enabled = true
+	gha.masked = map[string]bool{}
+	log.SetFlags(0)
+	log.SetOutput(ghaLogWriter{})
+	config.Warn = func(msg string) {
+		ghaCommand("warning", msg)
+	}
+	config.Loaded = func(cfg config.Config) {
+		for _, secret := range cfg.Secrets() {
+			if !gha.masked[secret] && secret != config.CredentialFallback {
+				gha.masked[secret] = true
+				fmt.Println("::add-mask::" + ghaEscape(secret))
+			}
+		}
+	}
+}
+
+// ghaCommand prints a workflow command such as an error annotation, which
+// the run's summary lists like a problem matcher's findings.
+func ghaCommand(kind, msg string) {
+	fmt.Printf("::%s title=x-cli::%s\n", kind, ghaEscape(msg))
+}
+
+// ghaEscape encodes the characters workflow commands cannot carry.
+func ghaEscape(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// ghaLogWriter turns log output, which reports problems, into annotations:
+// warnings when decorated as one and errors otherwise.
+type ghaLogWriter struct{}
+
+func (ghaLogWriter) Write(p []byte) (int, error) {
+	msg := strings.TrimSpace(string(p))
+	for _, prefix := range []string{"⚠️ ", icons["⚠️"].label + " "} {
+		if rest, ok := strings.CutPrefix(msg, prefix); ok {
+			ghaCommand("warning", rest)
+			return len(p), nil
+		}
+	}
+	ghaCommand("error", strings.TrimPrefix(msg, icons["❌"].label+" "))
+	return len(p), nil
+}
+
+// noteGHATweet remembers the first tweet posted, the root of a thread.
+func noteGHATweet(tweetID string) {
+	if gha.enabled && gha.tweetID == "" {
+		gha.tweetID = tweetID
+	}
+}
+
+func noteGHAScheduled(tweet scheduledTweet) {
+	if gha.enabled && gha.scheduled == "" {
+		gha.scheduled, gha.scheduledAt = tweet.ID, tweet.ScheduleTime
+	}
+}
+
+// writeGHAOutputs appends the step outputs to $GITHUB_OUTPUT: tweet-id and
+// tweet-url for a posted tweet, scheduled-id and scheduled-at for a
+// scheduled one.
+func writeGHAOutputs() {
+	path := os.Getenv("GITHUB_OUTPUT")
+	if !gha.enabled || path == "" {
+		return
+	}
+
+	var out strings.Builder
+	if gha.tweetID != "" {
+		fmt.Fprintf(&out, "tweet-id=%s\ntweet-url=%s\n", gha.tweetID, tweetURL("", gha.tweetID))
+	}
+	if gha.scheduled != "" {
+		fmt.Fprintf(&out, "scheduled-id=%s\nscheduled-at=%s\n", gha.scheduled, gha.scheduledAt.Format(time.RFC3339))
+	}
+	if out.Len() == 0 {
+		return
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err == nil {
+		_, err = f.WriteString(out.String())
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if err != nil {
+		ghaCommand("warning", fmt.Sprintf("Failed to write step outputs: %v", err))
+	}
+}
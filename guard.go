@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/kalikim/x-cli/config"
+)
+
+// checkBannedContent returns an error naming every banned word or pattern
+// from the configuration that text matches.
+func checkBannedContent(cfg config.Config, text string) error {
+	var hits []string
+
+	for _, word := range cfg.BannedWords {
+		word = strings.TrimSpace(word)
+		if word == "" {
+			continue
+		}
+		re := regexp.MustCompile(`(?i)(^|[^\pL\pN_])` + regexp.QuoteMeta(word) + `($|[^\pL\pN_])`)
+		if re.MatchString(text) {
+			hits = append(hits, fmt.Sprintf("%q", word))
+		}
+	}
+
+	for _, pattern := range cfg.BannedPatterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("invalid banned pattern %q: %w", pattern, err)
+		}
+		if re.MatchString(text) {
+			hits = append(hits, fmt.Sprintf("/%s/", pattern))
+		}
+	}
+
+	if len(hits) > 0 {
+		return invalidInput(fmt.Errorf("tweet matches banned content: %s (use --force to post anyway)", strings.Join(hits, ", ")))
+	}
+
+	return nil
+}
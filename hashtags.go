@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/kalikim/x-cli/config"
+)
+
+var hashtagSetTagPattern = regexp.MustCompile(`^#[\p{L}\p{N}_]+$`)
+
+// hashtagSetTags resolves a comma-separated list of hashtag_sets names to
+// their hashtags, in order and without repeats.
+func hashtagSetTags(cfg config.Config, spec string) ([]string, error) {
+	var tags []string
+	seen := map[string]bool{}
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		set, ok := cfg.HashtagSets[name]
+		if !ok {
+			return nil, invalidInput(fmt.Errorf("unknown hashtag set %q (configured: %s)", name, hashtagSetNames(cfg)))
+		}
+		for _, tag := range strings.Fields(set) {
+			if !hashtagSetTagPattern.MatchString(tag) {
+				return nil, invalidInput(fmt.Errorf("hashtag set %s: %q is not a hashtag", name, tag))
+			}
+			if key := strings.ToLower(tag); !seen[key] {
+				seen[key] = true
+				tags = append(tags, tag)
+			}
+		}
+	}
+	return tags, nil
+}
+
+func hashtagSetNames(cfg config.Config) string {
+	if len(cfg.HashtagSets) == 0 {
+		return "none; add hashtag_sets to the config"
+	}
+	names := make([]string, 0, len(cfg.HashtagSets))
+	for name := range cfg.HashtagSets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// appendHashtags adds the tags text does not already carry, matched
+// case-insensitively as X does, in their own paragraph.
+func appendHashtags(text string, tags []string) string {
+	present := map[string]bool{}
+	for _, tag := range hashtagPattern.FindAllString(text, -1) {
+		present[strings.ToLower(tag)] = true
+	}
+	var missing []string
+	for _, tag := range tags {
+		if !present[strings.ToLower(tag)] {
+			missing = append(missing, tag)
+		}
+	}
+	if len(missing) == 0 {
+		return text
+	}
+	return text + "\n\n" + strings.Join(missing, " ")
+}
+
+// applyHashtags appends tags to text and checks that the combined tweet
+// still fits.
+func applyHashtags(text string, tags []string) (string, error) {
+	combined := appendHashtags(text, tags)
+	if n := tweetLength(combined); n > maxTweetLength {
+		return "", invalidInput(fmt.Errorf("tweet text plus hashtags is %d characters, exceeding the %d character limit by %d (use fewer --tags sets)",
+			n, maxTweetLength, n-maxTweetLength))
+	}
+	return combined, nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// healthServer answers GET /healthz for container orchestrators: 200 while
+// the daemon checks its queues on time, 503 once its heartbeat is stale or
+// it is shutting down.
+type healthServer struct {
+	srv *http.Server
+
+	mu       sync.Mutex
+	status   daemonStatus
+	paused   bool
+	stopping bool
+}
+
+// healthReport is the body of a /healthz response.
+type healthReport struct {
+	Status    string     `json:"status"`
+	PID       int        `json:"pid"`
+	StartedAt time.Time  `json:"started_at"`
+	LastCheck *time.Time `json:"last_check,omitempty"`
+	Paused    bool       `json:"paused"`
+}
+
+// startHealthServer listens on addr, such as ":8080", right away so a
+// port already in use stops the daemon before it starts.
+func startHealthServer(addr string, status daemonStatus) (*healthServer, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	h := &healthServer{status: status}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", h.serveHealth)
+	h.srv = &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+	go h.srv.Serve(ln)
+	return h, nil
+}
+
+func (h *healthServer) update(status daemonStatus, paused bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.status = status
+	h.paused = paused
+}
+
+// stop makes /healthz fail while the daemon finishes its current post.
+func (h *healthServer) stop() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.stopping = true
+}
+
+func (h *healthServer) Close() error {
+	return h.srv.Close()
+}
+
+func (h *healthServer) serveHealth(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	report := healthReport{Status: "ok", PID: h.status.PID, StartedAt: h.status.StartedAt, Paused: h.paused}
+	code := http.StatusOK
+	switch {
+	case h.stopping:
+		report.Status, code = "stopping", http.StatusServiceUnavailable
+	case h.status.LastCheck.IsZero():
+		// Still waiting out the startup jitter.
+		report.Status = "starting"
+	case time.Since(h.status.LastCheck) > h.status.staleAfter():
+		report.Status, code = "stale", http.StatusServiceUnavailable
+	}
+	if !h.status.LastCheck.IsZero() {
+		lastCheck := h.status.LastCheck
+		report.LastCheck = &lastCheck
+	}
+	h.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,534 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/kalikim/x-cli/config"
+	"github.com/spf13/cobra"
+)
+
+const historyFile = "history.json"
+
+type historyEntry struct {
+	TweetID     string    `json:"tweet_id"`
+	Text        string    `json:"text"`
+	Image       string    `json:"image,omitempty"`
+	PostedAt    time.Time `json:"posted_at"`
+	ScheduledID string    `json:"scheduled_id,omitempty"`
+	// ScheduledFor is the time a scheduled tweet was due, for measuring how
+	// late the daemon posted it.
+	ScheduledFor *time.Time `json:"scheduled_for,omitempty"`
+	Queue        string     `json:"queue,omitempty"`
+	Key          string     `json:"key,omitempty"`
+	Labels       []string   `json:"labels,omitempty"`
+	Campaign     string     `json:"campaign,omitempty"`
+	Variant      string     `json:"variant,omitempty"`
+	ResharedFrom string     `json:"reshared_from,omitempty"`
+}
+
+// searchIndexFile keeps the search index between searches. It is derived
+// from the other stores and always stays local, even with a bucket.
+const searchIndexFile = "search_index.json"
+
+// searchIndexVersion changes whenever tokenize or the stored index format
+// does, so an old index is rebuilt.
+const searchIndexVersion = 1
+
+// searchDocument is a single searchable item: a posted tweet from the
+// history, a pending scheduled tweet or a draft.
+type searchDocument struct {
+	Source string    `json:"source"`
+	ID     string    `json:"id"`
+	Text   string    `json:"text"`
+	Image  string    `json:"image,omitempty"`
+	Time   time.Time `json:"time"`
+}
+
+type searchOptions struct {
+	Since     time.Time
+	Until     time.Time
+	MediaOnly bool
+	Source    string
+}
+
+func newHistoryCmd() *cobra.Command {
+	historyCmd := &cobra.Command{
+		Use:   "history",
+		Short: "Inspect previously posted tweets",
+	}
+
+	var since, until, source string
+	var mediaOnly bool
+
+	searchCmd := &cobra.Command{
+		Use:   "search [query]",
+		Short: "Search posted tweets, scheduled tweets and drafts",
+		Long: "Search the tweets in history.json, the scheduler queues and drafts.json by\n" +
+			"keyword. The index is kept in " + searchIndexFile + " and rebuilt when any of\n" +
+			"them changes.",
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var query string
+			if len(args) > 0 {
+				query = args[0]
+			}
+
+			opts := searchOptions{MediaOnly: mediaOnly, Source: source}
+			if since != "" {
+				t, err := parseDateFilter(since)
+				if err != nil {
+					return fmt.Errorf("invalid --since: %w", err)
+				}
+				opts.Since = t
+			}
+			if until != "" {
+				t, err := parseDateFilter(until)
+				if err != nil {
+					return fmt.Errorf("invalid --until: %w", err)
+				}
+				// Make --until inclusive of the whole day when only a date is given.
+				if len(strings.TrimSpace(until)) == len("2006-01-02") {
+					t = t.Add(24*time.Hour - time.Nanosecond)
+				}
+				opts.Until = t
+			}
+
+			switch opts.Source {
+			case "", "posted", "scheduled", "draft":
+			default:
+				return fmt.Errorf("invalid --source %q (use posted, scheduled or draft)", opts.Source)
+			}
+
+			return searchHistory(query, opts)
+		},
+	}
+
+	searchCmd.Flags().StringVar(&since, "since", "", "Only include items on or after this date (YYYY-MM-DD)")
+	searchCmd.Flags().StringVar(&until, "until", "", "Only include items on or before this date (YYYY-MM-DD)")
+	searchCmd.Flags().BoolVar(&mediaOnly, "media-only", false, "Only include items with media attached")
+	searchCmd.Flags().StringVar(&source, "source", "", "Restrict results to 'posted', 'scheduled' or 'draft' items")
+
+	var listLabels string
+	var limit int
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List posted tweets, newest first",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			labels, err := parseLabels(listLabels)
+			if err != nil {
+				return err
+			}
+			return listHistory(labels, limit)
+		},
+	}
+	listCmd.Flags().StringVar(&listLabels, "label", "", "Only show tweets carrying all of these comma-separated labels")
+	listCmd.Flags().IntVarP(&limit, "limit", "n", 20, "Show at most this many tweets (0 for all)")
+
+	historyCmd.AddCommand(searchCmd, listCmd)
+	return historyCmd
+}
+
+func loadHistory() ([]historyEntry, error) {
+	data, err := readStore(historyFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []historyEntry{}, nil
+		}
+		return nil, err
+	}
+	return decodeHistory(data)
+}
+
+func decodeHistory(data []byte) ([]historyEntry, error) {
+	if data == nil {
+		return []historyEntry{}, nil
+	}
+
+	var entries []historyEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// updateHistory applies fn to the history entries under the store lock.
+func updateHistory(fn func([]historyEntry) []historyEntry) error {
+	return updateStore(historyFile, func(data []byte) ([]byte, error) {
+		entries, err := decodeHistory(data)
+		if err != nil {
+			return nil, err
+		}
+		return json.MarshalIndent(fn(entries), "", "  ")
+	})
+}
+
+func recordHistory(entry historyEntry) error {
+	return updateHistory(func(entries []historyEntry) []historyEntry {
+		return append(entries, entry)
+	})
+}
+
+// tagHistory adds labels and a campaign to the history entries of the given
+// tweet IDs, for tweets recorded before they were known.
+func tagHistory(tweetIDs, labels []string, campaign string) error {
+	if len(tweetIDs) == 0 || (len(labels) == 0 && campaign == "") {
+		return nil
+	}
+
+	ids := map[string]bool{}
+	for _, id := range tweetIDs {
+		ids[id] = true
+	}
+
+	return updateHistory(func(entries []historyEntry) []historyEntry {
+		for i := range entries {
+			if !ids[entries[i].TweetID] {
+				continue
+			}
+			for _, label := range labels {
+				if !hasLabels(entries[i].Labels, []string{label}) {
+					entries[i].Labels = append(entries[i].Labels, label)
+				}
+			}
+			if campaign != "" {
+				entries[i].Campaign = campaign
+			}
+		}
+		return entries
+	})
+}
+
+func listHistory(labels []string, limit int) error {
+	entries, err := loadHistory()
+	if err != nil {
+		return fmt.Errorf("loading history: %w", err)
+	}
+
+	var matched []historyEntry
+	for _, entry := range entries {
+		if hasLabels(entry.Labels, labels) {
+			matched = append(matched, entry)
+		}
+	}
+	sort.SliceStable(matched, func(i, j int) bool {
+		return matched[i].PostedAt.After(matched[j].PostedAt)
+	})
+	if limit > 0 && len(matched) > limit {
+		matched = matched[:limit]
+	}
+
+	if len(matched) == 0 {
+		say("📭", "No posted tweets found")
+		return nil
+	}
+
+	for _, entry := range matched {
+		fmt.Printf("ID: %s\n", entry.TweetID)
+		fmt.Printf("Text: %s\n", entry.Text)
+		if entry.Image != "" {
+			fmt.Printf("Image: %s\n", entry.Image)
+		}
+		if len(entry.Labels) > 0 {
+			fmt.Printf("Labels: %s\n", strings.Join(entry.Labels, ", "))
+		}
+		fmt.Printf("Posted: %s\n", entry.PostedAt.Format("2006-01-02 15:04:05"))
+		fmt.Println("---")
+	}
+
+	return nil
+}
+
+func searchHistory(query string, opts searchOptions) error {
+	index, err := openSearchIndex()
+	if err != nil {
+		return err
+	}
+	results := index.search(query, opts)
+
+	if len(results) == 0 {
+		say("🔍", "No matching tweets found")
+		return nil
+	}
+
+	say("🔍", "Found %d matching tweet(s):\n", len(results))
+	for _, doc := range results {
+		fmt.Printf("ID: %s (%s)\n", doc.ID, doc.Source)
+		fmt.Printf("Text: %s\n", doc.Text)
+		if doc.Image != "" {
+			fmt.Printf("Image: %s\n", doc.Image)
+		}
+		fmt.Printf("Date: %s\n", doc.Time.Format("2006-01-02 15:04:05"))
+		fmt.Println("---")
+	}
+
+	return nil
+}
+
+// searchSource is the raw content of one store the search index covers.
+type searchSource struct {
+	name string
+	data []byte
+}
+
+// openSearchIndex returns the saved search index, or builds and saves a new
+// one when the history, a queue or the drafts changed since it was built.
+func openSearchIndex() (*searchIndex, error) {
+	sources, err := readSearchSources()
+	if err != nil {
+		return nil, err
+	}
+
+	hash := sha256.New()
+	fmt.Fprintf(hash, "v%d\x00", searchIndexVersion)
+	for _, src := range sources {
+		fmt.Fprintf(hash, "%s\x00%d\x00", src.name, len(src.data))
+		hash.Write(src.data)
+	}
+	sum := hex.EncodeToString(hash.Sum(nil))
+
+	if idx := loadSearchIndex(sum); idx != nil {
+		return idx, nil
+	}
+
+	docs, err := searchDocuments(sources)
+	if err != nil {
+		return nil, err
+	}
+	idx := buildSearchIndex(docs)
+	if err := saveSearchIndex(idx, sum); err != nil {
+		log.Print(decorate("⚠️", fmt.Sprintf("Failed to save the search index: %v", err)))
+	}
+	return idx, nil
+}
+
+// readSearchSources reads the history, every known queue and the drafts.
+// A missing store has nil data.
+func readSearchSources() ([]searchSource, error) {
+	names := []string{historyFile}
+	for _, queue := range knownQueues(config.LoadConfig()) {
+		names = append(names, scheduleFile(queue))
+	}
+
+	sources := make([]searchSource, 0, len(names)+1)
+	for _, name := range names {
+		data, err := readStore(name)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("reading %s: %w", name, err)
+		}
+		sources = append(sources, searchSource{name, data})
+	}
+
+	data, err := os.ReadFile(draftsFile)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("reading %s: %w", draftsFile, err)
+	}
+	return append(sources, searchSource{draftsFile, data}), nil
+}
+
+func searchDocuments(sources []searchSource) ([]*searchDocument, error) {
+	var docs []*searchDocument
+	for _, src := range sources {
+		switch src.name {
+		case historyFile:
+			history, err := decodeHistory(src.data)
+			if err != nil {
+				return nil, fmt.Errorf("loading history: %w", err)
+			}
+			for _, entry := range history {
+				docs = append(docs, &searchDocument{Source: "posted", ID: entry.TweetID, Text: entry.Text, Image: entry.Image, Time: entry.PostedAt})
+			}
+		case draftsFile:
+			drafts, err := decodeDrafts(src.data)
+			if err != nil {
+				return nil, fmt.Errorf("loading drafts: %w", err)
+			}
+			for _, d := range drafts {
+				docs = append(docs, &searchDocument{Source: "draft", ID: d.ID, Text: d.Text, Image: d.Image, Time: d.SavedAt})
+			}
+		default:
+			tweets, err := decodeScheduledTweets(src.data)
+			if err != nil {
+				return nil, fmt.Errorf("loading scheduled tweets from %s: %w", src.name, err)
+			}
+			for _, tweet := range tweets {
+				docs = append(docs, &searchDocument{Source: "scheduled", ID: tweet.ID, Text: tweet.Text, Image: tweet.Image, Time: tweet.ScheduleTime})
+			}
+		}
+	}
+	return docs, nil
+}
+
+// storedSearchIndex is the search index as kept in search_index.json. Sum
+// fingerprints the stores it was built from.
+type storedSearchIndex struct {
+	Sum      string            `json:"sum"`
+	Docs     []*searchDocument `json:"docs"`
+	Postings map[string][]int  `json:"postings"`
+}
+
+// loadSearchIndex returns the saved index if it was built from the stores
+// fingerprinted by sum, and nil otherwise.
+func loadSearchIndex(sum string) *searchIndex {
+	data, err := os.ReadFile(searchIndexFile)
+	if err != nil {
+		return nil
+	}
+	var stored storedSearchIndex
+	if err := json.Unmarshal(data, &stored); err != nil || stored.Sum != sum {
+		return nil
+	}
+	for _, postings := range stored.Postings {
+		for _, i := range postings {
+			if i < 0 || i >= len(stored.Docs) {
+				return nil
+			}
+		}
+	}
+
+	idx := &searchIndex{docs: stored.Docs, postings: stored.Postings}
+	idx.sortTokens()
+	return idx
+}
+
+func saveSearchIndex(idx *searchIndex, sum string) error {
+	data, err := json.Marshal(storedSearchIndex{Sum: sum, Docs: idx.docs, Postings: idx.postings})
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(searchIndexFile, data, 0644)
+}
+
+// searchIndex is an inverted index mapping lowercase tokens to the documents
+// containing them.
+type searchIndex struct {
+	docs     []*searchDocument
+	postings map[string][]int
+	tokens   []string
+}
+
+func buildSearchIndex(docs []*searchDocument) *searchIndex {
+	idx := &searchIndex{docs: docs, postings: map[string][]int{}}
+
+	for i, doc := range docs {
+		seen := map[string]bool{}
+		for _, tok := range tokenize(doc.Text) {
+			if seen[tok] {
+				continue
+			}
+			seen[tok] = true
+			idx.postings[tok] = append(idx.postings[tok], i)
+		}
+	}
+	idx.sortTokens()
+
+	return idx
+}
+
+// sortTokens lists the indexed tokens in order, for prefix lookups.
+func (idx *searchIndex) sortTokens() {
+	idx.tokens = make([]string, 0, len(idx.postings))
+	for tok := range idx.postings {
+		idx.tokens = append(idx.tokens, tok)
+	}
+	sort.Strings(idx.tokens)
+}
+
+// search returns documents containing every query term (prefix match),
+// filtered by opts and ordered newest first. An empty query matches all.
+func (idx *searchIndex) search(query string, opts searchOptions) []*searchDocument {
+	terms := tokenize(query)
+
+	var candidates map[int]struct{}
+	if len(terms) == 0 {
+		candidates = make(map[int]struct{}, len(idx.docs))
+		for i := range idx.docs {
+			candidates[i] = struct{}{}
+		}
+	}
+
+	for _, term := range terms {
+		matches := idx.prefixMatches(term)
+		if candidates == nil {
+			candidates = matches
+			continue
+		}
+		for i := range candidates {
+			if _, ok := matches[i]; !ok {
+				delete(candidates, i)
+			}
+		}
+	}
+
+	var results []*searchDocument
+	for i := range candidates {
+		doc := idx.docs[i]
+		if opts.Source != "" && doc.Source != opts.Source {
+			continue
+		}
+		if opts.MediaOnly && doc.Image == "" {
+			continue
+		}
+		if !opts.Since.IsZero() && doc.Time.Before(opts.Since) {
+			continue
+		}
+		if !opts.Until.IsZero() && doc.Time.After(opts.Until) {
+			continue
+		}
+		results = append(results, doc)
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Time.After(results[j].Time)
+	})
+
+	return results
+}
+
+func (idx *searchIndex) prefixMatches(term string) map[int]struct{} {
+	matches := map[int]struct{}{}
+
+	start := sort.SearchStrings(idx.tokens, term)
+	for _, tok := range idx.tokens[start:] {
+		if !strings.HasPrefix(tok, term) {
+			break
+		}
+		for _, i := range idx.postings[tok] {
+			matches[i] = struct{}{}
+		}
+	}
+
+	return matches
+}
+
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r) && r != '#' && r != '@' && r != '_'
+	})
+}
+
+func parseDateFilter(value string) (time.Time, error) {
+	formats := []string{
+		"2006-01-02 15:04",
+		"2006-01-02",
+	}
+
+	for _, format := range formats {
+		if t, err := time.ParseInLocation(format, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, errors.New("use 'YYYY-MM-DD' or 'YYYY-MM-DD HH:MM'")
+}
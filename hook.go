@@ -0,0 +1,126 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+const hookMarker = "# installed by x-cli hook install"
+
+// gitTagHookScript is a pre-push hook: git runs it before pushing, with one
+// line per ref, and it announces tags the remote does not have yet. Tags
+// that only arrive by fetching are never announced. x-cli is looked up on
+// PATH when the hook runs.
+const gitTagHookScript = `#!/bin/sh
+%s git-tag
+if ! command -v x-cli >/dev/null 2>&1; then
+	echo "x-cli: not found on PATH; new tags are not announced" >&2
+	exit 0
+fi
+while read -r local_ref local_sha remote_ref remote_sha; do
+	case "$remote_ref" in
+	refs/tags/*) ;;
+	*) continue ;;
+	esac
+	case "$local_sha" in
+	*[!0]*) ;;
+	*) continue ;;
+	esac
+	case "$remote_sha" in
+	*[!0]*) continue ;;
+	esac
+	x-cli release announce --tag "${remote_ref#refs/tags/}"%s </dev/null || echo "x-cli: failed to announce ${remote_ref#refs/tags/}" >&2
+done
+exit 0
+`
+
+func newHookCmd() *cobra.Command {
+	hookCmd := &cobra.Command{
+		Use:   "hook",
+		Short: "Manage git hook integrations",
+	}
+
+	var notesFile, tmpl string
+	var force bool
+
+	installCmd := &cobra.Command{
+		Use:   "install git-tag",
+		Short: "Install a git hook that tweets tags when they are pushed",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if args[0] != "git-tag" {
+				return fmt.Errorf("unknown hook %q (supported: git-tag)", args[0])
+			}
+			return installGitTagHook(notesFile, tmpl, force)
+		},
+	}
+
+	installCmd.Flags().StringVar(&notesFile, "notes", "", "Release notes file passed to 'release announce'")
+	installCmd.Flags().StringVar(&tmpl, "template", "", "Tweet template passed to 'release announce'")
+	installCmd.Flags().BoolVar(&force, "force", false, "Overwrite an existing hook not installed by x-cli")
+
+	hookCmd.AddCommand(installCmd)
+	return hookCmd
+}
+
+func installGitTagHook(notesFile, tmpl string, force bool) error {
+	out, err := exec.Command("git", "rev-parse", "--git-path", "hooks").Output()
+	if err != nil {
+		return errors.New("not inside a git repository")
+	}
+	hooksDir := strings.TrimSpace(string(out))
+
+	hookPath := filepath.Join(hooksDir, "pre-push")
+	if existing, err := os.ReadFile(hookPath); err == nil {
+		if !strings.Contains(string(existing), hookMarker) && !force {
+			return fmt.Errorf("%s already exists; use --force to overwrite", hookPath)
+		}
+	}
+
+	var extra string
+	if notesFile != "" {
+		abs, err := filepath.Abs(notesFile)
+		if err != nil {
+			return fmt.Errorf("resolving notes path: %w", err)
+		}
+		extra += " --notes " + shellQuote(abs)
+	}
+	if tmpl != "" {
+		extra += " --template " + shellQuote(tmpl)
+	}
+
+	script := fmt.Sprintf(gitTagHookScript, hookMarker, extra)
+
+	if err := os.MkdirAll(hooksDir, 0755); err != nil {
+		return fmt.Errorf("creating hooks directory: %w", err)
+	}
+	if err := os.WriteFile(hookPath, []byte(script), 0755); err != nil {
+		return fmt.Errorf("writing hook: %w", err)
+	}
+
+	// Earlier versions installed a reference-transaction hook, which also
+	// fired for tags fetched from upstream.
+	legacy := filepath.Join(hooksDir, "reference-transaction")
+	if existing, err := os.ReadFile(legacy); err == nil && strings.Contains(string(existing), hookMarker) {
+		if err := os.Remove(legacy); err != nil {
+			return fmt.Errorf("removing the old hook %s: %w", legacy, err)
+		}
+	}
+
+	say("✅", "Installed git tag hook at %s", hookPath)
+	say("💡", "Tags will be announced with 'x-cli release announce' when you push them")
+	if _, err := exec.LookPath("x-cli"); err != nil {
+		say("⚠️", "x-cli is not on your PATH; install it with 'go install' so the hook can run it")
+	}
+	return nil
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
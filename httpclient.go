@@ -0,0 +1,68 @@
+package main
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const apiTimeout = 20 * time.Second
+
+var (
+	sharedClient     *http.Client
+	sharedClientOnce sync.Once
+)
+
+// apiClient returns the HTTP client shared by every command and the daemon,
+// so posts, uploads and lookups reuse kept-alive connections instead of
+// dialing and handshaking per request.
+func apiClient() *http.Client {
+	sharedClientOnce.Do(func() {
+		sharedClient = &http.Client{Timeout: apiTimeout, Transport: apiTransport()}
+	})
+	return sharedClient
+}
+
+// apiClientWithTimeout returns a client sharing apiClient's connection pool
+// but with a different overall timeout, e.g. for large downloads.
+func apiClientWithTimeout(timeout time.Duration) *http.Client {
+	client := *apiClient()
+	client.Timeout = timeout
+	return &client
+}
+
+func apiTransport() http.RoundTripper {
+	switch {
+	case offlineMode:
+		return &userAgentTransport{base: &mockTransport{logFile: mockLogFile()}}
+	case cassetteTransport != nil:
+		return &userAgentTransport{base: cassetteTransport}
+	}
+
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   10 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          32,
+		MaxIdleConnsPerHost:   8,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: apiTimeout,
+		ExpectContinueTimeout: time.Second,
+	}
+
+	// XCLI_HTTP2=0 falls back to HTTP/1.1 for proxies that mishandle h2.
+	if v, err := strconv.ParseBool(os.Getenv("XCLI_HTTP2")); err == nil && !v {
+		transport.ForceAttemptHTTP2 = false
+		transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+
+	return &userAgentTransport{base: &usageTransport{base: transport}}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/kalikim/x-cli/config"
+)
+
+// Posting states recorded on a scheduled tweet. An empty state means the
+// tweet is waiting for its time.
+const (
+	// statePosting is written just before the API call. Finding it on the
+	// next check means the daemon stopped mid-request and the tweet may or
+	// may not be live.
+	statePosting = "posting"
+	// statePosted is written as soon as the API returns a tweet ID, before
+	// history and follow-ups are recorded.
+	statePosted = "posted"
+)
+
+const userTweetsEndpoint = "https://api.twitter.com/2/users/%s/tweets"
+
+// errScheduledTweetGone means the entry was cancelled while it was being
+// prepared for posting.
+var errScheduledTweetGone = errors.New("scheduled tweet no longer exists")
+
+// newIdempotencyKey returns a random key that identifies a scheduled tweet
+// across retries, restarts and history.
+func newIdempotencyKey() string {
+	key, err := generateNonce()
+	if err != nil {
+		return generateTweetID()
+	}
+	return key
+}
+
+// assignIdempotencyKeys gives entries written before keys existed a key.
+func assignIdempotencyKeys(tweets []scheduledTweet) {
+	for i := range tweets {
+		if tweets[i].Key == "" {
+			tweets[i].Key = newIdempotencyKey()
+		}
+	}
+}
+
+// setPostingState records state (and the posted tweet ID, if any) on the
+// scheduled tweet with the given ID and returns the stored entry.
+func setPostingState(queue, id, state, postedID string) (scheduledTweet, error) {
+	return updateScheduledTweet(queue, id, func(tweet *scheduledTweet) {
+		tweet.State = state
+		tweet.PostedID = postedID
+	})
+}
+
+// updateScheduledTweet applies fn to the scheduled tweet with the given ID
+// under the store lock and returns the stored entry.
+func updateScheduledTweet(queue, id string, fn func(*scheduledTweet)) (scheduledTweet, error) {
+	var stored scheduledTweet
+	err := updateScheduledTweets(queue, func(tweets []scheduledTweet) ([]scheduledTweet, error) {
+		for i := range tweets {
+			if tweets[i].ID != id {
+				continue
+			}
+			fn(&tweets[i])
+			if tweets[i].Key == "" {
+				tweets[i].Key = newIdempotencyKey()
+			}
+			stored = tweets[i]
+			return tweets, nil
+		}
+		return nil, errScheduledTweetGone
+	})
+	return stored, err
+}
+
+// findInterruptedPost works out whether a tweet left in the posting state
+// made it to the account. History is checked first, then the account's most
+// recent tweets for identical text.
+func findInterruptedPost(client *http.Client, cfg config.Config, tweet scheduledTweet) (string, bool, error) {
+	if entry, ok, err := historyEntryForKey(tweet.Key); err != nil {
+		return "", false, err
+	} else if ok {
+		return entry.TweetID, true, nil
+	}
+
+	userID, err := accountUserID(client, cfg)
+	if err != nil {
+		return "", false, err
+	}
+
+	body, err := signedGet(client, cfg, fmt.Sprintf(userTweetsEndpoint, userID)+"?max_results=20")
+	if err != nil {
+		return "", false, fmt.Errorf("fetching recent tweets: %w", err)
+	}
+	return matchRecentTweet(body, tweet.postText(), tweet.Image != "")
+}
+
+// trailingMediaLink is the t.co link X appends to the text of a tweet with
+// media attached.
+var trailingMediaLink = regexp.MustCompile(`\s*https://t\.co/\w+\s*$`)
+
+// matchRecentTweet returns the ID of the tweet in a user tweets response
+// whose text is text. For tweets with media the link X appends is dropped
+// before comparing.
+func matchRecentTweet(body []byte, text string, hasMedia bool) (string, bool, error) {
+	var recent struct {
+		Data []struct {
+			ID   string `json:"id"`
+			Text string `json:"text"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &recent); err != nil {
+		return "", false, fmt.Errorf("decoding recent tweets: %w", err)
+	}
+
+	want := comparableTweetText(text)
+	for _, t := range recent.Data {
+		posted := t.Text
+		if hasMedia {
+			posted = trailingMediaLink.ReplaceAllString(posted, "")
+		}
+		if comparableTweetText(posted) == want {
+			return t.ID, true, nil
+		}
+	}
+
+	return "", false, nil
+}
+
+// comparableTweetText normalizes what the API changes on the way in: HTML
+// entities and links rewritten to t.co.
+func comparableTweetText(text string) string {
+	text = countURLPattern.ReplaceAllString(html.UnescapeString(text), "<url>")
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// historyEntryForKey returns the history entry recorded for an idempotency
+// key, if any.
+func historyEntryForKey(key string) (historyEntry, bool, error) {
+	if key == "" {
+		return historyEntry{}, false, nil
+	}
+
+	entries, err := loadHistory()
+	if err != nil {
+		return historyEntry{}, false, err
+	}
+
+	for _, entry := range entries {
+		if entry.Key == key {
+			return entry, true, nil
+		}
+	}
+
+	return historyEntry{}, false, nil
+}
@@ -0,0 +1,36 @@
+package main
+
+import "testing"
+
+func TestMatchRecentTweet(t *testing.T) {
+	body := []byte(`{"data":[
+		{"id":"1","text":"Hello https://t.co/zzz999"},
+		{"id":"2","text":"Launch day! https://t.co/AbC123"},
+		{"id":"3","text":"Read https://t.co/link01 https://t.co/media2"},
+		{"id":"4","text":"Tom &amp; Jerry"}
+	]}`)
+
+	tests := []struct {
+		name     string
+		text     string
+		hasMedia bool
+		wantID   string
+	}{
+		{"media tweet", "Launch day!", true, "2"},
+		{"media tweet with a link", "Read https://example.com/post", true, "3"},
+		{"html entities", "Tom & Jerry", false, "4"},
+		{"text tweet keeps its link", "Hello", false, ""},
+		{"no match", "Something else", true, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, found, err := matchRecentTweet(body, tt.text, tt.hasMedia)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if found != (tt.wantID != "") || id != tt.wantID {
+				t.Errorf("matchRecentTweet(%q) = %q, %v; want %q", tt.text, id, found, tt.wantID)
+			}
+		})
+	}
+}
@@ -0,0 +1,418 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kalikim/x-cli/config"
+	"github.com/spf13/cobra"
+)
+
+const (
+	inboxCursorFile = "inbox_cursor.json"
+	inboxPageSize   = 100
+	// inboxMaxPages bounds how far back a run catches up after a long break.
+	inboxMaxPages = 5
+	// inboxFirstRunLimit is how many items per source the first run shows,
+	// before there is a cursor.
+	inboxFirstRunLimit = 20
+)
+
+// inboxCursor is the newest mention and direct message already handled.
+type inboxCursor struct {
+	MentionID string `json:"mention_id,omitempty"`
+	DMID      string `json:"dm_id,omitempty"`
+}
+
+type inboxKind int
+
+const (
+	inboxMention inboxKind = iota
+	inboxDM
+)
+
+// inboxItem is a mention or direct message waiting for the user.
+type inboxItem struct {
+	Kind           inboxKind
+	ID             string
+	ConversationID string
+	Text           string
+	CreatedAt      time.Time
+	Username       string
+	Name           string
+}
+
+func newInboxCmd() *cobra.Command {
+	var profile string
+	var noDMs bool
+
+	cmd := &cobra.Command{
+		Use:   "inbox",
+		Short: "Go through new mentions and direct messages and reply, like or ignore each",
+		Long: `Go through new mentions and direct messages one at a time and reply, like
+or ignore each.
+
+Handled items are remembered in ~/.x-cli/inbox_cursor.json, so the next run
+only shows what arrived since. Quitting leaves the remaining items unseen.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.LoadConfig().ForProfile(profile)
+			if err != nil {
+				return invalidInput(err)
+			}
+			if err := cfg.Validate(); err != nil {
+				return err
+			}
+
+			client := apiClient()
+			userID, err := accountUserID(client, cfg)
+			if err != nil {
+				return err
+			}
+
+			cursor, err := loadInboxCursor(cfg)
+			if err != nil {
+				return fmt.Errorf("loading inbox cursor: %w", err)
+			}
+
+			mentions, newestMention, err := fetchNewMentions(client, cfg, userID, cursor.MentionID)
+			if err != nil {
+				return fmt.Errorf("loading mentions: %w", err)
+			}
+			items := mentions
+
+			newestDM := ""
+			if !noDMs {
+				dms, newest, err := fetchNewDMs(client, cfg, userID, cursor.DMID)
+				if err != nil {
+					say("⚠️", "Skipping direct messages: %v (use --no-dms to stop checking)", err)
+				}
+				items = append(items, dms...)
+				newestDM = newest
+			}
+
+			sort.SliceStable(items, func(i, j int) bool {
+				return items[i].CreatedAt.Before(items[j].CreatedAt)
+			})
+
+			if len(items) == 0 {
+				say("📭", "No new mentions or messages")
+				// Direct messages the account sent itself still move the cursor.
+				return advanceInboxCursor(cfg, newestMention, newestDM)
+			}
+
+			say("📬", "%d new item(s)", len(items))
+			done, err := runInbox(client, cfg, userID, items)
+			if err != nil {
+				return err
+			}
+			if done {
+				return advanceInboxCursor(cfg, newestMention, newestDM)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&profile, "profile", "", "Check the inbox of this configured profile")
+	cmd.Flags().BoolVar(&noDMs, "no-dms", false, "Only show mentions")
+	return cmd
+}
+
+// fetchNewMentions returns the mentions newer than sinceID and the newest
+// mention ID seen.
+func fetchNewMentions(client *http.Client, cfg config.Config, userID, sinceID string) ([]inboxItem, string, error) {
+	var items []inboxItem
+	newest := ""
+	pageToken := ""
+
+	for pages := 0; pages < inboxMaxPages; pages++ {
+		page, err := fetchTimeline(client, cfg, mentionsEndpoint, userID, pageToken, sinceID, inboxPageSize)
+		if err != nil {
+			return nil, "", err
+		}
+		for _, t := range page.Tweets {
+			if !newerID(t.ID, sinceID) {
+				continue
+			}
+			if newerID(t.ID, newest) {
+				newest = t.ID
+			}
+			items = append(items, inboxItem{
+				Kind:      inboxMention,
+				ID:        t.ID,
+				Text:      t.Text,
+				CreatedAt: t.CreatedAt,
+				Username:  t.Username,
+				Name:      t.Name,
+			})
+		}
+		if sinceID == "" || page.NextToken == "" {
+			break
+		}
+		pageToken = page.NextToken
+	}
+
+	return newestItems(items, sinceID), newest, nil
+}
+
+// fetchNewDMs returns the direct messages received after sinceID and the
+// newest message ID seen, including the account's own messages.
+func fetchNewDMs(client *http.Client, cfg config.Config, userID, sinceID string) ([]inboxItem, string, error) {
+	var items []inboxItem
+	newest := ""
+	pageToken := ""
+
+	for pages := 0; pages < inboxMaxPages; pages++ {
+		page, err := fetchDMEvents(client, cfg, dmEventsEndpoint, pageToken, inboxPageSize)
+		if err != nil {
+			return nil, "", err
+		}
+		reachedCursor := false
+		for _, m := range page.Messages {
+			if !newerID(m.ID, sinceID) {
+				reachedCursor = true
+				continue
+			}
+			if newerID(m.ID, newest) {
+				newest = m.ID
+			}
+			if m.SenderID == userID {
+				continue
+			}
+			items = append(items, inboxItem{
+				Kind:           inboxDM,
+				ID:             m.ID,
+				ConversationID: m.ConversationID,
+				Text:           m.Text,
+				CreatedAt:      m.CreatedAt,
+				Username:       m.Username,
+				Name:           m.Name,
+			})
+		}
+		if sinceID == "" || reachedCursor || page.NextToken == "" {
+			break
+		}
+		pageToken = page.NextToken
+	}
+
+	return newestItems(items, sinceID), newest, nil
+}
+
+// newestItems caps the first run, when there is no cursor yet, to the most
+// recent items.
+func newestItems(items []inboxItem, sinceID string) []inboxItem {
+	if sinceID != "" || len(items) <= inboxFirstRunLimit {
+		return items
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return newerID(items[i].ID, items[j].ID)
+	})
+	return items[:inboxFirstRunLimit]
+}
+
+// runInbox prompts for each item, oldest first, and moves the cursor past
+// it once handled. It reports whether every item was handled.
+func runInbox(client *http.Client, cfg config.Config, userID string, items []inboxItem) (bool, error) {
+	in := bufio.NewReader(os.Stdin)
+	now := time.Now()
+
+	for i, item := range items {
+		fmt.Println()
+		kind, icon := "Mention", "💬"
+		if item.Kind == inboxDM {
+			kind, icon = "Message", "✉️"
+		}
+		say(icon, "[%d/%d] %s from @%s (%s) · %s ago", i+1, len(items), kind, item.Username, item.Name, formatTweetAge(item.CreatedAt, now))
+		for _, line := range strings.Split(item.Text, "\n") {
+			fmt.Println("  " + line)
+		}
+
+		for handled := false; !handled; {
+			fmt.Print("[r]eply, [l]ike, [i]gnore, [q]uit (default i): ")
+			answer, err := readInboxLine(in)
+			if err != nil {
+				fmt.Println()
+				return false, nil
+			}
+
+			switch strings.ToLower(answer) {
+			case "", "i", "ignore":
+				handled = true
+			case "l", "like":
+				if item.Kind == inboxDM {
+					say("⚠️", "Direct messages can't be liked")
+					continue
+				}
+				if err := likeTweet(client, cfg, userID, item.ID); err != nil {
+					say("❌", "Like failed: %v", err)
+					continue
+				}
+				say("❤️", "Liked @%s's mention", item.Username)
+				handled = true
+			case "r", "reply":
+				fmt.Print("Reply (empty to cancel): ")
+				text, err := readInboxLine(in)
+				if err != nil {
+					fmt.Println()
+					return false, nil
+				}
+				if text == "" {
+					continue
+				}
+				handled = sendInboxReply(client, cfg, item, text)
+			case "q", "quit":
+				return false, nil
+			default:
+				say("⚠️", "Unknown choice %q", answer)
+			}
+		}
+
+		cursor := inboxCursor{}
+		if item.Kind == inboxMention {
+			cursor.MentionID = item.ID
+		} else {
+			cursor.DMID = item.ID
+		}
+		if err := advanceInboxCursor(cfg, cursor.MentionID, cursor.DMID); err != nil {
+			return false, fmt.Errorf("saving inbox cursor: %w", err)
+		}
+	}
+	return true, nil
+}
+
+// sendInboxReply answers a mention with a reply tweet or a direct message in
+// its conversation, and reports whether it was sent.
+func sendInboxReply(client *http.Client, cfg config.Config, item inboxItem, text string) bool {
+	if item.Kind == inboxDM {
+		if _, err := sendDM(client, cfg, item.ConversationID, text); err != nil {
+			say("❌", "Message failed: %v", err)
+			return false
+		}
+		say("✉️", "Messaged @%s", item.Username)
+		return true
+	}
+
+	if n := tweetLength(text); n > maxTweetLength {
+		say("❌", "Reply is %d characters, over the %d limit", n, maxTweetLength)
+		return false
+	}
+	if err := checkBannedContent(cfg, text); err != nil {
+		say("❌", "%v", err)
+		return false
+	}
+	warnings, err := checkContentRules(cfg, []ruleText{{"", text}}, false)
+	if err != nil {
+		say("❌", "%v", err)
+		return false
+	}
+	for _, warning := range warnings {
+		say("⚠️", "%s", warning)
+	}
+
+	ids, err := publishReplies(client, cfg, item.ID, []string{text})
+	if err != nil {
+		say("❌", "Reply failed: %v", err)
+		return false
+	}
+	say("✅", "Replied to @%s (ID: %s)", item.Username, ids[0])
+	return true
+}
+
+func readInboxLine(in *bufio.Reader) (string, error) {
+	line, err := in.ReadString('\n')
+	if err != nil && (line == "" || !errors.Is(err, io.EOF)) {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
+
+func inboxCursorPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".x-cli", inboxCursorFile), nil
+}
+
+// loadInboxCursorStore reads the cursors of all accounts, keyed like the
+// rate-limit store.
+func loadInboxCursorStore(path string) (map[string]inboxCursor, error) {
+	store := map[string]inboxCursor{}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &store); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return store, nil
+}
+
+func loadInboxCursor(cfg config.Config) (inboxCursor, error) {
+	path, err := inboxCursorPath()
+	if err != nil {
+		return inboxCursor{}, err
+	}
+
+	var cursor inboxCursor
+	err = withFileLock(path, func() error {
+		store, err := loadInboxCursorStore(path)
+		if err != nil {
+			return err
+		}
+		cursor = store[rateLimitAccount(cfg)]
+		return nil
+	})
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return inboxCursor{}, err
+	}
+	return cursor, nil
+}
+
+// advanceInboxCursor moves cfg's cursor forward to the given IDs; empty or
+// older IDs leave it where it is.
+func advanceInboxCursor(cfg config.Config, mentionID, dmID string) error {
+	if mentionID == "" && dmID == "" {
+		return nil
+	}
+	path, err := inboxCursorPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	return withFileLock(path, func() error {
+		store, err := loadInboxCursorStore(path)
+		if err != nil {
+			return err
+		}
+		account := rateLimitAccount(cfg)
+		cursor := store[account]
+		if newerID(mentionID, cursor.MentionID) {
+			cursor.MentionID = mentionID
+		}
+		if newerID(dmID, cursor.DMID) {
+			cursor.DMID = dmID
+		}
+		store[account] = cursor
+
+		data, err := json.MarshalIndent(store, "", "  ")
+		if err != nil {
+			return err
+		}
+		return writeFileAtomic(path, data, 0600)
+	})
+}
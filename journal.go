@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// schedulerJournalFile is the daemon's write-ahead journal. Every step of
+// posting a scheduled tweet is appended and synced before the step's effect
+// is saved anywhere else, so a daemon that dies in between can tell on
+// startup exactly what already went out.
+const schedulerJournalFile = "scheduler_journal.ndjson"
+
+// Journal events, in the order they are written for one tweet.
+const (
+	journalPosting = "posting"
+	journalPosted  = "posted"
+	journalReply   = "reply"
+	journalDone    = "done"
+)
+
+type journalRecord struct {
+	Time    time.Time `json:"time"`
+	Queue   string    `json:"queue,omitempty"`
+	ID      string    `json:"id"`
+	Key     string    `json:"key,omitempty"`
+	Event   string    `json:"event"`
+	TweetID string    `json:"tweet_id,omitempty"`
+}
+
+// journalEntry is what the journal says about one scheduled tweet.
+type journalEntry struct {
+	Queue    string
+	ID       string
+	Key      string
+	PostedID string
+	Replies  []string
+	Done     bool
+	records  []journalRecord
+}
+
+// appendJournal writes rec to the journal and syncs it to disk.
+func appendJournal(rec journalRecord) error {
+	rec.Time = time.Now()
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(schedulerJournalFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("opening journal: %w", err)
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("writing journal: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("syncing journal: %w", err)
+	}
+	return f.Close()
+}
+
+// loadJournal replays the journal into one entry per scheduled tweet. A line
+// torn by a crash mid-write is ignored.
+func loadJournal() (map[string]*journalEntry, error) {
+	entries := map[string]*journalEntry{}
+
+	f, err := os.Open(schedulerJournalFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return entries, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("opening journal: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var rec journalRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil || rec.ID == "" {
+			continue
+		}
+
+		k := journalKey(rec.Queue, rec.ID)
+		entry := entries[k]
+		if entry == nil || rec.Event == journalPosting {
+			// A new posting attempt starts the tweet's story afresh.
+			entry = &journalEntry{Queue: rec.Queue, ID: rec.ID}
+			entries[k] = entry
+		}
+		entry.Key = rec.Key
+		entry.records = append(entry.records, rec)
+
+		switch rec.Event {
+		case journalPosted:
+			entry.PostedID = rec.TweetID
+		case journalReply:
+			entry.Replies = append(entry.Replies, rec.TweetID)
+		case journalDone:
+			entry.Done = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading journal: %w", err)
+	}
+
+	return entries, nil
+}
+
+func journalKey(queue, id string) string {
+	return queue + "/" + id
+}
+
+// recoverFromJournal reconciles the queues with the journal when the daemon
+// starts. Tweets the journal shows as posted get their posted state and ID
+// back if saving them was lost, so they are never sent again. Finished
+// entries are then dropped from the journal.
+func recoverFromJournal() error {
+	entries, err := loadJournal()
+	if err != nil {
+		return err
+	}
+
+	var open []*journalEntry
+	for _, entry := range entries {
+		if entry.Done {
+			continue
+		}
+
+		stored, err := updateScheduledTweet(entry.Queue, entry.ID, func(t *scheduledTweet) {
+			if entry.PostedID != "" && t.PostedID == "" {
+				t.State = statePosted
+				t.PostedID = entry.PostedID
+			}
+		})
+		if errors.Is(err, errScheduledTweetGone) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("recovering tweet %s: %w", entry.ID, err)
+		}
+
+		if entry.PostedID != "" {
+			say("♻️", "Tweet %s was posted as %s before the daemon stopped; finishing it", stored.ID, entry.PostedID)
+		}
+		open = append(open, entry)
+	}
+
+	return rewriteJournal(open)
+}
+
+// compactJournal drops the records of finished tweets.
+func compactJournal() error {
+	entries, err := loadJournal()
+	if err != nil {
+		return err
+	}
+
+	var open []*journalEntry
+	for _, entry := range entries {
+		if !entry.Done {
+			open = append(open, entry)
+		}
+	}
+	return rewriteJournal(open)
+}
+
+// rewriteJournal replaces the journal with the records of entries.
+func rewriteJournal(entries []*journalEntry) error {
+	var data []byte
+	for _, entry := range entries {
+		for _, rec := range entry.records {
+			line, err := json.Marshal(rec)
+			if err != nil {
+				return err
+			}
+			data = append(append(data, line...), '\n')
+		}
+	}
+
+	if len(data) == 0 {
+		if err := os.Remove(schedulerJournalFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	return writeFileAtomic(schedulerJournalFile, data, 0644)
+}
+
+// journalProgress returns the journal's record of a tweet that has been
+// posted but not finished, if any.
+func journalProgress(queue, id string) (*journalEntry, error) {
+	entries, err := loadJournal()
+	if err != nil {
+		return nil, err
+	}
+	entry := entries[journalKey(queue, id)]
+	if entry == nil || entry.Done || entry.PostedID == "" {
+		return nil, nil
+	}
+	return entry, nil
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/kalikim/x-cli/config"
+	"github.com/spf13/cobra"
+)
+
+// k8sNamePattern is a DNS-1123 label, which Kubernetes requires of
+// namespaces and of the names used here.
+var k8sNamePattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+// Kubernetes sends SIGTERM and kills the pod after the grace period, so the
+// daemon gives up on the current post a little before that.
+const (
+	k8sGracePeriod = 30
+	k8sStopTimeout = "25s"
+	k8sHealthPort  = 8080
+)
+
+// k8sManifest holds what the manifest template needs.
+type k8sManifest struct {
+	Name        string
+	Namespace   string
+	Image       string
+	Secret      string
+	VolumeClaim string
+	Cron        string
+	TimeZone    string
+	Args        []string
+	GracePeriod int
+	HealthPort  int
+	// Pod is the rendered pod spec.
+	Pod string
+}
+
+func newSchedulerExportK8sCmd(queue *string) *cobra.Command {
+	var m k8sManifest
+	var text string
+
+	cmd := &cobra.Command{
+		Use:   "export-k8s",
+		Short: "Print a Kubernetes manifest that runs the daemon or a one-shot post",
+		Long: "Print a Kubernetes manifest for running x-cli in a cluster: a Deployment\n" +
+			"running the scheduler daemon with a /healthz liveness probe, or, with --cron\n" +
+			"and --text, a CronJob that posts a tweet on a schedule. Credentials and the\n" +
+			"config are read from a Secret, whose keys become environment variables.",
+		Example: `  x-cli scheduler export-k8s --image ghcr.io/acme/x-cli:1.4 --namespace social | kubectl apply -f -
+  x-cli scheduler export-k8s --image ghcr.io/acme/x-cli:1.4 --volume-claim x-cli-data --queue work
+  x-cli scheduler export-k8s --image ghcr.io/acme/x-cli:1.4 --name standup --cron "0 9 * * 1-5" --time-zone Europe/Berlin --text "Standup in 15 minutes"`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if m.Image == "" {
+				return invalidInput(errors.New("--image is required, e.g. ghcr.io/acme/x-cli:1.4"))
+			}
+			for _, f := range []struct{ flag, value string }{
+				{"--name", m.Name}, {"--namespace", m.Namespace}, {"--secret", m.Secret}, {"--volume-claim", m.VolumeClaim},
+			} {
+				if f.value != "" && !k8sNamePattern.MatchString(f.value) {
+					return invalidInput(fmt.Errorf("invalid %s %q (use lowercase letters, digits and '-')", f.flag, f.value))
+				}
+			}
+			if (m.Cron == "") != (text == "") {
+				return invalidInput(errors.New("--cron and --text go together"))
+			}
+			if m.TimeZone != "" && m.Cron == "" {
+				return invalidInput(errors.New("--time-zone needs --cron"))
+			}
+
+			if m.Cron != "" {
+				if err := validateCronSpec(m.Cron); err != nil {
+					return invalidInput(err)
+				}
+				if m.TimeZone != "" {
+					if _, err := time.LoadLocation(m.TimeZone); err != nil {
+						return invalidInput(fmt.Errorf("invalid --time-zone %q: %w", m.TimeZone, err))
+					}
+				}
+				if err := validateTweetText(text); err != nil {
+					return invalidInput(err)
+				}
+				m.Args = []string{"--text", text}
+			} else {
+				m.Args = []string{"scheduler", "daemon", "--log-format", "json",
+					"--health-addr", ":" + strconv.Itoa(k8sHealthPort), "--stop-timeout", k8sStopTimeout}
+			}
+			if *queue != "" {
+				m.Args = append(m.Args, "--queue", *queue)
+			}
+			m.GracePeriod = k8sGracePeriod
+			m.HealthPort = k8sHealthPort
+
+			if m.VolumeClaim == "" && config.Peek().Storage.Backend == "" {
+				fmt.Fprintln(os.Stderr, decorate("⚠️", "The queues and history will live in the pod and be lost when it restarts; "+
+					"use --volume-claim or configure storage in XCLI_CONFIG"))
+			}
+			var pod strings.Builder
+			if err := k8sPodTemplate.Execute(&pod, m); err != nil {
+				return err
+			}
+			m.Pod = pod.String()
+			return k8sTemplate.Execute(os.Stdout, m)
+		},
+	}
+	cmd.Flags().StringVar(&m.Image, "image", "", "Container image with the x-cli binary as its entrypoint")
+	cmd.Flags().StringVar(&m.Namespace, "namespace", "default", "Namespace of the resources")
+	cmd.Flags().StringVar(&m.Name, "name", "x-cli", "Name of the Deployment or CronJob")
+	cmd.Flags().StringVar(&m.Secret, "secret", "x-cli", "Secret holding TWITTER_* credentials and optionally XCLI_CONFIG")
+	cmd.Flags().StringVar(&m.VolumeClaim, "volume-claim", "", "PersistentVolumeClaim to keep the queues and history in (default: the pod's own disk)")
+	cmd.Flags().StringVar(&m.Cron, "cron", "", "Emit a CronJob with this schedule, e.g. \"0 9 * * 1-5\", instead of the daemon")
+	cmd.Flags().StringVar(&m.TimeZone, "time-zone", "", "Time zone of --cron, e.g. Europe/Berlin (default: the cluster's, usually UTC)")
+	cmd.Flags().StringVar(&text, "text", "", "Tweet the CronJob posts")
+	return cmd
+}
+
+// validateCronSpec checks that spec has the five fields of a cron schedule
+// or is one of the @ shorthands Kubernetes accepts.
+func validateCronSpec(spec string) error {
+	switch spec {
+	case "@yearly", "@annually", "@monthly", "@weekly", "@daily", "@midnight", "@hourly":
+		return nil
+	}
+	if len(strings.Fields(spec)) != 5 {
+		return fmt.Errorf("invalid --cron %q (use five fields: minute hour day-of-month month day-of-week)", spec)
+	}
+	return nil
+}
+
+// indentLines indents every non-empty line of s by n spaces.
+func indentLines(s string, n int) string {
+	lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = strings.Repeat(" ", n) + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+var k8sFuncs = template.FuncMap{"quote": strconv.Quote, "indent": indentLines}
+
+// k8sPodTemplate is the pod spec of both kinds. The pod reads its whole
+// configuration from the environment, so no config file is mounted.
+var k8sPodTemplate = template.Must(template.New("pod").Funcs(k8sFuncs).Parse(`
+{{- if .Cron}}restartPolicy: Never
+{{end -}}
+terminationGracePeriodSeconds: {{.GracePeriod}}
+containers:
+  - name: x-cli
+    image: {{quote .Image}}
+    args:
+{{- range .Args}}
+      - {{quote .}}
+{{- end}}
+    workingDir: /data
+    env:
+      - name: XCLI_CONFIG_FROM_ENV_ONLY
+        value: "1"
+    envFrom:
+      - secretRef:
+          name: {{.Secret}}
+{{- if not .Cron}}
+    ports:
+      - name: health
+        containerPort: {{.HealthPort}}
+    livenessProbe:
+      httpGet:
+        path: /healthz
+        port: health
+      periodSeconds: 30
+      failureThreshold: 3
+{{- end}}
+    volumeMounts:
+      - name: data
+        mountPath: /data
+volumes:
+  - name: data
+{{- if .VolumeClaim}}
+    persistentVolumeClaim:
+      claimName: {{.VolumeClaim}}
+{{- else}}
+    emptyDir: {}
+{{- end}}
+`))
+
+// k8sTemplate wraps the pod spec in a Deployment or a CronJob. A CronJob
+// never retries or overlaps, so a post that fails halfway is not sent twice.
+var k8sTemplate = template.Must(template.New("k8s").Funcs(k8sFuncs).Parse(`# Create the secret first, for example:
+#   kubectl -n {{.Namespace}} create secret generic {{.Secret}} \
+#     --from-literal=TWITTER_API_KEY=... --from-literal=TWITTER_API_SECRET=... \
+#     --from-literal=TWITTER_ACCESS_TOKEN=... --from-literal=TWITTER_ACCESS_SECRET=... \
+#     --from-file=XCLI_CONFIG=config.json
+{{- if .Cron}}
+apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: {{.Name}}
+  namespace: {{.Namespace}}
+  labels:
+    app.kubernetes.io/name: x-cli
+spec:
+  schedule: {{quote .Cron}}
+{{- if .TimeZone}}
+  timeZone: {{quote .TimeZone}}
+{{- end}}
+  concurrencyPolicy: Forbid
+  jobTemplate:
+    spec:
+      backoffLimit: 0
+      template:
+        metadata:
+          labels:
+            app.kubernetes.io/name: x-cli
+            app.kubernetes.io/instance: {{.Name}}
+        spec:
+{{indent .Pod 10}}
+{{- else}}
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: {{.Name}}
+  namespace: {{.Namespace}}
+  labels:
+    app.kubernetes.io/name: x-cli
+spec:
+  # A second replica would post every tweet twice.
+  replicas: 1
+  strategy:
+    type: Recreate
+  selector:
+    matchLabels:
+      app.kubernetes.io/name: x-cli
+      app.kubernetes.io/instance: {{.Name}}
+  template:
+    metadata:
+      labels:
+        app.kubernetes.io/name: x-cli
+        app.kubernetes.io/instance: {{.Name}}
+    spec:
+{{indent .Pod 6}}
+{{- end}}
+`))
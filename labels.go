@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var labelPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// parseLabels splits a comma-separated --label value into distinct,
+// lowercase labels.
+func parseLabels(spec string) ([]string, error) {
+	var labels []string
+	seen := map[string]bool{}
+
+	for _, label := range strings.Split(spec, ",") {
+		label = strings.ToLower(strings.TrimSpace(label))
+		if label == "" || seen[label] {
+			continue
+		}
+		if !labelPattern.MatchString(label) {
+			return nil, invalidInput(fmt.Errorf("invalid label %q (use letters, digits, '-' and '_')", label))
+		}
+		seen[label] = true
+		labels = append(labels, label)
+	}
+
+	return labels, nil
+}
+
+// hasLabels reports whether have includes every label in want.
+func hasLabels(have, want []string) bool {
+	for _, w := range want {
+		found := false
+		for _, h := range have {
+			if h == w {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// filterScheduledByLabels keeps the tweets carrying every label in want.
+func filterScheduledByLabels(tweets []scheduledTweet, want []string) []scheduledTweet {
+	if len(want) == 0 {
+		return tweets
+	}
+
+	var kept []scheduledTweet
+	for _, tweet := range tweets {
+		if hasLabels(tweet.Labels, want) {
+			kept = append(kept, tweet)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// tweetLangPattern matches the BCP 47 codes --lang takes, such as ja, en
+// or pt-BR.
+var tweetLangPattern = regexp.MustCompile(`^[a-z]{2,3}(-[A-Za-z0-9]{2,8})?$`)
+
+// scriptLanguages maps writing systems to the language a tweet in them is
+// most likely in. Kana is checked before Han, so Japanese text with kanji is
+// not taken for Chinese.
+var scriptLanguages = []struct {
+	script *unicode.RangeTable
+	lang   string
+}{
+	{unicode.Hiragana, "ja"},
+	{unicode.Katakana, "ja"},
+	{unicode.Hangul, "ko"},
+	{unicode.Han, "zh"},
+	{unicode.Thai, "th"},
+	{unicode.Arabic, "ar"},
+	{unicode.Hebrew, "he"},
+	{unicode.Greek, "el"},
+	{unicode.Cyrillic, "ru"},
+	{unicode.Devanagari, "hi"},
+}
+
+// latinStopwords are frequent short words that tell Latin-script languages
+// apart.
+var latinStopwords = map[string][]string{
+	"en": {"the", "and", "is", "are", "you", "to", "of", "for", "with", "this", "that", "it", "we", "our", "in", "on"},
+	"es": {"el", "la", "los", "las", "que", "y", "es", "en", "por", "con", "para", "una", "del", "nuestro"},
+	"fr": {"le", "la", "les", "et", "est", "des", "pour", "une", "avec", "dans", "sur", "nous", "vous", "du"},
+	"de": {"der", "die", "das", "und", "ist", "nicht", "mit", "für", "ein", "eine", "wir", "auf", "den", "zu"},
+	"pt": {"o", "os", "que", "e", "não", "com", "para", "uma", "do", "da", "em", "nosso", "você"},
+	"it": {"il", "di", "che", "e", "la", "per", "non", "con", "una", "gli", "nel", "sono", "del"},
+}
+
+// detectLanguage guesses the language of text from the script most of its
+// letters are in, and for Latin script from common words. It returns "" when
+// it cannot tell. Links, mentions and hashtags are ignored.
+func detectLanguage(text string) string {
+	text = spellSkipPattern.ReplaceAllString(text, " ")
+
+	counts := map[string]int{}
+	latin, letters := 0, 0
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		if unicode.Is(unicode.Latin, r) {
+			latin++
+			continue
+		}
+		for _, s := range scriptLanguages {
+			if unicode.Is(s.script, r) {
+				counts[s.lang]++
+				break
+			}
+		}
+	}
+	if letters == 0 {
+		return ""
+	}
+
+	best, bestCount := "", 0
+	for _, s := range scriptLanguages {
+		if n := counts[s.lang]; n > bestCount {
+			best, bestCount = s.lang, n
+		}
+	}
+	// Any kana makes Han text Japanese.
+	if counts["ja"] > 0 && best == "zh" {
+		best, bestCount = "ja", bestCount+counts["ja"]
+	}
+	if bestCount*2 >= letters-latin && bestCount > latin {
+		return best
+	}
+	return detectLatinLanguage(text)
+}
+
+// detectLatinLanguage picks the language whose common words occur most
+// often in text, needing at least two hits and a clear lead.
+func detectLatinLanguage(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	})
+	hits := map[string]int{}
+	for lang, stopwords := range latinStopwords {
+		for _, w := range words {
+			for _, s := range stopwords {
+				if w == s {
+					hits[lang]++
+				}
+			}
+		}
+	}
+
+	best, bestHits, second := "", 0, 0
+	for _, lang := range []string{"en", "es", "fr", "de", "pt", "it"} {
+		switch n := hits[lang]; {
+		case n > bestHits:
+			best, bestHits, second = lang, n, bestHits
+		case n > second:
+			second = n
+		}
+	}
+	if bestHits < 2 || bestHits == second {
+		return ""
+	}
+	return best
+}
+
+// isCJKLanguage reports whether lang is written mostly in characters X
+// counts as two, which halves the characters that fit in a tweet.
+func isCJKLanguage(lang string) bool {
+	base, _, _ := strings.Cut(lang, "-")
+	return base == "ja" || base == "zh" || base == "ko"
+}
+
+// usesOwnDictionary reports whether lang needs a spell checking dictionary
+// of its own; the default one is assumed to be English.
+func usesOwnDictionary(lang string) bool {
+	base, _, _ := strings.Cut(lang, "-")
+	return base != "" && base != "en"
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"slices"
+	"time"
+)
+
+const (
+	// defaultMaxMedianLateness is the median lateness above which the
+	// daemon warns that scheduled tweets cannot be trusted to go out on time.
+	defaultMaxMedianLateness = time.Minute
+	// latencyWindow is how many recent posts the daemon's median covers.
+	latencyWindow = 20
+)
+
+// latencyTracker keeps the lateness of the daemon's most recent posts.
+type latencyTracker struct {
+	recent    []time.Duration
+	threshold time.Duration
+	warned    bool
+}
+
+// record adds one post's lateness and warns once each time the rolling
+// median rises above the threshold.
+func (t *latencyTracker) record(lateness time.Duration) {
+	t.recent = append(t.recent, lateness)
+	if len(t.recent) > latencyWindow {
+		t.recent = t.recent[len(t.recent)-latencyWindow:]
+	}
+
+	if t.threshold <= 0 {
+		return
+	}
+	median := t.median()
+	switch {
+	case median > t.threshold && !t.warned:
+		say("🐢", "Scheduled tweets are posting late: median %s over the last %d post(s) (threshold %s)", formatLateness(median), len(t.recent), t.threshold)
+		t.warned = true
+	case median <= t.threshold && t.warned:
+		say("✅", "Posting lateness is back to normal: median %s", formatLateness(median))
+		t.warned = false
+	}
+}
+
+func (t *latencyTracker) median() time.Duration {
+	return percentileDuration(t.recent, 50)
+}
+
+// percentileDuration returns the p-th percentile of durations using the
+// nearest-rank method, or 0 for none.
+func percentileDuration(durations []time.Duration, p int) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+	sorted := slices.Clone(durations)
+	slices.Sort(sorted)
+
+	rank := (p*len(sorted) + 99) / 100
+	return sorted[max(rank, 1)-1]
+}
+
+// formatLateness rounds d for display, keeping sub-second precision for
+// small values.
+func formatLateness(d time.Duration) string {
+	if d < time.Second && d > -time.Second {
+		return d.Round(time.Millisecond).String()
+	}
+	return d.Round(time.Second).String()
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kalikim/x-cli/config"
+	"github.com/spf13/cobra"
+)
+
+const usageTweetsEndpoint = "https://api.twitter.com/2/usage/tweets"
+
+// lowHeadroom is the share of a limit left below which it is flagged.
+const lowHeadroom = 0.1
+
+// tweetCap is the project's monthly tweet consumption from the usage API.
+type tweetCap struct {
+	Usage    int64
+	Cap      int64
+	ResetDay int
+}
+
+func newLimitsCmd() *cobra.Command {
+	var profile, output string
+
+	cmd := &cobra.Command{
+		Use:   "limits",
+		Short: "Show rate-limit headroom and monthly tweet-cap usage",
+		Long: `Show the monthly tweet cap consumed by the project and the rate-limit
+status of the endpoints x-cli uses. GET /2/users/me is checked live; limits
+for other endpoints, such as posting, are the ones X reported on their
+most recent call.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.LoadConfig().ForProfile(profile)
+			if err != nil {
+				return invalidInput(err)
+			}
+			if err := cfg.Validate(); err != nil {
+				return err
+			}
+
+			client := apiClient()
+			var problems []string
+			if _, err := signedGet(client, cfg, usersMeEndpoint); err != nil {
+				problems = append(problems, fmt.Sprintf("checking %s: %v", usersMeEndpoint, err))
+			}
+			usage, err := fetchTweetCap(client, cfg)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("monthly tweet cap unavailable: %v", err))
+			}
+			limits, err := accountRateLimits(cfg)
+			if err != nil {
+				return fmt.Errorf("loading rate limits: %w", err)
+			}
+
+			for _, p := range problems {
+				say("⚠️", "%s", p)
+			}
+			return writeLimits(usage, limits, output, time.Now())
+		},
+	}
+	cmd.Flags().StringVar(&profile, "profile", "", "Show limits for this configured profile")
+	cmd.Flags().StringVarP(&output, "output", "o", "table", "Output format: table, json, yaml or tsv")
+	return cmd
+}
+
+// flexInt accepts numbers the usage API sends either bare or as strings.
+type flexInt int64
+
+func (n *flexInt) UnmarshalJSON(data []byte) error {
+	v, err := strconv.ParseInt(strings.Trim(string(data), `"`), 10, 64)
+	if err != nil {
+		return err
+	}
+	*n = flexInt(v)
+	return nil
+}
+
+func fetchTweetCap(client *http.Client, cfg config.Config) (*tweetCap, error) {
+	body, err := signedGet(client, cfg, usageTweetsEndpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp struct {
+		Data struct {
+			ProjectUsage flexInt `json:"project_usage"`
+			ProjectCap   flexInt `json:"project_cap"`
+			CapResetDay  int     `json:"cap_reset_day"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("decoding usage response: %w", err)
+	}
+	if resp.Data.ProjectCap == 0 {
+		return nil, fmt.Errorf("usage response has no project cap: %s", strings.TrimSpace(string(body)))
+	}
+	return &tweetCap{Usage: int64(resp.Data.ProjectUsage), Cap: int64(resp.Data.ProjectCap), ResetDay: resp.Data.CapResetDay}, nil
+}
+
+// nextCapReset returns the next start of day resetDay after now, clamped to
+// the length of short months.
+func nextCapReset(resetDay int, now time.Time) time.Time {
+	for months := 0; ; months++ {
+		first := time.Date(now.Year(), now.Month()+time.Month(months), 1, 0, 0, 0, 0, now.Location())
+		day := min(resetDay, first.AddDate(0, 1, -1).Day())
+		reset := first.AddDate(0, 0, day-1)
+		if reset.After(now) {
+			return reset
+		}
+	}
+}
+
+func writeLimits(usage *tweetCap, limits []rateLimit, format string, now time.Time) error {
+	columns := []string{"endpoint", "used", "remaining", "limit", "resets", "seen"}
+	var rows [][]any
+
+	if usage != nil {
+		resets := "-"
+		if usage.ResetDay > 0 {
+			resets = nextCapReset(usage.ResetDay, now).Format("2006-01-02")
+		}
+		rows = append(rows, []any{
+			headroomMark("monthly tweet cap", usage.Cap-usage.Usage, usage.Cap, format),
+			usage.Usage, usage.Cap - usage.Usage, usage.Cap, resets, "now",
+		})
+	}
+
+	for _, l := range limits {
+		remaining := l.Remaining
+		if now.After(l.Reset) {
+			// The window has rolled over since X reported it.
+			remaining = l.Limit
+		}
+		rows = append(rows, []any{
+			headroomMark(l.Method+" "+l.Endpoint, int64(remaining), int64(l.Limit), format),
+			l.Limit - remaining, remaining, l.Limit,
+			formatLimitReset(l.Reset, now), formatSeenAgo(l.SeenAt, now),
+		})
+	}
+
+	if len(rows) == 0 && format == "table" {
+		say("📭", "No rate-limit data yet; it is recorded as x-cli talks to X")
+		return nil
+	}
+	return writeRows(format, columns, rows)
+}
+
+// headroomMark flags limits that are nearly used up in table output.
+func headroomMark(name string, remaining, limit int64, format string) string {
+	if format == "table" && limit > 0 && float64(remaining) < lowHeadroom*float64(limit) {
+		return decorate("⚠️", name)
+	}
+	return name
+}
+
+func formatLimitReset(reset, now time.Time) string {
+	if !reset.After(now) {
+		return "reset"
+	}
+	return "in " + reset.Sub(now).Round(time.Second).String()
+}
+
+func formatSeenAgo(seen, now time.Time) string {
+	ago := now.Sub(seen)
+	if ago < time.Minute {
+		return "now"
+	}
+	return formatCountdown(ago.Round(time.Minute)) + " ago"
+}
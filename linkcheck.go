@@ -0,0 +1,146 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/kalikim/x-cli/config"
+)
+
+// linkCheckTimeout bounds each link check so one slow site cannot stall a
+// post or a daemon cycle.
+const linkCheckTimeout = 10 * time.Second
+
+// Link check levels for the link_check config key.
+const (
+	linkCheckOff   = "off"
+	linkCheckWarn  = "warn"
+	linkCheckError = "error"
+)
+
+var errRedirectLoop = errors.New("redirect loop")
+
+// linkCheckLevel returns the configured link check level, "off" when unset.
+func linkCheckLevel(cfg config.Config) (string, error) {
+	switch cfg.LinkCheck {
+	case "":
+		return linkCheckOff, nil
+	case linkCheckOff, linkCheckWarn, linkCheckError:
+		return cfg.LinkCheck, nil
+	}
+	return "", fmt.Errorf("invalid link_check %q (use off, warn or error)", cfg.LinkCheck)
+}
+
+// extractLinks returns the distinct links in texts, in order of appearance.
+func extractLinks(texts ...string) []string {
+	seen := map[string]bool{}
+	var links []string
+	for _, text := range texts {
+		for _, link := range countURLPattern.FindAllString(text, -1) {
+			link = strings.TrimRight(link, ".,;:!?)\"'")
+			if key := linkKey(link); !seen[key] {
+				seen[key] = true
+				links = append(links, link)
+			}
+		}
+	}
+	return links
+}
+
+// checkLinks requests every link and returns a description of each one that
+// is dead: unreachable, answering 4xx/5xx, or redirecting in a loop.
+func checkLinks(client *http.Client, links []string) []string {
+	checker := *client
+	checker.Timeout = linkCheckTimeout
+	checker.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		for _, prev := range via {
+			if prev.URL.String() == req.URL.String() {
+				return errRedirectLoop
+			}
+		}
+		if len(via) >= 10 {
+			return errors.New("too many redirects")
+		}
+		return nil
+	}
+
+	var dead []string
+	for _, link := range links {
+		if problem := checkLink(&checker, link); problem != "" {
+			dead = append(dead, fmt.Sprintf("%s %s", link, problem))
+		}
+	}
+	return dead
+}
+
+// checkLink returns why link is dead, or "" when it answers. HEAD is tried
+// first; servers that refuse it get a GET.
+func checkLink(client *http.Client, link string) string {
+	status, err := requestLink(client, http.MethodHead, link)
+	if err == nil && (status == http.StatusMethodNotAllowed || status == http.StatusNotImplemented || status == http.StatusForbidden) {
+		status, err = requestLink(client, http.MethodGet, link)
+	}
+
+	switch {
+	case errors.Is(err, errRedirectLoop):
+		return "redirects in a loop"
+	case err != nil:
+		return fmt.Sprintf("is unreachable: %v", err)
+	case status >= 400:
+		return fmt.Sprintf("returned HTTP %d", status)
+	}
+	return ""
+}
+
+func requestLink(client *http.Client, method, link string) (int, error) {
+	req, err := http.NewRequest(method, link, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("User-Agent", userAgent()+" link-check")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+// checkScheduledLinks checks a due tweet's links before the daemon posts it.
+// Dead links are logged at "warn" and returned as an error at "error" or
+// when the tweet was scheduled with --check-links.
+func checkScheduledLinks(client *http.Client, cfg config.Config, tweet scheduledTweet) error {
+	level, err := linkCheckLevel(cfg)
+	if err != nil {
+		return err
+	}
+	if tweet.CheckLinks {
+		level = linkCheckError
+	}
+
+	texts := append([]string{tweet.Text}, tweet.Variants...)
+	dead, err := verifyLinks(client, level, append(texts, tweet.Thread...)...)
+	for _, warning := range dead {
+		log.Printf("Dead link in tweet %s: %s", tweet.ID, warning)
+	}
+	return err
+}
+
+// verifyLinks checks the links in texts at the given level. Dead links are
+// returned as warnings at "warn" and as an error at "error".
+func verifyLinks(client *http.Client, level string, texts ...string) ([]string, error) {
+	if level == linkCheckOff {
+		return nil, nil
+	}
+
+	dead := checkLinks(client, extractLinks(texts...))
+	if len(dead) > 0 && level == linkCheckError {
+		return nil, fmt.Errorf("dead links: %s", strings.Join(dead, "; "))
+	}
+	return dead, nil
+}
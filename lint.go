@@ -0,0 +1,169 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/kalikim/x-cli/config"
+)
+
+const usersLookupEndpoint = "https://api.twitter.com/2/users/by"
+
+var (
+	mentionPattern = regexp.MustCompile(`(?:^|[^\w@])@(\w{1,15})\b`)
+	hashtagPattern = regexp.MustCompile(`#[^\s#]*`)
+	bareURLPattern = regexp.MustCompile(`(?i)^(?:www\.)?[a-z0-9-]+(?:\.[a-z0-9-]+)*\.(?:com|org|net|io|dev|co|app|ai|me|gg|ly|xyz|info|edu|gov|tv|so|sh)(?:[/?#]\S*)?$`)
+)
+
+// lintTweet returns human-readable warnings about common mistakes in text.
+// Mentions are verified against the users lookup endpoint; lookup failures
+// are reported as warnings rather than errors.
+func lintTweet(client *http.Client, cfg config.Config, text string) []string {
+	warnings := lintTweetText(text)
+
+	mentions := extractMentions(text)
+	if len(mentions) == 0 {
+		return warnings
+	}
+
+	missing, err := lookupMissingUsers(client, cfg, mentions)
+	if err != nil {
+		return append(warnings, fmt.Sprintf("could not verify mentions: %v", err))
+	}
+	for _, name := range missing {
+		warnings = append(warnings, fmt.Sprintf("@%s does not match an existing account", name))
+	}
+
+	return warnings
+}
+
+// lintTweetText performs the checks that need no network access.
+func lintTweetText(text string) []string {
+	var warnings []string
+
+	if strings.Contains(text, "  ") {
+		warnings = append(warnings, "text contains double spaces")
+	}
+
+	for i, line := range strings.Split(text, "\n") {
+		if strings.TrimRightFunc(line, unicode.IsSpace) != line {
+			warnings = append(warnings, fmt.Sprintf("line %d has trailing whitespace", i+1))
+		}
+	}
+
+	for _, loc := range hashtagPattern.FindAllStringIndex(text, -1) {
+		tag := text[loc[0]:loc[1]]
+		if problem := hashtagProblem(text, loc[0], tag); problem != "" {
+			warnings = append(warnings, fmt.Sprintf("hashtag %q %s", tag, problem))
+		}
+	}
+
+	for _, field := range strings.Fields(text) {
+		word := strings.TrimRight(field, ".,;:!?)\"'")
+		word = strings.TrimLeft(word, "(\"'")
+		switch {
+		case strings.HasPrefix(strings.ToLower(word), "http://"):
+			warnings = append(warnings, fmt.Sprintf("%s uses http://; prefer https://", word))
+		case !strings.Contains(word, "://") && !strings.Contains(word, "@") && bareURLPattern.MatchString(word):
+			warnings = append(warnings, fmt.Sprintf("%s looks like a link missing https://", word))
+		}
+	}
+
+	return warnings
+}
+
+// hashtagProblem explains why the hashtag starting at offset in text will not
+// be linked, or returns "" when it looks fine.
+func hashtagProblem(text string, offset int, tag string) string {
+	if offset > 0 {
+		prev := []rune(text[:offset])
+		if r := prev[len(prev)-1]; unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return "is attached to the preceding word and will not be linked"
+		}
+	}
+
+	body := strings.TrimPrefix(tag, "#")
+	trimmed := strings.TrimRight(body, ".,;:!?)\"'")
+	if trimmed == "" {
+		return "is empty"
+	}
+
+	allDigits := true
+	for i, r := range trimmed {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' {
+			return fmt.Sprintf("will be cut off at %q", trimmed[i:])
+		}
+		if !unicode.IsDigit(r) {
+			allDigits = false
+		}
+	}
+	if allDigits {
+		return "contains only digits and will not be linked"
+	}
+
+	return ""
+}
+
+func extractMentions(text string) []string {
+	seen := map[string]bool{}
+	var mentions []string
+
+	for _, match := range mentionPattern.FindAllStringSubmatch(text, -1) {
+		name := match[1]
+		if key := strings.ToLower(name); !seen[key] {
+			seen[key] = true
+			mentions = append(mentions, name)
+		}
+	}
+
+	return mentions
+}
+
+// lookupMissingUsers returns the usernames that do not resolve to an account.
+func lookupMissingUsers(client *http.Client, cfg config.Config, usernames []string) ([]string, error) {
+	found, err := resolveUsers(client, cfg, usernames)
+	if err != nil {
+		return nil, err
+	}
+
+	var missing []string
+	for _, name := range usernames {
+		if found[strings.ToLower(name)] == "" {
+			missing = append(missing, name)
+		}
+	}
+
+	return missing, nil
+}
+
+// resolveUsers maps the lowercased usernames that exist to their user IDs.
+func resolveUsers(client *http.Client, cfg config.Config, usernames []string) (map[string]string, error) {
+	endpoint := usersLookupEndpoint + "?usernames=" + url.QueryEscape(strings.Join(usernames, ","))
+
+	body, err := signedGet(client, cfg, endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp struct {
+		Data []struct {
+			ID       string `json:"id"`
+			Username string `json:"username"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("decoding user lookup response: %w", err)
+	}
+
+	found := map[string]string{}
+	for _, user := range resp.Data {
+		found[strings.ToLower(user.Username)] = user.ID
+	}
+
+	return found, nil
+}
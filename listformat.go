@@ -0,0 +1,207 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// listColumn is one selectable field of `scheduler list` machine output.
+type listColumn struct {
+	name  string
+	value func(tweet scheduledTweet, now time.Time) any
+}
+
+var listColumns = []listColumn{
+	{"id", func(t scheduledTweet, _ time.Time) any { return t.ID }},
+	{"time", func(t scheduledTweet, _ time.Time) any { return t.ScheduleTime.Format(time.RFC3339) }},
+	{"status", func(t scheduledTweet, now time.Time) any { return scheduledStatus(t, now) }},
+	{"text", func(t scheduledTweet, _ time.Time) any { return t.Text }},
+	{"image", func(t scheduledTweet, _ time.Time) any { return t.Image }},
+	{"thread", func(t scheduledTweet, _ time.Time) any { return len(t.Thread) }},
+	{"reply_to", func(t scheduledTweet, _ time.Time) any { return t.ReplyTo }},
+	{"profile", func(t scheduledTweet, _ time.Time) any { return t.Profile }},
+	{"labels", func(t scheduledTweet, _ time.Time) any { return strings.Join(t.Labels, ",") }},
+	{"campaign", func(t scheduledTweet, _ time.Time) any { return t.Campaign }},
+	{"last_error", func(t scheduledTweet, _ time.Time) any { return t.LastError }},
+}
+
+const defaultListColumns = "id,time,status,text"
+
+var listOutputFormats = []string{"table", "json", "yaml", "tsv"}
+
+// scheduledStatus is the machine-readable state of a scheduled tweet.
+func scheduledStatus(tweet scheduledTweet, now time.Time) string {
+	switch {
+	case tweet.State != "":
+		return tweet.State
+	case tweet.Paused:
+		return "paused"
+	case tweet.ScheduleTime.Before(now):
+		return "overdue"
+	default:
+		return "pending"
+	}
+}
+
+// parseListColumns resolves a comma-separated column list.
+func parseListColumns(spec string) ([]listColumn, error) {
+	var columns []listColumn
+
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.TrimSpace(strings.ToLower(name))
+		if name == "" {
+			continue
+		}
+
+		found := false
+		for _, c := range listColumns {
+			if c.name == name {
+				columns = append(columns, c)
+				found = true
+				break
+			}
+		}
+		if !found {
+			var names []string
+			for _, c := range listColumns {
+				names = append(names, c.name)
+			}
+			return nil, invalidInput(fmt.Errorf("unknown column %q (available: %s)", name, strings.Join(names, ", ")))
+		}
+	}
+
+	if len(columns) == 0 {
+		return nil, invalidInput(fmt.Errorf("--columns cannot be empty"))
+	}
+	return columns, nil
+}
+
+// writeScheduledTweets renders tweets in one of listOutputFormats.
+func writeScheduledTweets(tweets []scheduledTweet, format, columnSpec string) error {
+	columns, err := parseListColumns(columnSpec)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	names := make([]string, len(columns))
+	for i, c := range columns {
+		names[i] = c.name
+	}
+	rows := make([][]any, len(tweets))
+	for i, tweet := range tweets {
+		for _, c := range columns {
+			rows[i] = append(rows[i], c.value(tweet, now))
+		}
+	}
+
+	return writeRows(format, names, rows)
+}
+
+// writeRows renders rows under the given column names in one of
+// listOutputFormats.
+func writeRows(format string, columns []string, rows [][]any) error {
+	switch format {
+	case "table":
+		return writeListTable(columns, rows)
+	case "tsv":
+		return writeListTSV(columns, rows)
+	case "json":
+		return writeListJSON(columns, rows)
+	case "yaml":
+		writeListYAML(columns, rows)
+		return nil
+	default:
+		return invalidInput(fmt.Errorf("unknown output format %q (use %s)", format, strings.Join(listOutputFormats, ", ")))
+	}
+}
+
+func writeListTable(columns []string, rows [][]any) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+	var header []string
+	for _, c := range columns {
+		header = append(header, strings.ToUpper(c))
+	}
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+
+	for _, row := range rows {
+		cells := make([]string, len(row))
+		for i, v := range row {
+			cell := flattenCell(fmt.Sprint(v))
+			if columns[i] == "text" || columns[i] == "title" {
+				cell = truncateText(cell, 50)
+			}
+			cells[i] = cell
+		}
+		fmt.Fprintln(w, strings.Join(cells, "\t"))
+	}
+
+	return w.Flush()
+}
+
+func writeListTSV(columns []string, rows [][]any) error {
+	fmt.Println(strings.Join(columns, "\t"))
+
+	for _, row := range rows {
+		cells := make([]string, len(row))
+		for i, v := range row {
+			cells[i] = flattenCell(fmt.Sprint(v))
+		}
+		fmt.Println(strings.Join(cells, "\t"))
+	}
+
+	return nil
+}
+
+func writeListJSON(columns []string, rows [][]any) error {
+	objects := make([]map[string]any, 0, len(rows))
+	for _, row := range rows {
+		obj := map[string]any{}
+		for i, c := range columns {
+			obj[c] = row[i]
+		}
+		objects = append(objects, obj)
+	}
+
+	data, err := json.MarshalIndent(objects, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(data))
+	return nil
+}
+
+// writeListYAML emits a YAML sequence of mappings in column order. Strings
+// are double-quoted, which YAML reads with JSON escaping rules.
+func writeListYAML(columns []string, rows [][]any) {
+	if len(rows) == 0 {
+		fmt.Println("[]")
+		return
+	}
+
+	for _, row := range rows {
+		for i, c := range columns {
+			prefix := "  "
+			if i == 0 {
+				prefix = "- "
+			}
+
+			value := fmt.Sprint(row[i])
+			if s, ok := row[i].(string); ok {
+				value = strconv.Quote(s)
+			}
+			fmt.Printf("%s%s: %s\n", prefix, c, value)
+		}
+	}
+}
+
+// flattenCell keeps a value on one line so tabular output stays aligned.
+func flattenCell(s string) string {
+	return strings.NewReplacer("\t", " ", "\r\n", " ", "\n", " ").Replace(s)
+}
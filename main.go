@@ -2,10 +2,7 @@ package main
 
 import (
 	"bytes"
-	"crypto/hmac"
 	"crypto/rand"
-	"crypto/sha1"
-	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
@@ -13,15 +10,18 @@ import (
 	"io"
 	"log"
 	"mime"
+	"mime/multipart"
 	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/kalikim/x-cli/config"
+	"github.com/kalikim/x-cli/oauth1"
 	"github.com/spf13/cobra"
 )
 
@@ -33,150 +33,959 @@ const (
 type tweetPayload struct {
 	Text  string           `json:"text"`
 	Media *tweetMediaBlock `json:"media,omitempty"`
+	Reply *tweetReplyBlock `json:"reply,omitempty"`
+	tweetExtras
+}
+
+// tweetExtras are optional create-tweet fields used by ads and marketing
+// workflows. They apply to the first tweet of a thread only.
+type tweetExtras struct {
+	CardURI               string `json:"card_uri,omitempty"`
+	DirectMessageDeepLink string `json:"direct_message_deep_link,omitempty"`
+	// Nullcast makes a promoted-only ("dark") post that never appears on
+	// the account's timeline or to followers.
+	Nullcast bool `json:"nullcast,omitempty"`
 }
 
 type tweetMediaBlock struct {
 	MediaIDs []string `json:"media_ids"`
 }
 
+type tweetReplyBlock struct {
+	InReplyToTweetID string `json:"in_reply_to_tweet_id"`
+}
+
 type scheduledTweet struct {
-	Text      string    `json:"text"`
-	Image     string    `json:"image,omitempty"`
-	ScheduleTime time.Time `json:"schedule_time"`
-	ID        string    `json:"id"`
+	Text  string `json:"text"`
+	Image string `json:"image,omitempty"`
+	// AltText describes the image in Image for screen readers.
+	AltText string `json:"alt_text,omitempty"`
+	// Subtitles are captions attached to the video in Image.
+	Subtitles *subtitleTrack `json:"subtitles,omitempty"`
+	// Thumbnail is the poster image of the video in Image.
+	Thumbnail    *videoThumbnail `json:"thumbnail,omitempty"`
+	ScheduleTime time.Time       `json:"schedule_time"`
+	ID           string          `json:"id"`
+	Thread       []string        `json:"thread,omitempty"`
+	ReplyTo      string          `json:"reply_to,omitempty"`
+	FollowUp     *followUp       `json:"follow_up,omitempty"`
+	Paused       bool            `json:"paused,omitempty"`
+	// Profile names the account the tweet is posted from; empty uses the
+	// queue's profile.
+	Profile string   `json:"profile,omitempty"`
+	Labels  []string `json:"labels,omitempty"`
+	// Campaign groups tweets for `campaign status` and bulk cancel.
+	Campaign string `json:"campaign,omitempty"`
+	// Variants are alternative texts for A/B tests; the daemon posts one of
+	// Text and Variants at random and records its letter in Variant.
+	Variants []string `json:"variants,omitempty"`
+	Variant  string   `json:"variant,omitempty"`
+	// ResharedFrom is the ID of the tweet this one re-shares, set by
+	// `suggest reshare`.
+	ResharedFrom string `json:"reshared_from,omitempty"`
+	// Plan is "<plan>/<slot>" for tweets owned by a content plan, which
+	// `plan apply` may change or remove.
+	Plan string `json:"plan,omitempty"`
+	// Mentions pins each mentioned handle (lowercased) to the user ID it
+	// resolved to when the tweet was scheduled.
+	Mentions map[string]string `json:"mentions,omitempty"`
+	// Watermark is the --watermark given when the tweet was scheduled; it
+	// replaces the config's for this tweet.
+	Watermark *config.Watermark `json:"watermark,omitempty"`
+	// CaptionOverlay is the --caption-overlay text and style the tweet was
+	// scheduled with.
+	CaptionOverlay *config.CaptionOverlay `json:"caption_overlay,omitempty"`
+	// CheckLinks makes the daemon refuse to post while a link is dead,
+	// whatever link_check says.
+	CheckLinks bool `json:"check_links,omitempty"`
+	// LastError is the most recent reason the daemon failed to post it.
+	LastError string `json:"last_error,omitempty"`
+	// FailingSince is when the current run of failures began.
+	FailingSince *time.Time `json:"failing_since,omitempty"`
+	// NoticeSentFor is the schedule time the daemon's posting notice went
+	// out for; rescheduling the tweet sends a new one.
+	NoticeSentFor *time.Time `json:"notice_sent_for,omitempty"`
+	// PostNow makes the daemon post the tweet at its next check, ahead of
+	// its schedule and regardless of quiet hours; see `scheduler post`.
+	PostNow bool `json:"post_now,omitempty"`
+	tweetExtras
+	// Key identifies the tweet across daemon restarts; State and PostedID
+	// track how far posting got so an interrupted cycle is never repeated.
+	Key      string `json:"key,omitempty"`
+	State    string `json:"state,omitempty"`
+	PostedID string `json:"posted_id,omitempty"`
+	// Replies are the IDs of the thread replies posted so far; a thread cut
+	// short by a failed reply is retried from the last of them.
+	Replies []string `json:"replies,omitempty"`
 }
 
 func main() {
 	var text string
 	var image string
 	var scheduleAt string
+	var firstComment string
+	var followUpText, followUpAfter string
+	var queue, profile, labelSpec, campaign string
+	var templateName, tagSpec string
+	var templateVarFlags []string
+	var noColor, noEmoji, offline, envOnly, ghaFlag bool
+	var cassette, cassetteMode string
+	var extras tweetExtras
+	var watermark config.Watermark
+	var caption config.CaptionOverlay
+	var variants []string
+	var subtitles subtitleTrack
+	var thumbnailSpec, altText, tweetLang string
+	var fromClipboard, assumeYes, noShortcodes, noLint, noSpellcheck, strictSpellcheck, noFooter, autoThread, polish, force, checkLinksFlag, copyMedia, notifySlack bool
 
 	rootCmd := &cobra.Command{
 		Use:   "x-cli",
 		Short: "Post to X (Twitter) from your terminal 🚀",
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if templateName != "" {
+				t, err := findTemplate(templateName)
+				if err != nil {
+					return err
+				}
+				vars, err := parseTemplateVars(templateVarFlags)
+				if err != nil {
+					return err
+				}
+				if text, err = fillTemplate(t, vars); err != nil {
+					return err
+				}
+				if image == "" {
+					image = t.Image
+				}
+			} else if len(templateVarFlags) > 0 {
+				return invalidInput(errors.New("--var needs --template"))
+			}
+
+			if fromClipboard {
+				clip, err := readClipboardText()
+				if err != nil {
+					return fmt.Errorf("reading clipboard: %w", err)
+				}
+				text = clip
+			}
+
 			text = strings.TrimSpace(text)
 			if text == "" {
-				return errors.New("text flag cannot be empty")
+				return invalidInput(errors.New("text flag cannot be empty"))
+			}
+			if !noShortcodes {
+				text = expandShortcodes(text)
+			}
+			if !autoThread {
+				if err := validateTweetText(text); err != nil {
+					return fmt.Errorf("%w (use --auto-thread to post it as a thread)", err)
+				}
 			}
 
-			cfg := config.LoadConfig()
-			if err := cfg.Validate(); err != nil {
+			firstComment = strings.TrimSpace(firstComment)
+			if firstComment != "" {
+				if !noShortcodes {
+					firstComment = expandShortcodes(firstComment)
+				}
+				if err := validateTweetText(firstComment); err != nil {
+					return fmt.Errorf("first comment: %w", err)
+				}
+			}
+
+			if len(variants) > 0 && scheduleAt == "" {
+				return invalidInput(errors.New("--variant needs --schedule; the daemon picks the variant when posting"))
+			}
+			if len(variants) > maxVariants {
+				return invalidInput(fmt.Errorf("at most %d variants are supported", maxVariants))
+			}
+			for i := range variants {
+				variants[i] = strings.TrimSpace(variants[i])
+				if variants[i] == "" {
+					return invalidInput(errors.New("variant text cannot be empty"))
+				}
+				if !noShortcodes {
+					variants[i] = expandShortcodes(variants[i])
+				}
+				if err := validateTweetText(variants[i]); err != nil {
+					return fmt.Errorf("variant %s: %w", strings.ToUpper(variantLetter(i+1)), err)
+				}
+			}
+
+			var fu *followUp
+			if followUpText = strings.TrimSpace(followUpText); followUpText != "" {
+				if !noShortcodes {
+					followUpText = expandShortcodes(followUpText)
+				}
+				if err := validateTweetText(followUpText); err != nil {
+					return fmt.Errorf("follow-up: %w", err)
+				}
+				if _, err := parseDelay(followUpAfter); err != nil {
+					return fmt.Errorf("--after: %w", err)
+				}
+				fu = &followUp{Text: followUpText, Delay: followUpAfter}
+			}
+
+			if err := validateQueueName(queue); err != nil {
 				return err
 			}
 
-			// Handle scheduling
+			if err := validateTweetExtras(extras, image); err != nil {
+				return err
+			}
+			if image != "" {
+				if err := validateMedia(image); err != nil {
+					return err
+				}
+			}
+			media := mediaOptions{AltText: altText}
+			if altText != "" {
+				if err := validateAltText(altText, image); err != nil {
+					return err
+				}
+			}
+			if tweetLang != "" {
+				if !tweetLangPattern.MatchString(tweetLang) {
+					return invalidInput(fmt.Errorf("invalid --lang %q (use a code such as en, ja or pt-BR)", tweetLang))
+				}
+				if !cmd.Flags().Changed("subtitles-lang") {
+					subtitles.Lang = tweetLang
+				}
+			}
+			if subtitles.Path != "" {
+				if err := validateSubtitles(subtitles, image); err != nil {
+					return err
+				}
+				media.Subtitles = &subtitles
+			} else if cmd.Flags().Changed("subtitles-lang") {
+				return invalidInput(errors.New("--subtitles-lang needs --subtitles"))
+			}
+			if thumbnailSpec != "" {
+				t, err := parseThumbnail(thumbnailSpec, image)
+				if err != nil {
+					return err
+				}
+				media.Thumbnail = &t
+			}
+			if watermark.Image == "" && (cmd.Flags().Changed("position") || cmd.Flags().Changed("opacity")) {
+				return invalidInput(errors.New("--position and --opacity need --watermark"))
+			}
+			if watermark.Image != "" {
+				if image == "" {
+					return invalidInput(errors.New("--watermark needs --image"))
+				}
+				data, err := os.ReadFile(image)
+				if err != nil {
+					return fmt.Errorf("reading media: %w", err)
+				}
+				if !overlayable(detectMime(image, data)) {
+					return invalidInput(errors.New("--watermark works on JPEG and PNG images"))
+				}
+				if watermark, err = resolveWatermark(watermark); err != nil {
+					return err
+				}
+				// The daemon may run from another directory.
+				if watermark.Image, err = filepath.Abs(watermark.Image); err != nil {
+					return err
+				}
+			}
+			if caption.Text == "" && (cmd.Flags().Changed("overlay-font") || cmd.Flags().Changed("overlay-color") || cmd.Flags().Changed("overlay-position")) {
+				return invalidInput(errors.New("--overlay-font, --overlay-color and --overlay-position need --caption-overlay"))
+			}
+			if caption.Text != "" {
+				if image == "" {
+					return invalidInput(errors.New("--caption-overlay needs --image"))
+				}
+				data, err := os.ReadFile(image)
+				if err != nil {
+					return fmt.Errorf("reading media: %w", err)
+				}
+				if !overlayable(detectMime(image, data)) {
+					return invalidInput(errors.New("--caption-overlay works on JPEG and PNG images"))
+				}
+			}
+			if copyMedia && (scheduleAt == "" || image == "") {
+				return invalidInput(errors.New("--copy-media needs --schedule and --image"))
+			}
+
+			labels, err := parseLabels(labelSpec)
+			if err != nil {
+				return err
+			}
+			if err := validateCampaignName(campaign); err != nil {
+				return err
+			}
+
+			cfg, err := configForTweet(config.LoadConfig(), queue, profile)
+			if err != nil {
+				return err
+			}
+			if err := cfg.Validate(); err != nil {
+				return err
+			}
+			if err := checkWritable(cfg); err != nil {
+				return err
+			}
+			action := actionPost
 			if scheduleAt != "" {
-				return handleScheduledTweet(text, image, scheduleAt)
+				action = actionSchedule
+			}
+			if err := checkAllowed(cfg, action); err != nil {
+				return err
+			}
+			if watermark.Image != "" {
+				cfg.Watermark = watermark
+			}
+			if caption.Text != "" {
+				// Styles not given as flags come from the config.
+				style := cfg.CaptionOverlay
+				style.Text = caption.Text
+				for _, f := range []struct {
+					flag  string
+					field *string
+					value string
+				}{{"overlay-font", &style.Font, caption.Font}, {"overlay-color", &style.Color, caption.Color}, {"overlay-position", &style.Position, caption.Position}} {
+					if cmd.Flags().Changed(f.flag) {
+						*f.field = f.value
+					}
+				}
+				if caption, err = resolveCaptionOverlay(style); err != nil {
+					return err
+				}
+				cfg.CaptionOverlay = caption
+			}
+			if notifySlack {
+				if scheduleAt != "" {
+					return invalidInput(errors.New("--notify-slack applies to immediate posts; the daemon reports scheduled tweets to slack_webhook on its own"))
+				}
+				if cfg.SlackWebhook == "" {
+					return invalidInput(errors.New("--notify-slack needs slack_webhook in the config (or XCLI_SLACK_WEBHOOK)"))
+				}
 			}
 
-			// Post immediately
-			client := &http.Client{Timeout: 20 * time.Second}
+			if !cmd.Flags().Changed("polish") {
+				polish = cfg.Polish
+			}
+			if polish {
+				text = polishText(text)
+				firstComment = polishText(firstComment)
+				for i := range variants {
+					variants[i] = polishText(variants[i])
+				}
+				if fu != nil {
+					fu.Text = polishText(fu.Text)
+				}
+			}
 
-			var mediaIDs []string
-			if image != "" {
-				id, err := uploadMedia(client, cfg, image)
+			if tagSpec != "" {
+				tags, err := hashtagSetTags(cfg, tagSpec)
 				if err != nil {
 					return err
 				}
-				mediaIDs = append(mediaIDs, id)
+				if autoThread {
+					// Like the footer, the hashtags land on the last segment.
+					text = appendHashtags(text, tags)
+				} else if text, err = applyHashtags(text, tags); err != nil {
+					return err
+				}
+				for i := range variants {
+					if variants[i], err = applyHashtags(variants[i], tags); err != nil {
+						return fmt.Errorf("variant %s: %w", strings.ToUpper(variantLetter(i+1)), err)
+					}
+				}
+			}
+
+			if !noFooter {
+				if autoThread {
+					// The footer lands on the last segment once split.
+					footer, err := renderFooter(cfg)
+					if err != nil {
+						return err
+					}
+					text = appendFooter(text, footer)
+				} else {
+					withFooter, err := applyFooter(cfg, text)
+					if err != nil {
+						return err
+					}
+					text = withFooter
+				}
+				for i := range variants {
+					withFooter, err := applyFooter(cfg, variants[i])
+					if err != nil {
+						return fmt.Errorf("variant %s: %w", strings.ToUpper(variantLetter(i+1)), err)
+					}
+					variants[i] = withFooter
+				}
+			}
+
+			segments := []string{text}
+			if autoThread {
+				segments = splitThread(text, maxTweetLength)
+			}
+
+			if !force {
+				if err := checkBannedContent(cfg, text+"\n"+firstComment+"\n"+followUpText+"\n"+strings.Join(variants, "\n")); err != nil {
+					return err
+				}
+			}
+
+			var ruleTexts []ruleText
+			for i, segment := range segments {
+				name := ""
+				if len(segments) > 1 {
+					name = fmt.Sprintf("tweet %d", i+1)
+				}
+				ruleTexts = append(ruleTexts, ruleText{name, segment})
+			}
+			for i, variant := range variants {
+				ruleTexts = append(ruleTexts, ruleText{"variant " + strings.ToUpper(variantLetter(i+1)), variant})
+			}
+			if firstComment != "" {
+				ruleTexts = append(ruleTexts, ruleText{"first comment", firstComment})
+			}
+			if followUpText != "" {
+				ruleTexts = append(ruleTexts, ruleText{"follow-up", followUpText})
+			}
+			ruleWarnings, err := checkContentRules(cfg, ruleTexts, force)
+			if err != nil {
+				return err
+			}
+			accessibilityWarnings, err := checkAccessibility(cfg, ruleTexts, image, media, force)
+			if err != nil {
+				return err
 			}
 
-			if err := postTweet(client, cfg, text, mediaIDs); err != nil {
+			client := apiClient()
+
+			linkLevel, err := linkCheckLevel(cfg)
+			if err != nil {
 				return err
 			}
+			if checkLinksFlag {
+				linkLevel = linkCheckError
+			}
+			if force && linkLevel == linkCheckError {
+				linkLevel = linkCheckWarn
+			}
+			deadLinks, err := verifyLinks(client, linkLevel, text, firstComment, followUpText, strings.Join(variants, "\n"))
+			if err != nil {
+				return invalidInput(fmt.Errorf("%w (use --force to post anyway)", err))
+			}
+			for _, warning := range deadLinks {
+				say("⚠️", "Dead link: %s", warning)
+			}
 
-			if len(mediaIDs) > 0 {
-				fmt.Println("✅ Tweet with media posted successfully!")
-			} else {
-				fmt.Println("✅ Tweet posted successfully!")
+			if !noLint {
+				for _, warning := range lintTweet(client, cfg, text) {
+					say("⚠️", "%s", warning)
+				}
+				for _, warning := range ruleWarnings {
+					say("⚠️", "%s", warning)
+				}
+				for _, warning := range accessibilityWarnings {
+					say("⚠️", "Accessibility: %s", warning)
+				}
+			}
+
+			lang := tweetLang
+			if lang == "" {
+				lang = detectLanguage(text)
+			}
+			if !noSpellcheck && cfg.SpellcheckDictionary == "" && usesOwnDictionary(lang) {
+				say("💡", "Spell check skipped for %s text: set spellcheck_dictionary to a word list for %s to check it", lang, lang)
+			} else if !noSpellcheck {
+				misspelled, err := spellcheck(cfg, text)
+				switch {
+				case errors.Is(err, errNoSpellchecker):
+					say("💡", "Spell check skipped: install hunspell or aspell, or set spellcheck_dictionary in config")
+				case err != nil:
+					return fmt.Errorf("spell check: %w", err)
+				case len(misspelled) > 0 && (strictSpellcheck || cfg.SpellcheckStrict):
+					return invalidInput(fmt.Errorf("possible misspellings: %s (use --no-spellcheck to post anyway)", strings.Join(misspelled, ", ")))
+				case len(misspelled) > 0:
+					say("⚠️", "Possible misspellings: %s", strings.Join(misspelled, ", "))
+				}
+			}
+
+			// Clipboard contents are easy to get wrong, so show them first.
+			if fromClipboard && !assumeYes {
+				for _, segment := range segments {
+					previewTweet(segment)
+				}
+				ok, err := confirm("Post this tweet?")
+				if err != nil {
+					return err
+				}
+				if !ok {
+					say("❎", "Cancelled")
+					return nil
+				}
+			}
+
+			// Handle scheduling
+			if scheduleAt != "" {
+				tweet := scheduledTweet{Text: segments[0], Image: image, AltText: media.AltText, Subtitles: media.Subtitles, Thumbnail: media.Thumbnail, Thread: segments[1:], FollowUp: fu, Profile: profile, Labels: labels, Campaign: campaign, Variants: variants, CheckLinks: checkLinksFlag, tweetExtras: extras}
+				if watermark.Image != "" {
+					tweet.Watermark = &watermark
+				}
+				if caption.Text != "" {
+					tweet.CaptionOverlay = &caption
+				}
+				if firstComment != "" {
+					tweet.Thread = append(tweet.Thread, firstComment)
+				}
+				return handleScheduledTweet(cfg, queue, tweet, scheduleAt, force, copyMedia)
+			}
+
+			// Post immediately
+			ids, err := publishThread(client, cfg, segments, image, media, extras)
+			if err != nil {
+				failed := notificationTweet{Text: segments[len(ids)], HasMedia: image != "" && len(ids) == 0, Labels: labels, Campaign: campaign}
+				emitEvent(client, cfg, tweetFailedEvent(cfg, failed, err.Error()))
+				if notifySlack {
+					notifySlackChannel(client, cfg, notification{
+						Title:  "Tweet failed",
+						Text:   err.Error(),
+						Tweets: []notificationTweet{failed},
+					})
+				}
+				if len(ids) > 0 {
+					return fmt.Errorf("thread stopped after %d of %d tweets: %w", len(ids), len(segments), err)
+				}
+				return err
+			}
+			tweetID := ids[len(ids)-1]
+			if err := tagHistory(ids, labels, campaign); err != nil {
+				say("⚠️", "Failed to tag tweet in history: %v", err)
+			}
+
+			switch {
+			case len(ids) > 1:
+				say("🧵", "Thread of %d tweets posted successfully! (IDs: %s)", len(ids), strings.Join(ids, ", "))
+			case image != "":
+				say("✅", "Tweet with media posted successfully! (ID: %s)", tweetID)
+			default:
+				say("✅", "Tweet posted successfully! (ID: %s)", tweetID)
+			}
+			if notifySlack {
+				notifySlackChannel(client, cfg, notification{
+					Title:  "Tweet posted",
+					Tweets: []notificationTweet{{ID: ids[0], Text: strings.Join(segments, "\n\n"), URL: tweetURL("", ids[0])}},
+				})
+			}
+
+			if firstComment != "" {
+				ids, err := publishReplies(client, cfg, tweetID, []string{firstComment})
+				if err != nil {
+					return fmt.Errorf("tweet %s was posted but the first comment failed: %w", tweetID, err)
+				}
+				if err := tagHistory(ids, labels, campaign); err != nil {
+					say("⚠️", "Failed to tag tweet in history: %v", err)
+				}
+				say("💬", "First comment posted (ID: %s)", ids[0])
+			}
+
+			if fu != nil {
+				reply, err := newFollowUpTweet(ids[0], *fu, time.Now())
+				if err != nil {
+					return err
+				}
+				reply.Profile = profile
+				reply.Labels = labels
+				reply.Campaign = campaign
+				if err := saveScheduledTweet(queue, reply); err != nil {
+					return fmt.Errorf("saving follow-up: %w", err)
+				}
+				say("⏰", "Follow-up reply scheduled for %s (ID: %s)", reply.ScheduleTime.Format("2006-01-02 15:04:05"), reply.ID)
+				say("💡", "Run 'x-cli scheduler daemon' to post it")
 			}
 			return nil
 		},
 	}
 
 	// Add scheduler command
+	var schedulerQueue string
+
 	schedulerCmd := &cobra.Command{
 		Use:   "scheduler",
 		Short: "Manage scheduled tweets",
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return validateQueueName(schedulerQueue)
+		},
 	}
+	schedulerCmd.PersistentFlags().StringVar(&schedulerQueue, "queue", "", "Named queue to operate on (default queue when empty)")
+
+	var listOutput, listColumnSpec, listLabels string
 
 	listCmd := &cobra.Command{
 		Use:   "list",
 		Short: "List all scheduled tweets",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return listScheduledTweets()
+			labels, err := parseLabels(listLabels)
+			if err != nil {
+				return err
+			}
+
+			if listOutput == "" && cmd.Flags().Changed("columns") {
+				listOutput = "table"
+			}
+			if listOutput == "" {
+				return listScheduledTweets(schedulerQueue, labels)
+			}
+
+			tweets, err := loadScheduledTweets(schedulerQueue)
+			if err != nil {
+				return fmt.Errorf("loading scheduled tweets: %w", err)
+			}
+			warnings, err := checkQueueHealth(schedulerQueue, tweets)
+			if err != nil {
+				return err
+			}
+			if err := writeScheduledTweets(filterScheduledByLabels(tweets, labels), listOutput, listColumnSpec); err != nil {
+				return err
+			}
+			// Warnings go to stderr so the listing stays machine-readable.
+			for _, w := range warnings {
+				fmt.Fprintln(os.Stderr, decorate("⚠️", w.Msg))
+			}
+			return nil
 		},
 	}
+	listCmd.Flags().StringVarP(&listOutput, "output", "o", "", "Output format: table, json, yaml or tsv")
+	listCmd.Flags().StringVar(&listColumnSpec, "columns", defaultListColumns, "Comma-separated columns: id, time, status, text, image, thread, reply_to, profile, labels, campaign, last_error")
+	listCmd.Flags().StringVar(&listLabels, "label", "", "Only show tweets carrying all of these comma-separated labels")
+
+	var timingFlags daemonTimingFlags
+	var daemonVerbose bool
+	var healthAddr, logFormat string
 
 	daemonCmd := &cobra.Command{
 		Use:   "daemon",
 		Short: "Run scheduler daemon to post scheduled tweets",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runSchedulerDaemon()
+			switch logFormat {
+			case "text":
+			case "json":
+				configureJSONLogs()
+			default:
+				return invalidInput(fmt.Errorf("invalid --log-format %q (use text or json)", logFormat))
+			}
+			return runSchedulerDaemon(timingFlags, daemonVerbose, healthAddr)
 		},
 	}
+	daemonCmd.Flags().StringVar(&timingFlags.interval, "interval", "", "Time between queue checks (default 30s)")
+	daemonCmd.Flags().StringVar(&timingFlags.retryBackoff, "retry-backoff", "", "Wait before retrying a failed tweet, doubled on each failure (default: the interval)")
+	daemonCmd.Flags().StringVar(&timingFlags.maxRetryBackoff, "max-retry-backoff", "", "Upper bound for the retry wait (default 15m)")
+	daemonCmd.Flags().StringVar(&timingFlags.jitter, "jitter", "", "Wait a random time up to this long before the first check")
+	daemonCmd.Flags().StringVar(&timingFlags.maxLateness, "max-lateness", "", "Warn when the median posting delay exceeds this (default 1m, 0 to disable)")
+	daemonCmd.Flags().BoolVar(&daemonVerbose, "verbose", false, "Print how late each scheduled tweet was posted")
+	daemonCmd.Flags().StringVar(&timingFlags.stopTimeout, "stop-timeout", "", "On SIGTERM, wait at most this long for the current post before exiting (default: until it is done)")
+	daemonCmd.Flags().StringVar(&timingFlags.noticeBefore, "notice-before", "", "Announce each scheduled tweet this long before posting it, e.g. 15m (to Slack, webhooks and, with notice_desktop, the desktop)")
+	daemonCmd.Flags().StringVar(&healthAddr, "health-addr", "", "Serve GET /healthz on this address, e.g. :8080")
+	daemonCmd.Flags().StringVar(&logFormat, "log-format", "text", "Log format: text, or json for one JSON object per line on stdout")
 
 	cancelCmd := &cobra.Command{
 		Use:   "cancel [tweet-id]",
 		Short: "Cancel a scheduled tweet",
 		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return cancelScheduledTweet(args[0])
+			cfg, err := configForQueue(config.LoadConfig(), schedulerQueue)
+			if err != nil {
+				return err
+			}
+			if err := checkAllowed(cfg, actionCancel); err != nil {
+				return err
+			}
+			return cancelScheduledTweet(schedulerQueue, args[0])
+		},
+	}
+
+	var pauseReason string
+
+	pauseCmd := &cobra.Command{
+		Use:   "pause [tweet-id]",
+		Short: "Pause the scheduler, one queue with --queue, or a single scheduled tweet",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var id string
+			if len(args) > 0 {
+				id = args[0]
+			}
+			return pauseScheduler(schedulerQueue, id, pauseReason)
+		},
+	}
+	pauseCmd.Flags().StringVar(&pauseReason, "reason", "", "Note why the scheduler was paused")
+
+	resumeCmd := &cobra.Command{
+		Use:   "resume [tweet-id]",
+		Short: "Resume the scheduler, one queue with --queue, or a single scheduled tweet",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var id string
+			if len(args) > 0 {
+				id = args[0]
+			}
+			return resumeScheduler(schedulerQueue, id)
 		},
 	}
 
-	schedulerCmd.AddCommand(listCmd, daemonCmd, cancelCmd)
-	rootCmd.AddCommand(schedulerCmd)
+	schedulerCmd.AddCommand(listCmd, daemonCmd, cancelCmd, pauseCmd, resumeCmd, newQueuesCmd(), newSchedulerStatusCmd(), newSchedulerSyncCmd(&schedulerQueue), newSchedulerExportK8sCmd(&schedulerQueue), newSchedulerAdoptCmd(&schedulerQueue),
+		newSchedulerPostCmd(&schedulerQueue), newSchedulerPostNextCmd(&schedulerQueue))
+	rootCmd.AddCommand(
+		schedulerCmd,
+		newHistoryCmd(),
+		newTUICmd(),
+		newReleaseCmd(),
+		newHookCmd(),
+		newAnnounceCmd(),
+		newSnapCmd(),
+		newCardCheckCmd(),
+		newCountCmd(),
+		newUpgradeCmd(),
+		newDoctorCmd(),
+		newSpacesCmd(),
+		newStatsCmd(),
+		newCampaignCmd(),
+		newLimitsCmd(),
+		newReadCmd(),
+		newOpenCmd(),
+		newInboxCmd(),
+		newAlertCmd(),
+		newDMCmd(),
+		newSuggestCmd(),
+		newRenderCmd(),
+		newDigestCmd(),
+		newHooksCmd(),
+		newBatchCmd(),
+		newTemplateCmd(),
+		newDraftCmd(),
+		newPlanCmd(),
+	)
 
 	rootCmd.Flags().StringVarP(&text, "text", "t", "", "Tweet text")
 	rootCmd.Flags().StringVarP(&image, "image", "i", "", "Path to image file")
+	rootCmd.Flags().StringVar(&templateName, "template", "", "Fill and post this saved template instead of --text (see 'x-cli template')")
+	rootCmd.Flags().StringArrayVar(&templateVarFlags, "var", nil, "Template variable as name=value (repeatable, needs --template)")
 	rootCmd.Flags().StringVarP(&scheduleAt, "schedule", "s", "", "Schedule tweet (format: '2024-12-25 15:30' or '15:30' for today)")
-	rootCmd.MarkFlagRequired("text")
+	rootCmd.Flags().StringVar(&firstComment, "first-comment", "", "Reply to the new tweet with this text right after posting")
+	rootCmd.Flags().BoolVar(&autoThread, "auto-thread", false, "Split text longer than 280 characters into a numbered thread")
+	rootCmd.Flags().StringVar(&followUpText, "follow-up", "", "Schedule a reply to the new tweet (requires --after)")
+	rootCmd.Flags().StringVar(&followUpAfter, "after", "", "Delay before the follow-up reply, e.g. 30m, 2h or 1d")
+	rootCmd.Flags().StringVar(&queue, "queue", "", "Post or schedule through a named queue and its profile")
+	rootCmd.Flags().StringVar(&profile, "profile", "", "Post from this configured profile instead of the queue's")
+	rootCmd.Flags().StringVar(&tagSpec, "tags", "", "Append the hashtags of these comma-separated hashtag_sets from the config")
+	rootCmd.Flags().StringVar(&labelSpec, "label", "", "Comma-separated labels for grouping and filtering, e.g. launch,q3")
+	rootCmd.Flags().StringArrayVar(&variants, "variant", nil, "Alternative text for an A/B test; the daemon posts one text at random (repeatable, needs --schedule)")
+	rootCmd.Flags().StringVar(&campaign, "campaign", "", "Group the tweet into a campaign for status and bulk cancel")
+	rootCmd.Flags().StringVar(&extras.CardURI, "card-uri", "", "Attach an ads card (card://...) to the tweet")
+	rootCmd.Flags().StringVar(&extras.DirectMessageDeepLink, "dm-deep-link", "", "Add a Send a private message button (https://twitter.com/messages/compose?recipient_id=...)")
+	rootCmd.Flags().BoolVar(&extras.Nullcast, "nullcast", false, "Create a promoted-only tweet that is not shown to followers")
+	rootCmd.Flags().BoolVar(&fromClipboard, "from-clipboard", false, "Read the tweet text from the system clipboard")
+	rootCmd.Flags().BoolVarP(&assumeYes, "yes", "y", false, "Skip the confirmation prompt")
+	rootCmd.Flags().BoolVar(&noShortcodes, "no-shortcodes", false, "Do not expand :shortcode: emoji in the tweet text")
+	rootCmd.Flags().StringVar(&tweetLang, "lang", "", "Language of the tweet, e.g. ja or pt-BR, for spell checking and --subtitles (default: detected)")
+	rootCmd.Flags().StringVar(&altText, "alt-text", "", "Describe the image in --image for screen readers (up to 1000 characters)")
+	rootCmd.Flags().StringVar(&subtitles.Path, "subtitles", "", "Attach captions from this SRT file to the video in --image")
+	rootCmd.Flags().StringVar(&subtitles.Lang, "subtitles-lang", "en", "Language code of the --subtitles captions, e.g. en or pt-BR")
+	rootCmd.Flags().StringVar(&thumbnailSpec, "thumbnail", "", "Poster image of the video in --image: a JPEG or PNG, or frame@00:05 for a frame of the video (needs ffmpeg)")
+	rootCmd.Flags().StringVar(&watermark.Image, "watermark", "", "Stamp this logo (PNG with transparency works best) on the JPEG or PNG in --image")
+	rootCmd.Flags().StringVar(&watermark.Position, "position", defaultWatermarkPosition, "Where --watermark goes: "+strings.Join(watermarkPositions, ", "))
+	rootCmd.Flags().Float64Var(&watermark.Opacity, "opacity", 1, "Opacity of --watermark, from 0 to 1")
+	rootCmd.Flags().StringVar(&caption.Text, "caption-overlay", "", "Draw this text across the JPEG or PNG in --image, e.g. \"SALE ENDS FRIDAY\"")
+	rootCmd.Flags().StringVar(&caption.Font, "overlay-font", "bold", "Weight of the --caption-overlay text: "+strings.Join(captionFonts, ", "))
+	rootCmd.Flags().StringVar(&caption.Color, "overlay-color", "#ffffff", "Color of the --caption-overlay text as #rrggbb")
+	rootCmd.Flags().StringVar(&caption.Position, "overlay-position", "bottom", "Where --caption-overlay goes: "+strings.Join(captionPositions, ", "))
+	rootCmd.Flags().BoolVar(&copyMedia, "copy-media", false, "Copy the scheduled tweet's image, subtitles and thumbnail into scheduled_media/ so moving the originals does not break posting")
+	rootCmd.Flags().BoolVar(&checkLinksFlag, "check-links", false, "Request every link first and refuse to post if one is dead; scheduled tweets are checked again when due")
+	rootCmd.Flags().BoolVar(&noLint, "no-lint", false, "Skip the hashtag, mention and formatting checks")
+	rootCmd.Flags().BoolVar(&noSpellcheck, "no-spellcheck", false, "Skip the spell check")
+	rootCmd.Flags().BoolVar(&strictSpellcheck, "strict-spellcheck", false, "Refuse to post when the spell check flags words")
+	rootCmd.Flags().BoolVar(&noFooter, "no-footer", false, "Do not append the configured footer")
+	rootCmd.Flags().BoolVar(&polish, "polish", false, "Tidy the typography: curly quotes, em dashes for --, single spaces and composed accents (set polish in the config to make it the default)")
+	rootCmd.Flags().BoolVar(&notifySlack, "notify-slack", false, "Report the post to the Slack webhook in the config")
+	rootCmd.Flags().BoolVar(&force, "force", false, "Post even if the text matches banned content, breaks content rules or accessibility checks set to error, or the schedule is too close to another tweet")
+	rootCmd.MarkFlagsOneRequired("text", "from-clipboard", "template")
+	rootCmd.MarkFlagsRequiredTogether("follow-up", "after")
+	rootCmd.MarkFlagsMutuallyExclusive("text", "from-clipboard", "template")
+	rootCmd.MarkFlagsMutuallyExclusive("card-uri", "dm-deep-link")
+	rootCmd.MarkFlagsMutuallyExclusive("card-uri", "image")
+	rootCmd.MarkFlagsMutuallyExclusive("variant", "auto-thread")
+	// Replies to a dark post would be public.
+	rootCmd.MarkFlagsMutuallyExclusive("nullcast", "auto-thread")
+	rootCmd.MarkFlagsMutuallyExclusive("nullcast", "first-comment")
+	rootCmd.MarkFlagsMutuallyExclusive("nullcast", "follow-up")
+
+	rootCmd.PersistentFlags().BoolVar(&noColor, "no-color", false, "Disable colored output (also honors NO_COLOR and TERM=dumb)")
+	rootCmd.PersistentFlags().BoolVar(&noEmoji, "no-emoji", false, "Use plain-text labels instead of emoji in output")
+	rootCmd.PersistentFlags().BoolVar(&offline, "offline", false, "Send API calls to a built-in fake and log them instead of contacting X (or set XCLI_MOCK=1)")
+	rootCmd.PersistentFlags().StringVar(&cassette, "cassette", "", "Record or replay API calls with this HTTP fixture file (development)")
+	rootCmd.PersistentFlags().StringVar(&cassetteMode, "cassette-mode", "replay", "Cassette mode: replay or record")
+	rootCmd.PersistentFlags().BoolVar(&stripEXIFFlag, "strip-exif", true, "Remove EXIF, XMP and text metadata such as GPS position from images before upload")
+	rootCmd.PersistentFlags().StringVar(&uploadRateFlag, "upload-rate", "", "Limit media upload bandwidth, e.g. 500KB/s (0 for unlimited)")
+	rootCmd.PersistentFlags().BoolVar(&envOnly, "config-from-env-only", false, "Ignore config files; read settings from the environment and XCLI_CONFIG only (or set XCLI_CONFIG_FROM_ENV_ONLY=1)")
+	rootCmd.PersistentFlags().BoolVar(&ghaFlag, "gha", false, "GitHub Actions mode: mask secrets, print annotations and write tweet-id and tweet-url to $GITHUB_OUTPUT")
+	cobra.OnInitialize(func() {
+		configureOutput(noColor, noEmoji)
+		if ghaFlag {
+			configureGHA()
+			// The error is printed once below, as an annotation.
+			rootCmd.SilenceErrors = true
+		}
+		envOnlyFromEnv, _ := strconv.ParseBool(os.Getenv("XCLI_CONFIG_FROM_ENV_ONLY"))
+		config.EnvOnly = envOnly || envOnlyFromEnv
+		configureOffline(offline)
+		if err := configureCassette(cassette, cassetteMode); err != nil {
+			log.Print(err)
+			os.Exit(exitCode(err))
+		}
+		if err := configureStorage(config.Peek()); err != nil {
+			log.Print(err)
+			os.Exit(exitCode(err))
+		}
+		stripEXIFFlagSet = rootCmd.PersistentFlags().Changed("strip-exif")
+		if _, err := parseUploadRate(uploadRateFlag); err != nil {
+			log.Print(err)
+			os.Exit(exitCode(err))
+		}
+		refreshUpdateCheck()
+	})
+
+	// Flag parsing errors are usage mistakes; subcommands inherit this.
+	rootCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return invalidInput(err)
+	})
+
+	rootCmd.Version = version
+
+	started := time.Now()
+	ran, err := rootCmd.ExecuteC()
+	recordCommandUsage(ran, started, err)
+	writeGHAOutputs()
+	printUpdateNotice()
+	if err != nil {
+		log.Print(err)
+		os.Exit(exitCode(err))
+	}
+}
+
+// mediaOptions are the extras of a tweet's image or video: alt text for an
+// image, subtitles and a thumbnail for a video.
+type mediaOptions struct {
+	AltText   string
+	Subtitles *subtitleTrack
+	Thumbnail *videoThumbnail
+}
+
+// publishTweet uploads the optional image (with its media options), posts
+// the tweet and records it in the local history, returning the new tweet
+// ID.
+func publishTweet(client *http.Client, cfg config.Config, text, image string, media mediaOptions, extras tweetExtras) (string, error) {
+	if err := checkAllowed(cfg, actionPost); err != nil {
+		return "", err
+	}
+	var mediaIDs []string
+	if image != "" {
+		id, err := uploadMedia(client, cfg, image)
+		if err != nil {
+			return "", err
+		}
+		if media.AltText != "" {
+			if err := setAltText(client, cfg, id, media.AltText); err != nil {
+				return "", err
+			}
+		}
+		if media.Subtitles != nil {
+			if err := attachSubtitles(client, cfg, id, *media.Subtitles); err != nil {
+				return "", err
+			}
+		}
+		if media.Thumbnail != nil {
+			// X picks a frame itself when this fails, so the tweet still goes out.
+			if err := setVideoThumbnail(client, cfg, image, id, *media.Thumbnail); err != nil {
+				log.Print(decorate("⚠️", fmt.Sprintf("Failed to set the video thumbnail: %v", err)))
+			}
+		}
+		mediaIDs = append(mediaIDs, id)
+	}
+
+	tweetID, err := postTweet(client, cfg, text, mediaIDs, extras)
+	if err != nil {
+		return "", err
+	}
+	noteGHATweet(tweetID)
 
-	if err := rootCmd.Execute(); err != nil {
-		log.Fatal(err)
+	if err := recordHistory(historyEntry{TweetID: tweetID, Text: text, Image: image, PostedAt: time.Now()}); err != nil {
+		log.Print(decorate("⚠️", fmt.Sprintf("Failed to record tweet in history: %v", err)))
 	}
+	now := time.Now().UTC()
+	emitEvent(client, cfg, tweetPostedEvent(cfg, notificationTweet{ID: tweetID, Text: text, URL: tweetURL("", tweetID), CreatedAt: &now, HasMedia: image != ""}))
+	if err := mirrorToDiscord(client, cfg, tweetID, text, image); err != nil {
+		log.Print(decorate("⚠️", fmt.Sprintf("Failed to mirror tweet to Discord: %v", err)))
+	}
+
+	return tweetID, nil
 }
 
-func postTweet(client *http.Client, cfg config.Config, text string, mediaIDs []string) error {
-	payload := tweetPayload{Text: text}
+func postTweet(client *http.Client, cfg config.Config, text string, mediaIDs []string, extras tweetExtras) (string, error) {
+	payload := tweetPayload{Text: text, tweetExtras: extras}
 	if len(mediaIDs) > 0 {
 		payload.Media = &tweetMediaBlock{MediaIDs: mediaIDs}
 	}
 
+	return postTweetPayload(client, cfg, payload)
+}
+
+func postTweetPayload(client *http.Client, cfg config.Config, payload tweetPayload) (string, error) {
 	body, err := json.Marshal(payload)
 	if err != nil {
-		return fmt.Errorf("encoding tweet payload: %w", err)
+		return "", fmt.Errorf("encoding tweet payload: %w", err)
 	}
 
-	req, err := http.NewRequest(http.MethodPost, tweetEndpoint, bytes.NewReader(body))
+	status, respBody, err := sendSigned(client, cfg, http.MethodPost, tweetEndpoint, body, "application/json")
 	if err != nil {
-		return fmt.Errorf("creating tweet request: %w", err)
+		return "", fmt.Errorf("posting tweet: %w", err)
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	header, err := buildOAuth1Header(http.MethodPost, tweetEndpoint, nil, cfg)
-	if err != nil {
-		return err
+	if status >= 300 {
+		return "", newAPIError(status, respBody)
 	}
-	req.Header.Set("Authorization", header)
 
-	resp, err := client.Do(req)
-	if err != nil {
-		return fmt.Errorf("posting tweet: %w", err)
+	var created struct {
+		Data struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(respBody, &created); err != nil {
+		return "", fmt.Errorf("decoding tweet response: %w", err)
 	}
-	defer resp.Body.Close()
 
-	respBody, err := io.ReadAll(resp.Body)
+	return created.Data.ID, nil
+}
+
+// deleteTweet deletes a posted tweet of cfg's account.
+func deleteTweet(client *http.Client, cfg config.Config, tweetID string) error {
+	if err := checkAllowed(cfg, actionDelete); err != nil {
+		return err
+	}
+	status, respBody, err := sendSigned(client, cfg, http.MethodDelete, tweetEndpoint+"/"+tweetID, nil, "")
 	if err != nil {
-		return fmt.Errorf("reading tweet response: %w", err)
+		return fmt.Errorf("deleting tweet: %w", err)
 	}
-
-	if resp.StatusCode >= 300 {
-		return fmt.Errorf("twitter API error (%d): %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	if status >= 300 {
+		return newAPIError(status, respBody)
 	}
 
+	var deleted struct {
+		Data struct {
+			Deleted bool `json:"deleted"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(respBody, &deleted); err != nil {
+		return fmt.Errorf("decoding delete response: %w", err)
+	}
+	if !deleted.Data.Deleted {
+		return fmt.Errorf("tweet %s was not deleted", tweetID)
+	}
 	return nil
 }
 
@@ -188,22 +997,68 @@ func uploadMedia(client *http.Client, cfg config.Config, path string) (string, e
 
 	mimeType := detectMime(path, data)
 
-	params := map[string]string{
-		"media_data": base64.StdEncoding.EncodeToString(data),
+	hooked, cleanup, err := runMediaHook(cfg, path, mimeType, data)
+	if err != nil {
+		return "", err
+	}
+	defer cleanup()
+	if hooked != path {
+		if data, err = os.ReadFile(hooked); err != nil {
+			return "", fmt.Errorf("reading media: %w", err)
+		}
+		mimeType = detectMime(hooked, data)
+	}
+
+	if strings.HasPrefix(mimeType, "video/") {
+		if err := checkVideo(hooked); err != nil {
+			return "", err
+		}
+	}
+
+	if data, err = applyImageOverlays(cfg, mimeType, data); err != nil {
+		return "", err
+	}
+
+	if shouldStripEXIF(cfg) {
+		stripped, err := stripImageMetadata(data, mimeType)
+		if err != nil {
+			return "", fmt.Errorf("removing metadata from %s: %w (use --strip-exif=false to upload it as is)", path, err)
+		}
+		data = stripped
 	}
 
+	fields := map[string]string{}
 	if strings.HasPrefix(mimeType, "image/") {
-		params["media_category"] = "tweet_image"
+		fields["media_category"] = "tweet_image"
 	}
 
-	body, err := signedPost(client, cfg, mediaUploadEndpoint, params)
+	// The same banner attached to many scheduled tweets is uploaded once
+	// and its media ID reused until X expires it.
+	cacheKey := mediaCacheKey(cfg, fields["media_category"], data)
+	if id, ok := cachedMediaID(cacheKey); ok {
+		say("♻️", "Reusing media %s uploaded earlier for %s", id, filepath.Base(path))
+		return id, nil
+	}
+
+	rate, err := uploadRate(cfg)
+	if err != nil {
+		return "", err
+	}
+	if rate > 0 {
+		client = throttledClient(client, rate, int64(len(data)))
+	}
+
+	// The raw bytes go in a multipart "media" part: a third smaller than the
+	// base64 media_data form field.
+	body, err := signedMultipartPost(client, cfg, mediaUploadEndpoint, fields, "media", filepath.Base(path), data)
 	if err != nil {
 		return "", fmt.Errorf("uploading media: %w", err)
 	}
 
 	var resp struct {
-		MediaIDString string `json:"media_id_string"`
-		Error         struct {
+		MediaIDString    string `json:"media_id_string"`
+		ExpiresAfterSecs int64  `json:"expires_after_secs"`
+		Error            struct {
 			Message string `json:"message"`
 		} `json:"error"`
 		Errors []struct {
@@ -226,146 +1081,134 @@ func uploadMedia(client *http.Client, cfg config.Config, path string) (string, e
 		}
 	}
 
-	return resp.MediaIDString, nil
-}
-
-func signedPost(client *http.Client, cfg config.Config, endpoint string, params map[string]string) ([]byte, error) {
-	body := encodeParams(params)
-
-	req, err := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(body))
-	if err != nil {
-		return nil, fmt.Errorf("creating request: %w", err)
+	if err := cacheMediaID(cacheKey, resp.MediaIDString, time.Duration(resp.ExpiresAfterSecs)*time.Second); err != nil {
+		log.Print(decorate("⚠️", fmt.Sprintf("Failed to cache media ID: %v", err)))
 	}
 
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-
-	header, err := buildOAuth1Header(http.MethodPost, endpoint, params, cfg)
-	if err != nil {
-		return nil, err
-	}
-	req.Header.Set("Authorization", header)
+	return resp.MediaIDString, nil
+}
 
-	resp, err := client.Do(req)
+func signedGet(client *http.Client, cfg config.Config, endpoint string) ([]byte, error) {
+	status, responseBody, err := sendSigned(client, cfg, http.MethodGet, endpoint, nil, "")
 	if err != nil {
 		return nil, fmt.Errorf("performing request: %w", err)
 	}
-	defer resp.Body.Close()
-
-	responseBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("reading response: %w", err)
-	}
 
-	if resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("twitter API error (%d): %s", resp.StatusCode, strings.TrimSpace(string(responseBody)))
+	if status >= 300 {
+		return nil, newAPIError(status, responseBody)
 	}
 
 	return responseBody, nil
 }
 
-func buildOAuth1Header(method, rawURL string, params map[string]string, cfg config.Config) (string, error) {
-	nonce, err := generateNonce()
-	if err != nil {
-		return "", err
-	}
-
-	timestamp := fmt.Sprintf("%d", time.Now().Unix())
+// signedMultipartPost sends fields and one file part as multipart/form-data.
+// Unlike urlencoded forms, multipart fields are not part of the OAuth
+// signature.
+func signedMultipartPost(client *http.Client, cfg config.Config, endpoint string, fields map[string]string, fileField, fileName string, data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
 
-	oauthParams := map[string]string{
-		"oauth_consumer_key":     cfg.APIKey,
-		"oauth_nonce":            nonce,
-		"oauth_signature_method": "HMAC-SHA1",
-		"oauth_timestamp":        timestamp,
-		"oauth_token":            cfg.AccessToken,
-		"oauth_version":          "1.0",
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
 	}
-
-	baseURL, queryParams, err := normalizeURL(rawURL)
-	if err != nil {
-		return "", err
+	sort.Strings(names)
+	for _, name := range names {
+		if err := writer.WriteField(name, fields[name]); err != nil {
+			return nil, fmt.Errorf("encoding form: %w", err)
+		}
 	}
 
-	signingValues := url.Values{}
-	for k, v := range params {
-		signingValues.Add(k, v)
+	part, err := writer.CreateFormFile(fileField, fileName)
+	if err != nil {
+		return nil, fmt.Errorf("encoding form: %w", err)
 	}
-	for k, v := range oauthParams {
-		signingValues.Add(k, v)
+	if _, err := part.Write(data); err != nil {
+		return nil, fmt.Errorf("encoding form: %w", err)
 	}
-	for k, vs := range queryParams {
-		for _, v := range vs {
-			signingValues.Add(k, v)
-		}
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("encoding form: %w", err)
 	}
 
-	parameterString := encodeValues(signingValues)
-	baseString := strings.ToUpper(method) + "&" + percentEncode(baseURL) + "&" + percentEncode(parameterString)
-	signingKey := percentEncode(cfg.APISecret) + "&" + percentEncode(cfg.AccessSecret)
-
-	mac := hmac.New(sha1.New, []byte(signingKey))
-	mac.Write([]byte(baseString))
-	signature := base64.StdEncoding.EncodeToString(mac.Sum(nil))
-
-	oauthParams["oauth_signature"] = signature
-
-	headerValues := make([]string, 0, len(oauthParams))
-	keys := make([]string, 0, len(oauthParams))
-	for k := range oauthParams {
-		keys = append(keys, k)
+	status, responseBody, err := sendSigned(client, cfg, http.MethodPost, endpoint, buf.Bytes(), writer.FormDataContentType())
+	if err != nil {
+		return nil, fmt.Errorf("performing request: %w", err)
 	}
-	sort.Strings(keys)
-	for _, k := range keys {
-		headerValues = append(headerValues, fmt.Sprintf("%s=\"%s\"", percentEncode(k), percentEncode(oauthParams[k])))
+
+	if status >= 300 {
+		return nil, newAPIError(status, responseBody)
 	}
 
-	return "OAuth " + strings.Join(headerValues, ", "), nil
+	return responseBody, nil
 }
 
-func encodeParams(params map[string]string) string {
-	if len(params) == 0 {
-		return ""
-	}
-
-	values := url.Values{}
-	for k, v := range params {
-		values.Add(k, v)
+// sendSigned signs and sends a request, returning the status and body. Write
+// calls are recorded in the audit log and reported rate limits in
+// rate_limits.json. When X rejects the OAuth timestamp,
+// the clock offset is learned from the response and the request is re-signed
+// once.
+func sendSigned(client *http.Client, cfg config.Config, method, endpoint string, body []byte, contentType string) (int, []byte, error) {
+	if method != http.MethodGet {
+		if err := checkWritable(cfg); err != nil {
+			return 0, nil, err
+		}
 	}
 
-	return values.Encode()
-}
+	for attempt := 0; ; attempt++ {
+		var reader io.Reader
+		if body != nil {
+			reader = bytes.NewReader(body)
+		}
 
-func encodeValues(values url.Values) string {
-	if len(values) == 0 {
-		return ""
-	}
+		req, err := http.NewRequest(method, endpoint, reader)
+		if err != nil {
+			return 0, nil, fmt.Errorf("creating request: %w", err)
+		}
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
 
-	keys := make([]string, 0, len(values))
-	for k := range values {
-		keys = append(keys, k)
-	}
-	sort.Strings(keys)
+		if err := signRequest(req, cfg); err != nil {
+			return 0, nil, err
+		}
 
-	var builder strings.Builder
-	first := true
-	for _, k := range keys {
-		vs := values[k]
-		sort.Strings(vs)
-		for _, v := range vs {
-			if !first {
-				builder.WriteByte('&')
+		resp, err := client.Do(req)
+		if err != nil {
+			if method != http.MethodGet {
+				recordAudit(cfg, method, endpoint, body, 0, err)
 			}
-			first = false
-			builder.WriteString(percentEncode(k))
-			builder.WriteByte('=')
-			builder.WriteString(percentEncode(v))
+			return 0, nil, err
+		}
+
+		responseBody, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		recordRateLimit(cfg, method, endpoint, resp.Header)
+		if method != http.MethodGet {
+			recordAudit(cfg, method, endpoint, body, resp.StatusCode, nil)
+		}
+		if err != nil {
+			return 0, nil, fmt.Errorf("reading response: %w", err)
+		}
+
+		if attempt == 0 && isTimestampRejected(resp.StatusCode, responseBody) && adjustClock(resp.Header.Get("Date")) {
+			log.Print(decorate("⚠️", fmt.Sprintf("Local clock differs from X by %s; retrying with a corrected timestamp", time.Duration(clockOffset.Load()))))
+			continue
 		}
-	}
 
-	return builder.String()
+		return resp.StatusCode, responseBody, nil
+	}
 }
 
-func percentEncode(s string) string {
-	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+// signRequest signs req with the credentials in cfg, using the clock
+// corrected for any offset learned from X.
+func signRequest(req *http.Request, cfg config.Config) error {
+	signer := oauth1.Signer{Now: oauthNow}
+	return signer.SignRequest(req, oauth1.Credentials{
+		ConsumerKey:    cfg.APIKey,
+		ConsumerSecret: cfg.APISecret,
+		Token:          cfg.AccessToken,
+		TokenSecret:    cfg.AccessSecret,
+	})
 }
 
 func generateNonce() (string, error) {
@@ -376,24 +1219,6 @@ func generateNonce() (string, error) {
 	return hex.EncodeToString(buf), nil
 }
 
-func normalizeURL(raw string) (string, url.Values, error) {
-	parsed, err := url.Parse(raw)
-	if err != nil {
-		return "", nil, fmt.Errorf("parsing URL: %w", err)
-	}
-
-	scheme := strings.ToLower(parsed.Scheme)
-	host := strings.ToLower(parsed.Host)
-	path := parsed.EscapedPath()
-	if path == "" {
-		path = "/"
-	}
-
-	base := scheme + "://" + host + path
-
-	return base, parsed.Query(), nil
-}
-
 func detectMime(path string, data []byte) string {
 	if ext := filepath.Ext(path); ext != "" {
 		if typ := mime.TypeByExtension(ext); typ != "" {
@@ -403,35 +1228,91 @@ func detectMime(path string, data []byte) string {
 
 	return http.DetectContentType(data)
 }
-func handleScheduledTweet(text, image, scheduleAt string) error {
+
+// handleScheduledTweet validates scheduleAt and adds tweet to queue for that
+// time, assigning it a new ID.
+func handleScheduledTweet(cfg config.Config, queue string, tweet scheduledTweet, scheduleAt string, force, copyMedia bool) error {
+	tweet, err := scheduleTweet(cfg, queue, tweet, scheduleAt, force, copyMedia)
+	if err != nil {
+		return err
+	}
+
+	noteGHAScheduled(tweet)
+	say("✅", "Tweet scheduled for %s (ID: %s)", tweet.ScheduleTime.Format("2006-01-02 15:04:05"), tweet.ID)
+	if !daemonAlive(0, time.Now()) {
+		say("💡", "Run 'x-cli scheduler daemon' to start the scheduler")
+	}
+	return nil
+}
+
+// scheduleTweet is handleScheduledTweet without the messages; it returns
+// the tweet as saved.
+func scheduleTweet(cfg config.Config, queue string, tweet scheduledTweet, scheduleAt string, force, copyMedia bool) (scheduledTweet, error) {
+	if err := checkAllowed(cfg, actionSchedule); err != nil {
+		return tweet, err
+	}
 	scheduleTime, err := parseScheduleTime(scheduleAt)
 	if err != nil {
-		return fmt.Errorf("invalid schedule time: %w", err)
+		return tweet, invalidInput(fmt.Errorf("invalid schedule time: %w", err))
 	}
 
 	if scheduleTime.Before(time.Now()) {
-		return errors.New("schedule time must be in the future")
+		return tweet, invalidInput(errors.New("schedule time must be in the future"))
 	}
 
-	tweet := scheduledTweet{
-		Text:         text,
-		Image:        image,
-		ScheduleTime: scheduleTime,
-		ID:           generateTweetID(),
+	tweet.ScheduleTime = scheduleTime
+	tweet.ID = generateTweetID()
+
+	if tweet.Image != "" {
+		if err := validateMedia(tweet.Image); err != nil {
+			return tweet, err
+		}
 	}
 
-	if err := saveScheduledTweet(tweet); err != nil {
-		return fmt.Errorf("saving scheduled tweet: %w", err)
+	if !force {
+		if err := checkScheduleConflicts(cfg, queue, tweet); err != nil {
+			return tweet, fmt.Errorf("%w (use --force to schedule anyway)", err)
+		}
 	}
 
-	fmt.Printf("✅ Tweet scheduled for %s (ID: %s)\n", scheduleTime.Format("2006-01-02 15:04:05"), tweet.ID)
-	fmt.Println("💡 Run 'x-cli scheduler daemon' to start the scheduler")
-	return nil
+	if err := pinMentions(apiClient(), cfg, &tweet); err != nil {
+		say("⚠️", "Could not resolve mentions; they will not be checked before posting: %v", err)
+	}
+
+	if copyMedia && tweet.Image != "" {
+		dest, err := copyScheduledMedia(tweet.Image, tweet.ID)
+		if err != nil {
+			return tweet, err
+		}
+		tweet.Image = dest
+		if tweet.Subtitles != nil {
+			dest, err := copyScheduledMedia(tweet.Subtitles.Path, tweet.ID)
+			if err != nil {
+				removeScheduledMedia(tweet.Image)
+				return tweet, err
+			}
+			tweet.Subtitles = &subtitleTrack{Path: dest, Lang: tweet.Subtitles.Lang}
+		}
+		if tweet.Thumbnail != nil && tweet.Thumbnail.Image != "" {
+			dest, err := copyScheduledMedia(tweet.Thumbnail.Image, tweet.ID)
+			if err != nil {
+				removeScheduledTweetMedia(tweet)
+				return tweet, err
+			}
+			tweet.Thumbnail = &videoThumbnail{Image: dest}
+		}
+	}
+
+	if err := saveScheduledTweet(queue, tweet); err != nil {
+		removeScheduledTweetMedia(tweet)
+		return tweet, fmt.Errorf("saving scheduled tweet: %w", err)
+	}
+	return tweet, nil
 }
 
 func parseScheduleTime(scheduleAt string) (time.Time, error) {
 	now := time.Now()
-	
+
 	// Try different time formats
 	formats := []string{
 		"2006-01-02 15:04:05",
@@ -461,24 +1342,56 @@ func generateTweetID() string {
 	return fmt.Sprintf("tweet_%d", time.Now().UnixNano())
 }
 
-func saveScheduledTweet(tweet scheduledTweet) error {
-	tweets, err := loadScheduledTweets()
-	if err != nil {
-		tweets = []scheduledTweet{}
+// saveScheduledTweet adds tweet to queue: through the daemon running in
+// this directory, which picks it up at once, or in the store directly.
+func saveScheduledTweet(queue string, tweet scheduledTweet) error {
+	_, err := sendControl(controlRequest{Op: controlEnqueue, Queue: queue, Tweet: &tweet})
+	if !errors.Is(err, errNoDaemon) {
+		return err
 	}
+	return storeScheduledTweet(queue, tweet)
+}
+
+func storeScheduledTweet(queue string, tweet scheduledTweet) error {
+	return updateScheduledTweets(queue, func(tweets []scheduledTweet) ([]scheduledTweet, error) {
+		return append(tweets, tweet), nil
+	})
+}
 
-	tweets = append(tweets, tweet)
-	return saveScheduledTweets(tweets)
+// updateScheduledTweets applies fn to the queue's tweets while holding the
+// store lock, so concurrent invocations and the daemon never lose entries.
+// With a storage bucket, fn runs again if another machine changed the queue
+// in the meantime.
+func updateScheduledTweets(queue string, fn func([]scheduledTweet) ([]scheduledTweet, error)) error {
+	return updateStore(scheduleFile(queue), func(data []byte) ([]byte, error) {
+		tweets, err := decodeScheduledTweets(data)
+		if err != nil {
+			return nil, err
+		}
+
+		tweets, err = fn(tweets)
+		if err != nil {
+			return nil, err
+		}
+		return encodeScheduledTweets(tweets)
+	})
 }
 
-func loadScheduledTweets() ([]scheduledTweet, error) {
-	data, err := os.ReadFile("scheduled_tweets.json")
+func loadScheduledTweets(queue string) ([]scheduledTweet, error) {
+	data, err := readStore(scheduleFile(queue))
 	if err != nil {
 		if os.IsNotExist(err) {
 			return []scheduledTweet{}, nil
 		}
 		return nil, err
 	}
+	return decodeScheduledTweets(data)
+}
+
+func decodeScheduledTweets(data []byte) ([]scheduledTweet, error) {
+	if data == nil {
+		return []scheduledTweet{}, nil
+	}
 
 	var tweets []scheduledTweet
 	if err := json.Unmarshal(data, &tweets); err != nil {
@@ -488,130 +1401,737 @@ func loadScheduledTweets() ([]scheduledTweet, error) {
 	return tweets, nil
 }
 
-func saveScheduledTweets(tweets []scheduledTweet) error {
-	data, err := json.MarshalIndent(tweets, "", "  ")
+func encodeScheduledTweets(tweets []scheduledTweet) ([]byte, error) {
+	assignIdempotencyKeys(tweets)
+	return json.MarshalIndent(tweets, "", "  ")
+}
+
+func listScheduledTweets(queue string, labels []string) error {
+	all, err := loadScheduledTweets(queue)
+	if err != nil {
+		return fmt.Errorf("loading scheduled tweets: %w", err)
+	}
+	warnings, err := checkQueueHealth(queue, all)
 	if err != nil {
 		return err
 	}
+	tweets := filterScheduledByLabels(all, labels)
 
-	return os.WriteFile("scheduled_tweets.json", data, 0644)
-}
-
-func listScheduledTweets() error {
-	tweets, err := loadScheduledTweets()
+	state, err := loadSchedulerState()
 	if err != nil {
-		return fmt.Errorf("loading scheduled tweets: %w", err)
+		return fmt.Errorf("loading scheduler state: %w", err)
+	}
+	if msg := state.pauseMessage(queue); msg != "" {
+		say("⏸️", "%s", msg)
 	}
 
 	if len(tweets) == 0 {
-		fmt.Println("📭 No scheduled tweets found")
+		say("📭", "No scheduled tweets found in the %s queue", queueLabel(queue))
+		printQueueWarnings(warnings)
 		return nil
 	}
 
-	fmt.Printf("📅 Found %d scheduled tweet(s):\n\n", len(tweets))
+	say("📅", "Found %d scheduled tweet(s) in the %s queue:\n", len(tweets), queueLabel(queue))
 	for _, tweet := range tweets {
-		status := "⏰ Pending"
+		status := decorate("⏰", "Pending")
 		if tweet.ScheduleTime.Before(time.Now()) {
-			status = "⚠️ Overdue"
+			status = decorate("⚠️", "Overdue")
+		}
+		if tweet.Paused {
+			status = decorate("⏸️", "Paused")
 		}
 
 		fmt.Printf("ID: %s\n", tweet.ID)
 		fmt.Printf("Text: %s\n", tweet.Text)
+		for i, v := range tweet.Variants {
+			fmt.Printf("Variant %s: %s\n", strings.ToUpper(variantLetter(i+1)), v)
+		}
+		if tweet.Variant != "" {
+			fmt.Printf("Chosen variant: %s\n", strings.ToUpper(tweet.Variant))
+		}
 		if tweet.Image != "" {
 			fmt.Printf("Image: %s\n", tweet.Image)
 		}
+		if tweet.AltText != "" {
+			fmt.Printf("Alt text: %s\n", tweet.AltText)
+		}
+		if tweet.Subtitles != nil {
+			fmt.Printf("Subtitles: %s (%s)\n", tweet.Subtitles.Path, tweet.Subtitles.Lang)
+		}
+		if tweet.Thumbnail != nil {
+			fmt.Printf("Thumbnail: %s\n", tweet.Thumbnail)
+		}
+		if len(tweet.Thread) > 0 {
+			fmt.Printf("Thread: %d follow-up tweet(s)\n", len(tweet.Thread))
+		}
+		if tweet.ReplyTo != "" {
+			fmt.Printf("Reply to: %s\n", tweet.ReplyTo)
+		}
+		if tweet.FollowUp != nil {
+			fmt.Printf("Follow-up: %q after %s\n", tweet.FollowUp.Text, tweet.FollowUp.Delay)
+		}
+		if tweet.Profile != "" {
+			fmt.Printf("Profile: %s\n", tweet.Profile)
+		}
+		if len(tweet.Labels) > 0 {
+			fmt.Printf("Labels: %s\n", strings.Join(tweet.Labels, ", "))
+		}
+		if tweet.Campaign != "" {
+			fmt.Printf("Campaign: %s\n", tweet.Campaign)
+		}
+		if tweet.LastError != "" {
+			fmt.Printf("Last error: %s\n", tweet.LastError)
+		}
 		fmt.Printf("Scheduled: %s\n", tweet.ScheduleTime.Format("2006-01-02 15:04:05"))
 		fmt.Printf("Status: %s\n", status)
 		fmt.Println("---")
 	}
 
+	printQueueWarnings(warnings)
 	return nil
 }
 
-func cancelScheduledTweet(tweetID string) error {
-	tweets, err := loadScheduledTweets()
+// cancelScheduledTweet removes a tweet from queue, through the running
+// daemon when there is one.
+func cancelScheduledTweet(queue, tweetID string) error {
+	_, err := sendControl(controlRequest{Op: controlCancel, Queue: queue, ID: tweetID})
+	if errors.Is(err, errNoDaemon) {
+		_, err = removeScheduledTweet(queue, tweetID)
+	}
 	if err != nil {
-		return fmt.Errorf("loading scheduled tweets: %w", err)
+		return err
 	}
 
-	var updatedTweets []scheduledTweet
-	found := false
+	say("✅", "Cancelled scheduled tweet: %s", tweetID)
+	return nil
+}
 
-	for _, tweet := range tweets {
-		if tweet.ID != tweetID {
-			updatedTweets = append(updatedTweets, tweet)
-		} else {
-			found = true
+// removeScheduledTweet deletes a tweet and its copied media from the store.
+func removeScheduledTweet(queue, tweetID string) (scheduledTweet, error) {
+	var cancelled scheduledTweet
+	err := updateScheduledTweets(queue, func(tweets []scheduledTweet) ([]scheduledTweet, error) {
+		var updatedTweets []scheduledTweet
+		found := false
+
+		for _, tweet := range tweets {
+			if tweet.ID != tweetID {
+				updatedTweets = append(updatedTweets, tweet)
+			} else {
+				found = true
+				cancelled = tweet
+			}
+		}
+
+		if !found {
+			return nil, fmt.Errorf("tweet with ID %s not found in the %s queue", tweetID, queueLabel(queue))
+		}
+		return updatedTweets, nil
+	})
+	if err != nil {
+		return cancelled, fmt.Errorf("cancelling scheduled tweet: %w", err)
+	}
+	removeScheduledTweetMedia(cancelled)
+	return cancelled, nil
+}
+
+func runSchedulerDaemon(flags daemonTimingFlags, verbose bool, healthAddr string) error {
+	cfg := config.LoadConfig()
+
+	timing, err := resolveDaemonTiming(cfg.Daemon, flags)
+	if err != nil {
+		return err
+	}
+	if err := validateWebhooks(cfg.Webhooks); err != nil {
+		return err
+	}
+
+	unlock, err := acquireDaemonLock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	say("🚀", "Starting tweet scheduler daemon...")
+	if jsonLog == nil {
+		fmt.Println("Press Ctrl+C to stop")
+	}
+
+	// Every queue must be able to sign its requests before we start.
+	for _, queue := range knownQueues(cfg) {
+		qcfg, err := configForQueue(cfg, queue)
+		if err != nil {
+			return err
+		}
+		if err := qcfg.Validate(); err != nil {
+			return fmt.Errorf("queue %s: %w", queueLabel(queue), err)
+		}
+		if err := checkWritable(qcfg); err != nil {
+			return fmt.Errorf("queue %s: %w", queueLabel(queue), err)
+		}
+		if _, err := queueQuietHours(cfg, queue); err != nil {
+			return err
+		}
+	}
+
+	if _, err := uploadRate(cfg); err != nil {
+		return err
+	}
+	queueChecks, err := resolveQueueHealth(cfg.QueueHealth)
+	if err != nil {
+		return err
+	}
+
+	if err := recoverFromJournal(); err != nil {
+		return fmt.Errorf("recovering from %s: %w", schedulerJournalFile, err)
+	}
+
+	client := apiClient()
+	status := daemonStatus{PID: os.Getpid(), StartedAt: time.Now(), Interval: timing.Interval}
+	retries := newRetryBackoff(timing)
+	session := newDaemonSession(verbose, timing.MaxMedianLateness, timing.StopTimeout)
+	notice := postingNotice{Before: timing.NoticeBefore, Desktop: cfg.Daemon.NoticeDesktop}
+
+	var health *healthServer
+	if healthAddr != "" {
+		if health, err = startHealthServer(healthAddr, status); err != nil {
+			return fmt.Errorf("starting health endpoint: %w", err)
+		}
+		defer health.Close()
+		go func() {
+			<-session.stopping
+			health.stop()
+		}()
+		say("🩺", "Serving /healthz on %s", healthAddr)
+	}
+
+	if delay := timing.startupDelay(); delay > 0 {
+		say("⏳", "Waiting %s before the first check", delay.Round(time.Second))
+		select {
+		case <-time.After(delay):
+		case <-session.stopping:
 		}
 	}
+	// Scheduling, cancelling or pausing wakes the daemon right away instead
+	// of at the next interval.
+	wake := make(chan struct{}, 1)
+	changed, stopWatch, err := watchScheduleFiles()
+	if err != nil {
+		log.Printf("Error watching schedule files, checking every %s instead: %v", timing.Interval, err)
+	} else {
+		defer stopWatch()
+		go func() {
+			for range changed {
+				notify(wake)
+			}
+		}()
+	}
 
-	if !found {
-		return fmt.Errorf("tweet with ID %s not found", tweetID)
+	// Commands run in this directory hand their changes to the daemon
+	// through the control socket instead of racing it for the files.
+	control, err := startControlServer(cfg, wake)
+	if err != nil {
+		log.Printf("Error starting control socket, commands will edit the schedule files directly: %v", err)
+	} else {
+		defer control.Close()
 	}
 
-	if err := saveScheduledTweets(updatedTweets); err != nil {
-		return fmt.Errorf("saving updated tweets: %w", err)
+	wasPaused := false
+	pausedQueues := map[string]bool{}
+	line := newStatusLine()
+
+	for !session.stopped() {
+		status.LastCheck = time.Now()
+		if err := writeDaemonStatus(status); err != nil {
+			log.Printf("Error writing daemon status: %v", err)
+		}
+		if control != nil {
+			control.update(status, session)
+		}
+
+		state, err := loadSchedulerState()
+		if err != nil {
+			log.Printf("Error loading scheduler state: %v", err)
+		}
+		if health != nil {
+			health.update(status, state.Paused)
+		}
+		if state.Paused {
+			if !wasPaused {
+				say("⏸️", "Scheduler is paused; waiting for 'x-cli scheduler resume'")
+			}
+			wasPaused = true
+			tweets, _ := loadAllScheduledTweets(cfg)
+			line.wait(timing.Interval, tweets, true, wake, session.stopping)
+			continue
+		}
+		if wasPaused {
+			say("▶️", "Scheduler resumed")
+			wasPaused = false
+		}
+
+		var pending []scheduledTweet
+		for _, queue := range knownQueues(cfg) {
+			if _, ok := state.PausedQueues[queue]; ok {
+				if !pausedQueues[queue] {
+					say("⏸️", "The %s queue is paused; waiting for 'x-cli scheduler resume --queue %s'", queueLabel(queue), queue)
+					pausedQueues[queue] = true
+				}
+				continue
+			}
+			if pausedQueues[queue] {
+				say("▶️", "The %s queue resumed", queueLabel(queue))
+				delete(pausedQueues, queue)
+			}
+			queued := processQueue(client, cfg, queue, notice, line, retries, session)
+			session.reportQueueHealth(client, cfg, queue, queueChecks.check(queue, queued, time.Now()))
+			pending = append(pending, queued...)
+		}
+		if session.stopped() {
+			break
+		}
+		runDueReports(client, cfg, time.Now())
+		runDueAlerts(client, cfg, time.Now())
+		runDueDigest(cfg, time.Now())
+
+		line.wait(nextCheckDelay(timing.Interval, pending, notice, time.Now()), pending, false, wake, session.stopping)
 	}
 
-	fmt.Printf("✅ Cancelled scheduled tweet: %s\n", tweetID)
+	if err := compactJournal(); err != nil {
+		log.Printf("Error compacting %s: %v", schedulerJournalFile, err)
+	}
+	stoppedAt := time.Now()
+	status.StoppedAt = &stoppedAt
+	if err := writeDaemonStatus(status); err != nil {
+		log.Printf("Error writing daemon status: %v", err)
+	}
+	session.printSummary()
 	return nil
 }
 
-func runSchedulerDaemon() error {
-	fmt.Println("🚀 Starting tweet scheduler daemon...")
-	fmt.Println("Press Ctrl+C to stop")
+// processQueue posts the due tweets of one queue, each signed with its own
+// profile or the queue's, announces those about to be posted, and returns
+// the tweets still waiting.
+func processQueue(client *http.Client, cfg config.Config, queue string, notice postingNotice, line *statusLine, retries *retryBackoff, session *daemonSession) []scheduledTweet {
+	tweets, err := loadScheduledTweets(queue)
+	if err != nil {
+		log.Printf("Error loading scheduled tweets for queue %s: %v", queueLabel(queue), err)
+		return nil
+	}
 
-	cfg := config.LoadConfig()
-	if err := cfg.Validate(); err != nil {
-		return err
+	limit := cfg.Queues[queue].MaxPerDay
+	postedCount := 0
+	if limit > 0 {
+		if postedCount, err = postedToday(queue); err != nil {
+			log.Printf("Error counting today's posts for queue %s: %v", queueLabel(queue), err)
+		}
+	}
+
+	// Queues added to the config while the daemon runs are checked here.
+	quiet, err := queueQuietHours(cfg, queue)
+	if err != nil {
+		log.Printf("Error reading quiet hours: %v", err)
+	}
+
+	var remainingTweets, added []scheduledTweet
+	posted := map[string]bool{}
+	capped, deferred := false, false
+	now := time.Now()
+
+	failures := map[string]string{}
+	fail := func(id string, err error) time.Duration {
+		failures[id] = err.Error()
+		return retries.failed(id, now)
 	}
 
-	client := &http.Client{Timeout: 20 * time.Second}
+	for _, tweet := range tweets {
+		if tweet.Paused {
+			remainingTweets = append(remainingTweets, tweet)
+			continue
+		}
+
+		if notice.noticeDue(tweet, now) {
+			sendPostingNotice(client, cfg, queue, tweet, notice, now)
+		}
+
+		// A manual run only posts the tweets it was asked to.
+		due := tweet.PostNow || (!session.manual && !tweet.ScheduleTime.After(now))
+		if !due || !retries.ready(tweet.ID, now) || session.stopped() {
+			remainingTweets = append(remainingTweets, tweet)
+			continue
+		}
+
+		if quiet.contains(now) && !tweet.PostNow {
+			if !deferred {
+				say("🌙", "Queue %s is in quiet hours (%s); holding due tweets until %s", queueLabel(queue), quiet, quiet.reopens(now).Format("15:04"))
+				deferred = true
+			}
+			remainingTweets = append(remainingTweets, tweet)
+			continue
+		}
+
+		if limit > 0 && postedCount >= limit {
+			if !capped {
+				say("🚦", "Queue %s reached its cap of %d tweet(s) today; holding the rest", queueLabel(queue), limit)
+				capped = true
+			}
+			remainingTweets = append(remainingTweets, tweet)
+			continue
+		}
 
-	for {
-		tweets, err := loadScheduledTweets()
+		qcfg, err := configForTweet(cfg, queue, tweet.Profile)
+		if err == nil {
+			err = qcfg.Validate()
+		}
 		if err != nil {
-			log.Printf("Error loading scheduled tweets: %v", err)
-			time.Sleep(30 * time.Second)
+			log.Printf("Error loading credentials for tweet %s: %v", tweet.ID, err)
+			fail(tweet.ID, err)
+			line.recordResult(decorate("❌", tweet.ID+" has no usable profile"))
+			remainingTweets = append(remainingTweets, tweet)
 			continue
 		}
 
-		var remainingTweets []scheduledTweet
-		now := time.Now()
+		if tweet.PostedID == "" && tweet.State != statePosting {
+			if err := checkScheduledLinks(client, qcfg, tweet); err != nil {
+				log.Printf("Error checking links of tweet %s: %v", tweet.ID, err)
+				wait := fail(tweet.ID, err)
+				line.recordResult(decorate("❌", tweet.ID+" has dead links, checking again in "+wait.String()))
+				remainingTweets = append(remainingTweets, tweet)
+				continue
+			}
+
+			changed, err := checkPinnedMentions(client, qcfg, tweet)
+			if err != nil {
+				log.Printf("Error checking mentions of tweet %s: %v", tweet.ID, err)
+			}
+			for _, warning := range changed {
+				say("⚠️", "Tweet %s: %s", tweet.ID, warning)
+			}
+		}
+
+		tweetID := tweet.PostedID
+		if tweet.State == statePosting {
+			id, found, err := findInterruptedPost(client, qcfg, tweet)
+			if err != nil {
+				log.Printf("Error checking whether interrupted tweet %s was posted: %v", tweet.ID, err)
+				wait := fail(tweet.ID, err)
+				line.recordResult(decorate("❌", tweet.ID+" interrupted, checking again in "+wait.String()))
+				remainingTweets = append(remainingTweets, tweet)
+				continue
+			}
+			if found {
+				say("♻️", "Tweet %s was already posted before an interruption", tweet.ID)
+				tweetID = id
+				if err := appendJournal(journalRecord{Queue: queue, ID: tweet.ID, Key: tweet.Key, Event: journalPosted, TweetID: id}); err != nil {
+					log.Printf("Error journaling tweet %s: %v", tweet.ID, err)
+				}
+			}
+		}
+
+		if tweetID == "" {
+			if len(tweet.Variants) > 0 && tweet.Variant == "" {
+				tweet.Variant = pickVariant(tweet)
+			}
+			say("📤", "Posting scheduled tweet: %s", tweet.postText())
 
-		for _, tweet := range tweets {
-			if tweet.ScheduleTime.Before(now) || tweet.ScheduleTime.Equal(now) {
-				fmt.Printf("📤 Posting scheduled tweet: %s\n", tweet.Text)
-				
-				var mediaIDs []string
-				if tweet.Image != "" {
-					id, err := uploadMedia(client, cfg, tweet.Image)
-					if err != nil {
-						log.Printf("Error uploading media for tweet %s: %v", tweet.ID, err)
+			var mediaIDs []string
+			if tweet.Image != "" {
+				mcfg := qcfg
+				if tweet.Watermark != nil {
+					mcfg.Watermark = *tweet.Watermark
+				}
+				if tweet.CaptionOverlay != nil {
+					mcfg.CaptionOverlay = *tweet.CaptionOverlay
+				}
+				id, err := uploadMedia(client, mcfg, tweet.Image)
+				if err != nil {
+					log.Printf("Error uploading media for tweet %s: %v", tweet.ID, err)
+					wait := fail(tweet.ID, err)
+					line.recordResult(decorate("❌", tweet.ID+" media upload failed, retrying in "+wait.String()))
+					remainingTweets = append(remainingTweets, tweet)
+					continue
+				}
+				if tweet.AltText != "" {
+					if err := setAltText(client, qcfg, id, tweet.AltText); err != nil {
+						log.Printf("Error setting alt text for tweet %s: %v", tweet.ID, err)
+						wait := fail(tweet.ID, err)
+						line.recordResult(decorate("❌", tweet.ID+" alt text failed, retrying in "+wait.String()))
+						remainingTweets = append(remainingTweets, tweet)
+						continue
+					}
+				}
+				if tweet.Subtitles != nil {
+					if err := attachSubtitles(client, qcfg, id, *tweet.Subtitles); err != nil {
+						log.Printf("Error attaching subtitles for tweet %s: %v", tweet.ID, err)
+						wait := fail(tweet.ID, err)
+						line.recordResult(decorate("❌", tweet.ID+" subtitles failed, retrying in "+wait.String()))
 						remainingTweets = append(remainingTweets, tweet)
 						continue
 					}
-					mediaIDs = append(mediaIDs, id)
 				}
+				if tweet.Thumbnail != nil {
+					if err := setVideoThumbnail(client, mcfg, tweet.Image, id, *tweet.Thumbnail); err != nil {
+						log.Print(decorate("⚠️", fmt.Sprintf("Failed to set the video thumbnail for tweet %s: %v", tweet.ID, err)))
+					}
+				}
+				mediaIDs = append(mediaIDs, id)
+			}
+
+			payload := tweetPayload{Text: tweet.postText(), tweetExtras: tweet.tweetExtras}
+			if len(mediaIDs) > 0 {
+				payload.Media = &tweetMediaBlock{MediaIDs: mediaIDs}
+			}
+			if tweet.ReplyTo != "" {
+				payload.Reply = &tweetReplyBlock{InReplyToTweetID: tweet.ReplyTo}
+			}
+
+			// The chosen variant is stored with the posting state so a retry
+			// after a crash looks for, and posts, the same text.
+			variant := tweet.Variant
+			marked, err := updateScheduledTweet(queue, tweet.ID, func(t *scheduledTweet) {
+				t.State = statePosting
+				t.PostedID = ""
+				if t.Variant == "" {
+					t.Variant = variant
+				}
+			})
+			if err != nil {
+				if !errors.Is(err, errScheduledTweetGone) {
+					log.Printf("Error marking tweet %s as posting: %v", tweet.ID, err)
+					remainingTweets = append(remainingTweets, tweet)
+				}
+				continue
+			}
+			tweet = marked
+
+			if err := appendJournal(journalRecord{Queue: queue, ID: tweet.ID, Key: tweet.Key, Event: journalPosting}); err != nil {
+				log.Printf("Error journaling tweet %s: %v", tweet.ID, err)
+				wait := fail(tweet.ID, err)
+				line.recordResult(decorate("❌", tweet.ID+" not journaled, retrying in "+wait.String()))
+				remainingTweets = append(remainingTweets, tweet)
+				continue
+			}
+
+			tweetID, err = postTweetPayload(client, qcfg, payload)
+			if err != nil {
+				log.Printf("Error posting tweet %s: %v", tweet.ID, err)
+				wait := fail(tweet.ID, err)
+				line.recordResult(decorate("❌", tweet.ID+" failed, retrying in "+wait.String()))
+				// A rejected request was definitely not posted. Anything else
+				// (a timeout, a dropped connection) stays in the posting
+				// state and is checked against the account next time.
+				var apiErr *apiError
+				if errors.As(err, &apiErr) {
+					if _, err := setPostingState(queue, tweet.ID, "", ""); err != nil {
+						log.Printf("Error resetting tweet %s: %v", tweet.ID, err)
+					}
+					tweet.State = ""
+				}
+				remainingTweets = append(remainingTweets, tweet)
+				continue
+			}
+
+			// The journal is the first record of a successful post.
+			if err := appendJournal(journalRecord{Queue: queue, ID: tweet.ID, Key: tweet.Key, Event: journalPosted, TweetID: tweetID}); err != nil {
+				log.Printf("Error journaling tweet %s: %v", tweet.ID, err)
+			}
+			if !tweet.PostNow {
+				session.recordLateness(tweet.ID, time.Since(tweet.ScheduleTime))
+			}
+		}
+
+		recovered := tweet.State == statePosted
+		if tweet.State != statePosted || tweet.PostedID != tweetID {
+			if _, err := setPostingState(queue, tweet.ID, statePosted, tweetID); err != nil {
+				log.Printf("Error marking tweet %s as posted: %v", tweet.ID, err)
+			}
+		}
+		postedCount++
+
+		if _, found, _ := historyEntryForKey(tweet.Key); !found {
+			entry := historyEntry{
+				TweetID:      tweetID,
+				Text:         tweet.postText(),
+				Image:        tweet.Image,
+				PostedAt:     time.Now(),
+				ScheduledID:  tweet.ID,
+				ScheduledFor: &tweet.ScheduleTime,
+				Queue:        queue,
+				Key:          tweet.Key,
+				Labels:       tweet.Labels,
+				Campaign:     tweet.Campaign,
+				Variant:      tweet.Variant,
+				ResharedFrom: tweet.ResharedFrom,
+			}
+			if err := recordHistory(entry); err != nil {
+				log.Printf("Error recording tweet %s in history: %v", tweet.ID, err)
+			}
+		}
+
+		// Replies that went out before a failure or an interruption are not
+		// posted again; the thread continues from the last one. The journal
+		// may know of a reply whose saving to the queue was lost.
+		replies := tweet.Replies
+		if recovered && len(tweet.Thread) > 0 {
+			progress, err := journalProgress(queue, tweet.ID)
+			if err != nil {
+				log.Printf("Error reading journal for tweet %s: %v", tweet.ID, err)
+			}
+			switch {
+			case progress != nil && progress.PostedID == tweetID && len(progress.Replies) <= len(tweet.Thread):
+				if len(progress.Replies) > len(replies) {
+					replies = progress.Replies
+				}
+			case len(replies) == 0 || len(replies) > len(tweet.Thread):
+				say("⚠️", "Tweet %s was interrupted after posting; check its thread replies by hand", tweet.ID)
+				replies, tweet.Thread = nil, nil
+			}
+		}
+
+		parentID := tweetID
+		if len(replies) > 0 {
+			parentID = replies[len(replies)-1]
+		}
+		var replyErr error
+		for i := len(replies); i < len(tweet.Thread); i++ {
+			replyID, err := postTweetPayload(client, qcfg, tweetPayload{Text: tweet.Thread[i], Reply: &tweetReplyBlock{InReplyToTweetID: parentID}})
+			if err != nil {
+				log.Printf("Error posting thread reply %d of %d for tweet %s: %v", i+1, len(tweet.Thread), tweet.ID, err)
+				replyErr = fmt.Errorf("thread reply %d of %d: %w", i+1, len(tweet.Thread), err)
+				break
+			}
+			if err := appendJournal(journalRecord{Queue: queue, ID: tweet.ID, Key: tweet.Key, Event: journalReply, TweetID: replyID}); err != nil {
+				log.Printf("Error journaling tweet %s: %v", tweet.ID, err)
+			}
+			if err := recordHistory(historyEntry{TweetID: replyID, Text: tweet.Thread[i], PostedAt: time.Now(), Labels: tweet.Labels, Campaign: tweet.Campaign}); err != nil {
+				log.Printf("Error recording thread reply for tweet %s in history: %v", tweet.ID, err)
+			}
+			replies = append(replies, replyID)
+			parentID = replyID
+		}
 
-				if err := postTweet(client, cfg, tweet.Text, mediaIDs); err != nil {
-					log.Printf("Error posting tweet %s: %v", tweet.ID, err)
+		// The rest of the thread, first comment included, stays queued and
+		// is retried after the last reply that went out.
+		if replyErr != nil {
+			stored, err := updateScheduledTweet(queue, tweet.ID, func(t *scheduledTweet) {
+				t.Replies = replies
+			})
+			if err != nil {
+				if !errors.Is(err, errScheduledTweetGone) {
+					log.Printf("Error saving the thread progress of tweet %s: %v", tweet.ID, err)
 					remainingTweets = append(remainingTweets, tweet)
-					continue
 				}
+				continue
+			}
+			wait := fail(tweet.ID, replyErr)
+			line.recordResult(decorate("❌", fmt.Sprintf("%s thread stopped after %d of %d replies, retrying in %s", tweet.ID, len(replies), len(tweet.Thread), wait)))
+			remainingTweets = append(remainingTweets, stored)
+			continue
+		}
+		posted[tweet.ID] = true
+		retries.succeeded(tweet.ID)
 
-				fmt.Printf("✅ Successfully posted scheduled tweet: %s\n", tweet.ID)
+		if tweet.FollowUp != nil {
+			reply, err := newFollowUpTweet(tweetID, *tweet.FollowUp, time.Now())
+			if err != nil {
+				log.Printf("Error scheduling follow-up for tweet %s: %v", tweet.ID, err)
 			} else {
-				remainingTweets = append(remainingTweets, tweet)
+				reply.Profile = tweet.Profile
+				reply.Labels = tweet.Labels
+				reply.Campaign = tweet.Campaign
+				added = append(added, reply)
+				say("⏰", "Follow-up %s scheduled for %s", reply.ID, reply.ScheduleTime.Format("2006-01-02 15:04:05"))
 			}
 		}
 
-		if len(remainingTweets) != len(tweets) {
-			if err := saveScheduledTweets(remainingTweets); err != nil {
-				log.Printf("Error saving updated tweets: %v", err)
+		if err := appendJournal(journalRecord{Queue: queue, ID: tweet.ID, Key: tweet.Key, Event: journalDone}); err != nil {
+			log.Printf("Error journaling tweet %s: %v", tweet.ID, err)
+		}
+
+		if err := mirrorToDiscord(client, cfg, tweetID, tweet.postText(), tweet.Image); err != nil {
+			log.Printf("Error mirroring tweet %s to Discord: %v", tweet.ID, err)
+		}
+		removeScheduledTweetMedia(tweet)
+		say("✅", "Successfully posted scheduled tweet: %s", tweet.ID)
+		line.recordResult(decorate("✅", "posted "+tweet.ID))
+		hookTweet := scheduledHookTweet(tweet, queue, tweetID)
+		emitEvent(client, cfg, tweetPostedEvent(cfg, hookTweet))
+		notifySlackChannel(client, cfg, notification{
+			Title:  "Scheduled tweet posted",
+			Text:   fmt.Sprintf("%s from the %s queue, scheduled for %s", tweet.ID, queueLabel(queue), tweet.ScheduleTime.Format("2006-01-02 15:04")),
+			Tweets: []notificationTweet{hookTweet},
+		})
+	}
+
+	session.posted += len(posted)
+	session.failed += len(failures)
+	if len(posted) == 0 && len(failures) == 0 {
+		return remainingTweets
+	}
+
+	// Merge into the store as it is now: tweets may have been scheduled,
+	// cancelled or paused while we were posting.
+	var newlyFailed []scheduledTweet
+	err = updateScheduledTweets(queue, func(current []scheduledTweet) ([]scheduledTweet, error) {
+		var kept []scheduledTweet
+		newlyFailed = nil
+		for _, tweet := range current {
+			if posted[tweet.ID] {
+				continue
+			}
+			if msg, ok := failures[tweet.ID]; ok {
+				if msg != tweet.LastError {
+					failed := tweet
+					failed.LastError = msg
+					newlyFailed = append(newlyFailed, failed)
+				}
+				tweet.LastError = msg
+				if tweet.FailingSince == nil {
+					tweet.FailingSince = &now
+				}
 			}
+			kept = append(kept, tweet)
+		}
+		return append(kept, added...), nil
+	})
+	if err != nil {
+		log.Printf("Error saving updated tweets: %v", err)
+		return append(remainingTweets, added...)
+	}
+
+	// Retries that fail the same way again are not reported twice.
+	for _, tweet := range newlyFailed {
+		hookTweet := scheduledHookTweet(tweet, queue, "")
+		emitEvent(client, cfg, tweetFailedEvent(cfg, hookTweet, tweet.LastError))
+		notifySlackChannel(client, cfg, notification{
+			Title:  "Scheduled tweet failed",
+			Text:   fmt.Sprintf("%s in the %s queue will be retried: %s", tweet.ID, queueLabel(queue), tweet.LastError),
+			Tweets: []notificationTweet{hookTweet},
+		})
+	}
+
+	current, _ := loadScheduledTweets(queue)
+	return current
+}
+
+// validateTweetExtras checks the shape of --card-uri and --dm-deep-link
+// before anything is posted or scheduled.
+func validateTweetExtras(extras tweetExtras, image string) error {
+	if extras.CardURI != "" {
+		if !strings.HasPrefix(extras.CardURI, "card://") || len(extras.CardURI) == len("card://") {
+			return invalidInput(fmt.Errorf("invalid card URI %q (expected card://<id>)", extras.CardURI))
 		}
+		if image != "" || extras.DirectMessageDeepLink != "" {
+			return invalidInput(errors.New("a card cannot be combined with media or a DM deep link"))
+		}
+	}
 
-		time.Sleep(30 * time.Second) // Check every 30 seconds
+	if link := extras.DirectMessageDeepLink; link != "" {
+		u, err := url.Parse(link)
+		if err != nil || u.Scheme != "https" || (u.Host != "twitter.com" && u.Host != "x.com") ||
+			u.Path != "/messages/compose" || u.Query().Get("recipient_id") == "" {
+			return invalidInput(fmt.Errorf("invalid DM deep link %q (expected https://twitter.com/messages/compose?recipient_id=<id>)", link))
+		}
 	}
-}
\ No newline at end of file
+
+	return nil
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// scheduledMediaDir holds copies of media attached to scheduled tweets, next
+// to the schedule files, so moving or deleting the original does not break
+// posting.
+const scheduledMediaDir = "scheduled_media"
+
+// mediaSizeLimits are X's upload limits by MIME type.
+var mediaSizeLimits = map[string]int64{
+	"image/jpeg":      5 << 20,
+	"image/png":       5 << 20,
+	"image/webp":      5 << 20,
+	"image/gif":       15 << 20,
+	"video/mp4":       512 << 20,
+	"video/quicktime": 512 << 20,
+}
+
+// validateMedia checks that path is a readable file of a type and size X
+// accepts.
+func validateMedia(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return invalidInput(fmt.Errorf("media: %w", err))
+	}
+	if !info.Mode().IsRegular() {
+		return invalidInput(fmt.Errorf("media %s is not a regular file", path))
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return invalidInput(fmt.Errorf("media: %w", err))
+	}
+	defer f.Close()
+
+	// Content sniffing needs at most the first 512 bytes.
+	head := make([]byte, 512)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return fmt.Errorf("reading media %s: %w", path, err)
+	}
+
+	if info.Size() == 0 {
+		return invalidInput(fmt.Errorf("media %s is empty", path))
+	}
+
+	mimeType, _, _ := strings.Cut(detectMime(path, head[:n]), ";")
+	limit, ok := mediaSizeLimits[mimeType]
+	if !ok {
+		return invalidInput(fmt.Errorf("media %s has unsupported type %s (use JPEG, PNG, WebP, GIF, MP4 or MOV)", path, mimeType))
+	}
+	if info.Size() > limit {
+		return invalidInput(fmt.Errorf("media %s is %s, over the %s limit for %s", path, formatBytes(info.Size()), formatBytes(limit), mimeType))
+	}
+	if strings.HasPrefix(mimeType, "video/") {
+		return checkVideo(path)
+	}
+
+	return nil
+}
+
+// copyScheduledMedia copies path into scheduledMediaDir under the scheduled
+// tweet's ID and returns the copy's path.
+func copyScheduledMedia(path, tweetID string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("reading media: %w", err)
+	}
+
+	dest := filepath.Join(scheduledMediaDir, tweetID+strings.ToLower(filepath.Ext(path)))
+	if err := os.MkdirAll(scheduledMediaDir, 0755); err != nil {
+		return "", fmt.Errorf("creating %s: %w", scheduledMediaDir, err)
+	}
+	if err := writeFileAtomic(dest, data, 0644); err != nil {
+		return "", fmt.Errorf("copying media: %w", err)
+	}
+	return dest, nil
+}
+
+// removeScheduledTweetMedia deletes the copies of a scheduled tweet's image,
+// subtitles and thumbnail.
+func removeScheduledTweetMedia(tweet scheduledTweet) {
+	removeScheduledMedia(tweet.Image)
+	if tweet.Subtitles != nil {
+		removeScheduledMedia(tweet.Subtitles.Path)
+	}
+	if tweet.Thumbnail != nil {
+		removeScheduledMedia(tweet.Thumbnail.Image)
+	}
+}
+
+// removeScheduledMedia deletes a copy made by copyScheduledMedia once its
+// tweet is posted or cancelled. Other paths are left alone.
+func removeScheduledMedia(path string) {
+	if path != "" && filepath.Dir(path) == scheduledMediaDir {
+		os.Remove(path)
+	}
+}
+
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", n)
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/kalikim/x-cli/config"
+)
+
+const (
+	mediaCacheFile = "media_cache.json"
+	// defaultMediaTTL is how long X keeps an uploaded media ID usable when
+	// the upload response does not say.
+	defaultMediaTTL = 24 * time.Hour
+	// mediaExpiryMargin keeps a cached ID from being used just before X
+	// drops it, e.g. while a thread is still being posted.
+	mediaExpiryMargin = 10 * time.Minute
+)
+
+// cachedMedia is an uploaded file's media ID and when X stops accepting it.
+type cachedMedia struct {
+	MediaID   string    `json:"media_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func mediaCachePath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".x-cli", mediaCacheFile), nil
+}
+
+// mediaCacheKey identifies an upload by its bytes, category and account:
+// media IDs can only be attached by the account that uploaded them.
+func mediaCacheKey(cfg config.Config, category string, data []byte) string {
+	h := sha256.New()
+	h.Write([]byte(cfg.AccessToken))
+	h.Write([]byte{0})
+	h.Write([]byte(category))
+	h.Write([]byte{0})
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func loadMediaCache(path string) (map[string]cachedMedia, error) {
+	cache := map[string]cachedMedia{}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cache, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &cache); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return cache, nil
+}
+
+// cachedMediaID returns the media ID of an earlier upload with the same key
+// if X still accepts it.
+func cachedMediaID(key string) (string, bool) {
+	path, err := mediaCachePath()
+	if err != nil {
+		return "", false
+	}
+
+	var entry cachedMedia
+	err = withFileLock(path, func() error {
+		cache, err := loadMediaCache(path)
+		if err != nil {
+			return err
+		}
+		entry = cache[key]
+		return nil
+	})
+	if err != nil || entry.MediaID == "" || time.Now().Add(mediaExpiryMargin).After(entry.ExpiresAt) {
+		return "", false
+	}
+	return entry.MediaID, true
+}
+
+// cacheMediaID remembers an upload's media ID for ttl, dropping expired
+// entries along the way.
+func cacheMediaID(key, mediaID string, ttl time.Duration) error {
+	path, err := mediaCachePath()
+	if err != nil {
+		return err
+	}
+	if ttl <= 0 {
+		ttl = defaultMediaTTL
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return withFileLock(path, func() error {
+		cache, err := loadMediaCache(path)
+		if err != nil {
+			// A corrupt cache only costs re-uploads; start over.
+			cache = map[string]cachedMedia{}
+		}
+
+		now := time.Now()
+		for k, entry := range cache {
+			if now.After(entry.ExpiresAt) {
+				delete(cache, k)
+			}
+		}
+		cache[key] = cachedMedia{MediaID: mediaID, ExpiresAt: now.Add(ttl)}
+
+		data, err := json.MarshalIndent(cache, "", "  ")
+		if err != nil {
+			return err
+		}
+		return writeFileAtomic(path, data, 0600)
+	})
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/kalikim/x-cli/config"
+)
+
+// mediaHookTimeout bounds a pre-upload command; video tools can be slow.
+const mediaHookTimeout = 5 * time.Minute
+
+// mediaHookFor returns the configured command for mimeType: an exact match
+// first, then "type/*", then "*".
+func mediaHookFor(cfg config.Config, mimeType string) string {
+	major, _, _ := strings.Cut(mimeType, "/")
+	for _, key := range []string{mimeType, major + "/*", "*"} {
+		if command := strings.TrimSpace(cfg.MediaHooks[key]); command != "" {
+			return command
+		}
+	}
+	return ""
+}
+
+// runMediaHook runs the pre-upload command for mimeType, if any, on a
+// temporary copy of data named like path. It returns the path to upload,
+// which is path itself without a hook, and a cleanup function removing the
+// copy. The original file is never changed.
+func runMediaHook(cfg config.Config, path, mimeType string, data []byte) (string, func(), error) {
+	command := mediaHookFor(cfg, mimeType)
+	if command == "" {
+		return path, func() {}, nil
+	}
+
+	dir, err := os.MkdirTemp("", "x-cli-media-")
+	if err != nil {
+		return "", nil, err
+	}
+	cleanup := func() { os.RemoveAll(dir) }
+
+	// The name keeps its extension, which many tools go by.
+	work := filepath.Join(dir, filepath.Base(path))
+	if err := os.WriteFile(work, data, 0600); err != nil {
+		cleanup()
+		return "", nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), mediaHookTimeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command+` "`+work+`"`)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command+" "+shellQuote(work))
+	}
+	cmd.Env = append(os.Environ(), "XCLI_MEDIA_TYPE="+mimeType, "XCLI_MEDIA_SOURCE="+path)
+	var output bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &output, &output
+
+	if err := cmd.Run(); err != nil {
+		cleanup()
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			err = fmt.Errorf("timed out after %s", mediaHookTimeout)
+		} else if msg := strings.TrimSpace(output.String()); msg != "" {
+			err = fmt.Errorf("%w: %s", err, msg)
+		}
+		return "", nil, fmt.Errorf("media hook for %s (%s) failed: %w", filepath.Base(path), mimeType, err)
+	}
+
+	if info, err := os.Stat(work); err != nil || info.Size() == 0 {
+		cleanup()
+		return "", nil, fmt.Errorf("media hook for %s removed or emptied the file", filepath.Base(path))
+	}
+	return work, cleanup, nil
+}
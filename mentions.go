@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/kalikim/x-cli/config"
+)
+
+// tweetMentions returns the distinct @mentions in every text the tweet may
+// post: the main text, its variants and its thread.
+func tweetMentions(tweet scheduledTweet) []string {
+	texts := append([]string{tweet.Text}, tweet.Variants...)
+	texts = append(texts, tweet.Thread...)
+	return extractMentions(strings.Join(texts, "\n"))
+}
+
+// pinMentions resolves the tweet's mentions and records their user IDs, so
+// the daemon can tell when a handle changes hands before the tweet goes out.
+func pinMentions(client *http.Client, cfg config.Config, tweet *scheduledTweet) error {
+	mentions := tweetMentions(*tweet)
+	if len(mentions) == 0 {
+		return nil
+	}
+
+	found, err := resolveUsers(client, cfg, mentions)
+	if err != nil {
+		return err
+	}
+
+	tweet.Mentions = nil
+	for _, name := range mentions {
+		if id := found[strings.ToLower(name)]; id != "" {
+			if tweet.Mentions == nil {
+				tweet.Mentions = map[string]string{}
+			}
+			tweet.Mentions[strings.ToLower(name)] = id
+		}
+	}
+	return nil
+}
+
+// checkPinnedMentions looks the pinned mentions up again and describes every
+// handle that no longer resolves or now belongs to a different account.
+func checkPinnedMentions(client *http.Client, cfg config.Config, tweet scheduledTweet) ([]string, error) {
+	if len(tweet.Mentions) == 0 {
+		return nil, nil
+	}
+
+	names := make([]string, 0, len(tweet.Mentions))
+	for name := range tweet.Mentions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	found, err := resolveUsers(client, cfg, names)
+	if err != nil {
+		return nil, err
+	}
+
+	var changed []string
+	for _, name := range names {
+		switch id := found[name]; {
+		case id == "":
+			changed = append(changed, fmt.Sprintf("@%s no longer resolves (renamed or suspended account)", name))
+		case id != tweet.Mentions[name]:
+			changed = append(changed, fmt.Sprintf("@%s now belongs to a different account (user %s, was %s)", name, id, tweet.Mentions[name]))
+		}
+	}
+	return changed, nil
+}
@@ -0,0 +1,362 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kalikim/x-cli/config"
+	"github.com/kalikim/x-cli/vcr"
+)
+
+const defaultMockLogFile = "mock_requests.ndjson"
+
+// offlineMode routes every API call to mockTransport instead of the network.
+// It is enabled with --offline or XCLI_MOCK=1.
+var offlineMode bool
+
+func configureOffline(flag bool) {
+	offlineMode = flag
+	if v, err := strconv.ParseBool(os.Getenv("XCLI_MOCK")); err == nil && v {
+		offlineMode = true
+	}
+	if !offlineMode {
+		return
+	}
+
+	// Requests are still signed, so fill in placeholders for anything unset.
+	config.CredentialFallback = "offline"
+}
+
+// cassetteTransport, when set by --cassette, records or replays every API
+// call through a vcr cassette.
+var cassetteTransport *vcr.Transport
+
+func configureCassette(path, modeName string) error {
+	if path == "" {
+		return nil
+	}
+	if offlineMode {
+		return invalidInput(errors.New("--cassette cannot be combined with offline mode"))
+	}
+
+	mode, err := vcr.ParseMode(modeName)
+	if err != nil {
+		return invalidInput(err)
+	}
+
+	transport, err := vcr.New(path, mode, nil)
+	if err != nil {
+		return err
+	}
+	cassetteTransport = transport
+	return nil
+}
+
+func mockLogFile() string {
+	if path := os.Getenv("XCLI_MOCK_LOG"); path != "" {
+		return path
+	}
+	return defaultMockLogFile
+}
+
+// mockRequest is one line of the mock request log.
+type mockRequest struct {
+	Time   time.Time `json:"time"`
+	Method string    `json:"method"`
+	URL    string    `json:"url"`
+	Body   string    `json:"body,omitempty"`
+	Status int       `json:"status"`
+}
+
+// mockTransport is an in-process fake of the X API. It answers the endpoints
+// x-cli uses with plausible responses, refuses everything else, and appends
+// every request to logFile.
+type mockTransport struct {
+	logFile string
+	mu      sync.Mutex
+}
+
+func (t *mockTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		data, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		body = data
+	}
+
+	status, payload := t.respond(req, body)
+
+	if err := t.record(mockRequest{
+		Time:   time.Now(),
+		Method: req.Method,
+		URL:    req.URL.String(),
+		Body:   vcr.RedactBody(req.Header.Get("Content-Type"), body),
+		Status: status,
+	}); err != nil {
+		return nil, fmt.Errorf("recording offline request: %w", err)
+	}
+
+	return &http.Response{
+		StatusCode: status,
+		Status:     fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		Header: http.Header{
+			"Content-Type": []string{"application/json"},
+			"Date":         []string{time.Now().UTC().Format(http.TimeFormat)},
+			// A generous, never-exhausted window, so `limits` has data.
+			"X-Rate-Limit-Limit":     []string{"300"},
+			"X-Rate-Limit-Remaining": []string{"299"},
+			"X-Rate-Limit-Reset":     []string{strconv.FormatInt(time.Now().Add(15*time.Minute).Unix(), 10)},
+		},
+		Body:       io.NopCloser(bytes.NewReader(payload)),
+		Request:    req,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+	}, nil
+}
+
+func (t *mockTransport) respond(req *http.Request, body []byte) (int, []byte) {
+	id := strconv.FormatInt(time.Now().UnixNano(), 10)
+	endpoint := req.URL.Scheme + "://" + req.URL.Host + req.URL.Path
+
+	switch {
+	case req.Method == http.MethodPost && endpoint == tweetEndpoint:
+		var tweet tweetPayload
+		if err := json.Unmarshal(body, &tweet); err != nil {
+			return http.StatusBadRequest, mockError("invalid JSON payload")
+		}
+		return http.StatusCreated, mustJSON(map[string]any{"data": map[string]string{"id": id, "text": tweet.Text}})
+
+	case req.Method == http.MethodGet && endpoint == tweetEndpoint:
+		var tweets []map[string]any
+		for _, tweetID := range strings.Split(req.URL.Query().Get("ids"), ",") {
+			if tweetID != "" {
+				// Metrics are derived from the ID so lookups agree across runs.
+				h := fnv.New32a()
+				h.Write([]byte(tweetID))
+				likes := int(h.Sum32() % 20)
+				tweets = append(tweets, map[string]any{"id": tweetID, "public_metrics": map[string]int{"impression_count": likes * 37, "like_count": likes}})
+			}
+		}
+		return http.StatusOK, mustJSON(map[string]any{"data": tweets})
+
+	case req.Method == http.MethodGet && strings.HasPrefix(endpoint, tweetEndpoint+"/") && tweetIDPattern.MatchString(strings.TrimPrefix(endpoint, tweetEndpoint+"/")):
+		return http.StatusOK, mustJSON(map[string]any{
+			"data": map[string]any{
+				"id":             strings.TrimPrefix(endpoint, tweetEndpoint+"/"),
+				"text":           "Offline tweet rendered by x-cli. Long enough to wrap onto a second line of the preview card, with a #hashtag and a link https://example.com",
+				"author_id":      "1",
+				"created_at":     time.Now().Add(-time.Hour).UTC().Format(time.RFC3339),
+				"public_metrics": map[string]int{"like_count": 42, "retweet_count": 7, "reply_count": 3},
+			},
+			"includes": map[string]any{"users": []map[string]string{{"id": "1", "username": "offline", "name": "Offline Account"}}},
+		})
+
+	case req.Method == http.MethodDelete && strings.HasPrefix(endpoint, tweetEndpoint+"/") && tweetIDPattern.MatchString(strings.TrimPrefix(endpoint, tweetEndpoint+"/")):
+		return http.StatusOK, mustJSON(map[string]any{"data": map[string]bool{"deleted": true}})
+
+	case req.Method == http.MethodPost && endpoint == mediaUploadEndpoint:
+		return http.StatusOK, mustJSON(map[string]any{"media_id_string": id, "expires_after_secs": 86400})
+
+	case req.Method == http.MethodPost && (endpoint == subtitlesCreateEndpoint || endpoint == mediaMetadataEndpoint):
+		return http.StatusOK, nil
+
+	case req.Method == http.MethodGet && endpoint == usageTweetsEndpoint:
+		return http.StatusOK, mustJSON(map[string]any{"data": map[string]any{"project_id": "1", "project_usage": "1250", "project_cap": "10000", "cap_reset_day": 1}})
+
+	case req.Method == http.MethodGet && endpoint == usersMeEndpoint:
+		return http.StatusOK, mustJSON(map[string]any{"data": map[string]string{"id": id, "username": "offline"}})
+
+	case req.Method == http.MethodGet && strings.HasPrefix(endpoint, "https://api.twitter.com/2/users/") &&
+		(strings.HasSuffix(endpoint, "/timelines/reverse_chronological") || strings.HasSuffix(endpoint, "/mentions")):
+		return http.StatusOK, mockTimeline(req.URL.Query().Get("pagination_token"))
+
+	case req.Method == http.MethodGet && (endpoint == dmEventsEndpoint ||
+		strings.HasPrefix(endpoint, "https://api.twitter.com/2/dm_conversations/with/") && strings.HasSuffix(endpoint, "/dm_events")):
+		return http.StatusOK, mockDMEvents()
+
+	case req.Method == http.MethodPost && strings.HasPrefix(endpoint, "https://api.twitter.com/2/dm_conversations/") && strings.HasSuffix(endpoint, "/messages"):
+		return http.StatusCreated, mustJSON(map[string]any{"data": map[string]string{
+			"dm_conversation_id": strings.Split(strings.TrimPrefix(endpoint, "https://api.twitter.com/2/dm_conversations/"), "/")[0],
+			"dm_event_id":        id,
+		}})
+
+	case req.Method == http.MethodPost && strings.HasPrefix(endpoint, "https://api.twitter.com/2/users/") && strings.HasSuffix(endpoint, "/likes"):
+		return http.StatusOK, mustJSON(map[string]any{"data": map[string]bool{"liked": true}})
+
+	case req.Method == http.MethodPost && strings.HasPrefix(endpoint, "https://api.twitter.com/2/users/") && strings.HasSuffix(endpoint, "/retweets"):
+		return http.StatusOK, mustJSON(map[string]any{"data": map[string]bool{"retweeted": true}})
+
+	case req.Method == http.MethodGet && strings.HasPrefix(endpoint, "https://api.twitter.com/2/users/") && strings.HasSuffix(endpoint, "/tweets"):
+		// Offline posts are not remembered; the account shows canned tweets.
+		return http.StatusOK, mockTimeline(req.URL.Query().Get("pagination_token"))
+
+	case req.Method == http.MethodGet && endpoint == spacesSearchEndpoint:
+		return http.StatusOK, mustJSON(map[string]any{
+			"data":     []map[string]any{mockSpace(id, req.URL.Query().Get("query"))},
+			"includes": map[string]any{"users": []map[string]string{{"id": "1", "username": "offline"}}},
+		})
+
+	case req.Method == http.MethodGet && strings.HasPrefix(endpoint, spacesEndpoint+"/"):
+		return http.StatusOK, mustJSON(map[string]any{
+			"data":     mockSpace(strings.TrimPrefix(endpoint, spacesEndpoint+"/"), "Offline Space"),
+			"includes": map[string]any{"users": []map[string]string{{"id": "1", "username": "offline"}}},
+		})
+
+	case req.Method == http.MethodGet && endpoint == usersLookupEndpoint:
+		var users []map[string]string
+		for _, name := range strings.Split(req.URL.Query().Get("usernames"), ",") {
+			if name != "" {
+				// IDs are derived from the name so lookups agree across runs.
+				h := fnv.New64a()
+				h.Write([]byte(strings.ToLower(name)))
+				users = append(users, map[string]string{"id": strconv.FormatUint(h.Sum64()>>1, 10), "username": name})
+			}
+		}
+		return http.StatusOK, mustJSON(map[string]any{"data": users})
+
+	case req.Method == http.MethodGet && endpoint == recentSearchEndpoint:
+		return http.StatusOK, mockSearch(req.URL.Query())
+
+	case req.Method == http.MethodPost && !strings.HasSuffix(req.URL.Host, "twitter.com"):
+		// Webhooks: every delivery is accepted.
+		return http.StatusOK, nil
+
+	case req.Method == http.MethodHead:
+		// Link checks: every link is reported alive.
+		return http.StatusOK, nil
+	}
+
+	return http.StatusNotFound, mockError(fmt.Sprintf("offline mode: no fake for %s %s", req.Method, endpoint))
+}
+
+func (t *mockTransport) record(entry mockRequest) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(t.logFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+func mockError(message string) []byte {
+	return mustJSON(map[string]any{"errors": []map[string]string{{"message": message}}})
+}
+
+func mustJSON(v any) []byte {
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
+
+// mockSpace is a scheduled Space starting in an hour.
+func mockSpace(id, title string) map[string]any {
+	return map[string]any{
+		"id":              id,
+		"state":           "scheduled",
+		"title":           title,
+		"scheduled_start": time.Now().Add(time.Hour).UTC().Format(time.RFC3339),
+		"creator_id":      "1",
+	}
+}
+
+// mockTimeline is a two-page timeline of canned tweets.
+func mockTimeline(pageToken string) []byte {
+	page, next := 1, "page2"
+	if pageToken == "page2" {
+		page, next = 2, ""
+	}
+
+	var tweets []map[string]any
+	for i := 1; i <= 3; i++ {
+		tweets = append(tweets, map[string]any{
+			"id":             fmt.Sprintf("17000000000000000%d%d", 3-page, 4-i),
+			"text":           fmt.Sprintf("Offline tweet %d on page %d", i, page),
+			"author_id":      "1",
+			"created_at":     time.Now().Add(-time.Duration(page*10+i) * time.Minute).UTC().Format(time.RFC3339),
+			"public_metrics": map[string]int{"like_count": i, "retweet_count": 0, "reply_count": 0},
+		})
+	}
+
+	resp := map[string]any{
+		"data":     tweets,
+		"includes": map[string]any{"users": []map[string]string{{"id": "1", "username": "offline", "name": "Offline Account"}}},
+		"meta":     map[string]any{"result_count": len(tweets)},
+	}
+	if next != "" {
+		resp["meta"].(map[string]any)["next_token"] = next
+	}
+	return mustJSON(resp)
+}
+
+// mockDMEvents is a single page of canned direct messages.
+func mockDMEvents() []byte {
+	var events []map[string]any
+	for i := 1; i <= 2; i++ {
+		events = append(events, map[string]any{
+			"id":                 fmt.Sprintf("180000000000000000%d", 3-i),
+			"event_type":         "MessageCreate",
+			"text":               fmt.Sprintf("Offline direct message %d", i),
+			"sender_id":          "2",
+			"dm_conversation_id": "1-2",
+			"created_at":         time.Now().Add(-time.Duration(i*5) * time.Minute).UTC().Format(time.RFC3339),
+		})
+	}
+	return mustJSON(map[string]any{
+		"data":     events,
+		"includes": map[string]any{"users": []map[string]string{{"id": "2", "username": "offline_friend", "name": "Offline Friend"}}},
+		"meta":     map[string]any{"result_count": len(events)},
+	})
+}
+
+// mockSearch answers recent search with one match per minute since the
+// requested start, so repeated runs of a saved search keep finding new
+// tweets.
+func mockSearch(query url.Values) []byte {
+	now := time.Now().UTC().Truncate(time.Minute)
+	var tweets []map[string]any
+	for i := 0; i < 3; i++ {
+		created := now.Add(-time.Duration(i) * time.Minute)
+		id := strconv.FormatInt(created.Unix(), 10) + "000000000"
+		if sinceID := query.Get("since_id"); sinceID != "" && !newerID(id, sinceID) {
+			break
+		}
+		tweets = append(tweets, map[string]any{
+			"id":         id,
+			"text":       "Offline match for " + query.Get("query"),
+			"author_id":  "2",
+			"created_at": created.Format(time.RFC3339),
+		})
+	}
+	return mustJSON(map[string]any{
+		"data":     tweets,
+		"includes": map[string]any{"users": []map[string]string{{"id": "2", "username": "offline_friend", "name": "Offline Friend"}}},
+		"meta":     map[string]any{"result_count": len(tweets)},
+	})
+}
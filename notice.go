@@ -0,0 +1,99 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/kalikim/x-cli/config"
+)
+
+// postingNotice is how the daemon announces tweets before posting them,
+// giving people a last chance to cancel automated posts.
+type postingNotice struct {
+	Before  time.Duration
+	Desktop bool
+}
+
+// noticeDue reports whether tweet, not yet due, is within the notice
+// window and has not been announced for its current schedule time.
+func (n postingNotice) noticeDue(tweet scheduledTweet, now time.Time) bool {
+	if n.Before <= 0 || tweet.Paused || tweet.PostNow || tweet.PostedID != "" || !tweet.ScheduleTime.After(now) {
+		return false
+	}
+	if tweet.NoticeSentFor != nil && tweet.NoticeSentFor.Equal(tweet.ScheduleTime) {
+		return false
+	}
+	return !tweet.ScheduleTime.After(now.Add(n.Before))
+}
+
+// noticeAt is when tweet should be announced, or the zero time when it
+// needs no notice.
+func (n postingNotice) noticeAt(tweet scheduledTweet) time.Time {
+	if n.Before <= 0 || tweet.Paused || (tweet.NoticeSentFor != nil && tweet.NoticeSentFor.Equal(tweet.ScheduleTime)) {
+		return time.Time{}
+	}
+	return tweet.ScheduleTime.Add(-n.Before)
+}
+
+// cancelCommand is the command that cancels a scheduled tweet in queue.
+func cancelCommand(queue, id string) string {
+	if queue != "" {
+		return fmt.Sprintf("x-cli scheduler cancel --queue %s %s", queue, id)
+	}
+	return "x-cli scheduler cancel " + id
+}
+
+// sendPostingNotice records that tweet was announced, then tells the Slack
+// webhook, the webhooks receiving tweet.upcoming and, when asked, the
+// desktop. The tweet is marked first so a failing channel never repeats
+// the notice; delivery failures are only logged.
+func sendPostingNotice(client *http.Client, cfg config.Config, queue string, tweet scheduledTweet, n postingNotice, now time.Time) {
+	when := tweet.ScheduleTime
+	_, err := updateScheduledTweet(queue, tweet.ID, func(t *scheduledTweet) {
+		if t.ScheduleTime.Equal(when) {
+			t.NoticeSentFor = &when
+		}
+	})
+	if err != nil {
+		if !errors.Is(err, errScheduledTweetGone) {
+			log.Printf("Error recording the notice for tweet %s: %v", tweet.ID, err)
+		}
+		return
+	}
+
+	hookTweet := scheduledHookTweet(tweet, queue, "")
+	cancel := cancelCommand(queue, tweet.ID)
+	title := "Posting in " + noticeLead(when.Sub(now))
+	msg := fmt.Sprintf("%s from the %s queue goes out at %s. To stop it, run: %s", tweet.ID, queueLabel(queue), when.Local().Format("15:04"), cancel)
+
+	ev := newHookEvent(cfg, eventTweetUpcoming, notification{Title: title, Text: msg})
+	ev.Tweet = &hookTweet
+	ev.CancelCommand = cancel
+	emitEvent(client, cfg, ev)
+	notifySlackChannel(client, cfg, notification{Title: title, Text: msg, Tweets: []notificationTweet{hookTweet}})
+	if n.Desktop {
+		if err := desktopNotify(title, tweet.postText()+"\n"+cancel); err != nil {
+			log.Print(decorate("⚠️", fmt.Sprintf("Failed to show the notice for tweet %s: %v", tweet.ID, err)))
+		}
+	}
+	say("🔔", "Tweet %s posts in %s; cancel with: %s", tweet.ID, noticeLead(when.Sub(now)), cancel)
+}
+
+// noticeLead renders the time left before posting in words, such as
+// "15 minutes" or "1h30m".
+func noticeLead(d time.Duration) string {
+	d = d.Round(time.Minute)
+	switch {
+	case d < time.Minute:
+		return "less than a minute"
+	case d == time.Minute:
+		return "1 minute"
+	case d < time.Hour:
+		return fmt.Sprintf("%d minutes", d/time.Minute)
+	}
+	return strings.TrimSuffix(d.String(), "0s")
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/kalikim/x-cli/config"
+)
+
+// notification is a message pushed to a webhook or the desktop, with the
+// tweets it is about.
+type notification struct {
+	Title  string              `json:"title"`
+	Text   string              `json:"text"`
+	Tweets []notificationTweet `json:"tweets,omitempty"`
+}
+
+// notificationTweet is the tweet object of notifications and webhook
+// events. The scheduling fields are set for tweets from the scheduler.
+type notificationTweet struct {
+	ID           string     `json:"id,omitempty"`
+	Text         string     `json:"text"`
+	Username     string     `json:"username,omitempty"`
+	URL          string     `json:"url,omitempty"`
+	CreatedAt    *time.Time `json:"created_at,omitempty"`
+	Queue        string     `json:"queue,omitempty"`
+	ScheduledID  string     `json:"scheduled_id,omitempty"`
+	ScheduledFor *time.Time `json:"scheduled_for,omitempty"`
+	HasMedia     bool       `json:"has_media,omitempty"`
+	Labels       []string   `json:"labels,omitempty"`
+	Campaign     string     `json:"campaign,omitempty"`
+}
+
+// notifyTarget is where a notification goes: a webhook URL, a Slack
+// incoming webhook or the desktop.
+type notifyTarget struct {
+	Kind string `json:"kind"`
+	URL  string `json:"url,omitempty"`
+}
+
+const (
+	notifyWebhook = "webhook"
+	notifySlack   = "slack"
+	notifyDesktop = "desktop"
+)
+
+// parseNotifyTarget checks a --notify kind and its URL.
+func parseNotifyTarget(kind, rawURL string) (notifyTarget, error) {
+	switch kind {
+	case notifyWebhook, notifySlack:
+		if rawURL == "" {
+			return notifyTarget{}, invalidInput(fmt.Errorf("--notify %s needs a URL (use --webhook https://...)", kind))
+		}
+		u, err := url.Parse(rawURL)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return notifyTarget{}, invalidInput(fmt.Errorf("invalid webhook URL %q", rawURL))
+		}
+		return notifyTarget{Kind: kind, URL: rawURL}, nil
+	case notifyDesktop:
+		if rawURL != "" {
+			return notifyTarget{}, invalidInput(errors.New("--webhook only applies to --notify webhook or slack"))
+		}
+		return notifyTarget{Kind: kind}, nil
+	}
+	return notifyTarget{}, invalidInput(fmt.Errorf("invalid --notify %q (use webhook, slack or desktop)", kind))
+}
+
+// String describes the target for listings, hiding webhook paths, which
+// usually embed a secret.
+func (t notifyTarget) String() string {
+	if t.Kind == notifyWebhook || t.Kind == notifySlack {
+		if u, err := url.Parse(t.URL); err == nil {
+			return t.Kind + " " + u.Host
+		}
+	}
+	return t.Kind
+}
+
+// sendNotification delivers ev to target: webhooks get the whole event,
+// Slack and the desktop its summary.
+func sendNotification(client *http.Client, target notifyTarget, ev hookEvent) error {
+	switch target.Kind {
+	case notifyWebhook:
+		return postWebhook(client, target.URL, ev)
+	case notifySlack:
+		return postSlack(client, target.URL, ev.notification)
+	case notifyDesktop:
+		return desktopNotify(ev.Title, ev.Text)
+	}
+	return fmt.Errorf("unknown notification target %q", target.Kind)
+}
+
+// postWebhook POSTs ev as JSON.
+func postWebhook(client *http.Client, webhookURL string, ev hookEvent) error {
+	return postJSON(client, webhookURL, ev)
+}
+
+// postSlack sends n to a Slack incoming webhook as a formatted message.
+func postSlack(client *http.Client, webhookURL string, n notification) error {
+	lines := []string{"*" + slackEscape(n.Title) + "*"}
+	if n.Text != "" {
+		lines = append(lines, slackEscape(n.Text))
+	}
+	for _, t := range n.Tweets {
+		quoted := "> " + strings.ReplaceAll(slackEscape(t.Text), "\n", "\n> ")
+		lines = append(lines, quoted)
+		if t.URL != "" {
+			lines = append(lines, fmt.Sprintf("<%s|View on X>", t.URL))
+		}
+	}
+	return postJSON(client, webhookURL, map[string]string{"text": strings.Join(lines, "\n")})
+}
+
+// notifySlackChannel sends n to the configured Slack webhook, if any. A
+// failed delivery is only logged: the tweet itself went out or failed
+// regardless.
+func notifySlackChannel(client *http.Client, cfg config.Config, n notification) {
+	if cfg.SlackWebhook == "" {
+		return
+	}
+	if err := postSlack(client, cfg.SlackWebhook, n); err != nil {
+		log.Printf("Error notifying Slack: %v", err)
+	}
+}
+
+// slackEscape escapes the characters Slack treats as markup.
+func slackEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
+// postJSON POSTs v as JSON to a webhook.
+func postJSON(client *http.Client, webhookURL string, v any) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("posting to webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// desktopNotify shows a notification with the platform's notifier.
+func desktopNotify(title, text string) error {
+	var name string
+	var args []string
+
+	switch runtime.GOOS {
+	case "darwin":
+		script := fmt.Sprintf("display notification %s with title %s", appleScriptString(text), appleScriptString(title))
+		name, args = "osascript", []string{"-e", script}
+	case "linux", "freebsd", "openbsd":
+		name, args = "notify-send", []string{"--app-name=x-cli", title, text}
+	default:
+		return fmt.Errorf("desktop notifications are not supported on %s (use --notify webhook)", runtime.GOOS)
+	}
+
+	bin, err := exec.LookPath(name)
+	if err != nil {
+		return fmt.Errorf("no desktop notifier found (%s)", name)
+	}
+	if out, err := exec.Command(bin, args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("%s: %v: %s", name, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func appleScriptString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
@@ -0,0 +1,231 @@
+// Package oauth1 signs HTTP requests with OAuth 1.0a HMAC-SHA1 (RFC 5849),
+// the scheme X uses for user-context API calls.
+package oauth1
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Credentials are the consumer (app) and token (user) key pairs a request is
+// signed with.
+type Credentials struct {
+	ConsumerKey    string
+	ConsumerSecret string
+	Token          string
+	TokenSecret    string
+}
+
+// Signer signs requests. The zero value uses the system clock and random
+// nonces; set Now or Nonce to correct clock skew or to reproduce a
+// signature.
+type Signer struct {
+	Now   func() time.Time
+	Nonce func() (string, error)
+}
+
+// SignRequest signs req with the default Signer.
+func SignRequest(req *http.Request, creds Credentials) error {
+	return Signer{}.SignRequest(req, creds)
+}
+
+// SignRequest sets req's Authorization header. Query parameters and, for
+// application/x-www-form-urlencoded bodies, form parameters are included in
+// the signature; the body is read through req.GetBody and left intact.
+func (s Signer) SignRequest(req *http.Request, creds Credentials) error {
+	params, err := formParams(req)
+	if err != nil {
+		return err
+	}
+
+	header, err := s.AuthorizationHeader(req.Method, req.URL.String(), params, creds)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", header)
+	return nil
+}
+
+// AuthorizationHeader returns the "OAuth ..." header value for a request to
+// rawURL with the given form parameters. Query parameters in rawURL are
+// signed too.
+func (s Signer) AuthorizationHeader(method, rawURL string, params url.Values, creds Credentials) (string, error) {
+	nonce, err := s.nonce()
+	if err != nil {
+		return "", err
+	}
+
+	oauthParams := map[string]string{
+		"oauth_consumer_key":     creds.ConsumerKey,
+		"oauth_nonce":            nonce,
+		"oauth_signature_method": "HMAC-SHA1",
+		"oauth_timestamp":        strconv.FormatInt(s.now().Unix(), 10),
+		"oauth_token":            creds.Token,
+		"oauth_version":          "1.0",
+	}
+
+	signed := url.Values{}
+	for k, vs := range params {
+		signed[k] = append(signed[k], vs...)
+	}
+	for k, v := range oauthParams {
+		signed.Add(k, v)
+	}
+
+	signature, err := Signature(method, rawURL, signed, creds.ConsumerSecret, creds.TokenSecret)
+	if err != nil {
+		return "", err
+	}
+	oauthParams["oauth_signature"] = signature
+
+	keys := make([]string, 0, len(oauthParams))
+	for k := range oauthParams {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	fields := make([]string, 0, len(keys))
+	for _, k := range keys {
+		fields = append(fields, fmt.Sprintf("%s=\"%s\"", PercentEncode(k), PercentEncode(oauthParams[k])))
+	}
+	return "OAuth " + strings.Join(fields, ", "), nil
+}
+
+// Signature returns the base64 HMAC-SHA1 signature of the request (RFC 5849
+// section 3.4.2). params must include the oauth_* protocol parameters.
+func Signature(method, rawURL string, params url.Values, consumerSecret, tokenSecret string) (string, error) {
+	base, err := BaseString(method, rawURL, params)
+	if err != nil {
+		return "", err
+	}
+
+	key := PercentEncode(consumerSecret) + "&" + PercentEncode(tokenSecret)
+	mac := hmac.New(sha1.New, []byte(key))
+	mac.Write([]byte(base))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// BaseString builds the signature base string (RFC 5849 section 3.4.1) from
+// the method, the URL with its query, and the remaining parameters.
+func BaseString(method, rawURL string, params url.Values) (string, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("parsing URL: %w", err)
+	}
+
+	all := url.Values{}
+	for k, vs := range parsed.Query() {
+		all[k] = append(all[k], vs...)
+	}
+	for k, vs := range params {
+		all[k] = append(all[k], vs...)
+	}
+
+	return strings.ToUpper(method) + "&" + PercentEncode(baseURL(parsed)) + "&" + PercentEncode(normalizeParams(all)), nil
+}
+
+// PercentEncode escapes s as RFC 5849 section 3.6 requires: everything but
+// unreserved characters, with spaces as %20.
+func PercentEncode(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+// baseURL is the scheme, host and path, lowercased where case does not
+// matter and without the default port (RFC 5849 section 3.4.1.2).
+func baseURL(u *url.URL) string {
+	scheme := strings.ToLower(u.Scheme)
+	host := strings.ToLower(u.Host)
+	if (scheme == "http" && strings.HasSuffix(host, ":80")) || (scheme == "https" && strings.HasSuffix(host, ":443")) {
+		host = host[:strings.LastIndex(host, ":")]
+	}
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	return scheme + "://" + host + path
+}
+
+// normalizeParams encodes names and values, sorts them by name then value
+// and joins them (RFC 5849 section 3.4.1.3.2).
+func normalizeParams(values url.Values) string {
+	var pairs [][2]string
+	for k, vs := range values {
+		for _, v := range vs {
+			pairs = append(pairs, [2]string{PercentEncode(k), PercentEncode(v)})
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i][0] != pairs[j][0] {
+			return pairs[i][0] < pairs[j][0]
+		}
+		return pairs[i][1] < pairs[j][1]
+	})
+
+	encoded := make([]string, len(pairs))
+	for i, p := range pairs {
+		encoded[i] = p[0] + "=" + p[1]
+	}
+	return strings.Join(encoded, "&")
+}
+
+// formParams reads the form parameters of a urlencoded request body.
+func formParams(req *http.Request) (url.Values, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if mediaType != "application/x-www-form-urlencoded" {
+		return nil, nil
+	}
+	if req.GetBody == nil {
+		return nil, fmt.Errorf("oauth1: cannot read the form body of %s %s without GetBody", req.Method, req.URL)
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, fmt.Errorf("oauth1: reading form body: %w", err)
+	}
+	return url.ParseQuery(string(bytes.TrimSpace(data)))
+}
+
+func (s Signer) now() time.Time {
+	if s.Now != nil {
+		return s.Now()
+	}
+	return time.Now()
+}
+
+func (s Signer) nonce() (string, error) {
+	if s.Nonce != nil {
+		return s.Nonce()
+	}
+	return NewNonce()
+}
+
+// NewNonce returns a random 128-bit hex nonce.
+func NewNonce() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generating nonce: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,213 @@
+package oauth1
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// xExample is the request from X's "Creating a signature" guide.
+var xExample = struct {
+	method, url, body string
+	creds             Credentials
+	nonce             string
+	timestamp         int64
+	signature         string
+}{
+	method: "POST",
+	url:    "https://api.twitter.com/1.1/statuses/update.json?include_entities=true",
+	body:   "status=Hello%20Ladies%20%2B%20Gentlemen%2C%20a%20signed%20OAuth%20request%21",
+	creds: Credentials{
+		ConsumerKey:    "xvz1evFS4wEEPTGEFPHBog",
+		ConsumerSecret: "kAcSOqF21Fu85e7zjz7ZN2U4ZRhfV3WpwPAoE3Z7kBw",
+		Token:          "370773112-GmHxMAgYyLbNEtIKZeRNFsMKPR9EyMZeS9weJAEb",
+		TokenSecret:    "LswwdoUaIvS8ltyTt5jkRh4J50vUPVVHtR2YPi5kE",
+	},
+	nonce:     "kYjzVBB8Y0ZFabxSWbWovY3uYSQ2pTgmZeNu2VS4cg",
+	timestamp: 1318622958,
+	signature: "hCtSmYh+iHYCEqBWrE7C7hYmtUk=",
+}
+
+func TestRFC5849PhotosExample(t *testing.T) {
+	// RFC 5849 section 1.2; the example sends no oauth_version.
+	params := url.Values{
+		"oauth_consumer_key":     {"dpf43f3p2l4k3l03"},
+		"oauth_token":            {"nnch734d00sl2jdk"},
+		"oauth_signature_method": {"HMAC-SHA1"},
+		"oauth_timestamp":        {"137131201"},
+		"oauth_nonce":            {"chapoH"},
+	}
+	rawURL := "http://photos.example.net/photos?file=vacation.jpg&size=original"
+
+	base, err := BaseString("GET", rawURL, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantBase := "GET&http%3A%2F%2Fphotos.example.net%2Fphotos&file%3Dvacation.jpg%26oauth_consumer_key%3Ddpf43f3p2l4k3l03" +
+		"%26oauth_nonce%3DchapoH%26oauth_signature_method%3DHMAC-SHA1%26oauth_timestamp%3D137131201" +
+		"%26oauth_token%3Dnnch734d00sl2jdk%26size%3Doriginal"
+	if base != wantBase {
+		t.Errorf("BaseString =\n%s\nwant\n%s", base, wantBase)
+	}
+
+	sig, err := Signature("GET", rawURL, params, "kd94hf93k423kf44", "pfkkdhi9sl3r4s00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The RFC prints MdpQcU8iPSUjWoN/UDMsK2sui9I=, which does not match its
+	// own base string and secrets; this is their HMAC-SHA1, computed
+	// independently.
+	if want := "QexuIj9vsVBRB7idJR+1SnjPpPg="; sig != want {
+		t.Errorf("Signature = %s, want %s", sig, want)
+	}
+}
+
+func TestRFC5849BaseStringExample(t *testing.T) {
+	// RFC 5849 section 3.4.1.1: query, body and protocol parameters with
+	// repeated names and encoded characters.
+	params := url.Values{
+		"c2":                     {""},
+		"a3":                     {"2 q"},
+		"oauth_consumer_key":     {"9djdj82h48djs9d2"},
+		"oauth_token":            {"kkk9d7dh3k39sjv7"},
+		"oauth_signature_method": {"HMAC-SHA1"},
+		"oauth_timestamp":        {"137131201"},
+		"oauth_nonce":            {"7d8f3e4a"},
+	}
+	base, err := BaseString("post", "http://example.com/request?b5=%3D%253D&a3=a&c%40=&a2=r%20b", params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "POST&http%3A%2F%2Fexample.com%2Frequest&a2%3Dr%2520b%26a3%3D2%2520q%26a3%3Da%26b5%3D%253D%25253D" +
+		"%26c%2540%3D%26c2%3D%26oauth_consumer_key%3D9djdj82h48djs9d2%26oauth_nonce%3D7d8f3e4a" +
+		"%26oauth_signature_method%3DHMAC-SHA1%26oauth_timestamp%3D137131201%26oauth_token%3Dkkk9d7dh3k39sjv7"
+	if base != want {
+		t.Errorf("BaseString =\n%s\nwant\n%s", base, want)
+	}
+}
+
+func TestXSignatureExample(t *testing.T) {
+	params := url.Values{
+		"status":                 {"Hello Ladies + Gentlemen, a signed OAuth request!"},
+		"oauth_consumer_key":     {xExample.creds.ConsumerKey},
+		"oauth_nonce":            {xExample.nonce},
+		"oauth_signature_method": {"HMAC-SHA1"},
+		"oauth_timestamp":        {"1318622958"},
+		"oauth_token":            {xExample.creds.Token},
+		"oauth_version":          {"1.0"},
+	}
+	sig, err := Signature(xExample.method, xExample.url, params, xExample.creds.ConsumerSecret, xExample.creds.TokenSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sig != xExample.signature {
+		t.Errorf("Signature = %s, want %s", sig, xExample.signature)
+	}
+}
+
+func TestSignerSignRequest(t *testing.T) {
+	req, err := http.NewRequest(xExample.method, xExample.url, strings.NewReader(xExample.body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	signer := Signer{
+		Now:   func() time.Time { return time.Unix(xExample.timestamp, 0) },
+		Nonce: func() (string, error) { return xExample.nonce, nil },
+	}
+	if err := signer.SignRequest(req, xExample.creds); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `OAuth oauth_consumer_key="xvz1evFS4wEEPTGEFPHBog", ` +
+		`oauth_nonce="kYjzVBB8Y0ZFabxSWbWovY3uYSQ2pTgmZeNu2VS4cg", ` +
+		`oauth_signature="hCtSmYh%2BiHYCEqBWrE7C7hYmtUk%3D", ` +
+		`oauth_signature_method="HMAC-SHA1", ` +
+		`oauth_timestamp="1318622958", ` +
+		`oauth_token="370773112-GmHxMAgYyLbNEtIKZeRNFsMKPR9EyMZeS9weJAEb", ` +
+		`oauth_version="1.0"`
+	if got := req.Header.Get("Authorization"); got != want {
+		t.Errorf("Authorization =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestPercentEncode(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"abcABC123-._~", "abcABC123-._~"},
+		{"Ladies + Gentlemen", "Ladies%20%2B%20Gentlemen"},
+		{"!*'()", "%21%2A%27%28%29"},
+		{":/?#[]@$&,;=%", "%3A%2F%3F%23%5B%5D%40%24%26%2C%3B%3D%25"},
+		{"a b", "a%20b"},
+		{"☃", "%E2%98%83"},
+		{"café", "caf%C3%A9"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := PercentEncode(tt.in); got != tt.want {
+			t.Errorf("PercentEncode(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestBaseURL(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"HTTP://EXAMPLE.com:80/r%20v/X?id=123", "http://example.com/r%20v/X"},
+		{"https://www.example.net:443/", "https://www.example.net/"},
+		{"https://api.x.com:8443/2/tweets", "https://api.x.com:8443/2/tweets"},
+		{"http://example.com:443/a", "http://example.com:443/a"},
+		{"https://example.com", "https://example.com/"},
+	}
+	for _, tt := range tests {
+		u, err := url.Parse(tt.in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := baseURL(u); got != tt.want {
+			t.Errorf("baseURL(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestFormParamsLeavesBodyReadable(t *testing.T) {
+	req, err := http.NewRequest("POST", "https://api.twitter.com/1.1/media/upload.json", strings.NewReader(xExample.body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+
+	params, err := formParams(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := params.Get("status"), "Hello Ladies + Gentlemen, a signed OAuth request!"; got != want {
+		t.Errorf("status = %q, want %q", got, want)
+	}
+
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != xExample.body {
+		t.Errorf("body after formParams = %q, want %q", body, xExample.body)
+	}
+}
+
+func TestFormParamsIgnoresOtherBodies(t *testing.T) {
+	req, err := http.NewRequest("POST", "https://api.twitter.com/2/tweets", strings.NewReader(`{"text":"a=b"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	params, err := formParams(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(params) != 0 {
+		t.Errorf("formParams of a JSON body = %v, want none", params)
+	}
+}
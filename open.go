@@ -0,0 +1,72 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	handlePattern  = regexp.MustCompile(`^@?[A-Za-z0-9_]{1,15}$`)
+	tweetIDPattern = regexp.MustCompile(`^\d+$`)
+)
+
+func newOpenCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "open <tweet-id|@handle|last>",
+		Short: "Open a tweet or profile on x.com in the default browser",
+		Long: `Open a tweet or profile on x.com in the default browser.
+
+"last" opens the most recently posted tweet from history. $BROWSER, when
+set, is used instead of the platform's default.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			target, err := openTarget(args[0])
+			if err != nil {
+				return err
+			}
+
+			say("🌐", "Opening %s", target)
+			return openBrowser(target)
+		},
+	}
+}
+
+// openTarget resolves an open argument to its x.com URL.
+func openTarget(arg string) (string, error) {
+	switch {
+	case arg == "last":
+		entry, err := lastPostedTweet()
+		if err != nil {
+			return "", err
+		}
+		return tweetURL("", entry.TweetID), nil
+	case tweetIDPattern.MatchString(arg):
+		return tweetURL("", arg), nil
+	case strings.HasPrefix(arg, "@") && handlePattern.MatchString(arg):
+		return "https://x.com/" + strings.TrimPrefix(arg, "@"), nil
+	}
+	return "", invalidInput(fmt.Errorf("%q is not a tweet ID, @handle or \"last\"", arg))
+}
+
+// lastPostedTweet returns the most recently posted tweet in history.
+func lastPostedTweet() (historyEntry, error) {
+	entries, err := loadHistory()
+	if err != nil {
+		return historyEntry{}, fmt.Errorf("loading history: %w", err)
+	}
+
+	var last historyEntry
+	for _, entry := range entries {
+		if entry.TweetID != "" && !entry.PostedAt.Before(last.PostedAt) {
+			last = entry
+		}
+	}
+	if last.TweetID == "" {
+		return historyEntry{}, errors.New("no posted tweets in history yet")
+	}
+	return last, nil
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/kalikim/x-cli/config"
+)
+
+// outputStyle decides how status messages are decorated. It is configured
+// once from --no-color, --no-emoji, NO_COLOR and TERM before a command runs.
+type outputStyle struct {
+	color bool
+	emoji bool
+}
+
+var style = outputStyle{emoji: true}
+
+// iconStyle describes how an icon degrades: the plain-text label printed when
+// emoji are disabled (empty drops the icon) and the ANSI color of the message.
+type iconStyle struct {
+	label string
+	color string
+}
+
+var icons = map[string]iconStyle{
+	"✅":  {label: "ok:", color: "32"},
+	"⚠️": {label: "warning:", color: "33"},
+	"❌":  {label: "error:", color: "31"},
+	"❎":  {label: "cancelled:", color: "33"},
+	"💡":  {label: "hint:", color: "36"},
+}
+
+func configureOutput(noColor, noEmoji bool) {
+	dumb := os.Getenv("TERM") == "dumb"
+	style.color = !noColor && !dumb && os.Getenv("NO_COLOR") == "" && isTerminal(os.Stdout)
+	style.emoji = !noEmoji && !dumb
+
+	config.Warn = func(msg string) {
+		log.Print(decorate("⚠️", msg))
+	}
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// decorate prefixes text with icon, or its plain-text label when emoji are
+// off, and colors the result when the terminal supports it.
+func decorate(icon, text string) string {
+	is := icons[icon]
+
+	prefix := icon
+	if !style.emoji {
+		prefix = is.label
+	}
+
+	line := text
+	if prefix != "" {
+		line = prefix + " " + text
+	}
+
+	if style.color && is.color != "" {
+		line = "\x1b[" + is.color + "m" + line + "\x1b[0m"
+	}
+	return line
+}
+
+// say prints a decorated status line to stdout.
+func say(icon, format string, args ...any) {
+	if jsonLog != nil {
+		jsonLog.Log(context.Background(), iconLevel(icon), fmt.Sprintf(format, args...))
+		return
+	}
+	if gha.enabled && (icon == "❌" || icon == "⚠️") {
+		ghaCommand(map[string]string{"❌": "error", "⚠️": "warning"}[icon], fmt.Sprintf(format, args...))
+		return
+	}
+	fmt.Println(decorate(icon, fmt.Sprintf(format, args...)))
+}
+
+// jsonLog, when set, receives status messages as JSON lines instead of
+// decorated text, for log collectors such as a container runtime's.
+var jsonLog *slog.Logger
+
+// configureJSONLogs sends status messages, log output and config warnings
+// to stdout as one JSON object per line: time, level and msg.
+func configureJSONLogs() {
+	jsonLog = slog.New(slog.NewJSONHandler(os.Stdout, nil))
+	style.color, style.emoji = false, false
+	log.SetFlags(0)
+	log.SetOutput(jsonLogWriter{})
+	config.Warn = func(msg string) {
+		jsonLog.Warn(msg)
+	}
+}
+
+func iconLevel(icon string) slog.Level {
+	switch icon {
+	case "❌":
+		return slog.LevelError
+	case "⚠️":
+		return slog.LevelWarn
+	}
+	return slog.LevelInfo
+}
+
+// jsonLogWriter turns log.Printf calls, which report errors, into JSON
+// lines.
+type jsonLogWriter struct{}
+
+func (jsonLogWriter) Write(p []byte) (int, error) {
+	jsonLog.Error(strings.TrimSpace(string(p)))
+	return len(p), nil
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"strings"
+
+	"github.com/kalikim/x-cli/config"
+)
+
+const overlayJPEGQuality = 92
+
+var (
+	captionFonts     = []string{"regular", "bold"}
+	captionPositions = []string{"top", "center", "bottom"}
+	// captionSizes is the height of a line of caption text as a share of
+	// the image height.
+	captionSizes = map[string]float64{"small": 1.0 / 18, "medium": 1.0 / 12, "large": 1.0 / 8}
+)
+
+// overlayable reports whether images of mimeType can get a caption or a
+// watermark.
+func overlayable(mimeType string) bool {
+	return mimeType == "image/jpeg" || mimeType == "image/png"
+}
+
+// applyImageOverlays draws the caption and then the watermark of cfg onto
+// a JPEG or PNG image and returns it re-encoded in the same format. Other
+// media, and any image when neither is set, are returned unchanged. Phone
+// photos are turned upright first, so top and bottom are the ones people
+// see.
+func applyImageOverlays(cfg config.Config, mimeType string, data []byte) ([]byte, error) {
+	if (cfg.CaptionOverlay.Text == "" && cfg.Watermark.Image == "") || !overlayable(mimeType) {
+		return data, nil
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("decoding image: %w", err)
+	}
+	if mimeType == "image/jpeg" {
+		img = orientImage(img, jpegOrientation(data))
+	}
+	b := img.Bounds()
+	canvas := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(canvas, canvas.Bounds(), img, b.Min, draw.Src)
+
+	if cfg.CaptionOverlay.Text != "" {
+		if err := drawCaption(canvas, cfg.CaptionOverlay); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.Watermark.Image != "" {
+		if err := drawWatermark(canvas, cfg.Watermark); err != nil {
+			return nil, err
+		}
+	}
+
+	var out bytes.Buffer
+	if mimeType == "image/png" {
+		err = png.Encode(&out, canvas)
+	} else {
+		err = jpeg.Encode(&out, canvas, &jpeg.Options{Quality: overlayJPEGQuality})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("encoding image: %w", err)
+	}
+	return out.Bytes(), nil
+}
+
+// resolveCaptionOverlay fills in the defaults of c and checks its text and
+// style.
+func resolveCaptionOverlay(c config.CaptionOverlay) (config.CaptionOverlay, error) {
+	for field, value := range map[*string]string{
+		&c.Font: "bold", &c.Size: "medium", &c.Position: "bottom", &c.Color: "#ffffff", &c.Background: "#00000099",
+	} {
+		if *field == "" {
+			*field = value
+		}
+	}
+
+	for _, r := range renderRunes(c.Text) {
+		if r != '\n' && (r < 0x20 || r > 0x7e) {
+			return c, invalidInput(fmt.Errorf("caption overlay text can only use ASCII characters, not %q", r))
+		}
+	}
+	if !hasLabels(captionFonts, []string{c.Font}) {
+		return c, invalidInput(fmt.Errorf("invalid caption font %q (use %s)", c.Font, strings.Join(captionFonts, ", ")))
+	}
+	if _, ok := captionSizes[c.Size]; !ok {
+		return c, invalidInput(fmt.Errorf("invalid caption size %q (use small, medium, large)", c.Size))
+	}
+	if !hasLabels(captionPositions, []string{c.Position}) {
+		return c, invalidInput(fmt.Errorf("invalid caption position %q (use %s)", c.Position, strings.Join(captionPositions, ", ")))
+	}
+	if col, err := parseOverlayColor(c.Color); err != nil || col.A != 0xff {
+		return c, invalidInput(fmt.Errorf("invalid caption color %q (use #rrggbb)", c.Color))
+	}
+	if c.Background != "none" {
+		if _, err := parseOverlayColor(c.Background); err != nil {
+			return c, invalidInput(fmt.Errorf("invalid caption background %q (use #rrggbb, #rrggbbaa or none)", c.Background))
+		}
+	}
+	return c, nil
+}
+
+// parseOverlayColor parses #rrggbb or #rrggbbaa.
+func parseOverlayColor(s string) (color.NRGBA, error) {
+	raw, err := hex.DecodeString(strings.TrimPrefix(s, "#"))
+	if err != nil || !strings.HasPrefix(s, "#") || (len(raw) != 3 && len(raw) != 4) {
+		return color.NRGBA{}, errors.New("invalid color")
+	}
+	col := color.NRGBA{R: raw[0], G: raw[1], B: raw[2], A: 0xff}
+	if len(raw) == 4 {
+		col.A = raw[3]
+	}
+	return col, nil
+}
+
+// drawCaption draws the text of c centered across canvas on a full-width
+// band. The glyphs are scaled up to the size's share of the image height,
+// and down again until the wrapped text fits.
+func drawCaption(canvas *image.RGBA, c config.CaptionOverlay) error {
+	c, err := resolveCaptionOverlay(c)
+	if err != nil {
+		return err
+	}
+	textColor, _ := parseOverlayColor(c.Color)
+
+	b := canvas.Bounds()
+	margin := int(float64(min(b.Dx(), b.Dy())) * watermarkMargin)
+	px := max(1, int(captionSizes[c.Size]*float64(b.Dy())/renderGlyphHeight+0.5))
+	var lines []string
+	for ; ; px-- {
+		lines = wrapRenderText(c.Text, max(1, (b.Dx()-2*margin)/(renderGlyphWidth*px)))
+		fits := len(lines)*renderGlyphHeight*px <= b.Dy()-2*margin
+		for _, line := range lines {
+			fits = fits && len(line)*renderGlyphWidth*px <= b.Dx()-2*margin
+		}
+		if fits || px == 1 {
+			break
+		}
+	}
+
+	lineHeight := renderGlyphHeight * px
+	height := len(lines) * lineHeight
+	top := b.Max.Y - margin - height
+	switch c.Position {
+	case "top":
+		top = b.Min.Y + margin
+	case "center":
+		top = b.Min.Y + (b.Dy()-height)/2
+	}
+
+	if c.Background != "none" {
+		background, _ := parseOverlayColor(c.Background)
+		band := image.Rect(b.Min.X, top-lineHeight/4, b.Max.X, top+height+lineHeight/4).Intersect(b)
+		draw.Draw(canvas, band, image.NewUniform(background), image.Point{}, draw.Over)
+	}
+
+	// The pixel font has one weight; bold is the text drawn again a few
+	// pixels to the right, like the render command's.
+	weight := 1
+	if c.Font == "bold" {
+		weight = max(2, px/4+1)
+	}
+	rc := &renderCanvas{img: canvas, scale: 1}
+	col := color.RGBA{R: textColor.R, G: textColor.G, B: textColor.B, A: 0xff}
+	for i, line := range lines {
+		x := b.Min.X + (b.Dx()-len(line)*renderGlyphWidth*px)/2
+		for dx := 0; dx < weight; dx++ {
+			rc.text(x+dx, top+i*lineHeight, line, col, px, false)
+		}
+	}
+	return nil
+}
+
+// jpegOrientation returns the EXIF orientation of a JPEG, or 1 when it
+// has none.
+func jpegOrientation(data []byte) uint16 {
+	i := 2
+	for i+4 <= len(data) && data[i] == 0xFF {
+		marker := data[i+1]
+		if marker == 0xDA {
+			break
+		}
+		end := i + 2 + int(binary.BigEndian.Uint16(data[i+2:]))
+		if end > len(data) {
+			break
+		}
+		if marker == 0xE1 {
+			if o := exifOrientation(data[i+4 : end]); o != 0 {
+				return o
+			}
+		}
+		i = end
+	}
+	return 1
+}
+
+// orientImage applies an EXIF orientation, returning the image as it is
+// meant to be displayed.
+func orientImage(img image.Image, orientation uint16) image.Image {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+
+	out := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2: // mirrored
+				sx, sy = w-1-x, y
+			case 3: // upside down
+				sx, sy = w-1-x, h-1-y
+			case 4: // mirrored upside down
+				sx, sy = x, h-1-y
+			case 5: // mirrored, rotated
+				sx, sy = y, x
+			case 6: // rotated 90° clockwise
+				sx, sy = y, h-1-x
+			case 7: // mirrored, rotated the other way
+				sx, sy = w-1-y, h-1-x
+			case 8: // rotated 90° counter-clockwise
+				sx, sy = w-1-y, x
+			}
+			out.Set(x, y, img.At(b.Min.X+sx, b.Min.Y+sy))
+		}
+	}
+	return out
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+const schedulerStateFile = "scheduler_state.json"
+
+// schedulerState holds queue-wide switches that the daemon re-reads on every
+// check.
+type schedulerState struct {
+	Paused   bool      `json:"paused"`
+	PausedAt time.Time `json:"paused_at,omitempty"`
+	Reason   string    `json:"reason,omitempty"`
+	// PausedQueues are the named queues paused on their own with
+	// `scheduler pause --queue`.
+	PausedQueues map[string]queuePause `json:"paused_queues,omitempty"`
+}
+
+type queuePause struct {
+	PausedAt time.Time `json:"paused_at"`
+	Reason   string    `json:"reason,omitempty"`
+}
+
+// queuePaused reports whether queue is held, either with the whole
+// scheduler or on its own.
+func (s schedulerState) queuePaused(queue string) bool {
+	_, ok := s.PausedQueues[queue]
+	return s.Paused || ok
+}
+
+// pauseMessage describes the pause that holds queue, or returns "" when
+// the queue is not paused.
+func (s schedulerState) pauseMessage(queue string) string {
+	what, at, reason := "Scheduler", s.PausedAt, s.Reason
+	if !s.Paused {
+		p, ok := s.PausedQueues[queue]
+		if !ok {
+			return ""
+		}
+		what, at, reason = "The "+queueLabel(queue)+" queue", p.PausedAt, p.Reason
+	}
+	msg := what + " paused since " + at.Format("2006-01-02 15:04:05")
+	if reason != "" {
+		msg += " (" + reason + ")"
+	}
+	return msg
+}
+
+func loadSchedulerState() (schedulerState, error) {
+	var state schedulerState
+
+	data, err := readStore(schedulerStateFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return state, nil
+		}
+		return state, err
+	}
+
+	if err := json.Unmarshal(data, &state); err != nil {
+		return state, err
+	}
+
+	return state, nil
+}
+
+func saveSchedulerState(state schedulerState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeStore(schedulerStateFile, data)
+}
+
+// pauseScheduler pauses a single tweet of queue when tweetID is not empty,
+// otherwise the named queue, or every queue when queue is the default one.
+func pauseScheduler(queue, tweetID, reason string) error {
+	if tweetID != "" {
+		if err := setTweetPaused(queue, tweetID, true); err != nil {
+			return err
+		}
+		say("⏸️", "Paused scheduled tweet: %s", tweetID)
+		return nil
+	}
+
+	state, err := loadSchedulerState()
+	if err != nil {
+		return fmt.Errorf("loading scheduler state: %w", err)
+	}
+
+	if queue != "" {
+		if state.PausedQueues == nil {
+			state.PausedQueues = map[string]queuePause{}
+		}
+		state.PausedQueues[queue] = queuePause{PausedAt: time.Now(), Reason: reason}
+	} else {
+		state.Paused = true
+		state.PausedAt = time.Now()
+		state.Reason = reason
+	}
+
+	if err := saveSchedulerState(state); err != nil {
+		return fmt.Errorf("saving scheduler state: %w", err)
+	}
+
+	if queue != "" {
+		say("⏸️", "The %s queue is paused; the daemon will not post from it until resumed", queueLabel(queue))
+		return nil
+	}
+	say("⏸️", "Scheduler paused; the daemon will not post until resumed")
+	return nil
+}
+
+// resumeScheduler resumes a single tweet of queue when tweetID is not
+// empty, otherwise the named queue, or the whole scheduler and every queue
+// when queue is the default one.
+func resumeScheduler(queue, tweetID string) error {
+	if tweetID != "" {
+		if err := setTweetPaused(queue, tweetID, false); err != nil {
+			return err
+		}
+		say("▶️", "Resumed scheduled tweet: %s", tweetID)
+		return nil
+	}
+
+	if queue != "" {
+		state, err := loadSchedulerState()
+		if err != nil {
+			return fmt.Errorf("loading scheduler state: %w", err)
+		}
+		if _, ok := state.PausedQueues[queue]; !ok {
+			return invalidInput(fmt.Errorf("the %s queue is not paused", queueLabel(queue)))
+		}
+		delete(state.PausedQueues, queue)
+		if err := saveSchedulerState(state); err != nil {
+			return fmt.Errorf("saving scheduler state: %w", err)
+		}
+		say("▶️", "The %s queue resumed", queueLabel(queue))
+		if state.Paused {
+			say("💡", "The whole scheduler is still paused; run 'x-cli scheduler resume' to resume it")
+		}
+		return nil
+	}
+
+	if err := saveSchedulerState(schedulerState{}); err != nil {
+		return fmt.Errorf("saving scheduler state: %w", err)
+	}
+
+	say("▶️", "Scheduler resumed")
+	return nil
+}
+
+func setTweetPaused(queue, tweetID string, paused bool) error {
+	err := updateScheduledTweets(queue, func(tweets []scheduledTweet) ([]scheduledTweet, error) {
+		found := false
+		for i := range tweets {
+			if tweets[i].ID == tweetID {
+				tweets[i].Paused = paused
+				found = true
+			}
+		}
+
+		if !found {
+			return nil, fmt.Errorf("tweet with ID %s not found in the %s queue", tweetID, queueLabel(queue))
+		}
+		return tweets, nil
+	})
+	if err != nil {
+		return fmt.Errorf("updating scheduled tweet: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,458 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kalikim/x-cli/config"
+	"github.com/spf13/cobra"
+)
+
+// contentPlan is a week of tweets written as YAML. Applying it makes the
+// queue hold exactly its slots for that week.
+type contentPlan struct {
+	// Name marks the tweets the plan owns; it defaults to the file name.
+	Name string `json:"name"`
+	// Week is a date in the planned week, "this" or "next". Weeks run
+	// Monday to Sunday.
+	Week     string     `json:"week"`
+	Timezone string     `json:"timezone"`
+	Queue    string     `json:"queue"`
+	Profile  string     `json:"profile"`
+	Labels   []string   `json:"labels"`
+	Campaign string     `json:"campaign"`
+	Slots    []planSlot `json:"slots"`
+}
+
+// planSlot is one tweet of a plan, on a weekday or date of the plan week.
+type planSlot struct {
+	// ID keeps a slot's identity when its time changes; without it a
+	// moved slot is removed and added again.
+	ID       string   `json:"id"`
+	Day      string   `json:"day"`
+	Date     string   `json:"date"`
+	Time     string   `json:"time"`
+	Text     string   `json:"text"`
+	Media    string   `json:"media"`
+	Labels   []string `json:"labels"`
+	Campaign string   `json:"campaign"`
+	Tags     []string `json:"tags"`
+}
+
+// planChange is one difference between a plan and its queue.
+type planChange struct {
+	Kind   byte // '+', '~' or '-'
+	Key    string
+	Tweet  scheduledTweet
+	Fields []string
+}
+
+var planWeekdays = map[string]time.Weekday{
+	"monday": time.Monday, "tuesday": time.Tuesday, "wednesday": time.Wednesday,
+	"thursday": time.Thursday, "friday": time.Friday, "saturday": time.Saturday,
+	"sunday": time.Sunday,
+}
+
+func newPlanCmd() *cobra.Command {
+	planCmd := &cobra.Command{
+		Use:   "plan",
+		Short: "Schedule a week of tweets from a YAML content plan",
+		Long: "A content plan is a YAML file listing a week's tweets by day and time:\n\n" +
+			"  name: weekly\n" +
+			"  week: next\n" +
+			"  timezone: Europe/Berlin\n" +
+			"  labels: [plan]\n" +
+			"  slots:\n" +
+			"    - id: launch\n" +
+			"      day: monday\n" +
+			"      time: \"09:00\"\n" +
+			"      text: We're live!\n" +
+			"      media: banner.png\n\n" +
+			"'plan apply' adds, changes and removes scheduled tweets until the queue\n" +
+			"matches the plan. Tweets scheduled by other means are never touched.",
+	}
+
+	var diffOnly bool
+	applyCmd := &cobra.Command{
+		Use:   "apply <plan.yaml>",
+		Short: "Reconcile the queue with a content plan",
+		Example: `  x-cli plan apply week42.yaml --diff
+  x-cli plan apply week42.yaml`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg := config.LoadConfig()
+			plan, err := loadContentPlan(args[0])
+			if err != nil {
+				return err
+			}
+			return applyContentPlan(cfg, plan, diffOnly)
+		},
+	}
+	applyCmd.Flags().BoolVar(&diffOnly, "diff", false, "Show the changes without applying them")
+
+	planCmd.AddCommand(applyCmd)
+	return planCmd
+}
+
+func loadContentPlan(path string) (contentPlan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return contentPlan{}, invalidInput(err)
+	}
+	doc, err := parseYAML(data)
+	if err != nil {
+		return contentPlan{}, invalidInput(fmt.Errorf("%s: %w", path, err))
+	}
+	raw, err := json.Marshal(doc)
+	if err != nil {
+		return contentPlan{}, err
+	}
+
+	var plan contentPlan
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&plan); err != nil {
+		return contentPlan{}, invalidInput(fmt.Errorf("%s: %s", path, strings.TrimPrefix(err.Error(), "json: ")))
+	}
+	if plan.Name == "" {
+		plan.Name = strings.ToLower(strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)))
+	}
+	if !labelPattern.MatchString(plan.Name) {
+		return contentPlan{}, invalidInput(fmt.Errorf("%s: invalid plan name %q (use lowercase letters, digits, '-' and '_')", path, plan.Name))
+	}
+	if err := validateQueueName(plan.Queue); err != nil {
+		return contentPlan{}, err
+	}
+	return plan, nil
+}
+
+// planWeek returns the location of the plan and the Monday its week starts.
+func planWeek(plan contentPlan, now time.Time) (*time.Location, time.Time, error) {
+	loc := time.Local
+	if plan.Timezone != "" {
+		var err error
+		if loc, err = time.LoadLocation(plan.Timezone); err != nil {
+			return nil, time.Time{}, invalidInput(fmt.Errorf("invalid timezone %q", plan.Timezone))
+		}
+	}
+
+	now = now.In(loc)
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	switch plan.Week {
+	case "", "this":
+	case "next":
+		day = day.AddDate(0, 0, 7)
+	default:
+		d, err := time.ParseInLocation("2006-01-02", plan.Week, loc)
+		if err != nil {
+			return nil, time.Time{}, invalidInput(fmt.Errorf("invalid week %q (use a date such as 2025-07-07, \"this\" or \"next\")", plan.Week))
+		}
+		day = d
+	}
+	// Weekday counts from Sunday; the plan week starts on Monday.
+	offset := (int(day.Weekday()) + 6) % 7
+	return loc, day.AddDate(0, 0, -offset), nil
+}
+
+// slotTime resolves when slot is due in the week starting at monday.
+func slotTime(slot planSlot, monday time.Time) (time.Time, error) {
+	clock, err := time.Parse("15:04", slot.Time)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q (use HH:MM)", slot.Time)
+	}
+
+	var day time.Time
+	switch {
+	case slot.Day != "" && slot.Date != "":
+		return time.Time{}, errors.New("set day or date, not both")
+	case slot.Day != "":
+		weekday, ok := planWeekdays[strings.ToLower(slot.Day)]
+		if !ok {
+			return time.Time{}, fmt.Errorf("invalid day %q (use monday to sunday)", slot.Day)
+		}
+		day = monday.AddDate(0, 0, (int(weekday)+6)%7)
+	case slot.Date != "":
+		if day, err = time.ParseInLocation("2006-01-02", slot.Date, monday.Location()); err != nil {
+			return time.Time{}, fmt.Errorf("invalid date %q (use YYYY-MM-DD)", slot.Date)
+		}
+		if day.Before(monday) || !day.Before(monday.AddDate(0, 0, 7)) {
+			return time.Time{}, fmt.Errorf("date %s is not in the week of %s", slot.Date, monday.Format("2006-01-02"))
+		}
+	default:
+		return time.Time{}, errors.New("needs a day or a date")
+	}
+	return time.Date(day.Year(), day.Month(), day.Day(), clock.Hour(), clock.Minute(), 0, 0, day.Location()), nil
+}
+
+// plannedTweets builds the tweets the plan wants, keyed by slot, with the
+// same checks as `batch`: every slot's text must fit and pass the banned
+// content and content rules of the plan's profile, so a bad plan fails
+// before any of it is applied. Slots already due are left out and returned
+// as skipped.
+func plannedTweets(cfg config.Config, plan contentPlan, monday, now time.Time) (map[string]scheduledTweet, []string, error) {
+	if len(plan.Slots) == 0 {
+		return nil, nil, invalidInput(fmt.Errorf("plan %s has no slots", plan.Name))
+	}
+	// Profiles may carry their own banned words and content rules.
+	pcfg, err := configForTweet(cfg, plan.Queue, plan.Profile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tweets := map[string]scheduledTweet{}
+	var skipped []string
+	for i, slot := range plan.Slots {
+		fail := func(err error) error {
+			return invalidInput(fmt.Errorf("slot %d: %w", i+1, err))
+		}
+		at, err := slotTime(slot, monday)
+		if err != nil {
+			return nil, nil, fail(err)
+		}
+		key := slot.ID
+		if key == "" {
+			key = at.Format("2006-01-02 15:04")
+		}
+		if _, dup := tweets[key]; dup || slices.Contains(skipped, key) {
+			return nil, nil, fail(fmt.Errorf("another slot is also %s (give them different ids)", key))
+		}
+
+		campaign := slot.Campaign
+		if campaign == "" {
+			campaign = plan.Campaign
+		}
+		tweet, err := batchTweet(pcfg, batchOp{
+			Text:     slot.Text,
+			Image:    slot.Media,
+			Profile:  plan.Profile,
+			Labels:   append(slices.Clone(plan.Labels), slot.Labels...),
+			Campaign: campaign,
+			Tags:     slot.Tags,
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("slot %s: %w", key, err)
+		}
+		if !at.After(now) {
+			skipped = append(skipped, key)
+			continue
+		}
+		tweet.ScheduleTime = at
+		tweet.Plan = plan.Name + "/" + key
+		tweets[key] = tweet
+	}
+	return tweets, skipped, nil
+}
+
+// diffContentPlan compares the planned tweets with the ones the plan owns
+// in the queue for the same week.
+func diffContentPlan(plan contentPlan, planned map[string]scheduledTweet, skipped []string, queued []scheduledTweet, monday time.Time) []planChange {
+	prefix := plan.Name + "/"
+	end := monday.AddDate(0, 0, 7)
+
+	var changes []planChange
+	seen := map[string]bool{}
+	for _, tweet := range queued {
+		key, ok := strings.CutPrefix(tweet.Plan, prefix)
+		if !ok || tweet.ScheduleTime.Before(monday) || !tweet.ScheduleTime.Before(end) {
+			continue
+		}
+		seen[key] = true
+		want, ok := planned[key]
+		switch {
+		case !ok && slices.Contains(skipped, key):
+			// The slot is already due; the daemon decides what happens.
+		case !ok:
+			changes = append(changes, planChange{Kind: '-', Key: key, Tweet: tweet})
+		default:
+			if fields := changedPlanFields(tweet, want); len(fields) > 0 {
+				updated := tweet
+				setPlanFields(&updated, want)
+				changes = append(changes, planChange{Kind: '~', Key: key, Tweet: updated, Fields: fields})
+			}
+		}
+	}
+	for key, tweet := range planned {
+		if !seen[key] {
+			changes = append(changes, planChange{Kind: '+', Key: key, Tweet: tweet})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		a, b := changes[i].Tweet.ScheduleTime, changes[j].Tweet.ScheduleTime
+		if !a.Equal(b) {
+			return a.Before(b)
+		}
+		return changes[i].Key < changes[j].Key
+	})
+	return changes
+}
+
+func changedPlanFields(have, want scheduledTweet) []string {
+	var fields []string
+	if !have.ScheduleTime.Equal(want.ScheduleTime) {
+		fields = append(fields, "time")
+	}
+	if have.Text != want.Text {
+		fields = append(fields, "text")
+	}
+	if have.Image != want.Image {
+		fields = append(fields, "media")
+	}
+	if !slices.Equal(have.Labels, want.Labels) {
+		fields = append(fields, "labels")
+	}
+	if have.Campaign != want.Campaign {
+		fields = append(fields, "campaign")
+	}
+	if have.Profile != want.Profile {
+		fields = append(fields, "profile")
+	}
+	return fields
+}
+
+// setPlanFields copies what a plan controls from want, keeping the state
+// the scheduler keeps, such as pauses and errors.
+func setPlanFields(tweet *scheduledTweet, want scheduledTweet) {
+	tweet.Text, tweet.Image, tweet.ScheduleTime = want.Text, want.Image, want.ScheduleTime
+	tweet.Labels, tweet.Campaign, tweet.Profile = want.Labels, want.Campaign, want.Profile
+	tweet.Mentions = want.Mentions
+}
+
+func applyContentPlan(cfg config.Config, plan contentPlan, diffOnly bool) error {
+	now := time.Now()
+	loc, monday, err := planWeek(plan, now)
+	if err != nil {
+		return err
+	}
+	planned, skipped, err := plannedTweets(cfg, plan, monday, now)
+	if err != nil {
+		return err
+	}
+	queued, err := loadScheduledTweets(plan.Queue)
+	if err != nil {
+		return fmt.Errorf("loading scheduled tweets: %w", err)
+	}
+	changes := diffContentPlan(plan, planned, skipped, queued, monday)
+
+	fmt.Printf("Plan %s for the week of %s (%s queue):\n\n", plan.Name, monday.Format("2006-01-02"), queueLabel(plan.Queue))
+	for _, key := range skipped {
+		fmt.Printf("  %s %s: already due, skipped\n", paintPlan('!', "!"), key)
+	}
+	for _, c := range changes {
+		printPlanChange(c, loc)
+	}
+	if len(changes) == 0 {
+		say("✅", "The queue already matches plan %s", plan.Name)
+		return nil
+	}
+	counts := map[byte]int{}
+	for _, c := range changes {
+		counts[c.Kind]++
+	}
+	fmt.Printf("\nPlan: %d to add, %d to change, %d to remove.\n", counts['+'], counts['~'], counts['-'])
+	if diffOnly {
+		return nil
+	}
+
+	if err := checkWritable(cfg); err != nil {
+		return err
+	}
+	pcfg, err := configForTweet(cfg, plan.Queue, plan.Profile)
+	if err != nil {
+		return err
+	}
+	if counts['+']+counts['~'] > 0 {
+		if err := checkAllowed(pcfg, actionSchedule); err != nil {
+			return err
+		}
+	}
+	if counts['-'] > 0 {
+		if err := checkAllowed(pcfg, actionCancel); err != nil {
+			return err
+		}
+	}
+
+	lastID := ""
+	for i, c := range changes {
+		if c.Kind == '+' {
+			// IDs come from the clock; make sure two adds never share one.
+			id := generateTweetID()
+			for id == lastID {
+				id = generateTweetID()
+			}
+			changes[i].Tweet.ID, lastID = id, id
+		}
+		if c.Kind == '+' || (c.Kind == '~' && slices.Contains(c.Fields, "text")) {
+			if err := pinMentions(apiClient(), pcfg, &changes[i].Tweet); err != nil {
+				say("⚠️", "Could not resolve mentions; they will not be checked before posting: %v", err)
+			}
+		}
+	}
+
+	// The whole plan is applied in one store update, so the daemon never
+	// sees half of it.
+	var removed []scheduledTweet
+	err = updateScheduledTweets(plan.Queue, func(tweets []scheduledTweet) ([]scheduledTweet, error) {
+		byID := map[string]planChange{}
+		for _, c := range changes {
+			if c.Kind != '+' {
+				byID[c.Tweet.ID] = c
+			}
+		}
+		removed = nil
+		var kept []scheduledTweet
+		for _, tweet := range tweets {
+			c, ok := byID[tweet.ID]
+			switch {
+			case !ok:
+				kept = append(kept, tweet)
+			case c.Kind == '~':
+				setPlanFields(&tweet, c.Tweet)
+				kept = append(kept, tweet)
+			default:
+				removed = append(removed, tweet)
+			}
+		}
+		for _, c := range changes {
+			if c.Kind == '+' {
+				kept = append(kept, c.Tweet)
+			}
+		}
+		return kept, nil
+	})
+	if err != nil {
+		return fmt.Errorf("saving scheduled tweets: %w", err)
+	}
+	for _, tweet := range removed {
+		removeScheduledTweetMedia(tweet)
+	}
+
+	say("✅", "Applied plan %s: %d added, %d changed, %d removed", plan.Name, counts['+'], counts['~'], counts['-'])
+	return nil
+}
+
+func printPlanChange(c planChange, loc *time.Location) {
+	at := c.Tweet.ScheduleTime.In(loc).Format("Mon 2006-01-02 15:04")
+	fmt.Printf("  %s %s  %s  %s\n", paintPlan(c.Kind, string(c.Kind)), at, c.Key, truncateText(c.Tweet.Text, 50))
+	if len(c.Fields) > 0 {
+		fmt.Printf("      changed: %s\n", strings.Join(c.Fields, ", "))
+	}
+}
+
+// paintPlan colors text for a change kind like a terraform plan: green
+// adds, yellow changes, red removals.
+func paintPlan(kind byte, text string) string {
+	colors := map[byte]string{'+': "32", '~': "33", '-': "31", '!': "36"}
+	if !style.color {
+		return text
+	}
+	return "\x1b[" + colors[kind] + "m" + text + "\x1b[0m"
+}
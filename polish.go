@@ -0,0 +1,124 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// polishText tidies copy pasted from documents: accented letters typed as a
+// letter plus a combining mark are composed (NFC), straight quotes become
+// curly, a double hyphen between words becomes an em dash, and runs of
+// spaces shrink to one. Links are left exactly as they are.
+func polishText(text string) string {
+	var out strings.Builder
+	last := 0
+	for _, loc := range countURLPattern.FindAllStringIndex(text, -1) {
+		out.WriteString(polishSegment(text[last:loc[0]], lastRune(out.String()), false))
+		out.WriteString(text[loc[0]:loc[1]])
+		last = loc[1]
+	}
+	out.WriteString(polishSegment(text[last:], lastRune(out.String()), true))
+	return out.String()
+}
+
+func lastRune(s string) rune {
+	runes := []rune(s)
+	if len(runes) == 0 {
+		return 0
+	}
+	return runes[len(runes)-1]
+}
+
+// polishSegment polishes text that holds no links; prev is the character
+// before it, which decides whether a leading quote opens or closes, and
+// final tells whether it ends the text.
+func polishSegment(text string, prev rune, final bool) string {
+	runes := composeLetters([]rune(text))
+
+	var out []rune
+	before := func() rune {
+		if len(out) > 0 {
+			return out[len(out)-1]
+		}
+		return prev
+	}
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		next := rune(0)
+		if i+1 < len(runes) {
+			next = runes[i+1]
+		}
+
+		switch {
+		case r == '"':
+			if opensQuote(before()) {
+				r = '“'
+			} else {
+				r = '”'
+			}
+		case r == '\'':
+			// A quote before a digit, as in '90s, is an apostrophe.
+			if opensQuote(before()) && !unicode.IsDigit(next) {
+				r = '‘'
+			} else {
+				r = '’'
+			}
+		case r == '-' && next == '-':
+			// "word--word" and "word -- word" are dashes; "--flag" is not.
+			end := i + 2
+			for end < len(runes) && runes[end] == '-' {
+				end++
+			}
+			after := rune(0)
+			if end < len(runes) {
+				after = runes[end]
+			}
+			b := before()
+			if (isWordRune(b) && isWordRune(after)) || (b != 0 && unicode.IsSpace(b) && (after == 0 || unicode.IsSpace(after))) {
+				out = append(out, '—')
+				i = end - 1
+				continue
+			}
+		case r == ' ' || r == '\t':
+			// Collapse runs of spaces and drop them at the end of a line.
+			end := i
+			for end < len(runes) && (runes[end] == ' ' || runes[end] == '\t') {
+				end++
+			}
+			if (end == len(runes) && final) || (end < len(runes) && runes[end] == '\n') {
+				i = end - 1
+				continue
+			}
+			out = append(out, ' ')
+			i = end - 1
+			continue
+		}
+		out = append(out, r)
+	}
+	return string(out)
+}
+
+// opensQuote reports whether a quote after r starts a quotation.
+func opensQuote(r rune) bool {
+	return r == 0 || unicode.IsSpace(r) || strings.ContainsRune("([{—–-/", r)
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// composeLetters replaces letters followed by combining marks with their
+// precomposed forms, mark by mark, so "e" + U+0301 becomes "é".
+func composeLetters(runes []rune) []rune {
+	out := make([]rune, 0, len(runes))
+	for _, r := range runes {
+		if n := len(out); n > 0 && unicode.Is(unicode.Mn, r) {
+			if composed, ok := composedLetters[[2]rune{out[n-1], r}]; ok {
+				out[n-1] = composed
+				continue
+			}
+		}
+		out = append(out, r)
+	}
+	return out
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/kalikim/x-cli/config"
+	"github.com/spf13/cobra"
+)
+
+// postNowWait is how long `scheduler post` waits for a running daemon to
+// report the tweet posted before leaving it to the daemon.
+const postNowWait = time.Minute
+
+func newSchedulerPostCmd(queue *string) *cobra.Command {
+	var now bool
+
+	cmd := &cobra.Command{
+		Use:   "post <tweet-id>",
+		Short: "Post a scheduled tweet now instead of at its scheduled time",
+		Long: "Post a queued tweet right away, keeping its scheduled time in the history.\n" +
+			"A running daemon posts it at once; otherwise this command posts it itself.\n" +
+			"Quiet hours do not hold it, but the queue's daily cap does. Without --now\n" +
+			"only a tweet that is already due is posted.",
+		Example: `  x-cli scheduler post tweet_1748768400000000000 --now
+  x-cli scheduler post tweet_1748768400000000000 --now --queue work`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			tweet, err := findScheduledTweet(*queue, args[0])
+			if err != nil {
+				return err
+			}
+			if !now && tweet.ScheduleTime.After(time.Now()) {
+				return invalidInput(fmt.Errorf("tweet %s is scheduled for %s; add --now to post it ahead of time",
+					tweet.ID, tweet.ScheduleTime.Local().Format("2006-01-02 15:04")))
+			}
+			return postScheduledNow(*queue, tweet)
+		},
+	}
+	cmd.Flags().BoolVar(&now, "now", false, "Post the tweet even though it is not due yet")
+	return cmd
+}
+
+func newSchedulerPostNextCmd(queue *string) *cobra.Command {
+	return &cobra.Command{
+		Use:   "post-next",
+		Short: "Post the next tweet in the queue now",
+		Long: "Post the queued tweet scheduled soonest right away, as `scheduler post --now`\n" +
+			"would. Paused tweets are skipped.",
+		Example: `  x-cli scheduler post-next
+  x-cli scheduler post-next --queue work`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			tweets, err := loadScheduledTweets(*queue)
+			if err != nil {
+				return fmt.Errorf("loading scheduled tweets: %w", err)
+			}
+			next, ok := nextQueuedTweet(tweets)
+			if !ok {
+				say("📭", "No tweets waiting in the %s queue", queueLabel(*queue))
+				return nil
+			}
+			return postScheduledNow(*queue, next)
+		},
+	}
+}
+
+// nextQueuedTweet returns the unpaused tweet scheduled soonest that is not
+// already being posted.
+func nextQueuedTweet(tweets []scheduledTweet) (scheduledTweet, bool) {
+	var waiting []scheduledTweet
+	for _, t := range tweets {
+		if !t.Paused && t.State == "" && t.PostedID == "" {
+			waiting = append(waiting, t)
+		}
+	}
+	if len(waiting) == 0 {
+		return scheduledTweet{}, false
+	}
+	sort.SliceStable(waiting, func(i, j int) bool { return waiting[i].ScheduleTime.Before(waiting[j].ScheduleTime) })
+	return waiting[0], true
+}
+
+func findScheduledTweet(queue, id string) (scheduledTweet, error) {
+	tweets, err := loadScheduledTweets(queue)
+	if err != nil {
+		return scheduledTweet{}, fmt.Errorf("loading scheduled tweets: %w", err)
+	}
+	for _, t := range tweets {
+		if t.ID == id {
+			return t, nil
+		}
+	}
+	return scheduledTweet{}, invalidInput(fmt.Errorf("tweet with ID %s not found in the %s queue", id, queueLabel(queue)))
+}
+
+// markPostNow flags a scheduled tweet for posting at the next check.
+func markPostNow(queue, id string) error {
+	_, err := updateScheduledTweet(queue, id, func(t *scheduledTweet) {
+		t.PostNow = true
+	})
+	if errors.Is(err, errScheduledTweetGone) {
+		return fmt.Errorf("tweet with ID %s not found in the %s queue", id, queueLabel(queue))
+	}
+	return err
+}
+
+// postScheduledNow posts tweet from queue ahead of its schedule. A running
+// daemon is asked to post it; otherwise this process takes the daemon lock
+// and makes one pass over the queue that posts only this tweet, with the
+// daemon's journal, history and notifications.
+func postScheduledNow(queue string, tweet scheduledTweet) error {
+	cfg := config.LoadConfig()
+	qcfg, err := configForTweet(cfg, queue, tweet.Profile)
+	if err != nil {
+		return err
+	}
+	if err := checkAllowed(qcfg, actionPost); err != nil {
+		return err
+	}
+	if tweet.Paused {
+		return invalidInput(fmt.Errorf("tweet %s is paused; resume it first with 'x-cli scheduler resume %s'", tweet.ID, tweet.ID))
+	}
+	state, err := loadSchedulerState()
+	if err != nil {
+		return fmt.Errorf("loading scheduler state: %w", err)
+	}
+	if state.Paused {
+		return invalidInput(errors.New("the scheduler is paused; resume it with 'x-cli scheduler resume' first"))
+	}
+	if state.queuePaused(queue) {
+		return invalidInput(fmt.Errorf("the %s queue is paused; resume it with 'x-cli scheduler resume --queue %s' first", queueLabel(queue), queue))
+	}
+
+	_, err = sendControl(controlRequest{Op: controlPostNow, Queue: queue, ID: tweet.ID})
+	if err == nil {
+		say("📤", "Asked the scheduler daemon to post %s now", tweet.ID)
+		return waitForDaemonPost(queue, tweet)
+	}
+	if !errors.Is(err, errNoDaemon) {
+		return err
+	}
+
+	if err := qcfg.Validate(); err != nil {
+		return err
+	}
+	if err := checkWritable(qcfg); err != nil {
+		return err
+	}
+	timing, err := resolveDaemonTiming(cfg.Daemon, daemonTimingFlags{})
+	if err != nil {
+		return err
+	}
+	unlock, err := acquireDaemonLock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := recoverFromJournal(); err != nil {
+		return fmt.Errorf("recovering from %s: %w", schedulerJournalFile, err)
+	}
+	if err := markPostNow(queue, tweet.ID); err != nil {
+		return err
+	}
+
+	session := newDaemonSession(false, 0, timing.StopTimeout)
+	session.manual = true
+	processQueue(apiClient(), cfg, queue, postingNotice{}, newStatusLine(), newRetryBackoff(timing), session)
+	if session.posted > 0 {
+		return nil
+	}
+
+	// Whatever held the tweet back, it goes out on schedule as before.
+	left, err := updateScheduledTweet(queue, tweet.ID, func(t *scheduledTweet) {
+		t.PostNow = false
+	})
+	if errors.Is(err, errScheduledTweetGone) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if left.LastError != "" {
+		return fmt.Errorf("tweet %s was not posted: %s", tweet.ID, left.LastError)
+	}
+	return fmt.Errorf("tweet %s was not posted; it stays scheduled for %s", tweet.ID, left.ScheduleTime.Local().Format("2006-01-02 15:04"))
+}
+
+// waitForDaemonPost follows the daemon posting tweet, reporting the posted
+// tweet or the error that holds it back.
+func waitForDaemonPost(queue string, tweet scheduledTweet) error {
+	deadline := time.Now().Add(postNowWait)
+	for time.Now().Before(deadline) {
+		time.Sleep(500 * time.Millisecond)
+
+		tweets, err := loadScheduledTweets(queue)
+		if err != nil {
+			return fmt.Errorf("loading scheduled tweets: %w", err)
+		}
+		var current *scheduledTweet
+		for i := range tweets {
+			if tweets[i].ID == tweet.ID {
+				current = &tweets[i]
+			}
+		}
+		if current == nil {
+			if entry, found, _ := historyEntryForKey(tweet.Key); found {
+				say("✅", "Posted %s: %s", tweet.ID, tweetURL("", entry.TweetID))
+			}
+			return nil
+		}
+		if current.LastError != "" && current.LastError != tweet.LastError {
+			return fmt.Errorf("the daemon could not post %s and will retry: %s", tweet.ID, current.LastError)
+		}
+	}
+	say("⏳", "The daemon has not posted %s yet; check 'x-cli scheduler status'", tweet.ID)
+	return nil
+}
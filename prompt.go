@@ -0,0 +1,43 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// confirm asks a yes/no question on the terminal, defaulting to no.
+func confirm(question string) (bool, error) {
+	fmt.Printf("%s [y/N]: ", question)
+
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && answer == "" {
+		return false, fmt.Errorf("reading answer: %w", err)
+	}
+
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+// previewTweet prints the tweet text in a box along with its length.
+func previewTweet(text string) {
+	if isRTLText(text) {
+		say("📝", "Preview (right to left):")
+	} else {
+		say("📝", "Preview:")
+	}
+	fmt.Println("┌────────────────────────────────────────")
+	for _, line := range strings.Split(text, "\n") {
+		fmt.Println("│ " + isolateBidi(line))
+	}
+	fmt.Println("└────────────────────────────────────────")
+	fmt.Printf("%d/%d characters\n", tweetLength(text), maxTweetLength)
+	if n := countBidiControls(text); n > 0 {
+		say("💡", "The text contains %d invisible direction mark(s), each counted as 2 characters", n)
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kalikim/x-cli/config"
+	"github.com/spf13/cobra"
+)
+
+const defaultScheduleFile = "scheduled_tweets.json"
+
+var queueNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// scheduleFile returns the file backing a queue. The unnamed default queue
+// keeps the original scheduled_tweets.json location.
+func scheduleFile(queue string) string {
+	if queue == "" {
+		return defaultScheduleFile
+	}
+	return "scheduled_tweets." + queue + ".json"
+}
+
+func validateQueueName(queue string) error {
+	if queue != "" && !queueNamePattern.MatchString(queue) {
+		return invalidInput(fmt.Errorf("invalid queue name %q (use lowercase letters, digits, '-' and '_')", queue))
+	}
+	return nil
+}
+
+func queueLabel(queue string) string {
+	if queue == "" {
+		return "default"
+	}
+	return queue
+}
+
+// queueProfile returns the profile configured for queue, or "" for the
+// default credentials.
+func queueProfile(cfg config.Config, queue string) string {
+	return cfg.Queues[queue].Profile
+}
+
+// configForQueue returns the configuration signed with the queue's profile.
+func configForQueue(cfg config.Config, queue string) (config.Config, error) {
+	qcfg, err := cfg.ForProfile(queueProfile(cfg, queue))
+	if err != nil {
+		return qcfg, fmt.Errorf("queue %s: %w", queueLabel(queue), err)
+	}
+	return qcfg, nil
+}
+
+// configForTweet returns the configuration for posting from queue, using
+// profile when set and the queue's own profile otherwise.
+func configForTweet(cfg config.Config, queue, profile string) (config.Config, error) {
+	if profile == "" {
+		return configForQueue(cfg, queue)
+	}
+	return cfg.ForProfile(profile)
+}
+
+// knownQueues lists the default queue, every queue declared in the config and
+// any queue that has a schedule file on disk or in the storage bucket, sorted
+// by name.
+func knownQueues(cfg config.Config) []string {
+	seen := map[string]bool{"": true}
+	queues := []string{""}
+
+	add := func(name string) {
+		if !seen[name] && validateQueueName(name) == nil {
+			seen[name] = true
+			queues = append(queues, name)
+		}
+	}
+
+	for name := range cfg.Queues {
+		add(name)
+	}
+
+	matches, _ := globStores("scheduled_tweets.*.json")
+	for _, m := range matches {
+		add(strings.TrimSuffix(strings.TrimPrefix(m, "scheduled_tweets."), ".json"))
+	}
+
+	sort.Strings(queues)
+	return queues
+}
+
+// loadAllScheduledTweets returns the tweets of every known queue.
+func loadAllScheduledTweets(cfg config.Config) ([]scheduledTweet, error) {
+	var all []scheduledTweet
+	for _, queue := range knownQueues(cfg) {
+		tweets, err := loadScheduledTweets(queue)
+		if err != nil {
+			return nil, fmt.Errorf("queue %s: %w", queueLabel(queue), err)
+		}
+		all = append(all, tweets...)
+	}
+	return all, nil
+}
+
+// postedToday counts history entries posted from queue since local midnight.
+func postedToday(queue string) (int, error) {
+	entries, err := loadHistory()
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	count := 0
+	for _, entry := range entries {
+		if entry.Queue == queue && entry.ScheduledID != "" && !entry.PostedAt.Before(midnight) {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+func newQueuesCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "queues",
+		Short: "List scheduler queues with their profile and daily cap",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg := config.LoadConfig()
+
+			for _, queue := range knownQueues(cfg) {
+				tweets, err := loadScheduledTweets(queue)
+				if err != nil {
+					return fmt.Errorf("queue %s: %w", queueLabel(queue), err)
+				}
+
+				profile := queueProfile(cfg, queue)
+				if profile == "" {
+					profile = "default"
+				}
+
+				limit := "none"
+				if n := cfg.Queues[queue].MaxPerDay; n > 0 {
+					today, err := postedToday(queue)
+					if err != nil {
+						return fmt.Errorf("loading history: %w", err)
+					}
+					limit = fmt.Sprintf("%d/%d today", today, n)
+				}
+
+				quiet := "none"
+				if qh, err := queueQuietHours(cfg, queue); err != nil {
+					return err
+				} else if qh != nil {
+					quiet = qh.String()
+				}
+
+				say("📬", "%s — profile: %s, pending: %d, daily cap: %s, quiet hours: %s", queueLabel(queue), profile, len(tweets), limit, quiet)
+			}
+
+			return nil
+		},
+	}
+}
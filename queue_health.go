@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/kalikim/x-cli/config"
+)
+
+const (
+	defaultMaxQueueSize = 200
+	defaultMaxFailing   = 6 * time.Hour
+)
+
+// queueHealth holds the resolved queue warning thresholds; zero disables a
+// warning.
+type queueHealth struct {
+	MaxSize    int
+	MaxFailing time.Duration
+}
+
+// queueWarning is one problem found in a queue. Key identifies it so the
+// daemon reports it once rather than on every check.
+type queueWarning struct {
+	Key string
+	Msg string
+}
+
+func resolveQueueHealth(cfg config.QueueHealth) (queueHealth, error) {
+	h := queueHealth{MaxSize: cfg.MaxSize, MaxFailing: defaultMaxFailing}
+	switch {
+	case cfg.MaxSize == 0:
+		h.MaxSize = defaultMaxQueueSize
+	case cfg.MaxSize < 0:
+		h.MaxSize = 0
+	}
+	if cfg.MaxFailing != "" {
+		d, err := time.ParseDuration(cfg.MaxFailing)
+		if err != nil || d < 0 {
+			return h, invalidInput(fmt.Errorf("invalid queue_health max_failing %q (use e.g. 6h, or 0 to disable)", cfg.MaxFailing))
+		}
+		h.MaxFailing = d
+	}
+	return h, nil
+}
+
+// check returns the warnings for the tweets of queue: too many waiting, or
+// unpaused tweets that have kept failing for longer than MaxFailing. Tweets
+// that failed before failing_since was recorded count from their schedule
+// time.
+func (h queueHealth) check(queue string, tweets []scheduledTweet, now time.Time) []queueWarning {
+	var warnings []queueWarning
+	if h.MaxSize > 0 && len(tweets) > h.MaxSize {
+		warnings = append(warnings, queueWarning{
+			Key: queue + "/size",
+			Msg: fmt.Sprintf("The %s queue holds %d tweets, more than its limit of %d; is the daemon keeping up?", queueLabel(queue), len(tweets), h.MaxSize),
+		})
+	}
+	if h.MaxFailing <= 0 {
+		return warnings
+	}
+	for _, t := range tweets {
+		if t.LastError == "" || t.Paused {
+			continue
+		}
+		since := t.ScheduleTime
+		if t.FailingSince != nil {
+			since = *t.FailingSince
+		}
+		if failing := now.Sub(since); failing > h.MaxFailing {
+			warnings = append(warnings, queueWarning{
+				Key: queue + "/failing/" + t.ID,
+				Msg: fmt.Sprintf("Tweet %s in the %s queue has been failing for %s: %s", t.ID, queueLabel(queue), formatTweetAge(since, now), t.LastError),
+			})
+		}
+	}
+	return warnings
+}
+
+// checkQueueHealth checks the tweets of queue against the configured
+// thresholds, for `scheduler list`.
+func checkQueueHealth(queue string, tweets []scheduledTweet) ([]queueWarning, error) {
+	h, err := resolveQueueHealth(config.Peek().QueueHealth)
+	if err != nil {
+		return nil, err
+	}
+	return h.check(queue, tweets, time.Now()), nil
+}
+
+func printQueueWarnings(warnings []queueWarning) {
+	for _, w := range warnings {
+		say("⚠️", "%s", w.Msg)
+	}
+}
+
+// reportQueueHealth logs the warnings for queue that are new since the last
+// check and sends them to Slack. A warning that clears is reported again if
+// the problem comes back.
+func (s *daemonSession) reportQueueHealth(client *http.Client, cfg config.Config, queue string, warnings []queueWarning) {
+	if s.queueWarned == nil {
+		s.queueWarned = map[string]bool{}
+	}
+	current := map[string]bool{}
+	for _, w := range warnings {
+		current[w.Key] = true
+		if s.queueWarned[w.Key] {
+			continue
+		}
+		s.queueWarned[w.Key] = true
+		say("⚠️", "%s", w.Msg)
+		notifySlackChannel(client, cfg, notification{Title: "Scheduler queue needs attention", Text: w.Msg})
+	}
+	for key := range s.queueWarned {
+		if strings.HasPrefix(key, queue+"/") && !current[key] {
+			delete(s.queueWarned, key)
+		}
+	}
+}
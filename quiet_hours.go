@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kalikim/x-cli/config"
+)
+
+// quietHours is a daily local-time window, in minutes after midnight, during
+// which the daemon does not post. A window may wrap past midnight.
+type quietHours struct {
+	start, end int
+}
+
+// parseQuietHours parses "HH:MM-HH:MM". It returns nil for "" and "off".
+func parseQuietHours(value string) (*quietHours, error) {
+	value = strings.TrimSpace(value)
+	if value == "" || value == "off" {
+		return nil, nil
+	}
+
+	from, to, found := strings.Cut(value, "-")
+	if !found {
+		return nil, invalidInput(fmt.Errorf("invalid quiet hours %q (use e.g. 23:00-07:00)", value))
+	}
+
+	var bounds [2]int
+	for i, part := range []string{from, to} {
+		t, err := time.Parse("15:04", strings.TrimSpace(part))
+		if err != nil {
+			return nil, invalidInput(fmt.Errorf("invalid quiet hours %q (use e.g. 23:00-07:00)", value))
+		}
+		bounds[i] = t.Hour()*60 + t.Minute()
+	}
+	if bounds[0] == bounds[1] {
+		return nil, invalidInput(fmt.Errorf("quiet hours %q start and end at the same time", value))
+	}
+
+	return &quietHours{start: bounds[0], end: bounds[1]}, nil
+}
+
+// queueQuietHours returns the quiet hours that apply to queue.
+func queueQuietHours(cfg config.Config, queue string) (*quietHours, error) {
+	value := cfg.QuietHours
+	if q := cfg.Queues[queue].QuietHours; q != "" {
+		value = q
+	}
+
+	qh, err := parseQuietHours(value)
+	if err != nil {
+		return nil, fmt.Errorf("queue %s: %w", queueLabel(queue), err)
+	}
+	return qh, nil
+}
+
+func minuteOfDay(t time.Time) int {
+	return t.Hour()*60 + t.Minute()
+}
+
+// contains reports whether t falls inside the window.
+func (q *quietHours) contains(t time.Time) bool {
+	if q == nil {
+		return false
+	}
+
+	m := minuteOfDay(t)
+	if q.start < q.end {
+		return m >= q.start && m < q.end
+	}
+	return m >= q.start || m < q.end
+}
+
+// reopens returns when the window containing t ends.
+func (q *quietHours) reopens(t time.Time) time.Time {
+	end := time.Date(t.Year(), t.Month(), t.Day(), q.end/60, q.end%60, 0, 0, t.Location())
+	if !end.After(t) {
+		end = end.AddDate(0, 0, 1)
+	}
+	return end
+}
+
+func (q *quietHours) String() string {
+	return fmt.Sprintf("%02d:%02d-%02d:%02d", q.start/60, q.start%60, q.end/60, q.end%60)
+}
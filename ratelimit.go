@@ -0,0 +1,160 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/kalikim/x-cli/config"
+)
+
+const rateLimitsFile = "rate_limits.json"
+
+// rateLimit is the rate-limit window X reported for one endpoint in a
+// response's x-rate-limit-* headers.
+type rateLimit struct {
+	Method    string    `json:"method"`
+	Endpoint  string    `json:"endpoint"`
+	Limit     int       `json:"limit"`
+	Remaining int       `json:"remaining"`
+	Reset     time.Time `json:"reset"`
+	SeenAt    time.Time `json:"seen_at"`
+}
+
+// idSegment matches path segments holding tweet, user or media IDs, which
+// share one rate limit per route. Short numbers are API versions.
+var idSegment = regexp.MustCompile(`/\d{3,}(/|$)`)
+
+func parseRateLimit(h http.Header) (rateLimit, bool) {
+	limit, err1 := strconv.Atoi(h.Get("X-Rate-Limit-Limit"))
+	remaining, err2 := strconv.Atoi(h.Get("X-Rate-Limit-Remaining"))
+	reset, err3 := strconv.ParseInt(h.Get("X-Rate-Limit-Reset"), 10, 64)
+	if err1 != nil || err2 != nil || err3 != nil {
+		return rateLimit{}, false
+	}
+	return rateLimit{Limit: limit, Remaining: remaining, Reset: time.Unix(reset, 0)}, true
+}
+
+// rateLimitRoute reduces a request URL to its route, e.g.
+// "api.twitter.com/2/tweets/:id".
+func rateLimitRoute(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	path := u.Path
+	for idSegment.MatchString(path) {
+		path = idSegment.ReplaceAllString(path, "/:id$1")
+	}
+	return u.Host + path
+}
+
+// rateLimitAccount identifies the account without storing its token.
+func rateLimitAccount(cfg config.Config) string {
+	sum := sha256.Sum256([]byte(cfg.AccessToken))
+	return hex.EncodeToString(sum[:8])
+}
+
+func rateLimitsPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".x-cli", rateLimitsFile), nil
+}
+
+// loadRateLimitStore reads the remembered limits, keyed by account.
+func loadRateLimitStore(path string) (map[string]map[string]rateLimit, error) {
+	store := map[string]map[string]rateLimit{}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &store); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// recordRateLimit remembers the limit a response reported, so `limits` can
+// show headroom for endpoints it cannot probe, such as posting. Failures
+// are ignored: the record is only informational.
+func recordRateLimit(cfg config.Config, method, endpoint string, h http.Header) {
+	limit, ok := parseRateLimit(h)
+	if !ok {
+		return
+	}
+	limit.Method = method
+	limit.Endpoint = rateLimitRoute(endpoint)
+	limit.SeenAt = time.Now()
+
+	path, err := rateLimitsPath()
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return
+	}
+
+	withFileLock(path, func() error {
+		store, err := loadRateLimitStore(path)
+		if err != nil {
+			store = map[string]map[string]rateLimit{}
+		}
+		account := rateLimitAccount(cfg)
+		if store[account] == nil {
+			store[account] = map[string]rateLimit{}
+		}
+		store[account][method+" "+limit.Endpoint] = limit
+
+		data, err := json.MarshalIndent(store, "", "  ")
+		if err != nil {
+			return err
+		}
+		return writeFileAtomic(path, data, 0600)
+	})
+}
+
+// accountRateLimits returns the remembered limits of cfg's account, sorted
+// by endpoint.
+func accountRateLimits(cfg config.Config) ([]rateLimit, error) {
+	path, err := rateLimitsPath()
+	if err != nil {
+		return nil, err
+	}
+
+	var limits []rateLimit
+	err = withFileLock(path, func() error {
+		store, err := loadRateLimitStore(path)
+		if err != nil {
+			return err
+		}
+		for _, limit := range store[rateLimitAccount(cfg)] {
+			limits = append(limits, limit)
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	sort.Slice(limits, func(i, j int) bool {
+		if limits[i].Endpoint != limits[j].Endpoint {
+			return limits[i].Endpoint < limits[j].Endpoint
+		}
+		return limits[i].Method < limits[j].Method
+	})
+	return limits, nil
+}
@@ -0,0 +1,392 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/kalikim/x-cli/config"
+	"github.com/spf13/cobra"
+)
+
+const (
+	readerDefaultPageSize = 20
+	readerListRows        = 10
+)
+
+type readerMode int
+
+const (
+	readerModeBrowse readerMode = iota
+	readerModeReply
+)
+
+// readerSource is a timeline the reader can show.
+type readerSource struct {
+	name     string
+	endpoint string
+}
+
+var (
+	readerHome     = readerSource{"Home timeline", homeTimelineEndpoint}
+	readerMentions = readerSource{"Mentions", mentionsEndpoint}
+)
+
+// readerState holds the reader's page of tweets and what the user is doing
+// with them between key presses.
+type readerState struct {
+	client   *http.Client
+	cfg      config.Config
+	userID   string
+	source   readerSource
+	pageSize int
+
+	tweets   []timelineTweet
+	selected int
+	// tokens are the pagination tokens of the pages before the current one;
+	// the current page's own token is last.
+	tokens []string
+	next   string
+
+	liked     map[string]bool
+	retweeted map[string]bool
+
+	mode    readerMode
+	draft   []byte
+	message string
+	// escape collects an arrow key's escape sequence.
+	escape []byte
+}
+
+func newReadCmd() *cobra.Command {
+	var mentions bool
+	var profile string
+	var pageSize int
+
+	cmd := &cobra.Command{
+		Use:   "read",
+		Short: "Read your home timeline or mentions and like, retweet or reply from the terminal",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if pageSize < 5 || pageSize > 100 {
+				return invalidInput(fmt.Errorf("--page-size must be between 5 and 100, got %d", pageSize))
+			}
+			if !isTerminal(os.Stdin) {
+				return errors.New("read requires an interactive terminal")
+			}
+
+			cfg, err := config.LoadConfig().ForProfile(profile)
+			if err != nil {
+				return invalidInput(err)
+			}
+			if err := cfg.Validate(); err != nil {
+				return err
+			}
+
+			client := apiClient()
+			userID, err := accountUserID(client, cfg)
+			if err != nil {
+				return err
+			}
+
+			state := &readerState{
+				client:    client,
+				cfg:       cfg,
+				userID:    userID,
+				source:    readerHome,
+				pageSize:  pageSize,
+				tokens:    []string{""},
+				liked:     map[string]bool{},
+				retweeted: map[string]bool{},
+			}
+			if mentions {
+				state.source = readerMentions
+			}
+			return runReader(state)
+		},
+	}
+	cmd.Flags().BoolVar(&mentions, "mentions", false, "Start with your mentions instead of the home timeline")
+	cmd.Flags().StringVar(&profile, "profile", "", "Read as this configured profile")
+	cmd.Flags().IntVar(&pageSize, "page-size", readerDefaultPageSize, "Tweets per page (5-100)")
+	return cmd
+}
+
+func runReader(s *readerState) error {
+	restore, err := enableRawMode()
+	if err != nil {
+		return fmt.Errorf("configuring terminal: %w", err)
+	}
+	defer restore()
+
+	s.load()
+
+	buf := make([]byte, 1)
+	for {
+		s.render()
+		if _, err := os.Stdin.Read(buf); err != nil {
+			return nil
+		}
+		if quit := s.handleKey(buf[0]); quit {
+			fmt.Print("\x1b[2J\x1b[H")
+			return nil
+		}
+	}
+}
+
+// load fetches the page whose token is last in s.tokens.
+func (s *readerState) load() {
+	page, err := fetchTimeline(s.client, s.cfg, s.source.endpoint, s.userID, s.tokens[len(s.tokens)-1], "", s.pageSize)
+	if err != nil {
+		s.message = decorate("❌", "Loading "+strings.ToLower(s.source.name)+": "+err.Error())
+		return
+	}
+	s.tweets = page.Tweets
+	s.next = page.NextToken
+	s.selected = 0
+}
+
+func (s *readerState) current() (timelineTweet, bool) {
+	if s.selected < 0 || s.selected >= len(s.tweets) {
+		return timelineTweet{}, false
+	}
+	return s.tweets[s.selected], true
+}
+
+// handleKey applies a single key press and reports whether the reader should
+// exit.
+func (s *readerState) handleKey(key byte) bool {
+	const (
+		keyCtrlC     = 3
+		keyEnter     = 13
+		keyEscape    = 27
+		keyBackspace = 127
+	)
+
+	if s.mode == readerModeReply {
+		switch key {
+		case keyCtrlC, keyEscape:
+			s.mode = readerModeBrowse
+			s.message = "Reply discarded"
+			s.draft = nil
+		case keyEnter:
+			s.sendReply()
+		case keyBackspace, 8:
+			s.draft = deleteLastRune(s.draft)
+		default:
+			s.draft = appendInput(s.draft, key)
+		}
+		return false
+	}
+
+	// Arrow keys arrive as ESC [ A/B; anything else after ESC is handled as
+	// a key of its own.
+	switch {
+	case len(s.escape) == 0 && key == keyEscape:
+		s.escape = []byte{key}
+		return false
+	case len(s.escape) == 1 && key == '[':
+		s.escape = append(s.escape, key)
+		return false
+	case len(s.escape) == 2:
+		s.escape = nil
+		switch key {
+		case 'A':
+			key = 'k'
+		case 'B':
+			key = 'j'
+		default:
+			return false
+		}
+	default:
+		s.escape = nil
+	}
+
+	switch key {
+	case 'q', keyCtrlC:
+		return true
+	case 'j':
+		if s.selected < len(s.tweets)-1 {
+			s.selected++
+		}
+	case 'k':
+		if s.selected > 0 {
+			s.selected--
+		}
+	case 'n':
+		if s.next == "" {
+			s.message = "No more tweets"
+			return false
+		}
+		s.tokens = append(s.tokens, s.next)
+		s.message = ""
+		s.load()
+	case 'p':
+		if len(s.tokens) == 1 {
+			s.message = "Already on the first page"
+			return false
+		}
+		s.tokens = s.tokens[:len(s.tokens)-1]
+		s.message = ""
+		s.load()
+	case 'g':
+		s.tokens = []string{""}
+		s.message = decorate("🔄", "Refreshed")
+		s.load()
+	case 'm':
+		if s.source == readerHome {
+			s.source = readerMentions
+		} else {
+			s.source = readerHome
+		}
+		s.tokens = []string{""}
+		s.message = ""
+		s.load()
+	case 'l':
+		s.like()
+	case 't':
+		s.retweet()
+	case 'r':
+		if _, ok := s.current(); ok {
+			s.mode = readerModeReply
+			s.message = ""
+		}
+	case 'o':
+		if t, ok := s.current(); ok {
+			if err := openBrowser(tweetURL(t.Username, t.ID)); err != nil {
+				s.message = decorate("❌", err.Error())
+			} else {
+				s.message = decorate("🌐", "Opened "+tweetURL(t.Username, t.ID))
+			}
+		}
+	}
+	return false
+}
+
+func (s *readerState) like() {
+	t, ok := s.current()
+	if !ok || s.liked[t.ID] {
+		return
+	}
+	if err := likeTweet(s.client, s.cfg, s.userID, t.ID); err != nil {
+		s.message = decorate("❌", "Like failed: "+err.Error())
+		return
+	}
+	s.liked[t.ID] = true
+	s.tweets[s.selected].Likes++
+	s.message = decorate("❤️", "Liked @"+t.Username)
+}
+
+func (s *readerState) retweet() {
+	t, ok := s.current()
+	if !ok || s.retweeted[t.ID] {
+		return
+	}
+	if err := retweetTweet(s.client, s.cfg, s.userID, t.ID); err != nil {
+		s.message = decorate("❌", "Retweet failed: "+err.Error())
+		return
+	}
+	s.retweeted[t.ID] = true
+	s.tweets[s.selected].Retweets++
+	s.message = decorate("🔁", "Retweeted @"+t.Username)
+}
+
+// sendReply posts the draft as a reply to the selected tweet, with the same
+// content checks as the dashboard's compose.
+func (s *readerState) sendReply() {
+	t, ok := s.current()
+	text := strings.TrimSpace(string(s.draft))
+	if !ok || text == "" {
+		s.message = decorate("⚠️", "Reply text cannot be empty")
+		return
+	}
+	if err := validateTweetText(text); err != nil {
+		s.message = decorate("❌", err.Error())
+		return
+	}
+	if err := checkBannedContent(s.cfg, text); err != nil {
+		s.message = decorate("❌", err.Error())
+		return
+	}
+	if _, err := checkContentRules(s.cfg, []ruleText{{"", text}}, false); err != nil {
+		s.message = decorate("❌", err.Error())
+		return
+	}
+
+	ids, err := publishReplies(s.client, s.cfg, t.ID, []string{text})
+	if err != nil {
+		s.message = decorate("❌", "Reply failed: "+err.Error())
+		return
+	}
+	s.tweets[s.selected].Replies++
+	s.draft = nil
+	s.mode = readerModeBrowse
+	s.message = decorate("💬", fmt.Sprintf("Replied to @%s (ID: %s)", t.Username, ids[0]))
+}
+
+func (s *readerState) render() {
+	now := time.Now()
+	lines := []string{fmt.Sprintf("x-cli read — %s (page %d)", s.source.name, len(s.tokens)), ""}
+
+	if len(s.tweets) == 0 {
+		lines = append(lines, "  (no tweets)")
+	}
+
+	// Keep the selection inside a window of readerListRows.
+	start := max(0, min(s.selected-readerListRows/2, len(s.tweets)-readerListRows))
+	for i := start; i < len(s.tweets) && i < start+readerListRows; i++ {
+		t := s.tweets[i]
+		marker := "  "
+		if i == s.selected {
+			marker = "> "
+		}
+		flags := ""
+		if s.liked[t.ID] {
+			flags += "♥"
+		}
+		if s.retweeted[t.ID] {
+			flags += "⇄"
+		}
+		lines = append(lines, fmt.Sprintf("%s%-16s %5s %s %s", marker, truncateText("@"+t.Username, 16), formatTweetAge(t.CreatedAt, now), isolateBidi(truncateText(t.Text, 60)), flags))
+	}
+
+	if t, ok := s.current(); ok {
+		lines = append(lines, "", fmt.Sprintf("%s (@%s) · %s", t.Name, t.Username, t.CreatedAt.Local().Format("2006-01-02 15:04")))
+		for _, line := range strings.Split(t.Text, "\n") {
+			lines = append(lines, "  "+line)
+		}
+		lines = append(lines, fmt.Sprintf("  ↩ %d  ⇄ %d  ♥ %d", t.Replies, t.Retweets, t.Likes))
+	}
+	lines = append(lines, "")
+
+	switch s.mode {
+	case readerModeBrowse:
+		lines = append(lines, "[j/k] move  [n/p] next/prev page  [l] like  [t] retweet  [r] reply  [o] open  [m] home/mentions  [g] refresh  [q] quit")
+	case readerModeReply:
+		count := tweetLength(string(s.draft))
+		lines = append(lines, fmt.Sprintf("Reply [%d/%d]: %s█", count, maxTweetLength, string(s.draft)), "[Enter] send  [Esc] cancel")
+	}
+
+	if s.message != "" {
+		lines = append(lines, s.message)
+	}
+
+	// Raw mode disables output post-processing, so lines need explicit CRs.
+	fmt.Print("\x1b[2J\x1b[H" + strings.Join(lines, "\r\n") + "\r\n")
+}
+
+// formatTweetAge renders how long ago t was, compactly: 45s, 12m, 3h, 5d.
+func formatTweetAge(t, now time.Time) string {
+	d := now.Sub(t)
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	}
+	return fmt.Sprintf("%dd", int(d.Hours()/24))
+}
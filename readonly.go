@@ -0,0 +1,20 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/kalikim/x-cli/config"
+)
+
+// errReadOnly is returned for any write to X while read-only mode is on.
+var errReadOnly = errors.New("read-only mode is on (read_only in the config, or XCLI_READ_ONLY); refusing to write to X")
+
+// checkWritable refuses write commands up front in read-only mode, before
+// they upload media or queue anything. sendSigned enforces the same rule
+// for every request, so a command that forgets to ask still cannot write.
+func checkWritable(cfg config.Config) error {
+	if cfg.ReadOnly {
+		return invalidInput(errReadOnly)
+	}
+	return nil
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"text/template"
+
+	"github.com/kalikim/x-cli/config"
+	"github.com/spf13/cobra"
+)
+
+const defaultReleaseTemplate = "🚀 {{.Tag}} is out!\n\n{{.Changelog}}"
+
+// releaseInfo is the data available to release announcement templates.
+type releaseInfo struct {
+	Tag       string
+	Name      string
+	URL       string
+	Changelog string
+}
+
+func newReleaseCmd() *cobra.Command {
+	releaseCmd := &cobra.Command{
+		Use:   "release",
+		Short: "Announce software releases",
+	}
+
+	var tag, notesFile, tmpl, scheduleAt string
+	var dryRun, noFooter, force bool
+
+	announceCmd := &cobra.Command{
+		Use:   "announce",
+		Short: "Post a templated tweet announcing a release",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			tag = strings.TrimSpace(tag)
+			if tag == "" {
+				return invalidInput(errors.New("tag flag cannot be empty"))
+			}
+
+			notes, err := loadReleaseNotes(tag, notesFile)
+			if err != nil {
+				return err
+			}
+
+			cfg := config.LoadConfig()
+
+			footer := ""
+			if !noFooter {
+				if footer, err = renderFooter(cfg); err != nil {
+					return err
+				}
+			}
+
+			text, err := renderReleaseTweet(tmpl, releaseInfo{Tag: tag, Changelog: notes}, footer)
+			if err != nil {
+				return err
+			}
+
+			if dryRun {
+				fmt.Println(text)
+				return nil
+			}
+
+			if err := checkPostTexts(cfg, []ruleText{{"", text}}, force); err != nil {
+				return err
+			}
+
+			if err := checkWritable(cfg); err != nil {
+				return err
+			}
+			if scheduleAt != "" {
+				return handleScheduledTweet(cfg, "", scheduledTweet{Text: text}, scheduleAt, force, false)
+			}
+
+			if err := cfg.Validate(); err != nil {
+				return err
+			}
+
+			client := apiClient()
+			if _, err := publishTweet(client, cfg, text, "", mediaOptions{}, tweetExtras{}); err != nil {
+				return err
+			}
+
+			say("✅", "Release %s announced!", tag)
+			return nil
+		},
+	}
+
+	announceCmd.Flags().StringVar(&tag, "tag", "", "Release tag or version (e.g. v1.2.0)")
+	announceCmd.Flags().StringVar(&notesFile, "notes", "", "Release notes or changelog file (defaults to the annotated tag message)")
+	announceCmd.Flags().StringVar(&tmpl, "template", defaultReleaseTemplate, "Tweet template ({{.Tag}}, {{.Changelog}})")
+	announceCmd.Flags().StringVarP(&scheduleAt, "schedule", "s", "", "Schedule the announcement instead of posting now")
+	announceCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print the rendered tweet without posting")
+	announceCmd.Flags().BoolVar(&noFooter, "no-footer", false, "Do not append the configured footer")
+	announceCmd.Flags().BoolVar(&force, "force", false, "Post even if the text matches banned content, breaks content rules or the schedule is too close to another tweet")
+	announceCmd.MarkFlagRequired("tag")
+
+	releaseCmd.AddCommand(announceCmd)
+	return releaseCmd
+}
+
+// loadReleaseNotes returns the notes for tag. When a file is given and it has
+// a heading mentioning the tag, only that section is used; otherwise the whole
+// file. Without a file, the annotated tag message from git is used.
+func loadReleaseNotes(tag, path string) (string, error) {
+	if path == "" {
+		out, err := exec.Command("git", "tag", "-l", "--format=%(contents)", tag).Output()
+		if err != nil {
+			return "", fmt.Errorf("reading tag message: %w", err)
+		}
+		return strings.TrimSpace(string(out)), nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("reading release notes: %w", err)
+	}
+
+	return extractChangelogSection(string(data), tag), nil
+}
+
+func extractChangelogSection(notes, tag string) string {
+	lines := strings.Split(notes, "\n")
+	version := strings.TrimPrefix(tag, "v")
+
+	start, level := -1, 0
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if !strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		heading := strings.TrimLeft(trimmed, "#")
+		headingLevel := len(trimmed) - len(heading)
+
+		if start >= 0 {
+			if headingLevel <= level {
+				return strings.TrimSpace(strings.Join(lines[start:i], "\n"))
+			}
+			continue
+		}
+
+		if strings.Contains(heading, version) {
+			start, level = i+1, headingLevel
+		}
+	}
+
+	if start >= 0 {
+		return strings.TrimSpace(strings.Join(lines[start:], "\n"))
+	}
+
+	return strings.TrimSpace(notes)
+}
+
+// renderReleaseText renders the template with the full changelog.
+func renderReleaseText(tmpl string, info releaseInfo) (string, error) {
+	t, err := template.New("release").Parse(tmpl)
+	if err != nil {
+		return "", fmt.Errorf("parsing template: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, info); err != nil {
+		return "", fmt.Errorf("rendering template: %w", err)
+	}
+
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// renderReleaseTweet renders the template followed by footer, shortening the
+// changelog excerpt so that the final tweet fits within the length limit.
+func renderReleaseTweet(tmpl string, info releaseInfo, footer string) (string, error) {
+	skeletonInfo := info
+	skeletonInfo.Changelog = ""
+	skeleton, err := renderReleaseText(tmpl, skeletonInfo)
+	if err != nil {
+		return "", err
+	}
+	skeleton = appendFooter(skeleton, footer)
+
+	budget := maxTweetLength - tweetLength(skeleton)
+	if budget <= 0 {
+		return "", fmt.Errorf("template is too long even without the changelog (%d characters)", tweetLength(skeleton))
+	}
+
+	info.Changelog = excerpt(info.Changelog, budget)
+	text, err := renderReleaseText(tmpl, info)
+	if err != nil {
+		return "", err
+	}
+
+	return appendFooter(text, footer), nil
+}
+
+// excerpt shortens text to at most max characters, preferring to cut at a
+// line or word boundary and marking the cut with an ellipsis.
+func excerpt(text string, max int) string {
+	runes := []rune(text)
+	if len(runes) <= max {
+		return text
+	}
+	if max <= 1 {
+		return ""
+	}
+
+	cut := string(runes[:max-1])
+	if i := strings.LastIndex(cut, "\n"); i > len(cut)/2 {
+		cut = cut[:i]
+	} else if i := strings.LastIndex(cut, " "); i > len(cut)/2 {
+		cut = cut[:i]
+	}
+
+	return strings.TrimSpace(cut) + "…"
+}
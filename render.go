@@ -0,0 +1,470 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/kalikim/x-cli/config"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// Layout in unscaled pixels; --scale multiplies everything.
+	renderWidth       = 520
+	renderPadding     = 16
+	renderAvatar      = 40
+	renderLineHeight  = 16
+	renderGlyphWidth  = 6
+	renderGlyphHeight = 13
+	renderMaxMedia    = 360
+	// renderMaxDownload bounds avatar and media downloads.
+	renderMaxDownload = 15 << 20
+)
+
+// renderedTweet is what a preview image shows.
+type renderedTweet struct {
+	Name      string
+	Username  string
+	Avatar    image.Image
+	Text      string
+	CreatedAt time.Time
+	Media     image.Image
+	// Metrics are only shown for published tweets.
+	HasMetrics bool
+	Replies    int
+	Retweets   int
+	Likes      int
+}
+
+// renderTheme holds a preview's colors.
+type renderTheme struct {
+	Background, Border, Text, Muted, Accent color.RGBA
+}
+
+var (
+	lightTheme = renderTheme{
+		Background: color.RGBA{0xff, 0xff, 0xff, 0xff},
+		Border:     color.RGBA{0xcf, 0xd9, 0xde, 0xff},
+		Text:       color.RGBA{0x0f, 0x14, 0x19, 0xff},
+		Muted:      color.RGBA{0x53, 0x64, 0x71, 0xff},
+		Accent:     color.RGBA{0x1d, 0x9b, 0xf0, 0xff},
+	}
+	darkTheme = renderTheme{
+		Background: color.RGBA{0x15, 0x20, 0x2b, 0xff},
+		Border:     color.RGBA{0x38, 0x44, 0x4d, 0xff},
+		Text:       color.RGBA{0xf7, 0xf9, 0xf9, 0xff},
+		Muted:      color.RGBA{0x8b, 0x98, 0xa5, 0xff},
+		Accent:     color.RGBA{0x1d, 0x9b, 0xf0, 0xff},
+	}
+)
+
+func newRenderCmd() *cobra.Command {
+	var text, imagePath, output, profile string
+	var scale int
+	var dark bool
+
+	cmd := &cobra.Command{
+		Use:   "render [tweet-id]",
+		Short: "Render a tweet, or text you have not posted yet, as a PNG mock-up",
+		Long: "Render a tweet as a PNG mock-up with avatar, name, handle, text and first\n" +
+			"image, for blog posts and review documents. Pass a tweet ID to render a\n" +
+			"published tweet, or --text (and --image) to preview a draft as your account.\n\n" +
+			"Rendering is best-effort: text uses a built-in bitmap font that covers ASCII,\n" +
+			"so emoji and other characters appear as placeholders, and only PNG, JPEG and\n" +
+			"GIF images are drawn.",
+		Example: `  x-cli render 1790000000000000000 -o preview.png
+  x-cli render --text "Shipping v2 today" --image shot.png -o draft.png --dark`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if (len(args) == 1) == (text != "") {
+				return invalidInput(errors.New("pass either a tweet ID or --text"))
+			}
+			if imagePath != "" && text == "" {
+				return invalidInput(errors.New("--image only applies with --text"))
+			}
+			if len(args) == 1 && !tweetIDPattern.MatchString(args[0]) {
+				return invalidInput(fmt.Errorf("invalid tweet ID %q", args[0]))
+			}
+			if scale < 1 || scale > 4 {
+				return invalidInput(fmt.Errorf("--scale must be between 1 and 4, got %d", scale))
+			}
+
+			cfg, err := config.LoadConfig().ForProfile(profile)
+			if err != nil {
+				return invalidInput(err)
+			}
+			if err := cfg.Validate(); err != nil {
+				return err
+			}
+			client := apiClient()
+
+			var tweet renderedTweet
+			if len(args) == 1 {
+				tweet, err = fetchRenderedTweet(client, cfg, args[0])
+			} else {
+				tweet, err = draftRenderedTweet(client, cfg, text, imagePath)
+			}
+			if err != nil {
+				return err
+			}
+
+			theme := lightTheme
+			if dark {
+				theme = darkTheme
+			}
+			img := renderTweet(tweet, theme, scale)
+
+			var buf bytes.Buffer
+			if err := png.Encode(&buf, img); err != nil {
+				return fmt.Errorf("encoding PNG: %w", err)
+			}
+			if err := writeFileAtomic(output, buf.Bytes(), 0644); err != nil {
+				return fmt.Errorf("writing %s: %w", output, err)
+			}
+			say("🖼️", "Rendered %s (%dx%d)", output, img.Bounds().Dx(), img.Bounds().Dy())
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&text, "text", "t", "", "Render this draft text instead of a posted tweet")
+	cmd.Flags().StringVarP(&imagePath, "image", "i", "", "Image to show with --text (PNG, JPEG or GIF)")
+	cmd.Flags().StringVarP(&output, "output", "o", "preview.png", "PNG file to write")
+	cmd.Flags().IntVar(&scale, "scale", 2, "Pixel density, 1-4")
+	cmd.Flags().BoolVar(&dark, "dark", false, "Use the dark theme")
+	cmd.Flags().StringVar(&profile, "profile", "", "Render drafts as this configured profile")
+	return cmd
+}
+
+// fetchRenderedTweet looks up a published tweet with its author and first
+// image. Images that cannot be loaded are left out with a warning.
+func fetchRenderedTweet(client *http.Client, cfg config.Config, tweetID string) (renderedTweet, error) {
+	params := url.Values{}
+	params.Set("tweet.fields", "created_at,public_metrics,author_id")
+	params.Set("expansions", "author_id,attachments.media_keys")
+	params.Set("user.fields", "name,username,profile_image_url")
+	params.Set("media.fields", "type,url,preview_image_url")
+
+	body, err := signedGet(client, cfg, tweetEndpoint+"/"+tweetID+"?"+params.Encode())
+	if err != nil {
+		return renderedTweet{}, fmt.Errorf("looking up tweet %s: %w", tweetID, err)
+	}
+
+	var resp struct {
+		Data struct {
+			Text          string    `json:"text"`
+			CreatedAt     time.Time `json:"created_at"`
+			PublicMetrics struct {
+				Likes    int `json:"like_count"`
+				Retweets int `json:"retweet_count"`
+				Replies  int `json:"reply_count"`
+			} `json:"public_metrics"`
+		} `json:"data"`
+		Includes struct {
+			Users []struct {
+				Name            string `json:"name"`
+				Username        string `json:"username"`
+				ProfileImageURL string `json:"profile_image_url"`
+			} `json:"users"`
+			Media []struct {
+				URL             string `json:"url"`
+				PreviewImageURL string `json:"preview_image_url"`
+			} `json:"media"`
+		} `json:"includes"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return renderedTweet{}, fmt.Errorf("decoding tweet: %w", err)
+	}
+
+	tweet := renderedTweet{
+		Text:       resp.Data.Text,
+		CreatedAt:  resp.Data.CreatedAt,
+		HasMetrics: true,
+		Replies:    resp.Data.PublicMetrics.Replies,
+		Retweets:   resp.Data.PublicMetrics.Retweets,
+		Likes:      resp.Data.PublicMetrics.Likes,
+	}
+	if len(resp.Includes.Users) > 0 {
+		u := resp.Includes.Users[0]
+		tweet.Name, tweet.Username = u.Name, u.Username
+		tweet.Avatar = downloadRenderImage(client, largeProfileImage(u.ProfileImageURL), "avatar")
+	}
+	if len(resp.Includes.Media) > 0 {
+		m := resp.Includes.Media[0]
+		src := m.URL
+		if src == "" {
+			src = m.PreviewImageURL
+		}
+		tweet.Media = downloadRenderImage(client, src, "image")
+	}
+	return tweet, nil
+}
+
+// draftRenderedTweet previews text, and optionally a local image, as cfg's
+// account would post it now.
+func draftRenderedTweet(client *http.Client, cfg config.Config, text, imagePath string) (renderedTweet, error) {
+	body, err := signedGet(client, cfg, usersMeEndpoint+"?user.fields=name,username,profile_image_url")
+	if err != nil {
+		return renderedTweet{}, fmt.Errorf("looking up account: %w", err)
+	}
+	var me struct {
+		Data struct {
+			Name            string `json:"name"`
+			Username        string `json:"username"`
+			ProfileImageURL string `json:"profile_image_url"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &me); err != nil {
+		return renderedTweet{}, fmt.Errorf("decoding account: %w", err)
+	}
+
+	tweet := renderedTweet{
+		Name:      me.Data.Name,
+		Username:  me.Data.Username,
+		Avatar:    downloadRenderImage(client, largeProfileImage(me.Data.ProfileImageURL), "avatar"),
+		Text:      text,
+		CreatedAt: time.Now(),
+	}
+	if imagePath != "" {
+		f, err := os.Open(imagePath)
+		if err != nil {
+			return renderedTweet{}, fmt.Errorf("opening image: %w", err)
+		}
+		defer f.Close()
+		if tweet.Media, _, err = image.Decode(f); err != nil {
+			return renderedTweet{}, invalidInput(fmt.Errorf("decoding %s: %w (use PNG, JPEG or GIF)", imagePath, err))
+		}
+	}
+	return tweet, nil
+}
+
+// largeProfileImage swaps the 48px "_normal" avatar X links to for the
+// 400px one.
+func largeProfileImage(src string) string {
+	return strings.Replace(src, "_normal.", "_400x400.", 1)
+}
+
+// downloadRenderImage fetches and decodes an image, returning nil (and
+// warning) when it cannot, so the preview is still rendered without it.
+func downloadRenderImage(client *http.Client, src, what string) image.Image {
+	if src == "" {
+		return nil
+	}
+	img, err := func() (image.Image, error) {
+		resp, err := client.Get(src)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("server returned %s", resp.Status)
+		}
+		img, _, err := image.Decode(io.LimitReader(resp.Body, renderMaxDownload))
+		return img, err
+	}()
+	if err != nil {
+		say("⚠️", "Leaving out the %s: %v", what, err)
+		return nil
+	}
+	return img
+}
+
+// renderTweet draws tweet as a card.
+func renderTweet(tweet renderedTweet, theme renderTheme, scale int) *image.RGBA {
+	textWidth := renderWidth - 2*renderPadding
+	lines := wrapRenderText(tweet.Text, textWidth/renderGlyphWidth)
+
+	textTop := renderPadding + renderAvatar + 12
+	y := textTop + len(lines)*renderLineHeight
+
+	var mediaRect image.Rectangle
+	if tweet.Media != nil {
+		b := tweet.Media.Bounds()
+		h := min(renderMaxMedia, textWidth*b.Dy()/max(1, b.Dx()))
+		w := min(textWidth, h*b.Dx()/max(1, b.Dy()))
+		y += 8
+		mediaRect = image.Rect(renderPadding, y, renderPadding+w, y+h)
+		y += h
+	}
+
+	dateTop := y + 12
+	height := dateTop + renderLineHeight + renderPadding
+	if tweet.HasMetrics {
+		height += 1 + 8 + renderLineHeight
+	}
+
+	c := &renderCanvas{img: image.NewRGBA(image.Rect(0, 0, renderWidth*scale, height*scale)), scale: scale}
+	c.fill(image.Rect(0, 0, renderWidth, height), theme.Border)
+	c.fill(image.Rect(1, 1, renderWidth-1, height-1), theme.Background)
+
+	c.avatar(renderPadding, renderPadding, tweet, theme)
+	nameX := renderPadding + renderAvatar + 10
+	name := tweet.Name
+	if name == "" {
+		name = tweet.Username
+	}
+	c.text(nameX, renderPadding+2, name, theme.Text, 1, true)
+	c.text(nameX, renderPadding+2+renderLineHeight, "@"+tweet.Username, theme.Muted, 1, false)
+
+	for i, line := range lines {
+		c.text(renderPadding, textTop+i*renderLineHeight, line, theme.Text, 1, false)
+	}
+	if tweet.Media != nil {
+		c.image(mediaRect, tweet.Media)
+	}
+
+	c.text(renderPadding, dateTop, tweet.CreatedAt.Local().Format("3:04 PM - Jan 2, 2006"), theme.Muted, 1, false)
+	if tweet.HasMetrics {
+		sepY := dateTop + renderLineHeight + 4
+		c.fill(image.Rect(renderPadding, sepY, renderWidth-renderPadding, sepY+1), theme.Border)
+		metrics := fmt.Sprintf("%d Replies   %d Reposts   %d Likes", tweet.Replies, tweet.Retweets, tweet.Likes)
+		c.text(renderPadding, sepY+9, metrics, theme.Muted, 1, false)
+	}
+	return c.img
+}
+
+// wrapRenderText breaks text into lines of at most cols glyphs, at spaces
+// where possible.
+func wrapRenderText(text string, cols int) []string {
+	var lines []string
+	for _, paragraph := range strings.Split(text, "\n") {
+		var line []rune
+		for _, word := range strings.Fields(paragraph) {
+			runes := renderRunes(word)
+			if len(line) > 0 && len(line)+1+len(runes) > cols {
+				lines = append(lines, string(line))
+				line = nil
+			}
+			for len(runes) > cols {
+				if len(line) > 0 {
+					lines = append(lines, string(line))
+					line = nil
+				}
+				lines = append(lines, string(runes[:cols]))
+				runes = runes[cols:]
+			}
+			if len(line) > 0 {
+				line = append(line, ' ')
+			}
+			line = append(line, runes...)
+		}
+		lines = append(lines, string(line))
+	}
+	return lines
+}
+
+// renderRunes drops characters that take no space, such as emoji variation
+// selectors and joiners, so each remaining rune is one glyph.
+func renderRunes(s string) []rune {
+	var runes []rune
+	for _, r := range s {
+		if unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf) {
+			continue
+		}
+		runes = append(runes, r)
+	}
+	return runes
+}
+
+// renderCanvas draws in unscaled pixels onto a scaled image.
+type renderCanvas struct {
+	img   *image.RGBA
+	scale int
+}
+
+func (c *renderCanvas) fill(r image.Rectangle, col color.RGBA) {
+	draw.Draw(c.img, image.Rect(r.Min.X*c.scale, r.Min.Y*c.scale, r.Max.X*c.scale, r.Max.Y*c.scale), image.NewUniform(col), image.Point{}, draw.Src)
+}
+
+// text draws s with its top-left corner at (x, y), each glyph pixel size
+// unscaled pixels wide. Bold is drawn twice, one device pixel apart.
+func (c *renderCanvas) text(x, y int, s string, col color.RGBA, size int, bold bool) {
+	px := c.scale * size
+	src := image.NewUniform(col)
+	for i, r := range []rune(s) {
+		glyph := fixedGlyphs[len(fixedGlyphs)-1]
+		if r >= 0x20 && r <= 0x7e {
+			glyph = fixedGlyphs[r-0x20]
+		}
+		gx := (x + i*renderGlyphWidth*size) * c.scale
+		for row, bits := range glyph {
+			for col := 0; col < renderGlyphWidth; col++ {
+				if bits&(1<<(renderGlyphWidth-1-col)) == 0 {
+					continue
+				}
+				dot := image.Rect(gx+col*px, y*c.scale+row*px, gx+(col+1)*px, y*c.scale+(row+1)*px)
+				if bold {
+					dot.Max.X++
+				}
+				draw.Draw(c.img, dot, src, image.Point{}, draw.Src)
+			}
+		}
+	}
+}
+
+// image draws img scaled into r with nearest-neighbor sampling.
+func (c *renderCanvas) image(r image.Rectangle, img image.Image) {
+	dst := image.Rect(r.Min.X*c.scale, r.Min.Y*c.scale, r.Max.X*c.scale, r.Max.Y*c.scale)
+	b := img.Bounds()
+	for y := dst.Min.Y; y < dst.Max.Y; y++ {
+		sy := b.Min.Y + (y-dst.Min.Y)*b.Dy()/dst.Dy()
+		for x := dst.Min.X; x < dst.Max.X; x++ {
+			sx := b.Min.X + (x-dst.Min.X)*b.Dx()/dst.Dx()
+			c.img.Set(x, y, img.At(sx, sy))
+		}
+	}
+}
+
+// avatar draws the author's picture cropped to a circle, or their initial
+// on the accent color when there is none.
+func (c *renderCanvas) avatar(x, y int, tweet renderedTweet, theme renderTheme) {
+	size := renderAvatar * c.scale
+	x0, y0 := x*c.scale, y*c.scale
+	radius := float64(size) / 2
+
+	var crop image.Rectangle
+	if tweet.Avatar != nil {
+		b := tweet.Avatar.Bounds()
+		side := min(b.Dx(), b.Dy())
+		crop = image.Rect(0, 0, side, side).Add(b.Min).Add(image.Pt((b.Dx()-side)/2, (b.Dy()-side)/2))
+	}
+
+	for dy := 0; dy < size; dy++ {
+		for dx := 0; dx < size; dx++ {
+			fx, fy := float64(dx)+0.5-radius, float64(dy)+0.5-radius
+			if fx*fx+fy*fy > radius*radius {
+				continue
+			}
+			if tweet.Avatar == nil {
+				c.img.Set(x0+dx, y0+dy, theme.Accent)
+				continue
+			}
+			sx := crop.Min.X + dx*crop.Dx()/size
+			sy := crop.Min.Y + dy*crop.Dy()/size
+			c.img.Set(x0+dx, y0+dy, tweet.Avatar.At(sx, sy))
+		}
+	}
+
+	if tweet.Avatar == nil {
+		initial := "?"
+		for _, r := range tweet.Name + tweet.Username {
+			initial = strings.ToUpper(string(r))
+			break
+		}
+		// Twice the glyph size, centered in the circle.
+		c.text(x+(renderAvatar-2*renderGlyphWidth)/2, y+(renderAvatar-2*renderGlyphHeight)/2, initial, color.RGBA{0xff, 0xff, 0xff, 0xff}, 2, false)
+	}
+}
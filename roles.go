@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/kalikim/x-cli/config"
+)
+
+// Actions a profile's allow list can name.
+const (
+	actionPost     = "post"
+	actionSchedule = "schedule"
+	actionDelete   = "delete"
+	actionCancel   = "cancel"
+	actionLike     = "like"
+	actionRetweet  = "retweet"
+	actionDM       = "dm"
+)
+
+var profileActions = []string{actionPost, actionSchedule, actionDelete, actionCancel, actionLike, actionRetweet, actionDM}
+
+// checkAllowed refuses action when the profile in use lists its allowed
+// actions and action is not one of them. It runs before any API call. The
+// scheduler daemon is not checked: it posts what the profile was allowed to
+// schedule.
+func checkAllowed(cfg config.Config, action string) error {
+	if cfg.Allow == nil {
+		return nil
+	}
+
+	profile := "the default profile"
+	if cfg.ProfileName != "" {
+		profile = fmt.Sprintf("profile %q", cfg.ProfileName)
+	}
+	for _, a := range cfg.Allow {
+		if !slices.Contains(profileActions, a) {
+			return invalidInput(fmt.Errorf("%s: unknown action %q in allow (use %s)", profile, a, strings.Join(profileActions, ", ")))
+		}
+	}
+	if slices.Contains(cfg.Allow, action) {
+		return nil
+	}
+
+	allowed := "nothing"
+	if len(cfg.Allow) > 0 {
+		allowed = strings.Join(cfg.Allow, ", ")
+	}
+	return invalidInput(fmt.Errorf("%s may not %s (allowed: %s)", profile, action, allowed))
+}
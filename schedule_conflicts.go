@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kalikim/x-cli/config"
+)
+
+// defaultMinSpacing keeps accidental back-to-back posts apart when
+// min_spacing is not configured.
+const defaultMinSpacing = 5 * time.Minute
+
+// minSpacing returns the configured gap between tweets from one account.
+func minSpacing(cfg config.Config) (time.Duration, error) {
+	if cfg.MinSpacing == "" {
+		return defaultMinSpacing, nil
+	}
+
+	d, err := time.ParseDuration(cfg.MinSpacing)
+	if err != nil || d < 0 {
+		return 0, invalidInput(fmt.Errorf("invalid min_spacing %q in config (use e.g. 10m, or 0 to disable)", cfg.MinSpacing))
+	}
+	return d, nil
+}
+
+// scheduleConflicts returns the pending tweets of every queue that will be
+// posted from the same account as tweet within the minimum spacing of it.
+func scheduleConflicts(cfg config.Config, queue string, tweet scheduledTweet) ([]scheduledTweet, time.Duration, error) {
+	spacing, err := minSpacing(cfg)
+	if err != nil || spacing == 0 {
+		return nil, spacing, err
+	}
+
+	account := tweetAccount(cfg, queue, tweet)
+
+	var conflicts []scheduledTweet
+	for _, q := range knownQueues(cfg) {
+		tweets, err := loadScheduledTweets(q)
+		if err != nil {
+			return nil, spacing, fmt.Errorf("queue %s: %w", queueLabel(q), err)
+		}
+
+		for _, other := range tweets {
+			if tweetAccount(cfg, q, other) != account {
+				continue
+			}
+			gap := other.ScheduleTime.Sub(tweet.ScheduleTime)
+			if gap < 0 {
+				gap = -gap
+			}
+			if gap < spacing {
+				conflicts = append(conflicts, other)
+			}
+		}
+	}
+
+	sort.Slice(conflicts, func(i, j int) bool {
+		return conflicts[i].ScheduleTime.Before(conflicts[j].ScheduleTime)
+	})
+	return conflicts, spacing, nil
+}
+
+// tweetAccount names the profile a tweet in queue is posted from.
+func tweetAccount(cfg config.Config, queue string, tweet scheduledTweet) string {
+	if tweet.Profile != "" {
+		return tweet.Profile
+	}
+	return queueProfile(cfg, queue)
+}
+
+// checkScheduleConflicts refuses a tweet that lands too close to another one
+// from the same account.
+func checkScheduleConflicts(cfg config.Config, queue string, tweet scheduledTweet) error {
+	conflicts, spacing, err := scheduleConflicts(cfg, queue, tweet)
+	if err != nil || len(conflicts) == 0 {
+		return err
+	}
+
+	var ids []string
+	for _, c := range conflicts {
+		ids = append(ids, fmt.Sprintf("%s at %s", c.ID, c.ScheduleTime.Format("15:04")))
+	}
+
+	return invalidInput(fmt.Errorf("tweet is scheduled within %s of %s from the same account", spacing, strings.Join(ids, ", ")))
+}
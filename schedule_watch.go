@@ -0,0 +1,49 @@
+package main
+
+import (
+	"strings"
+	"time"
+)
+
+// minWakeDelay keeps the daemon from spinning when a tweet is due within
+// the same instant it finished a check.
+const minWakeDelay = time.Second
+
+// isScheduleFile reports whether name is a store whose changes the daemon
+// reacts to: a queue file or the scheduler pause state. Lock files and the
+// temporary files written by writeFileAtomic do not count.
+func isScheduleFile(name string) bool {
+	if name == schedulerStateFile {
+		return true
+	}
+	return strings.HasPrefix(name, "scheduled_tweets") && strings.HasSuffix(name, ".json")
+}
+
+// nextCheckDelay returns how long the daemon waits before its next check:
+// the interval, or less when a pending tweet falls due or is to be
+// announced sooner. Tweets that are already due but held (retries, quiet
+// hours, caps) wait the interval.
+func nextCheckDelay(interval time.Duration, pending []scheduledTweet, notice postingNotice, now time.Time) time.Duration {
+	delay := interval
+	for _, tweet := range pending {
+		if tweet.Paused || !tweet.ScheduleTime.After(now) {
+			continue
+		}
+		if until := tweet.ScheduleTime.Sub(now); until < delay {
+			delay = until
+		}
+		if at := notice.noticeAt(tweet); at.After(now) && at.Sub(now) < delay {
+			delay = at.Sub(now)
+		}
+	}
+	return max(delay, minWakeDelay)
+}
+
+// notify signals changed without blocking; a pending signal already covers
+// any number of changes.
+func notify(changed chan<- struct{}) {
+	select {
+	case changed <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,57 @@
+//go:build linux
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// watchScheduleFiles uses inotify on the working directory to signal every
+// time a schedule file is written, replaced or removed. The returned
+// function stops the watch.
+func watchScheduleFiles() (<-chan struct{}, func(), error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, nil, fmt.Errorf("starting inotify: %w", err)
+	}
+
+	// writeFileAtomic renames files into place, so IN_MOVED_TO covers
+	// updates; IN_CLOSE_WRITE covers files written in place by hand.
+	const mask = syscall.IN_CLOSE_WRITE | syscall.IN_MOVED_TO | syscall.IN_DELETE
+	if _, err := syscall.InotifyAddWatch(fd, ".", mask); err != nil {
+		syscall.Close(fd)
+		return nil, nil, fmt.Errorf("watching working directory: %w", err)
+	}
+
+	// A non-blocking descriptor is handled by the runtime poller, so
+	// closing the file unblocks the reader below.
+	f := os.NewFile(uintptr(fd), "inotify")
+	changed := make(chan struct{}, 1)
+
+	go func() {
+		buf := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+		for {
+			n, err := f.Read(buf)
+			if err != nil {
+				return
+			}
+			for offset := 0; offset+syscall.SizeofInotifyEvent <= n; {
+				event := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+				nameStart := offset + syscall.SizeofInotifyEvent
+				name := buf[nameStart : nameStart+int(event.Len)]
+				for len(name) > 0 && name[len(name)-1] == 0 {
+					name = name[:len(name)-1]
+				}
+				if isScheduleFile(string(name)) {
+					notify(changed)
+				}
+				offset = nameStart + int(event.Len)
+			}
+		}
+	}()
+
+	return changed, func() { f.Close() }, nil
+}
@@ -0,0 +1,75 @@
+//go:build !linux
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// schedulePollInterval is how often schedule files are compared when the
+// platform has no inotify.
+const schedulePollInterval = time.Second
+
+// watchScheduleFiles polls the schedule files' sizes and modification times
+// and signals whenever they change. The returned function stops the watch.
+func watchScheduleFiles() (<-chan struct{}, func(), error) {
+	changed := make(chan struct{}, 1)
+	done := make(chan struct{})
+
+	go func() {
+		ticker := time.NewTicker(schedulePollInterval)
+		defer ticker.Stop()
+
+		last := scheduleFileStamps()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+
+			current := scheduleFileStamps()
+			if !sameStamps(last, current) {
+				notify(changed)
+			}
+			last = current
+		}
+	}()
+
+	return changed, func() { close(done) }, nil
+}
+
+type fileStamp struct {
+	size    int64
+	modTime time.Time
+}
+
+func scheduleFileStamps() map[string]fileStamp {
+	names, _ := filepath.Glob("scheduled_tweets*.json")
+	names = append(names, schedulerStateFile)
+
+	stamps := map[string]fileStamp{}
+	for _, name := range names {
+		if !isScheduleFile(name) {
+			continue
+		}
+		if info, err := os.Stat(name); err == nil {
+			stamps[name] = fileStamp{info.Size(), info.ModTime()}
+		}
+	}
+	return stamps
+}
+
+func sameStamps(a, b map[string]fileStamp) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for name, stamp := range a {
+		if other, ok := b[name]; !ok || !other.modTime.Equal(stamp.modTime) || other.size != stamp.size {
+			return false
+		}
+	}
+	return true
+}
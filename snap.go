@@ -0,0 +1,174 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/kalikim/x-cli/config"
+	"github.com/spf13/cobra"
+)
+
+// captureTool is an external command able to write a PNG to a file. The
+// placeholder "{file}" in args is replaced with the destination path; tools
+// with stdout set write the image to standard output instead.
+type captureTool struct {
+	name   string
+	args   []string
+	stdout bool
+}
+
+func newSnapCmd() *cobra.Command {
+	var text, output string
+	var fromClipboard, noFooter, force bool
+
+	snapCmd := &cobra.Command{
+		Use:   "snap",
+		Short: "Capture a screenshot (or clipboard image) and post it",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			text = strings.TrimSpace(text)
+			if text == "" {
+				return invalidInput(errors.New("text flag cannot be empty"))
+			}
+
+			cfg := config.LoadConfig()
+			if err := cfg.Validate(); err != nil {
+				return err
+			}
+			if err := checkWritable(cfg); err != nil {
+				return err
+			}
+			if !noFooter {
+				withFooter, err := applyFooter(cfg, text)
+				if err != nil {
+					return err
+				}
+				text = withFooter
+			}
+			if err := checkPostTexts(cfg, []ruleText{{"", text}}, force); err != nil {
+				return err
+			}
+
+			path := output
+			if path == "" {
+				tmp, err := os.CreateTemp("", "x-cli-snap-*.png")
+				if err != nil {
+					return fmt.Errorf("creating temporary file: %w", err)
+				}
+				tmp.Close()
+				path = tmp.Name()
+				defer os.Remove(path)
+			}
+
+			var err error
+			if fromClipboard {
+				err = captureImage(path, clipboardImageTools())
+			} else {
+				say("📸", "Select the screen region to capture...")
+				err = captureImage(path, screenshotTools())
+			}
+			if err != nil {
+				return err
+			}
+
+			client := apiClient()
+			if _, err := publishTweet(client, cfg, text, path, mediaOptions{}, tweetExtras{}); err != nil {
+				return err
+			}
+
+			say("✅", "Tweet with media posted successfully!")
+			return nil
+		},
+	}
+
+	snapCmd.Flags().StringVarP(&text, "text", "t", "", "Tweet text")
+	snapCmd.Flags().BoolVar(&fromClipboard, "clipboard", false, "Use the image currently on the clipboard instead of taking a screenshot")
+	snapCmd.Flags().StringVarP(&output, "output", "o", "", "Keep the captured image at this path")
+	snapCmd.Flags().BoolVar(&noFooter, "no-footer", false, "Do not append the configured footer")
+	snapCmd.Flags().BoolVar(&force, "force", false, "Post even if the text matches banned content or breaks content rules")
+	snapCmd.MarkFlagRequired("text")
+
+	return snapCmd
+}
+
+func screenshotTools() []captureTool {
+	switch runtime.GOOS {
+	case "darwin":
+		return []captureTool{{name: "screencapture", args: []string{"-i", "-x", "{file}"}}}
+	case "linux":
+		return []captureTool{
+			{name: "gnome-screenshot", args: []string{"-a", "-f", "{file}"}},
+			{name: "spectacle", args: []string{"-r", "-b", "-n", "-o", "{file}"}},
+			{name: "maim", args: []string{"-s", "{file}"}},
+			{name: "scrot", args: []string{"-s", "-o", "{file}"}},
+			{name: "import", args: []string{"{file}"}},
+		}
+	default:
+		return nil
+	}
+}
+
+func clipboardImageTools() []captureTool {
+	switch runtime.GOOS {
+	case "darwin":
+		return []captureTool{{name: "pngpaste", args: []string{"{file}"}}}
+	case "linux":
+		tools := []captureTool{
+			{name: "xclip", args: []string{"-selection", "clipboard", "-t", "image/png", "-o"}, stdout: true},
+		}
+		if os.Getenv("WAYLAND_DISPLAY") != "" {
+			tools = append([]captureTool{{name: "wl-paste", args: []string{"--type", "image/png"}, stdout: true}}, tools...)
+		}
+		return tools
+	default:
+		return nil
+	}
+}
+
+// captureImage runs the first installed tool from tools to produce an image
+// at path.
+func captureImage(path string, tools []captureTool) error {
+	var names []string
+
+	for _, tool := range tools {
+		names = append(names, tool.name)
+
+		bin, err := exec.LookPath(tool.name)
+		if err != nil {
+			continue
+		}
+
+		args := make([]string, len(tool.args))
+		for i, a := range tool.args {
+			args[i] = strings.ReplaceAll(a, "{file}", path)
+		}
+
+		cmd := exec.Command(bin, args...)
+		cmd.Stderr = os.Stderr
+		if tool.stdout {
+			out, err := cmd.Output()
+			if err != nil {
+				return fmt.Errorf("%s failed: %w", tool.name, err)
+			}
+			if err := os.WriteFile(path, out, 0600); err != nil {
+				return fmt.Errorf("writing image: %w", err)
+			}
+		} else if err := cmd.Run(); err != nil {
+			return fmt.Errorf("%s failed: %w", tool.name, err)
+		}
+
+		info, err := os.Stat(path)
+		if err != nil || info.Size() == 0 {
+			return errors.New("no image was captured")
+		}
+		return nil
+	}
+
+	if len(names) == 0 {
+		return fmt.Errorf("image capture is not supported on %s", runtime.GOOS)
+	}
+	return fmt.Errorf("no capture tool found; install one of: %s", strings.Join(names, ", "))
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/kalikim/x-cli/config"
+	"github.com/spf13/cobra"
+)
+
+const (
+	spacesEndpoint       = "https://api.twitter.com/2/spaces"
+	spacesSearchEndpoint = "https://api.twitter.com/2/spaces/search"
+
+	spaceFields = "title,state,scheduled_start,started_at,ended_at,participant_count,creator_id,host_ids,lang"
+)
+
+var spaceStates = []string{"all", "live", "scheduled"}
+
+var spaceColumns = []string{"id", "state", "title", "scheduled_start", "started_at", "participants", "host", "url"}
+
+// space is a Space as returned by the v2 Spaces endpoints, with the
+// creator's username filled in from the expanded users.
+type space struct {
+	ID               string    `json:"id"`
+	State            string    `json:"state"`
+	Title            string    `json:"title"`
+	ScheduledStart   time.Time `json:"scheduled_start"`
+	StartedAt        time.Time `json:"started_at"`
+	EndedAt          time.Time `json:"ended_at"`
+	ParticipantCount int       `json:"participant_count"`
+	CreatorID        string    `json:"creator_id"`
+	Lang             string    `json:"lang"`
+	Host             string    `json:"-"`
+}
+
+type spacesIncludes struct {
+	Users []struct {
+		ID       string `json:"id"`
+		Username string `json:"username"`
+	} `json:"users"`
+}
+
+func newSpacesCmd() *cobra.Command {
+	spacesCmd := &cobra.Command{
+		Use:   "spaces",
+		Short: "Look up live and scheduled Spaces",
+	}
+
+	var state, output string
+
+	searchCmd := &cobra.Command{
+		Use:   "search <query>",
+		Short: "Search Spaces by title",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !slices.Contains(spaceStates, state) {
+				return invalidInput(fmt.Errorf("unknown state %q (use %s)", state, strings.Join(spaceStates, ", ")))
+			}
+
+			cfg := config.LoadConfig()
+			if err := cfg.Validate(); err != nil {
+				return err
+			}
+
+			spaces, err := searchSpaces(apiClient(), cfg, args[0], state)
+			if err != nil {
+				return err
+			}
+			if len(spaces) == 0 && output == "table" {
+				say("📭", "No Spaces match %q", args[0])
+				return nil
+			}
+
+			return writeSpaces(spaces, output)
+		},
+	}
+	searchCmd.Flags().StringVar(&state, "state", "all", "Only show Spaces in this state: all, live or scheduled")
+	searchCmd.Flags().StringVarP(&output, "output", "o", "table", "Output format: table, json, yaml or tsv")
+
+	var showOutput string
+
+	showCmd := &cobra.Command{
+		Use:   "show <space-id|url>",
+		Short: "Show a Space's title, state, host and start time",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg := config.LoadConfig()
+			if err := cfg.Validate(); err != nil {
+				return err
+			}
+
+			s, err := fetchSpace(apiClient(), cfg, spaceIDFromArg(args[0]))
+			if err != nil {
+				return err
+			}
+
+			if showOutput != "" {
+				return writeSpaces([]space{s}, showOutput)
+			}
+			printSpace(s, time.Now())
+			return nil
+		},
+	}
+	showCmd.Flags().StringVarP(&showOutput, "output", "o", "", "Output format: table, json, yaml or tsv (default: details)")
+
+	spacesCmd.AddCommand(searchCmd, showCmd)
+	return spacesCmd
+}
+
+func searchSpaces(client *http.Client, cfg config.Config, query, state string) ([]space, error) {
+	params := url.Values{}
+	params.Set("query", query)
+	params.Set("state", state)
+	params.Set("space.fields", spaceFields)
+	params.Set("expansions", "creator_id")
+	params.Set("user.fields", "username")
+
+	body, err := signedGet(client, cfg, spacesSearchEndpoint+"?"+params.Encode())
+	if err != nil {
+		return nil, fmt.Errorf("searching spaces: %w", err)
+	}
+
+	var resp struct {
+		Data     []space        `json:"data"`
+		Includes spacesIncludes `json:"includes"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("decoding spaces: %w", err)
+	}
+
+	for i := range resp.Data {
+		resp.Data[i].Host = resp.Includes.username(resp.Data[i].CreatorID)
+	}
+	return resp.Data, nil
+}
+
+func fetchSpace(client *http.Client, cfg config.Config, id string) (space, error) {
+	if id == "" {
+		return space{}, invalidInput(errors.New("space ID cannot be empty"))
+	}
+
+	params := url.Values{}
+	params.Set("space.fields", spaceFields)
+	params.Set("expansions", "creator_id")
+	params.Set("user.fields", "username")
+
+	body, err := signedGet(client, cfg, spacesEndpoint+"/"+url.PathEscape(id)+"?"+params.Encode())
+	if err != nil {
+		return space{}, fmt.Errorf("fetching space: %w", err)
+	}
+
+	var resp struct {
+		Data     *space         `json:"data"`
+		Includes spacesIncludes `json:"includes"`
+		Errors   []struct {
+			Detail string `json:"detail"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return space{}, fmt.Errorf("decoding space: %w", err)
+	}
+	if resp.Data == nil {
+		if len(resp.Errors) > 0 {
+			return space{}, fmt.Errorf("space %s: %s", id, resp.Errors[0].Detail)
+		}
+		return space{}, fmt.Errorf("space %s not found", id)
+	}
+
+	s := *resp.Data
+	s.Host = resp.Includes.username(s.CreatorID)
+	return s, nil
+}
+
+func (inc spacesIncludes) username(id string) string {
+	for _, u := range inc.Users {
+		if u.ID == id {
+			return u.Username
+		}
+	}
+	return ""
+}
+
+// spaceIDFromArg accepts a bare Space ID or a Space link such as
+// https://x.com/i/spaces/1dRJZlbLkjexB.
+func spaceIDFromArg(arg string) string {
+	arg = strings.TrimSpace(arg)
+	if _, rest, found := strings.Cut(arg, "/spaces/"); found {
+		arg = rest
+	}
+	arg, _, _ = strings.Cut(arg, "?")
+	return strings.Trim(arg, "/")
+}
+
+func spaceURL(id string) string {
+	return "https://x.com/i/spaces/" + id
+}
+
+func writeSpaces(spaces []space, format string) error {
+	rows := make([][]any, len(spaces))
+	for i, s := range spaces {
+		rows[i] = []any{s.ID, s.State, s.Title, formatSpaceTime(s.ScheduledStart), formatSpaceTime(s.StartedAt), s.ParticipantCount, s.Host, spaceURL(s.ID)}
+	}
+
+	return writeRows(format, spaceColumns, rows)
+}
+
+func formatSpaceTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+func printSpace(s space, now time.Time) {
+	title := s.Title
+	if title == "" {
+		title = "(untitled)"
+	}
+
+	say("🎙️", "%s", title)
+	fmt.Printf("State: %s\n", s.State)
+	if s.Host != "" {
+		fmt.Printf("Host: @%s\n", s.Host)
+	}
+
+	switch s.State {
+	case "scheduled":
+		if !s.ScheduledStart.IsZero() {
+			local := s.ScheduledStart.Local()
+			fmt.Printf("Starts: %s (in %s)\n", local.Format("2006-01-02 15:04 MST"), formatCountdown(local.Sub(now).Round(time.Minute)))
+		}
+	case "live":
+		if !s.StartedAt.IsZero() {
+			fmt.Printf("Started: %s\n", s.StartedAt.Local().Format("2006-01-02 15:04 MST"))
+		}
+		fmt.Printf("Listeners: %d\n", s.ParticipantCount)
+	case "ended":
+		if !s.EndedAt.IsZero() {
+			fmt.Printf("Ended: %s\n", s.EndedAt.Local().Format("2006-01-02 15:04 MST"))
+		}
+	}
+
+	fmt.Printf("Link: %s\n", spaceURL(s.ID))
+}
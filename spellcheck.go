@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/kalikim/x-cli/config"
+)
+
+var errNoSpellchecker = errors.New("no spell checker available")
+
+// defaultDictionaries are word lists probed when neither a dictionary is
+// configured nor hunspell/aspell is installed.
+var defaultDictionaries = []string{
+	"/usr/share/dict/words",
+	"/usr/share/hunspell/en_US.dic",
+	"/usr/share/myspell/en_US.dic",
+}
+
+var spellSkipPattern = regexp.MustCompile(`(?i)\bhttps?://\S+|\bwww\.\S+|[@#]\w+|\S+@\S+\.\S+`)
+
+// spellcheck returns the words in text that the configured dictionary does
+// not recognize, in order of appearance. It returns errNoSpellchecker when no
+// dictionary or spell checking tool is available.
+func spellcheck(cfg config.Config, text string) ([]string, error) {
+	words := spellcheckWords(text)
+	if len(words) == 0 {
+		return nil, nil
+	}
+
+	var unknown []string
+	var err error
+
+	switch {
+	case cfg.SpellcheckDictionary != "":
+		unknown, err = checkWithWordList(cfg.SpellcheckDictionary, words)
+	case commandAvailable("hunspell"):
+		unknown, err = checkWithCommand(words, "hunspell", "-l")
+	case commandAvailable("aspell"):
+		unknown, err = checkWithCommand(words, "aspell", "list")
+	default:
+		err = errNoSpellchecker
+		for _, path := range defaultDictionaries {
+			if _, statErr := os.Stat(path); statErr == nil {
+				unknown, err = checkWithWordList(path, words)
+				break
+			}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	allowed := map[string]bool{}
+	for _, w := range cfg.SpellcheckWords {
+		allowed[strings.ToLower(w)] = true
+	}
+
+	seen := map[string]bool{}
+	var misspelled []string
+	for _, w := range unknown {
+		key := strings.ToLower(w)
+		if allowed[key] || seen[key] {
+			continue
+		}
+		seen[key] = true
+		misspelled = append(misspelled, w)
+	}
+
+	return misspelled, nil
+}
+
+// spellcheckWords extracts the words worth checking, skipping URLs, mentions,
+// hashtags, acronyms and anything containing digits.
+func spellcheckWords(text string) []string {
+	text = spellSkipPattern.ReplaceAllString(text, " ")
+
+	var words []string
+	for _, token := range strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\'' && r != '’'
+	}) {
+		token = strings.Trim(token, "'’")
+		if len([]rune(token)) < 2 || strings.IndexFunc(token, unicode.IsDigit) >= 0 {
+			continue
+		}
+		if isAcronymOrCamelCase(token) {
+			continue
+		}
+		words = append(words, token)
+	}
+
+	return words
+}
+
+func isAcronymOrCamelCase(word string) bool {
+	for i, r := range []rune(word) {
+		if i > 0 && unicode.IsUpper(r) {
+			return true
+		}
+	}
+	return false
+}
+
+func commandAvailable(name string) bool {
+	_, err := exec.LookPath(name)
+	return err == nil
+}
+
+func checkWithCommand(words []string, name string, args ...string) ([]string, error) {
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = strings.NewReader(strings.Join(words, "\n"))
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("running %s: %w", name, err)
+	}
+
+	return strings.Fields(string(out)), nil
+}
+
+// checkWithWordList looks words up in a plain word list or hunspell .dic
+// file (affix flags after "/" are ignored). Common English suffixes are
+// stripped so that inflected forms of listed words are accepted.
+func checkWithWordList(path string, words []string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening dictionary: %w", err)
+	}
+	defer f.Close()
+
+	dict := map[string]bool{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		entry, _, _ := strings.Cut(strings.TrimSpace(scanner.Text()), "/")
+		if entry != "" {
+			dict[strings.ToLower(entry)] = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading dictionary: %w", err)
+	}
+
+	var unknown []string
+	for _, w := range words {
+		if !inDictionary(dict, strings.ToLower(strings.ReplaceAll(w, "’", "'"))) {
+			unknown = append(unknown, w)
+		}
+	}
+
+	return unknown, nil
+}
+
+func inDictionary(dict map[string]bool, word string) bool {
+	if dict[word] {
+		return true
+	}
+
+	word = strings.TrimSuffix(word, "'s")
+	if dict[word] {
+		return true
+	}
+
+	for _, suffix := range []string{"s", "es", "ed", "d", "ing", "ly", "er", "est"} {
+		stem, ok := strings.CutSuffix(word, suffix)
+		if ok && len(stem) > 1 && (dict[stem] || dict[stem+"e"]) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,565 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kalikim/x-cli/config"
+	"github.com/spf13/cobra"
+)
+
+const statsReportsFile = "stats_reports.json"
+
+// tweetLookupBatch is the most IDs GET /2/tweets accepts per request.
+const tweetLookupBatch = 100
+
+var statsCSVHeader = []string{"report_time", "tweet_id", "posted_at", "text", "impressions", "likes", "retweets", "replies", "quotes", "bookmarks"}
+
+// statsReport is a recurring engagement report written by the scheduler
+// daemon.
+type statsReport struct {
+	To      string    `json:"to"`
+	Every   string    `json:"every"`
+	LastRun time.Time `json:"last_run,omitempty"`
+}
+
+// tweetMetrics are the public engagement counts of one posted tweet.
+type tweetMetrics struct {
+	TweetID     string
+	PostedAt    time.Time
+	Text        string
+	Impressions int
+	Likes       int
+	Retweets    int
+	Replies     int
+	Quotes      int
+	Bookmarks   int
+}
+
+func newStatsCmd() *cobra.Command {
+	statsCmd := &cobra.Command{
+		Use:   "stats",
+		Short: "Track engagement of posted tweets",
+	}
+
+	var to, every, since string
+	var stop bool
+
+	reportCmd := &cobra.Command{
+		Use:   "report",
+		Short: "Append engagement metrics of recent tweets to a CSV file",
+		Long: "Append engagement metrics of recent tweets to a CSV file. With --every the\n" +
+			"report is registered with the scheduler daemon, which appends a new set of\n" +
+			"rows each period.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if stop {
+				return removeStatsReport(to)
+			}
+
+			if every != "" {
+				if _, err := parsePeriod(every); err != nil {
+					return fmt.Errorf("--every: %w", err)
+				}
+				if err := saveStatsReport(statsReport{To: to, Every: every}); err != nil {
+					return err
+				}
+				say("📊", "Engagement report to %s scheduled every %s", to, every)
+				say("💡", "Run 'x-cli scheduler daemon' to write it")
+				return nil
+			}
+
+			window, err := parsePeriod(since)
+			if err != nil {
+				return fmt.Errorf("--since: %w", err)
+			}
+
+			cfg := config.LoadConfig()
+			if err := cfg.Validate(); err != nil {
+				return err
+			}
+
+			n, err := writeStatsReport(apiClient(), cfg, to, time.Now(), window)
+			if err != nil {
+				return err
+			}
+			say("✅", "Appended metrics for %d tweet(s) to %s", n, to)
+			return nil
+		},
+	}
+	reportCmd.Flags().StringVar(&to, "to", "", "CSV file to append to")
+	reportCmd.Flags().StringVar(&every, "every", "", "Let the scheduler daemon append a report this often (e.g. 1d, 1w)")
+	reportCmd.Flags().StringVar(&since, "since", "7d", "Include tweets posted within this period")
+	reportCmd.Flags().BoolVar(&stop, "stop", false, "Stop the recurring report for --to")
+	reportCmd.MarkFlagRequired("to")
+	reportCmd.MarkFlagsMutuallyExclusive("every", "stop")
+
+	var variantSince, variantCampaign, variantLabels, variantOutput string
+
+	variantsCmd := &cobra.Command{
+		Use:   "variants",
+		Short: "Compare engagement of A/B variants posted by the daemon",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			window, err := parsePeriod(variantSince)
+			if err != nil {
+				return fmt.Errorf("--since: %w", err)
+			}
+			labels, err := parseLabels(variantLabels)
+			if err != nil {
+				return err
+			}
+
+			cfg := config.LoadConfig()
+			if err := cfg.Validate(); err != nil {
+				return err
+			}
+
+			entries, err := loadHistory()
+			if err != nil {
+				return fmt.Errorf("loading history: %w", err)
+			}
+
+			cutoff := time.Now().Add(-window)
+			var tested []historyEntry
+			for _, entry := range entries {
+				if entry.Variant == "" || entry.PostedAt.Before(cutoff) {
+					continue
+				}
+				if variantCampaign != "" && entry.Campaign != variantCampaign {
+					continue
+				}
+				if !hasLabels(entry.Labels, labels) {
+					continue
+				}
+				tested = append(tested, entry)
+			}
+			if len(tested) == 0 {
+				say("📭", "No A/B variants were posted in that period")
+				return nil
+			}
+
+			metrics, err := fetchTweetMetrics(apiClient(), cfg, tested)
+			if err != nil {
+				return err
+			}
+
+			return writeVariantComparison(tested, metrics, variantOutput)
+		},
+	}
+	variantsCmd.Flags().StringVar(&variantSince, "since", "30d", "Include tweets posted within this period")
+	variantsCmd.Flags().StringVar(&variantCampaign, "campaign", "", "Only include tweets of this campaign")
+	variantsCmd.Flags().StringVar(&variantLabels, "label", "", "Only include tweets carrying all of these labels")
+	variantsCmd.Flags().StringVarP(&variantOutput, "output", "o", "table", "Output format: table, json, yaml or tsv")
+
+	var latencySince, latencyOutput string
+
+	latencyCmd := &cobra.Command{
+		Use:   "latency",
+		Short: "Show how late the daemon posted scheduled tweets, per queue",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			window, err := parsePeriod(latencySince)
+			if err != nil {
+				return fmt.Errorf("--since: %w", err)
+			}
+
+			cfg := config.LoadConfig()
+			timing, err := resolveDaemonTiming(cfg.Daemon, daemonTimingFlags{})
+			if err != nil {
+				return err
+			}
+
+			entries, err := loadHistory()
+			if err != nil {
+				return fmt.Errorf("loading history: %w", err)
+			}
+
+			return writeLatencyReport(entries, time.Now().Add(-window), timing.MaxMedianLateness, latencyOutput)
+		},
+	}
+	latencyCmd.Flags().StringVar(&latencySince, "since", "30d", "Include tweets posted within this period")
+	latencyCmd.Flags().StringVarP(&latencyOutput, "output", "o", "table", "Output format: table, json, yaml or tsv")
+
+	var usageSince, usageKind, usageOutput string
+
+	usageCmd := &cobra.Command{
+		Use:   "usage",
+		Short: "Show how often commands run and how long API calls take",
+		Long: "Summarize the local usage log: how often each command ran and failed, and\n" +
+			"the median, 95th percentile and slowest duration of each command and X API\n" +
+			"endpoint. Recording is off until usage_stats is set in the config, and the\n" +
+			"log never leaves this machine.",
+		Example: `  x-cli stats usage
+  x-cli stats usage --since 7d --kind api
+  x-cli stats usage -o json`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			window, err := parsePeriod(usageSince)
+			if err != nil {
+				return fmt.Errorf("--since: %w", err)
+			}
+			if usageKind != "" && usageKind != usageCommand && usageKind != usageAPI {
+				return invalidInput(fmt.Errorf("invalid --kind %q (use command or api)", usageKind))
+			}
+			if !recordingUsage() {
+				say("💡", "Usage is not being recorded; set \"usage_stats\": true in the config to start")
+			}
+
+			entries, err := loadUsage(time.Now().Add(-window))
+			if err != nil {
+				return fmt.Errorf("loading usage log: %w", err)
+			}
+			return writeUsageReport(entries, usageKind, usageOutput)
+		},
+	}
+	usageCmd.Flags().StringVar(&usageSince, "since", "30d", "Include usage within this period")
+	usageCmd.Flags().StringVar(&usageKind, "kind", "", "Only show commands or api calls")
+	usageCmd.Flags().StringVarP(&usageOutput, "output", "o", "table", "Output format: table, json, yaml or tsv")
+
+	statsCmd.AddCommand(reportCmd, variantsCmd, latencyCmd, usageCmd)
+	return statsCmd
+}
+
+// parsePeriod is parseDelay with an additional week suffix, e.g. "2w".
+func parsePeriod(value string) (time.Duration, error) {
+	if weeks, ok := strings.CutSuffix(strings.TrimSpace(value), "w"); ok {
+		n, err := strconv.Atoi(weeks)
+		if err != nil || n <= 0 {
+			return 0, invalidInput(fmt.Errorf("invalid period %q", value))
+		}
+		return time.Duration(n) * 7 * 24 * time.Hour, nil
+	}
+
+	return parseDelay(value)
+}
+
+// writeStatsReport looks up the metrics of tweets posted within window
+// before now and appends one row per tweet to the CSV file at path.
+func writeStatsReport(client *http.Client, cfg config.Config, path string, now time.Time, window time.Duration) (int, error) {
+	entries, err := loadHistory()
+	if err != nil {
+		return 0, fmt.Errorf("loading history: %w", err)
+	}
+
+	var recent []historyEntry
+	for _, entry := range entries {
+		if entry.TweetID != "" && entry.PostedAt.After(now.Add(-window)) {
+			recent = append(recent, entry)
+		}
+	}
+
+	metrics, err := fetchTweetMetrics(client, cfg, recent)
+	if err != nil {
+		return 0, err
+	}
+
+	return len(metrics), appendStatsCSV(path, now, metrics)
+}
+
+func fetchTweetMetrics(client *http.Client, cfg config.Config, entries []historyEntry) ([]tweetMetrics, error) {
+	var metrics []tweetMetrics
+
+	for start := 0; start < len(entries); start += tweetLookupBatch {
+		batch := entries[start:min(start+tweetLookupBatch, len(entries))]
+
+		ids := make([]string, len(batch))
+		for i, entry := range batch {
+			ids[i] = entry.TweetID
+		}
+
+		params := url.Values{}
+		params.Set("ids", strings.Join(ids, ","))
+		params.Set("tweet.fields", "public_metrics")
+
+		body, err := signedGet(client, cfg, tweetEndpoint+"?"+params.Encode())
+		if err != nil {
+			return nil, fmt.Errorf("fetching tweet metrics: %w", err)
+		}
+
+		var resp struct {
+			Data []struct {
+				ID            string `json:"id"`
+				PublicMetrics struct {
+					Impressions int `json:"impression_count"`
+					Likes       int `json:"like_count"`
+					Retweets    int `json:"retweet_count"`
+					Replies     int `json:"reply_count"`
+					Quotes      int `json:"quote_count"`
+					Bookmarks   int `json:"bookmark_count"`
+				} `json:"public_metrics"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(body, &resp); err != nil {
+			return nil, fmt.Errorf("decoding tweet metrics: %w", err)
+		}
+
+		// Deleted tweets are missing from the response and simply skipped.
+		byID := map[string]historyEntry{}
+		for _, entry := range batch {
+			byID[entry.TweetID] = entry
+		}
+		for _, t := range resp.Data {
+			entry := byID[t.ID]
+			m := t.PublicMetrics
+			metrics = append(metrics, tweetMetrics{
+				TweetID:     t.ID,
+				PostedAt:    entry.PostedAt,
+				Text:        entry.Text,
+				Impressions: m.Impressions,
+				Likes:       m.Likes,
+				Retweets:    m.Retweets,
+				Replies:     m.Replies,
+				Quotes:      m.Quotes,
+				Bookmarks:   m.Bookmarks,
+			})
+		}
+	}
+
+	return metrics, nil
+}
+
+// appendStatsCSV appends rows to path, writing the header first when the
+// file is new or empty.
+func appendStatsCSV(path string, reportTime time.Time, metrics []tweetMetrics) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("opening report: %w", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("opening report: %w", err)
+	}
+
+	w := csv.NewWriter(f)
+	if info.Size() == 0 {
+		w.Write(statsCSVHeader)
+	}
+
+	stamp := reportTime.Format(time.RFC3339)
+	for _, m := range metrics {
+		w.Write([]string{
+			stamp,
+			m.TweetID,
+			m.PostedAt.Format(time.RFC3339),
+			m.Text,
+			strconv.Itoa(m.Impressions),
+			strconv.Itoa(m.Likes),
+			strconv.Itoa(m.Retweets),
+			strconv.Itoa(m.Replies),
+			strconv.Itoa(m.Quotes),
+			strconv.Itoa(m.Bookmarks),
+		})
+	}
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return fmt.Errorf("writing report: %w", err)
+	}
+	return nil
+}
+
+func loadStatsReports() ([]statsReport, error) {
+	data, err := os.ReadFile(statsReportsFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var reports []statsReport
+	if err := json.Unmarshal(data, &reports); err != nil {
+		return nil, err
+	}
+	return reports, nil
+}
+
+// updateStatsReports applies fn to the registered reports under the store
+// lock.
+func updateStatsReports(fn func([]statsReport) ([]statsReport, error)) error {
+	return withFileLock(statsReportsFile, func() error {
+		reports, err := loadStatsReports()
+		if err != nil {
+			return fmt.Errorf("loading reports: %w", err)
+		}
+
+		if reports, err = fn(reports); err != nil {
+			return err
+		}
+
+		data, err := json.MarshalIndent(reports, "", "  ")
+		if err != nil {
+			return err
+		}
+		return writeFileAtomic(statsReportsFile, data, 0644)
+	})
+}
+
+// saveStatsReport registers report, replacing any report to the same file.
+func saveStatsReport(report statsReport) error {
+	return updateStatsReports(func(reports []statsReport) ([]statsReport, error) {
+		for i := range reports {
+			if reports[i].To == report.To {
+				report.LastRun = reports[i].LastRun
+				reports[i] = report
+				return reports, nil
+			}
+		}
+		return append(reports, report), nil
+	})
+}
+
+func removeStatsReport(to string) error {
+	err := updateStatsReports(func(reports []statsReport) ([]statsReport, error) {
+		for i := range reports {
+			if reports[i].To == to {
+				return append(reports[:i], reports[i+1:]...), nil
+			}
+		}
+		return nil, errors.New("no recurring report writes to " + to)
+	})
+	if err != nil {
+		return err
+	}
+
+	say("✅", "Stopped the recurring report to %s", to)
+	return nil
+}
+
+// runDueReports writes every registered report whose period has elapsed.
+// Each report covers the tweets posted during its period.
+func runDueReports(client *http.Client, cfg config.Config, now time.Time) {
+	reports, err := loadStatsReports()
+	if err != nil {
+		log.Printf("Error loading stats reports: %v", err)
+		return
+	}
+
+	for _, report := range reports {
+		period, err := parsePeriod(report.Every)
+		if err != nil {
+			log.Printf("Error in stats report to %s: %v", report.To, err)
+			continue
+		}
+		if !report.LastRun.IsZero() && now.Sub(report.LastRun) < period {
+			continue
+		}
+
+		n, err := writeStatsReport(client, cfg, report.To, now, period)
+		if err != nil {
+			log.Printf("Error writing stats report to %s: %v", report.To, err)
+			continue
+		}
+		say("📊", "Appended metrics for %d tweet(s) to %s", n, report.To)
+
+		err = updateStatsReports(func(reports []statsReport) ([]statsReport, error) {
+			for i := range reports {
+				if reports[i].To == report.To {
+					reports[i].LastRun = now
+				}
+			}
+			return reports, nil
+		})
+		if err != nil {
+			log.Printf("Error saving stats report state: %v", err)
+		}
+	}
+}
+
+// writeVariantComparison totals the metrics of each variant letter and
+// prints averages per tweet and the engagement rate.
+func writeVariantComparison(entries []historyEntry, metrics []tweetMetrics, format string) error {
+	variantOf := map[string]string{}
+	for _, entry := range entries {
+		variantOf[entry.TweetID] = entry.Variant
+	}
+
+	type totals struct {
+		tweets, impressions, engagements, likes, retweets, replies int
+	}
+	byVariant := map[string]*totals{}
+	var letters []string
+	for _, m := range metrics {
+		v := variantOf[m.TweetID]
+		if byVariant[v] == nil {
+			byVariant[v] = &totals{}
+			letters = append(letters, v)
+		}
+		t := byVariant[v]
+		t.tweets++
+		t.impressions += m.Impressions
+		t.likes += m.Likes
+		t.retweets += m.Retweets
+		t.replies += m.Replies
+		t.engagements += m.Likes + m.Retweets + m.Replies + m.Quotes + m.Bookmarks
+	}
+	sort.Strings(letters)
+
+	avg := func(total, n int) string {
+		return strconv.FormatFloat(float64(total)/float64(n), 'f', 1, 64)
+	}
+
+	rows := make([][]any, len(letters))
+	for i, v := range letters {
+		t := byVariant[v]
+		rate := "n/a"
+		if t.impressions > 0 {
+			rate = strconv.FormatFloat(100*float64(t.engagements)/float64(t.impressions), 'f', 2, 64) + "%"
+		}
+		rows[i] = []any{strings.ToUpper(v), t.tweets, avg(t.impressions, t.tweets), avg(t.likes, t.tweets), avg(t.retweets, t.tweets), avg(t.replies, t.tweets), rate}
+	}
+
+	return writeRows(format, []string{"variant", "tweets", "avg_impressions", "avg_likes", "avg_retweets", "avg_replies", "engagement_rate"}, rows)
+}
+
+// writeLatencyReport prints the median, 95th percentile and worst lateness
+// of scheduled tweets posted since cutoff, per queue, and warns about queues
+// whose median exceeds threshold.
+func writeLatencyReport(entries []historyEntry, cutoff time.Time, threshold time.Duration, format string) error {
+	byQueue := map[string][]time.Duration{}
+	for _, entry := range entries {
+		if entry.ScheduledFor == nil || entry.PostedAt.Before(cutoff) {
+			continue
+		}
+		byQueue[entry.Queue] = append(byQueue[entry.Queue], entry.PostedAt.Sub(*entry.ScheduledFor))
+	}
+	if len(byQueue) == 0 {
+		say("📭", "No scheduled tweets were posted in that period")
+		return nil
+	}
+
+	queues := make([]string, 0, len(byQueue))
+	for queue := range byQueue {
+		queues = append(queues, queue)
+	}
+	sort.Strings(queues)
+
+	rows := make([][]any, len(queues))
+	var slow []string
+	for i, queue := range queues {
+		d := byQueue[queue]
+		median := percentileDuration(d, 50)
+		rows[i] = []any{queueLabel(queue), len(d), formatLateness(median), formatLateness(percentileDuration(d, 95)), formatLateness(percentileDuration(d, 100))}
+		if threshold > 0 && median > threshold {
+			slow = append(slow, queueLabel(queue))
+		}
+	}
+
+	if err := writeRows(format, []string{"queue", "posts", "median", "p95", "max"}, rows); err != nil {
+		return err
+	}
+	if len(slow) > 0 {
+		fmt.Fprintln(os.Stderr, decorate("🐢", fmt.Sprintf("Median lateness exceeds %s in: %s", threshold, strings.Join(slow, ", "))))
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/kalikim/x-cli/config"
+)
+
+// maxStoreRetries bounds how often updateStore re-reads a store another
+// machine changed while it was being updated.
+const maxStoreRetries = 5
+
+// errStoreConflict means a store changed between reading and writing it.
+var errStoreConflict = errors.New("the store was changed by another process")
+
+// bucket holds the scheduler and history stores when storage is configured;
+// nil keeps them as files in the working directory.
+var bucket *bucketStore
+
+// configureStorage selects where the stores live, before a command runs.
+func configureStorage(cfg config.Config) error {
+	if cfg.Storage.Backend == "" {
+		return nil
+	}
+	b, err := newBucketStore(cfg.Storage)
+	if err != nil {
+		return invalidInput(fmt.Errorf("storage: %w", err))
+	}
+	bucket = b
+	return nil
+}
+
+// readStore returns the content of the store name. A missing store is an
+// error satisfying os.IsNotExist, as with os.ReadFile.
+func readStore(name string) ([]byte, error) {
+	if bucket == nil {
+		return os.ReadFile(name)
+	}
+	data, _, err := bucket.get(name)
+	return data, err
+}
+
+// writeStore replaces the store name.
+func writeStore(name string, data []byte) error {
+	if bucket == nil {
+		return writeFileAtomic(name, data, 0644)
+	}
+	return bucket.put(name, data)
+}
+
+// updateStore replaces the store name with what fn makes of its content,
+// which is nil for a missing store. Local processes take turns through the
+// file lock; with a bucket, a store changed by another machine in between
+// is read again and fn runs again.
+func updateStore(name string, fn func([]byte) ([]byte, error)) error {
+	return withFileLock(name, func() error {
+		if bucket == nil {
+			data, err := os.ReadFile(name)
+			if err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			if data, err = fn(data); err != nil {
+				return err
+			}
+			return writeFileAtomic(name, data, 0644)
+		}
+
+		for attempt := 1; ; attempt++ {
+			data, version, err := bucket.get(name)
+			if err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			if data, err = fn(data); err != nil {
+				return err
+			}
+			err = bucket.putIf(name, data, version)
+			if !errors.Is(err, errStoreConflict) || attempt == maxStoreRetries {
+				return err
+			}
+		}
+	})
+}
+
+// globStores lists the stores whose names match pattern.
+func globStores(pattern string) ([]string, error) {
+	if bucket == nil {
+		return filepath.Glob(pattern)
+	}
+	names, err := bucket.list()
+	if err != nil {
+		return nil, err
+	}
+	var matches []string
+	for _, name := range names {
+		if ok, _ := path.Match(pattern, name); ok {
+			matches = append(matches, name)
+		}
+	}
+	return matches, nil
+}
+
+// storeLocation describes where the store name lives, for messages.
+func storeLocation(name string) string {
+	if bucket == nil {
+		return name
+	}
+	return bucket.url(name)
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/kalikim/x-cli/config"
+)
+
+const subtitlesCreateEndpoint = "https://upload.twitter.com/1.1/media/subtitles/create.json"
+
+// maxSubtitlesSize is well above any real caption file; larger files are
+// almost certainly not SRT.
+const maxSubtitlesSize = 1 << 20
+
+// subtitleTrack is an SRT caption file attached to a tweet's video.
+type subtitleTrack struct {
+	Path string `json:"path"`
+	Lang string `json:"lang"`
+}
+
+var (
+	subtitleLangPattern   = regexp.MustCompile(`^[a-z]{2,3}(-[A-Za-z0-9]{2,8})?$`)
+	srtTimingPattern      = regexp.MustCompile(`\d{2}:\d{2}:\d{2}[,.]\d{3} --> \d{2}:\d{2}:\d{2}[,.]\d{3}`)
+	errSubtitlesNeedVideo = errors.New("--subtitles needs --image pointing to an MP4 or MOV video")
+)
+
+// subtitleDisplayNames label the caption menu in X's player; other
+// languages show their code.
+var subtitleDisplayNames = map[string]string{
+	"ar": "العربية",
+	"de": "Deutsch",
+	"en": "English",
+	"es": "Español",
+	"fr": "Français",
+	"hi": "हिन्दी",
+	"it": "Italiano",
+	"ja": "日本語",
+	"ko": "한국어",
+	"nl": "Nederlands",
+	"pt": "Português",
+	"ru": "Русский",
+	"tr": "Türkçe",
+	"zh": "中文",
+}
+
+// validateSubtitles checks that the track is a UTF-8 SRT file with at least
+// one cue, in a language code X accepts, for a video.
+func validateSubtitles(track subtitleTrack, mediaPath string) error {
+	if mediaPath == "" {
+		return invalidInput(errSubtitlesNeedVideo)
+	}
+	if err := checkIsVideo(mediaPath); err != nil {
+		return err
+	}
+	if !subtitleLangPattern.MatchString(track.Lang) {
+		return invalidInput(fmt.Errorf("invalid subtitles language %q (use a code such as en or pt-BR)", track.Lang))
+	}
+
+	info, err := os.Stat(track.Path)
+	if err != nil {
+		return invalidInput(fmt.Errorf("subtitles: %w", err))
+	}
+	if info.Size() > maxSubtitlesSize {
+		return invalidInput(fmt.Errorf("subtitles %s is %s, over the %s limit", track.Path, formatBytes(info.Size()), formatBytes(maxSubtitlesSize)))
+	}
+
+	data, err := os.ReadFile(track.Path)
+	if err != nil {
+		return invalidInput(fmt.Errorf("subtitles: %w", err))
+	}
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	if !utf8.Valid(data) {
+		return invalidInput(fmt.Errorf("subtitles %s is not UTF-8 text", track.Path))
+	}
+	if !srtTimingPattern.Match(data) {
+		return invalidInput(fmt.Errorf("subtitles %s has no SRT cues (expected lines like 00:00:01,000 --> 00:00:04,000)", track.Path))
+	}
+	return nil
+}
+
+// checkIsVideo makes sure path is a video subtitles can be attached to.
+func checkIsVideo(path string) error {
+	head := make([]byte, 512)
+	f, err := os.Open(path)
+	if err != nil {
+		return invalidInput(fmt.Errorf("media: %w", err))
+	}
+	n, _ := f.Read(head)
+	f.Close()
+
+	if !strings.HasPrefix(detectMime(path, head[:n]), "video/") {
+		return invalidInput(errSubtitlesNeedVideo)
+	}
+	return nil
+}
+
+// attachSubtitles uploads the SRT file and links it to the uploaded video
+// mediaID, so the captions show when the tweet is posted with it.
+func attachSubtitles(client *http.Client, cfg config.Config, mediaID string, track subtitleTrack) error {
+	data, err := os.ReadFile(track.Path)
+	if err != nil {
+		return fmt.Errorf("reading subtitles: %w", err)
+	}
+
+	body, err := signedMultipartPost(client, cfg, mediaUploadEndpoint, map[string]string{"media_category": "subtitles"}, "media", filepath.Base(track.Path), data)
+	if err != nil {
+		return fmt.Errorf("uploading subtitles: %w", err)
+	}
+	var uploaded struct {
+		MediaIDString string `json:"media_id_string"`
+	}
+	if err := json.Unmarshal(body, &uploaded); err != nil {
+		return fmt.Errorf("decoding subtitles upload response: %w", err)
+	}
+	if uploaded.MediaIDString == "" {
+		return fmt.Errorf("subtitles upload failed: %s", string(body))
+	}
+
+	lang, _, _ := strings.Cut(track.Lang, "-")
+	name := subtitleDisplayNames[lang]
+	if name == "" {
+		name = track.Lang
+	}
+
+	type subtitle struct {
+		MediaID      string `json:"media_id"`
+		LanguageCode string `json:"language_code"`
+		DisplayName  string `json:"display_name"`
+	}
+	var req struct {
+		MediaID       string `json:"media_id"`
+		MediaCategory string `json:"media_category"`
+		SubtitleInfo  struct {
+			Subtitles []subtitle `json:"subtitles"`
+		} `json:"subtitle_info"`
+	}
+	req.MediaID = mediaID
+	req.MediaCategory = "TweetVideo"
+	req.SubtitleInfo.Subtitles = []subtitle{{MediaID: uploaded.MediaIDString, LanguageCode: strings.ToUpper(lang), DisplayName: name}}
+
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("encoding subtitles request: %w", err)
+	}
+	status, respBody, err := sendSigned(client, cfg, http.MethodPost, subtitlesCreateEndpoint, payload, "application/json")
+	if err != nil {
+		return fmt.Errorf("attaching subtitles: %w", err)
+	}
+	if status >= 300 {
+		return fmt.Errorf("attaching subtitles: %w", newAPIError(status, respBody))
+	}
+	return nil
+}
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/kalikim/x-cli/config"
+	"github.com/spf13/cobra"
+)
+
+const (
+	defaultReshareTemplate = "ICYMI: {{.Text}}"
+	// reshareSlotAttempts is how many slots past a conflict a reshare may
+	// move before giving up.
+	reshareSlotAttempts = 10
+)
+
+// timeSensitive matches wording that ties a tweet to when it was posted, so
+// re-sharing it later would read wrong.
+var timeSensitive = regexp.MustCompile(`(?i)\b(today|tonight|tomorrow|yesterday|this (morning|afternoon|evening|week|weekend|month)|next (week|month)|last (night|week)|right now|live now|happening now|starts? in|ends? (today|tonight|soon)|last chance|\d{4}-\d{2}-\d{2})\b`)
+
+// reshareCandidate is a past tweet worth re-sharing and how it performed.
+type reshareCandidate struct {
+	Entry       historyEntry
+	Engagements int
+	Impressions int
+}
+
+// reshareData is what a reshare template can use.
+type reshareData struct {
+	Text     string
+	URL      string
+	PostedAt time.Time
+}
+
+func newSuggestCmd() *cobra.Command {
+	suggestCmd := &cobra.Command{
+		Use:   "suggest",
+		Short: "Suggest tweets to post based on your history",
+	}
+
+	var olderThan, at, every, tmpl, queue string
+	var top int
+	var assumeYes bool
+
+	reshareCmd := &cobra.Command{
+		Use:   "reshare",
+		Short: "Offer to reschedule your best-performing evergreen tweets",
+		Long: "Scan history for tweets older than --older-than that did better than most,\n" +
+			"skipping replies, earlier reshares and time-sensitive wording such as\n" +
+			"\"today\" or dates, and offer to schedule each again with --template.\n" +
+			"Accepted reshares are scheduled --every apart starting at --at.",
+		Example: `  x-cli suggest reshare
+  x-cli suggest reshare --older-than 60d --top 3 --at "2025-07-01 09:00" --every 2d
+  x-cli suggest reshare --template "Still true: {{.Text}}" --yes`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			minAge, err := parseDelay(olderThan)
+			if err != nil {
+				return fmt.Errorf("--older-than: %w", err)
+			}
+			spacing, err := parsePeriod(every)
+			if err != nil {
+				return fmt.Errorf("--every: %w", err)
+			}
+			if top <= 0 {
+				return invalidInput(fmt.Errorf("--top must be positive, got %d", top))
+			}
+			t, err := template.New("reshare").Parse(tmpl)
+			if err != nil {
+				return invalidInput(fmt.Errorf("parsing --template: %w", err))
+			}
+			if err := validateQueueName(queue); err != nil {
+				return err
+			}
+
+			start := time.Now().Add(24 * time.Hour).Truncate(time.Hour)
+			if at != "" {
+				if start, err = parseScheduleTime(at); err != nil {
+					return invalidInput(err)
+				}
+				if start.Before(time.Now()) {
+					return invalidInput(fmt.Errorf("--at %s is in the past", at))
+				}
+			}
+
+			cfg, err := configForQueue(config.LoadConfig(), queue)
+			if err != nil {
+				return err
+			}
+			if err := cfg.Validate(); err != nil {
+				return err
+			}
+			if err := checkAllowed(cfg, actionSchedule); err != nil {
+				return err
+			}
+			if err := checkWritable(cfg); err != nil {
+				return err
+			}
+
+			candidates, err := findReshareCandidates(cfg, time.Now().Add(-minAge), top)
+			if err != nil {
+				return err
+			}
+			if len(candidates) == 0 {
+				say("📭", "No evergreen tweets older than %s stand out yet", olderThan)
+				return nil
+			}
+
+			slot := start
+			scheduled := 0
+			for i, c := range candidates {
+				text, err := renderReshare(t, c.Entry)
+				if err != nil {
+					return err
+				}
+
+				fmt.Println()
+				say("♻️", "[%d/%d] Posted %s · %d engagements, %d impressions", i+1, len(candidates), c.Entry.PostedAt.Local().Format("2006-01-02"), c.Engagements, c.Impressions)
+				if n := tweetLength(text); n > maxTweetLength {
+					say("⚠️", "Skipping: the reshare would be %d characters, over the %d limit", n, maxTweetLength)
+					continue
+				}
+				if err := checkPostTexts(cfg, []ruleText{{"", text}}, false); err != nil {
+					say("⚠️", "Skipping: %v", err)
+					continue
+				}
+				previewTweet(text)
+
+				tweet := scheduledTweet{
+					Text:         text,
+					ID:           generateTweetID(),
+					Labels:       []string{"reshare"},
+					ResharedFrom: c.Entry.TweetID,
+				}
+				if slot, err = freeReshareSlot(cfg, queue, tweet, slot, spacing); err != nil {
+					return err
+				}
+				tweet.ScheduleTime = slot
+
+				if !assumeYes {
+					ok, err := confirm(fmt.Sprintf("Schedule for %s?", slot.Format("2006-01-02 15:04")))
+					if err != nil {
+						return err
+					}
+					if !ok {
+						continue
+					}
+				}
+
+				if c.Entry.Image != "" {
+					if _, err := os.Stat(c.Entry.Image); err == nil {
+						tweet.Image = c.Entry.Image
+					} else {
+						say("⚠️", "Image %s is gone; the reshare is text only", c.Entry.Image)
+					}
+				}
+				if err := saveScheduledTweet(queue, tweet); err != nil {
+					return fmt.Errorf("saving scheduled tweet: %w", err)
+				}
+				say("✅", "Reshare scheduled for %s (ID: %s)", slot.Format("2006-01-02 15:04"), tweet.ID)
+				scheduled++
+				slot = slot.Add(spacing)
+			}
+
+			if scheduled > 0 {
+				say("📅", "Scheduled %d reshare(s) in the %s queue", scheduled, queueLabel(queue))
+			}
+			return nil
+		},
+	}
+	reshareCmd.Flags().StringVar(&olderThan, "older-than", "30d", "Only consider tweets posted at least this long ago")
+	reshareCmd.Flags().IntVar(&top, "top", 5, "How many tweets to suggest")
+	reshareCmd.Flags().StringVar(&tmpl, "template", defaultReshareTemplate, "Reshare text using {{.Text}}, {{.URL}} and {{.PostedAt}}")
+	reshareCmd.Flags().StringVar(&at, "at", "", "When to post the first reshare (default: this hour tomorrow)")
+	reshareCmd.Flags().StringVar(&every, "every", "1d", "Time between accepted reshares")
+	reshareCmd.Flags().StringVar(&queue, "queue", "", "Schedule the reshares in this named queue")
+	reshareCmd.Flags().BoolVarP(&assumeYes, "yes", "y", false, "Schedule every suggestion without asking")
+
+	suggestCmd.AddCommand(reshareCmd)
+	return suggestCmd
+}
+
+// findReshareCandidates returns up to top evergreen tweets posted before
+// cutoff whose engagement beat the median of those considered, best first.
+func findReshareCandidates(cfg config.Config, cutoff time.Time, top int) ([]reshareCandidate, error) {
+	entries, err := loadHistory()
+	if err != nil {
+		return nil, fmt.Errorf("loading history: %w", err)
+	}
+
+	// Tweets already re-shared, posted or pending, are not suggested again.
+	reshared := map[string]bool{}
+	for _, entry := range entries {
+		if entry.ResharedFrom != "" {
+			reshared[entry.ResharedFrom] = true
+		}
+	}
+	pending, err := loadAllScheduledTweets(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("loading scheduled tweets: %w", err)
+	}
+	for _, tweet := range pending {
+		if tweet.ResharedFrom != "" {
+			reshared[tweet.ResharedFrom] = true
+		}
+	}
+
+	var eligible []historyEntry
+	for _, entry := range entries {
+		if entry.TweetID == "" || entry.PostedAt.After(cutoff) || entry.ResharedFrom != "" || reshared[entry.TweetID] {
+			continue
+		}
+		text := strings.TrimSpace(entry.Text)
+		if strings.HasPrefix(text, "@") || timeSensitive.MatchString(text) {
+			continue
+		}
+		eligible = append(eligible, entry)
+	}
+	if len(eligible) == 0 {
+		return nil, nil
+	}
+
+	metrics, err := fetchTweetMetrics(apiClient(), cfg, eligible)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := map[string]historyEntry{}
+	for _, entry := range eligible {
+		byID[entry.TweetID] = entry
+	}
+	var candidates []reshareCandidate
+	for _, m := range metrics {
+		candidates = append(candidates, reshareCandidate{
+			Entry:       byID[m.TweetID],
+			Engagements: m.Likes + m.Retweets + m.Replies + m.Quotes + m.Bookmarks,
+			Impressions: m.Impressions,
+		})
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Engagements > candidates[j].Engagements
+	})
+
+	// Only tweets above the median count as high-performing.
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+	median := candidates[len(candidates)/2].Engagements
+	var best []reshareCandidate
+	for _, c := range candidates {
+		if len(best) == top || (len(candidates) > 1 && c.Engagements <= median) || c.Engagements == 0 {
+			break
+		}
+		best = append(best, c)
+	}
+	return best, nil
+}
+
+// freeReshareSlot returns the first of slot, slot+spacing, ... that does not
+// conflict with a tweet already scheduled, e.g. by an earlier run.
+func freeReshareSlot(cfg config.Config, queue string, tweet scheduledTweet, slot time.Time, spacing time.Duration) (time.Time, error) {
+	var err error
+	for i := 0; i < reshareSlotAttempts; i++ {
+		tweet.ScheduleTime = slot
+		if err = checkScheduleConflicts(cfg, queue, tweet); err == nil {
+			return slot, nil
+		}
+		slot = slot.Add(spacing)
+	}
+	return time.Time{}, err
+}
+
+func renderReshare(t *template.Template, entry historyEntry) (string, error) {
+	var buf bytes.Buffer
+	data := reshareData{Text: entry.Text, URL: tweetURL("", entry.TweetID), PostedAt: entry.PostedAt}
+	if err := t.Execute(&buf, data); err != nil {
+		return "", invalidInput(fmt.Errorf("rendering --template: %w", err))
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
@@ -0,0 +1,411 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/kalikim/x-cli/config"
+	"github.com/spf13/cobra"
+)
+
+// syncStateFile records, per remote and queue, every tweet as it was after
+// the last sync, so a sync can tell an edit or a removal from a tweet the
+// other side has never seen.
+const syncStateFile = "sync_state.json"
+
+// Sync preferences for tweets edited on both sides.
+const (
+	preferLocal  = "local"
+	preferRemote = "remote"
+)
+
+// syncRemote is the other machine's copy of the stores.
+type syncRemote interface {
+	// read returns the content of file, or nothing when it does not exist.
+	read(file string) ([]byte, error)
+	write(file string, data []byte) error
+	String() string
+}
+
+// syncChange is one thing a sync does to either side.
+type syncChange struct {
+	Tweet  scheduledTweet
+	Action string
+	Reason string
+}
+
+// Sync actions.
+const (
+	syncPull       = "pull"
+	syncPush       = "push"
+	syncDropLocal  = "delete here"
+	syncDropRemote = "delete there"
+)
+
+func newSchedulerSyncCmd(queue *string) *cobra.Command {
+	var remoteSpec, prefer string
+	var diffOnly bool
+
+	cmd := &cobra.Command{
+		Use:   "sync",
+		Short: "Merge the queue with another machine's copy",
+		Long: "Merge the scheduled tweets of a queue with the copy on another machine, over\n" +
+			"ssh or through a shared folder, so tweets scheduled on either show up on both.\n" +
+			"Tweets added on one side are copied to the other, edits win over unchanged\n" +
+			"copies, and tweets cancelled or posted on one side are removed from the other.\n" +
+			"Run the daemon on one machine only.",
+		Example: `  x-cli scheduler sync --remote ssh://desktop/~/tweets --diff
+  x-cli scheduler sync --remote ssh://me@desktop/srv/x-cli
+  x-cli scheduler sync --remote ~/Dropbox/x-cli --queue work`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if remoteSpec == "" {
+				return invalidInput(errors.New("--remote is required (ssh://host/path or a directory)"))
+			}
+			if prefer != preferLocal && prefer != preferRemote {
+				return invalidInput(fmt.Errorf("invalid --prefer %q (use local or remote)", prefer))
+			}
+			remote, err := parseSyncRemote(remoteSpec)
+			if err != nil {
+				return err
+			}
+			if !diffOnly {
+				if err := checkWritable(config.LoadConfig()); err != nil {
+					return err
+				}
+			}
+			return syncScheduledTweets(*queue, remote, prefer, diffOnly)
+		},
+	}
+	cmd.Flags().StringVar(&remoteSpec, "remote", "", "The other copy: ssh://[user@]host/path, ssh://host/~/path, or a directory such as a synced folder")
+	cmd.Flags().StringVar(&prefer, "prefer", preferLocal, "Which side wins when a tweet was edited on both: local or remote")
+	cmd.Flags().BoolVar(&diffOnly, "diff", false, "Show the differences without changing either side")
+	return cmd
+}
+
+func parseSyncRemote(spec string) (syncRemote, error) {
+	if !strings.Contains(spec, "://") {
+		return dirRemote{dir: expandHome(spec)}, nil
+	}
+	u, err := url.Parse(spec)
+	if err != nil {
+		return nil, invalidInput(fmt.Errorf("invalid --remote %q: %w", spec, err))
+	}
+	switch u.Scheme {
+	case "file":
+		return dirRemote{dir: u.Path}, nil
+	case "ssh":
+		if u.Host == "" || u.Path == "" || u.Path == "/" {
+			return nil, invalidInput(fmt.Errorf("invalid --remote %q (use ssh://host/path)", spec))
+		}
+		host := u.Host
+		if u.User != nil {
+			host = u.User.Username() + "@" + host
+		}
+		// As with scp, "/~/path" is relative to the remote home directory.
+		dir := u.Path
+		if rest, ok := strings.CutPrefix(dir, "/~/"); ok {
+			dir = rest
+		}
+		return sshRemote{host: host, dir: dir}, nil
+	}
+	return nil, invalidInput(fmt.Errorf("unsupported --remote scheme %q (use ssh:// or a directory)", u.Scheme))
+}
+
+func expandHome(path string) string {
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, rest)
+		}
+	}
+	return path
+}
+
+// dirRemote is a directory, such as a network share or a folder kept in
+// sync by Dropbox or a mounted bucket.
+type dirRemote struct{ dir string }
+
+func (r dirRemote) read(file string) ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(r.dir, file))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return data, err
+}
+
+func (r dirRemote) write(file string, data []byte) error {
+	if err := os.MkdirAll(r.dir, 0755); err != nil {
+		return err
+	}
+	path := filepath.Join(r.dir, file)
+	return withFileLock(path, func() error {
+		return writeFileAtomic(path, data, 0644)
+	})
+}
+
+func (r dirRemote) String() string { return r.dir }
+
+// sshRemote reaches another machine with the ssh client, so keys, agents
+// and ~/.ssh/config work as usual.
+type sshRemote struct{ host, dir string }
+
+func (r sshRemote) read(file string) ([]byte, error) {
+	path := shellQuote(r.dir + "/" + file)
+	return r.run(nil, "if [ -f "+path+" ]; then cat "+path+"; fi")
+}
+
+func (r sshRemote) write(file string, data []byte) error {
+	dir, path := shellQuote(r.dir), shellQuote(r.dir+"/"+file)
+	tmp := shellQuote(r.dir + "/." + file + ".sync.tmp")
+	_, err := r.run(data, "mkdir -p "+dir+" && cat > "+tmp+" && mv "+tmp+" "+path)
+	return err
+}
+
+func (r sshRemote) run(stdin []byte, script string) ([]byte, error) {
+	if _, err := exec.LookPath("ssh"); err != nil {
+		return nil, errors.New("ssh is not installed")
+	}
+	cmd := exec.Command("ssh", "-o", "BatchMode=yes", r.host, script)
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			err = errors.New(msg)
+		}
+		return nil, fmt.Errorf("ssh %s: %w", r.host, err)
+	}
+	return out, nil
+}
+
+func (r sshRemote) String() string {
+	if strings.HasPrefix(r.dir, "/") {
+		return "ssh://" + r.host + r.dir
+	}
+	return "ssh://" + r.host + "/~/" + r.dir
+}
+
+// syncScheduledTweets merges queue with its copy on remote and saves the
+// result on both sides.
+func syncScheduledTweets(queue string, remote syncRemote, prefer string, diffOnly bool) error {
+	file := scheduleFile(queue)
+	data, err := remote.read(file)
+	if err != nil {
+		return fmt.Errorf("reading %s from %s: %w", file, remote, err)
+	}
+	var theirs []scheduledTweet
+	if len(bytes.TrimSpace(data)) > 0 {
+		if err := json.Unmarshal(data, &theirs); err != nil {
+			return fmt.Errorf("reading %s from %s: %w", file, remote, err)
+		}
+	}
+
+	states, err := loadSyncStates()
+	if err != nil {
+		return fmt.Errorf("loading sync state: %w", err)
+	}
+	stateKey := remote.String() + "#" + queueLabel(queue)
+	base := states[stateKey]
+
+	if diffOnly {
+		ours, err := loadScheduledTweets(queue)
+		if err != nil {
+			return fmt.Errorf("loading scheduled tweets: %w", err)
+		}
+		_, changes, conflicts := mergeScheduledTweets(base, ours, theirs, prefer)
+		printSyncChanges(remote, queue, changes, conflicts)
+		return nil
+	}
+
+	var merged []scheduledTweet
+	err = updateScheduledTweets(queue, func(ours []scheduledTweet) ([]scheduledTweet, error) {
+		var changes []syncChange
+		var conflicts int
+		merged, changes, conflicts = mergeScheduledTweets(base, ours, theirs, prefer)
+		// Keys are assigned before hashing so both sides store the same tweets.
+		assignIdempotencyKeys(merged)
+		printSyncChanges(remote, queue, changes, conflicts)
+
+		// The remote is written first: if that fails, neither side changes.
+		for _, c := range changes {
+			if c.Action == syncPush || c.Action == syncDropRemote {
+				out, err := json.MarshalIndent(merged, "", "  ")
+				if err != nil {
+					return nil, err
+				}
+				if err := remote.write(file, out); err != nil {
+					return nil, fmt.Errorf("writing %s to %s: %w", file, remote, err)
+				}
+				break
+			}
+		}
+		for _, c := range changes {
+			if c.Action == syncPull && c.Tweet.Image != "" {
+				if _, err := os.Stat(c.Tweet.Image); err != nil {
+					say("⚠️", "Tweet %s uses %s, which is not on this machine", c.Tweet.ID, c.Tweet.Image)
+				}
+			}
+			if c.Action == syncDropLocal {
+				removeScheduledTweetMedia(c.Tweet)
+			}
+		}
+		return merged, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	states[stateKey] = syncHashes(merged)
+	if err := saveSyncStates(states); err != nil {
+		return fmt.Errorf("saving sync state: %w", err)
+	}
+	return nil
+}
+
+// mergeScheduledTweets merges two copies of a queue given the hash of each
+// tweet at the last sync. A tweet removed on one side, because it was
+// cancelled or posted there, is removed from the other even if edited, so
+// it is never posted twice. A tweet edited on both sides is taken from the
+// preferred side; these are counted as conflicts.
+func mergeScheduledTweets(base map[string]string, ours, theirs []scheduledTweet, prefer string) ([]scheduledTweet, []syncChange, int) {
+	theirByID := map[string]scheduledTweet{}
+	for _, t := range theirs {
+		theirByID[t.ID] = t
+	}
+	ourByID := map[string]bool{}
+
+	var merged []scheduledTweet
+	var changes []syncChange
+	conflicts := 0
+	for _, our := range ours {
+		ourByID[our.ID] = true
+		their, inTheirs := theirByID[our.ID]
+		baseHash, synced := base[our.ID]
+		ourHash := tweetHash(our)
+
+		switch {
+		case !inTheirs && !synced:
+			merged = append(merged, our)
+			changes = append(changes, syncChange{our, syncPush, "new here"})
+		case !inTheirs:
+			reason := "cancelled or posted there"
+			if ourHash != baseHash {
+				reason += "; the edit here is dropped"
+				conflicts++
+			}
+			changes = append(changes, syncChange{our, syncDropLocal, reason})
+		default:
+			theirHash := tweetHash(their)
+			switch {
+			case ourHash == theirHash:
+				merged = append(merged, our)
+			case ourHash == baseHash:
+				merged = append(merged, their)
+				changes = append(changes, syncChange{their, syncPull, "edited there"})
+			case theirHash == baseHash:
+				merged = append(merged, our)
+				changes = append(changes, syncChange{our, syncPush, "edited here"})
+			case prefer == preferRemote:
+				conflicts++
+				merged = append(merged, their)
+				changes = append(changes, syncChange{their, syncPull, "edited on both sides; keeping the remote edit"})
+			default:
+				conflicts++
+				merged = append(merged, our)
+				changes = append(changes, syncChange{our, syncPush, "edited on both sides; keeping the local edit"})
+			}
+		}
+	}
+
+	for _, their := range theirs {
+		if ourByID[their.ID] {
+			continue
+		}
+		baseHash, synced := base[their.ID]
+		switch {
+		case !synced:
+			merged = append(merged, their)
+			changes = append(changes, syncChange{their, syncPull, "new there"})
+		default:
+			reason := "cancelled or posted here"
+			if tweetHash(their) != baseHash {
+				reason += "; the edit there is dropped"
+				conflicts++
+			}
+			changes = append(changes, syncChange{their, syncDropRemote, reason})
+		}
+	}
+
+	sort.SliceStable(merged, func(i, j int) bool { return merged[i].ScheduleTime.Before(merged[j].ScheduleTime) })
+	sort.SliceStable(changes, func(i, j int) bool { return changes[i].Tweet.ScheduleTime.Before(changes[j].Tweet.ScheduleTime) })
+	return merged, changes, conflicts
+}
+
+func printSyncChanges(remote syncRemote, queue string, changes []syncChange, conflicts int) {
+	if len(changes) == 0 {
+		say("✅", "The %s queue matches %s", queueLabel(queue), remote)
+		return
+	}
+	counts := map[string]int{}
+	for _, c := range changes {
+		counts[c.Action]++
+		fmt.Printf("  %-12s %s  %s  %s (%s)\n", c.Action, c.Tweet.ID, c.Tweet.ScheduleTime.Format("2006-01-02 15:04"), truncateText(c.Tweet.Text, 40), c.Reason)
+	}
+	fmt.Printf("\nSync: %d to pull, %d to push, %d to delete here, %d to delete there.\n",
+		counts[syncPull], counts[syncPush], counts[syncDropLocal], counts[syncDropRemote])
+	if conflicts > 0 {
+		say("⚠️", "%d tweet(s) were changed on both sides", conflicts)
+	}
+}
+
+func tweetHash(tweet scheduledTweet) string {
+	data, _ := json.Marshal(tweet)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func syncHashes(tweets []scheduledTweet) map[string]string {
+	hashes := map[string]string{}
+	for _, t := range tweets {
+		hashes[t.ID] = tweetHash(t)
+	}
+	return hashes
+}
+
+// loadSyncStates returns the tweet hashes of every remote and queue,
+// keyed by "<remote>#<queue>".
+func loadSyncStates() (map[string]map[string]string, error) {
+	states := map[string]map[string]string{}
+	data, err := os.ReadFile(syncStateFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return states, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &states); err != nil {
+		return nil, err
+	}
+	return states, nil
+}
+
+func saveSyncStates(states map[string]map[string]string) error {
+	data, err := json.MarshalIndent(states, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(syncStateFile, data, 0644)
+}
@@ -0,0 +1,342 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+const templatesFile = "templates.json"
+
+var templateVarPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// tweetTemplate is a reusable tweet skeleton. Text is a text/template whose
+// variables, such as {{.version}}, are filled from --var flags.
+type tweetTemplate struct {
+	Name        string    `json:"name"`
+	Text        string    `json:"text"`
+	Image       string    `json:"image,omitempty"`
+	Description string    `json:"description,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func newTemplateCmd() *cobra.Command {
+	templateCmd := &cobra.Command{
+		Use:   "template",
+		Short: "Save and fill reusable tweet templates",
+		Long: "Save tweet skeletons with variables written as {{.name}}, then post them\n" +
+			"with 'x-cli --template <name> --var name=value'. Templates are kept in\n" +
+			templatesFile + " in the current directory.",
+	}
+
+	var text, image, description string
+	var replace bool
+	saveCmd := &cobra.Command{
+		Use:   "save <name>",
+		Short: "Save a tweet template",
+		Example: `  x-cli template save release --text "x-cli {{.version}} is out: {{.url}}"
+  x-cli template save launch --text "We're live! 🚀" --image banner.png`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[0]
+			if !labelPattern.MatchString(name) {
+				return invalidInput(fmt.Errorf("invalid template name %q (use lowercase letters, digits, '-' and '_')", name))
+			}
+			text = strings.TrimSpace(text)
+			if text == "" {
+				return invalidInput(errors.New("text flag cannot be empty"))
+			}
+			vars, err := templateVars(name, text)
+			if err != nil {
+				return err
+			}
+			if image != "" {
+				if err := validateMedia(image); err != nil {
+					return err
+				}
+			}
+
+			err = updateTemplates(func(templates []tweetTemplate) ([]tweetTemplate, error) {
+				t := tweetTemplate{Name: name, Text: text, Image: image, Description: description, CreatedAt: time.Now()}
+				for i := range templates {
+					if templates[i].Name == name {
+						if !replace {
+							return nil, invalidInput(fmt.Errorf("template %s already exists (use --replace to overwrite it)", name))
+						}
+						templates[i] = t
+						return templates, nil
+					}
+				}
+				return append(templates, t), nil
+			})
+			if err != nil {
+				return err
+			}
+
+			say("📝", "Template %s saved", name)
+			if len(vars) > 0 {
+				say("💡", "Fill it with: x-cli --template %s %s", name, exampleVarFlags(vars))
+			}
+			return nil
+		},
+	}
+	saveCmd.Flags().StringVarP(&text, "text", "t", "", "Template text; write variables as {{.name}}")
+	saveCmd.Flags().StringVarP(&image, "image", "i", "", "Image posted with the template unless --image is given")
+	saveCmd.Flags().StringVar(&description, "description", "", "Note shown by 'template list'")
+	saveCmd.Flags().BoolVar(&replace, "replace", false, "Overwrite an existing template of the same name")
+
+	var listOutput string
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List saved templates and their variables",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			templates, err := loadTemplates()
+			if err != nil {
+				return fmt.Errorf("loading templates: %w", err)
+			}
+			if len(templates) == 0 && listOutput == "table" {
+				say("📭", "No templates saved (use 'x-cli template save')")
+				return nil
+			}
+
+			rows := make([][]any, 0, len(templates))
+			for _, t := range templates {
+				vars, _ := templateVars(t.Name, t.Text)
+				rows = append(rows, []any{t.Name, strings.Join(vars, ","), t.Description, t.Text})
+			}
+			return writeRows(listOutput, []string{"name", "vars", "description", "text"}, rows)
+		},
+	}
+	listCmd.Flags().StringVarP(&listOutput, "output", "o", "table", "Output format: table, json, yaml or tsv")
+
+	var useVars []string
+	useCmd := &cobra.Command{
+		Use:   "use <name>",
+		Short: "Print a template filled with --var values, without posting",
+		Example: `  x-cli template use release --var version=1.2.0 --var url=https://example.com
+  x-cli --template release --var version=1.2.0 --var url=https://example.com`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			t, err := findTemplate(args[0])
+			if err != nil {
+				return err
+			}
+			vars, err := parseTemplateVars(useVars)
+			if err != nil {
+				return err
+			}
+			filled, err := fillTemplate(t, vars)
+			if err != nil {
+				return err
+			}
+			fmt.Println(filled)
+			return nil
+		},
+	}
+	useCmd.Flags().StringArrayVar(&useVars, "var", nil, "Template variable as name=value (repeatable)")
+
+	removeCmd := &cobra.Command{
+		Use:   "remove <name>",
+		Short: "Delete a saved template",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := updateTemplates(func(templates []tweetTemplate) ([]tweetTemplate, error) {
+				for i := range templates {
+					if templates[i].Name == args[0] {
+						return append(templates[:i], templates[i+1:]...), nil
+					}
+				}
+				return nil, invalidInput(fmt.Errorf("no template named %s (see 'x-cli template list')", args[0]))
+			})
+			if err != nil {
+				return err
+			}
+			say("🗑️", "Removed template %s", args[0])
+			return nil
+		},
+	}
+
+	templateCmd.AddCommand(saveCmd, listCmd, useCmd, removeCmd)
+	return templateCmd
+}
+
+func loadTemplates() ([]tweetTemplate, error) {
+	data, err := os.ReadFile(templatesFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var templates []tweetTemplate
+	if err := json.Unmarshal(data, &templates); err != nil {
+		return nil, err
+	}
+	return templates, nil
+}
+
+// updateTemplates applies fn to the saved templates under the store lock.
+func updateTemplates(fn func([]tweetTemplate) ([]tweetTemplate, error)) error {
+	return withFileLock(templatesFile, func() error {
+		templates, err := loadTemplates()
+		if err != nil {
+			return fmt.Errorf("loading templates: %w", err)
+		}
+
+		if templates, err = fn(templates); err != nil {
+			return err
+		}
+		sort.Slice(templates, func(i, j int) bool { return templates[i].Name < templates[j].Name })
+
+		data, err := json.MarshalIndent(templates, "", "  ")
+		if err != nil {
+			return err
+		}
+		return writeFileAtomic(templatesFile, data, 0644)
+	})
+}
+
+func findTemplate(name string) (tweetTemplate, error) {
+	templates, err := loadTemplates()
+	if err != nil {
+		return tweetTemplate{}, fmt.Errorf("loading templates: %w", err)
+	}
+	for _, t := range templates {
+		if t.Name == name {
+			return t, nil
+		}
+	}
+	return tweetTemplate{}, invalidInput(fmt.Errorf("no template named %s (see 'x-cli template list')", name))
+}
+
+// parseTemplateVars turns name=value flags into a map.
+func parseTemplateVars(flags []string) (map[string]string, error) {
+	vars := map[string]string{}
+	for _, flag := range flags {
+		name, value, ok := strings.Cut(flag, "=")
+		if !ok || !templateVarPattern.MatchString(name) {
+			return nil, invalidInput(fmt.Errorf("invalid --var %q (use name=value)", flag))
+		}
+		vars[name] = value
+	}
+	return vars, nil
+}
+
+// fillTemplate renders t with vars. Every variable the template uses must
+// be given, and every variable given must be used, so typos do not post
+// half-filled tweets.
+func fillTemplate(t tweetTemplate, vars map[string]string) (string, error) {
+	used, err := templateVars(t.Name, t.Text)
+	if err != nil {
+		return "", err
+	}
+	var missing []string
+	for _, name := range used {
+		if _, ok := vars[name]; !ok {
+			missing = append(missing, "--var "+name+"=...")
+		}
+	}
+	if len(missing) > 0 {
+		return "", invalidInput(fmt.Errorf("template %s needs %s", t.Name, strings.Join(missing, " ")))
+	}
+	for name := range vars {
+		if !slices.Contains(used, name) {
+			return "", invalidInput(fmt.Errorf("template %s has no variable %q (it uses: %s)", t.Name, name, describeVars(used)))
+		}
+	}
+
+	tmpl, err := template.New(t.Name).Option("missingkey=error").Parse(t.Text)
+	if err != nil {
+		return "", fmt.Errorf("parsing template %s: %w", t.Name, err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, vars); err != nil {
+		return "", fmt.Errorf("filling template %s: %w", t.Name, err)
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// templateVars lists the variables text uses, sorted.
+func templateVars(name, text string) ([]string, error) {
+	tmpl, err := template.New(name).Parse(text)
+	if err != nil {
+		return nil, invalidInput(fmt.Errorf("parsing template %s: %w", name, err))
+	}
+	seen := map[string]bool{}
+	if tmpl.Tree != nil {
+		collectTemplateVars(tmpl.Tree.Root, seen)
+	}
+	vars := make([]string, 0, len(seen))
+	for v := range seen {
+		vars = append(vars, v)
+	}
+	sort.Strings(vars)
+	return vars, nil
+}
+
+func collectTemplateVars(node parse.Node, seen map[string]bool) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			collectTemplateVars(child, seen)
+		}
+	case *parse.ActionNode:
+		collectTemplateVars(n.Pipe, seen)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			collectTemplateVars(cmd, seen)
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			collectTemplateVars(arg, seen)
+		}
+	case *parse.FieldNode:
+		seen[n.Ident[0]] = true
+	case *parse.IfNode:
+		collectTemplateVars(n.Pipe, seen)
+		collectTemplateVars(n.List, seen)
+		collectTemplateVars(n.ElseList, seen)
+	case *parse.RangeNode:
+		collectTemplateVars(n.Pipe, seen)
+		collectTemplateVars(n.List, seen)
+		collectTemplateVars(n.ElseList, seen)
+	case *parse.WithNode:
+		collectTemplateVars(n.Pipe, seen)
+		collectTemplateVars(n.List, seen)
+		collectTemplateVars(n.ElseList, seen)
+	}
+}
+
+func describeVars(vars []string) string {
+	if len(vars) == 0 {
+		return "none"
+	}
+	return strings.Join(vars, ", ")
+}
+
+func exampleVarFlags(vars []string) string {
+	flags := make([]string, len(vars))
+	for i, v := range vars {
+		flags[i] = "--var " + v + "=..."
+	}
+	return strings.Join(flags, " ")
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const maxTweetLength = 280
+
+// tcoLength is the weight of any URL, which X shortens to a t.co link.
+const tcoLength = 23
+
+var countURLPattern = regexp.MustCompile(`(?i)https?://[^\s]+`)
+
+// lengthBreakdown explains how the weighted length of a tweet adds up.
+type lengthBreakdown struct {
+	Total int
+	URLs  []string
+	Emoji []string
+	// Wide counts characters outside the single-weight ranges, mostly CJK.
+	Wide  int
+	Other int
+}
+
+// measureTweet computes the weighted length X applies: URLs count as 23,
+// emoji sequences and CJK characters as 2, and most other characters as 1.
+// Like X, it counts the NFC form, where an accented letter is one character.
+func measureTweet(text string) lengthBreakdown {
+	var b lengthBreakdown
+	text = string(composeLetters([]rune(text)))
+
+	last := 0
+	for _, loc := range countURLPattern.FindAllStringIndex(text, -1) {
+		end := loc[1]
+		// Trailing punctuation is not part of the link.
+		for end > loc[0] && strings.ContainsRune(".,;:!?)\"'", rune(text[end-1])) {
+			end--
+		}
+		b.measureRunes([]rune(text[last:loc[0]]))
+		b.URLs = append(b.URLs, text[loc[0]:end])
+		last = end
+	}
+	b.measureRunes([]rune(text[last:]))
+
+	b.Total = len(b.URLs)*tcoLength + len(b.Emoji)*2 + b.Wide*2 + b.Other
+	return b
+}
+
+func (b *lengthBreakdown) measureRunes(runes []rune) {
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		// A variation selector or keycap makes characters such as © and 1
+		// emoji too.
+		case isEmojiBase(r) || (i+1 < len(runes) && (runes[i+1] == 0xFE0F || runes[i+1] == 0x20E3)):
+			start := i
+			i = emojiSequenceEnd(runes, i)
+			b.Emoji = append(b.Emoji, string(runes[start:i+1]))
+		case isSingleWeight(r):
+			b.Other++
+		default:
+			b.Wide++
+		}
+	}
+}
+
+// isSingleWeight reports whether r falls in the ranges X counts as one
+// character: Latin and most alphabetic scripts plus common punctuation.
+func isSingleWeight(r rune) bool {
+	return r <= 0x10FF ||
+		(r >= 0x2000 && r <= 0x200D) ||
+		(r >= 0x2010 && r <= 0x201F) ||
+		(r >= 0x2032 && r <= 0x2037)
+}
+
+func isEmojiBase(r rune) bool {
+	return (r >= 0x1F000 && r <= 0x1FAFF) ||
+		(r >= 0x2600 && r <= 0x27BF) ||
+		(r >= 0x2300 && r <= 0x23FF) ||
+		(r >= 0x2B00 && r <= 0x2BFF)
+}
+
+func isEmojiModifier(r rune) bool {
+	return r == 0xFE0F || r == 0xFE0E || r == 0x20E3 ||
+		(r >= 0x1F3FB && r <= 0x1F3FF) ||
+		(r >= 0xE0020 && r <= 0xE007F)
+}
+
+// emojiSequenceEnd returns the index of the last rune of the emoji sequence
+// starting at i, following modifiers, flag pairs and zero-width joiners.
+func emojiSequenceEnd(runes []rune, i int) int {
+	isFlag := func(r rune) bool { return r >= 0x1F1E6 && r <= 0x1F1FF }
+	if isFlag(runes[i]) && i+1 < len(runes) && isFlag(runes[i+1]) {
+		i++
+	}
+
+	for i+1 < len(runes) {
+		switch next := runes[i+1]; {
+		case isEmojiModifier(next):
+			i++
+		case next == 0x200D && i+2 < len(runes) && isEmojiBase(runes[i+2]):
+			i += 2
+		default:
+			return i
+		}
+	}
+	return i
+}
+
+// tweetLength returns the weighted length of text as counted against
+// maxTweetLength.
+func tweetLength(text string) int {
+	return measureTweet(text).Total
+}
+
+// validateTweetText checks that text is non-empty and fits in a single tweet.
+func validateTweetText(text string) error {
+	if strings.TrimSpace(text) == "" {
+		return invalidInput(errors.New("tweet text cannot be empty"))
+	}
+
+	if b := measureTweet(text); b.Total > maxTweetLength {
+		err := fmt.Errorf("tweet text is %d characters, exceeding the %d character limit by %d", b.Total, maxTweetLength, b.Total-maxTweetLength)
+		if b.Wide > b.Other {
+			err = fmt.Errorf("%w (Chinese, Japanese and Korean characters count as 2, so about %d fit)", err, maxTweetLength/2)
+		}
+		return invalidInput(err)
+	}
+
+	return nil
+}
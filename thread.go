@@ -0,0 +1,190 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/kalikim/x-cli/config"
+)
+
+// threadCounterReserve is the room kept in every segment for the " (i/n)"
+// counter appended by splitThread.
+const threadCounterReserve = len(" (99/99)")
+
+// splitThread splits text into tweet-sized segments, breaking on paragraph,
+// sentence and finally word boundaries, and numbers them "(i/n)" when more
+// than one segment is needed.
+func splitThread(text string, max int) []string {
+	text = strings.TrimSpace(text)
+	if tweetLength(text) <= max {
+		return []string{text}
+	}
+
+	limit := max - threadCounterReserve
+	var segments []string
+	var current strings.Builder
+
+	flush := func() {
+		if s := strings.TrimSpace(current.String()); s != "" {
+			segments = append(segments, s)
+		}
+		current.Reset()
+	}
+
+	for _, piece := range threadPieces(text, limit) {
+		sep := ""
+		if current.Len() > 0 {
+			sep = " "
+			// Full-width punctuation ends sentences written without spaces.
+			if strings.HasPrefix(piece, "\n") || strings.HasSuffix(current.String(), "。") ||
+				strings.HasSuffix(current.String(), "！") || strings.HasSuffix(current.String(), "？") {
+				sep = ""
+			}
+		}
+		if tweetLength(current.String()+sep+piece) > limit {
+			flush()
+			sep = ""
+			piece = strings.TrimLeft(piece, "\n")
+		}
+		current.WriteString(sep + piece)
+	}
+	flush()
+
+	if len(segments) == 1 {
+		return segments
+	}
+
+	for i := range segments {
+		segments[i] = fmt.Sprintf("%s (%d/%d)", segments[i], i+1, len(segments))
+	}
+	return segments
+}
+
+// threadPieces breaks text into the smallest units that should stay together:
+// sentences, or words when a sentence alone exceeds limit, each at most limit
+// long by tweet weight. Paragraph breaks are kept as a leading "\n\n" on the
+// first piece of each paragraph.
+func threadPieces(text string, limit int) []string {
+	var pieces []string
+
+	for p, paragraph := range strings.Split(text, "\n\n") {
+		paragraph = strings.TrimSpace(paragraph)
+		if paragraph == "" {
+			continue
+		}
+
+		prefix := ""
+		if p > 0 && len(pieces) > 0 {
+			prefix = "\n\n"
+		}
+
+		for _, sentence := range splitSentences(paragraph) {
+			if tweetLength(sentence) <= limit {
+				pieces = append(pieces, prefix+sentence)
+				prefix = ""
+				continue
+			}
+			for _, word := range strings.Fields(sentence) {
+				for _, chunk := range splitLongWord(word, limit) {
+					pieces = append(pieces, prefix+chunk)
+					prefix = ""
+				}
+			}
+		}
+	}
+
+	return pieces
+}
+
+// splitLongWord cuts word into chunks whose weighted length fits limit, for
+// unbroken text such as Japanese or Chinese that has no spaces to split on.
+func splitLongWord(word string, limit int) []string {
+	var chunks []string
+	for tweetLength(word) > limit {
+		runes := []rune(word)
+		n := 1
+		for n < len(runes) && tweetLength(string(runes[:n+1])) <= limit {
+			n++
+		}
+		chunks = append(chunks, string(runes[:n]))
+		word = string(runes[n:])
+	}
+	return append(chunks, word)
+}
+
+// splitSentences splits paragraph after sentence-ending punctuation: ASCII
+// ".!?" followed by a space or line break, or the full-width "。！？" used
+// without spaces in Chinese and Japanese.
+func splitSentences(paragraph string) []string {
+	var sentences []string
+	start := 0
+	runes := []rune(paragraph)
+
+	for i, r := range runes {
+		switch r {
+		case '。', '！', '？':
+		case '.', '!', '?':
+			if i+1 < len(runes) && runes[i+1] != ' ' && runes[i+1] != '\n' {
+				continue
+			}
+		default:
+			continue
+		}
+		if s := strings.TrimSpace(string(runes[start : i+1])); s != "" {
+			sentences = append(sentences, s)
+		}
+		start = i + 1
+	}
+
+	if s := strings.TrimSpace(string(runes[start:])); s != "" {
+		sentences = append(sentences, s)
+	}
+
+	return sentences
+}
+
+// publishThread posts the first segment (with the optional image and extras)
+// and chains the remaining segments as replies, returning every posted tweet
+// ID.
+func publishThread(client *http.Client, cfg config.Config, segments []string, image string, media mediaOptions, extras tweetExtras) ([]string, error) {
+	rootID, err := publishTweet(client, cfg, segments[0], image, media, extras)
+	if err != nil {
+		return nil, err
+	}
+
+	replyIDs, err := publishReplies(client, cfg, rootID, segments[1:])
+	return append([]string{rootID}, replyIDs...), err
+}
+
+// publishReplies posts each text as a reply to the previous tweet, starting
+// from parentID, and records them in the history.
+func publishReplies(client *http.Client, cfg config.Config, parentID string, texts []string) ([]string, error) {
+	if err := checkAllowed(cfg, actionPost); err != nil {
+		return nil, err
+	}
+	var ids []string
+
+	for _, text := range texts {
+		payload := tweetPayload{
+			Text:  text,
+			Reply: &tweetReplyBlock{InReplyToTweetID: parentID},
+		}
+
+		id, err := postTweetPayload(client, cfg, payload)
+		if err != nil {
+			return ids, fmt.Errorf("posting reply %d of %d: %w", len(ids)+1, len(texts), err)
+		}
+
+		if err := recordHistory(historyEntry{TweetID: id, Text: text, PostedAt: time.Now()}); err != nil {
+			log.Print(decorate("⚠️", fmt.Sprintf("Failed to record tweet in history: %v", err)))
+		}
+
+		ids = append(ids, id)
+		parentID = id
+	}
+
+	return ids, nil
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kalikim/x-cli/config"
+)
+
+// mediaMetadataEndpoint sets a media's metadata; X reads a video's poster
+// image from it.
+const mediaMetadataEndpoint = "https://upload.twitter.com/1.1/media/metadata/create.json"
+
+// videoThumbnail is the poster image of a tweet's video: an image file, or
+// the frame FrameAt into the video, such as "00:05".
+type videoThumbnail struct {
+	Image   string `json:"image,omitempty"`
+	FrameAt string `json:"frame_at,omitempty"`
+}
+
+// String returns the thumbnail as --thumbnail takes it.
+func (t videoThumbnail) String() string {
+	if t.FrameAt != "" {
+		return "frame@" + t.FrameAt
+	}
+	return t.Image
+}
+
+// parseThumbnail reads --thumbnail, either frame@TIME or the path of a JPEG
+// or PNG image, and checks it against the video at videoPath.
+func parseThumbnail(spec, videoPath string) (videoThumbnail, error) {
+	errNeedVideo := errors.New("--thumbnail needs --image pointing to a video")
+	if videoPath == "" {
+		return videoThumbnail{}, invalidInput(errNeedVideo)
+	}
+	head, err := readHead(videoPath)
+	if err != nil {
+		return videoThumbnail{}, invalidInput(fmt.Errorf("media: %w", err))
+	}
+	if !strings.HasPrefix(detectMime(videoPath, head), "video/") {
+		return videoThumbnail{}, invalidInput(errNeedVideo)
+	}
+
+	if at, ok := strings.CutPrefix(spec, "frame@"); ok {
+		offset, err := parseFrameOffset(at)
+		if err != nil {
+			return videoThumbnail{}, invalidInput(err)
+		}
+		if _, err := exec.LookPath("ffmpeg"); err != nil {
+			return videoThumbnail{}, invalidInput(errors.New("--thumbnail frame@ needs ffmpeg; install it or export the frame and pass --thumbnail cover.jpg"))
+		}
+		if info, err := probeVideo(videoPath); err == nil && info.Duration > 0 && offset >= info.Duration {
+			return videoThumbnail{}, invalidInput(fmt.Errorf("--thumbnail %s is past the end of the %s video", spec, formatVideoDuration(info.Duration)))
+		}
+		return videoThumbnail{FrameAt: at}, nil
+	}
+
+	head, err = readHead(spec)
+	if err != nil {
+		return videoThumbnail{}, invalidInput(fmt.Errorf("thumbnail: %w", err))
+	}
+	if !overlayable(detectMime(spec, head)) {
+		return videoThumbnail{}, invalidInput(fmt.Errorf("thumbnail %s is not a JPEG or PNG image (or use frame@00:05 for a frame of the video)", spec))
+	}
+	// The daemon may run from another directory.
+	path, err := filepath.Abs(spec)
+	if err != nil {
+		return videoThumbnail{}, err
+	}
+	return videoThumbnail{Image: path}, nil
+}
+
+// parseFrameOffset reads a position in a video: seconds ("5", "2.5"),
+// MM:SS or HH:MM:SS with optional fractions, or a duration such as "1m5s".
+func parseFrameOffset(s string) (time.Duration, error) {
+	invalid := fmt.Errorf("invalid frame time %q (use e.g. 00:05, 1:02.5 or 5s)", s)
+	if d, err := time.ParseDuration(s); err == nil {
+		if d < 0 {
+			return 0, invalid
+		}
+		return d, nil
+	}
+
+	parts := strings.Split(s, ":")
+	if len(parts) > 3 {
+		return 0, invalid
+	}
+	var secs float64
+	for i, part := range parts {
+		n, err := strconv.ParseFloat(part, 64)
+		if err != nil || n < 0 || (i > 0 && n >= 60) || (i < len(parts)-1 && strings.Contains(part, ".")) {
+			return 0, invalid
+		}
+		secs = secs*60 + n
+	}
+	return time.Duration(secs * float64(time.Second)), nil
+}
+
+func readHead(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	head := make([]byte, 512)
+	n, _ := f.Read(head)
+	return head[:n], nil
+}
+
+// extractFrame saves the frame at offset into the video as a JPEG in a
+// temporary directory, returning its path and a function that removes it.
+func extractFrame(videoPath string, offset time.Duration) (string, func(), error) {
+	dir, err := os.MkdirTemp("", "x-cli-thumbnail-")
+	if err != nil {
+		return "", func() {}, err
+	}
+	cleanup := func() { os.RemoveAll(dir) }
+
+	out := filepath.Join(dir, strings.TrimSuffix(filepath.Base(videoPath), filepath.Ext(videoPath))+"-poster.jpg")
+	var stderr bytes.Buffer
+	cmd := exec.Command("ffmpeg", "-v", "error", "-ss", strconv.FormatFloat(offset.Seconds(), 'f', 3, 64),
+		"-i", videoPath, "-frames:v", "1", "-q:v", "2", "-y", out)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		cleanup()
+		return "", func() {}, fmt.Errorf("extracting frame with ffmpeg: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	if _, err := os.Stat(out); err != nil {
+		cleanup()
+		return "", func() {}, errors.New("extracting frame with ffmpeg: no frame at that time")
+	}
+	return out, cleanup, nil
+}
+
+// setVideoThumbnail uploads the poster image and makes it the thumbnail of
+// the uploaded video mediaID.
+func setVideoThumbnail(client *http.Client, cfg config.Config, videoPath, mediaID string, thumb videoThumbnail) error {
+	poster := thumb.Image
+	if thumb.FrameAt != "" {
+		offset, err := parseFrameOffset(thumb.FrameAt)
+		if err != nil {
+			return err
+		}
+		path, cleanup, err := extractFrame(videoPath, offset)
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+		poster = path
+	}
+
+	posterID, err := uploadMedia(client, cfg, poster)
+	if err != nil {
+		return fmt.Errorf("uploading thumbnail: %w", err)
+	}
+
+	payload, err := json.Marshal(map[string]any{
+		"media_id":       mediaID,
+		"poster_info":    map[string]string{"media_id": posterID},
+		"media_category": "TweetVideo",
+	})
+	if err != nil {
+		return fmt.Errorf("encoding thumbnail request: %w", err)
+	}
+	status, respBody, err := sendSigned(client, cfg, http.MethodPost, mediaMetadataEndpoint, payload, "application/json")
+	if err != nil {
+		return fmt.Errorf("setting thumbnail: %w", err)
+	}
+	if status >= 300 {
+		return fmt.Errorf("setting thumbnail: %w", newAPIError(status, respBody))
+	}
+	return nil
+}
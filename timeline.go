@@ -0,0 +1,232 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/kalikim/x-cli/config"
+)
+
+const (
+	homeTimelineEndpoint = "https://api.twitter.com/2/users/%s/timelines/reverse_chronological"
+	mentionsEndpoint     = "https://api.twitter.com/2/users/%s/mentions"
+	likesEndpoint        = "https://api.twitter.com/2/users/%s/likes"
+	recentSearchEndpoint = "https://api.twitter.com/2/tweets/search/recent"
+	retweetsEndpoint     = "https://api.twitter.com/2/users/%s/retweets"
+)
+
+// timelineTweet is a tweet as shown by the reader, with its author resolved.
+type timelineTweet struct {
+	ID        string
+	Text      string
+	CreatedAt time.Time
+	AuthorID  string
+	Username  string
+	Name      string
+	Likes     int
+	Retweets  int
+	Replies   int
+}
+
+// timelinePage is one page of a timeline and the token of the next.
+type timelinePage struct {
+	Tweets    []timelineTweet
+	NextToken string
+}
+
+// accountUserID returns the numeric ID of cfg's account, read from the
+// access token when possible and looked up otherwise.
+func accountUserID(client *http.Client, cfg config.Config) (string, error) {
+	if id := accessTokenUserID(cfg.AccessToken); id != "" {
+		return id, nil
+	}
+
+	body, err := signedGet(client, cfg, usersMeEndpoint)
+	if err != nil {
+		return "", fmt.Errorf("looking up account: %w", err)
+	}
+	var me struct {
+		Data struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &me); err != nil {
+		return "", fmt.Errorf("decoding account: %w", err)
+	}
+	if me.Data.ID == "" {
+		return "", fmt.Errorf("looking up account: no ID in response")
+	}
+	return me.Data.ID, nil
+}
+
+// accountUsername looks up cfg's @handle.
+func accountUsername(client *http.Client, cfg config.Config) (string, error) {
+	body, err := signedGet(client, cfg, usersMeEndpoint)
+	if err != nil {
+		return "", fmt.Errorf("looking up account: %w", err)
+	}
+	var me struct {
+		Data struct {
+			Username string `json:"username"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &me); err != nil {
+		return "", fmt.Errorf("decoding account: %w", err)
+	}
+	if me.Data.Username == "" {
+		return "", fmt.Errorf("looking up account: no username in response")
+	}
+	return me.Data.Username, nil
+}
+
+// fetchTimeline loads a page of tweets from endpoint (a format string taking
+// the user ID), newest first. A non-empty sinceID limits the page to newer
+// tweets.
+func fetchTimeline(client *http.Client, cfg config.Config, endpoint, userID, pageToken, sinceID string, pageSize int) (timelinePage, error) {
+	query := url.Values{}
+	query.Set("max_results", strconv.Itoa(pageSize))
+	query.Set("tweet.fields", "created_at,public_metrics,author_id")
+	query.Set("expansions", "author_id")
+	query.Set("user.fields", "username,name")
+	if pageToken != "" {
+		query.Set("pagination_token", pageToken)
+	}
+	if sinceID != "" {
+		query.Set("since_id", sinceID)
+	}
+
+	body, err := signedGet(client, cfg, fmt.Sprintf(endpoint, userID)+"?"+query.Encode())
+	if err != nil {
+		return timelinePage{}, err
+	}
+	return parseTimelinePage(body)
+}
+
+// parseTimelinePage decodes a v2 tweet list with its expanded authors.
+func parseTimelinePage(body []byte) (timelinePage, error) {
+	var resp struct {
+		Data []struct {
+			ID            string    `json:"id"`
+			Text          string    `json:"text"`
+			CreatedAt     time.Time `json:"created_at"`
+			AuthorID      string    `json:"author_id"`
+			PublicMetrics struct {
+				Likes    int `json:"like_count"`
+				Retweets int `json:"retweet_count"`
+				Replies  int `json:"reply_count"`
+			} `json:"public_metrics"`
+		} `json:"data"`
+		Includes struct {
+			Users []struct {
+				ID       string `json:"id"`
+				Username string `json:"username"`
+				Name     string `json:"name"`
+			} `json:"users"`
+		} `json:"includes"`
+		Meta struct {
+			NextToken string `json:"next_token"`
+		} `json:"meta"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return timelinePage{}, fmt.Errorf("decoding timeline: %w", err)
+	}
+
+	users := map[string][2]string{}
+	for _, u := range resp.Includes.Users {
+		users[u.ID] = [2]string{u.Username, u.Name}
+	}
+
+	page := timelinePage{NextToken: resp.Meta.NextToken}
+	for _, t := range resp.Data {
+		author := users[t.AuthorID]
+		page.Tweets = append(page.Tweets, timelineTweet{
+			ID:        t.ID,
+			Text:      t.Text,
+			CreatedAt: t.CreatedAt,
+			AuthorID:  t.AuthorID,
+			Username:  author[0],
+			Name:      author[1],
+			Likes:     t.PublicMetrics.Likes,
+			Retweets:  t.PublicMetrics.Retweets,
+			Replies:   t.PublicMetrics.Replies,
+		})
+	}
+	return page, nil
+}
+
+// searchRecent looks up tweets from the last seven days matching query,
+// newest first. A non-empty sinceID limits the results to newer tweets;
+// otherwise startTime does, if set.
+func searchRecent(client *http.Client, cfg config.Config, query, sinceID string, startTime time.Time, pageSize int) (timelinePage, error) {
+	params := url.Values{}
+	params.Set("query", query)
+	params.Set("max_results", strconv.Itoa(pageSize))
+	params.Set("tweet.fields", "created_at,public_metrics,author_id")
+	params.Set("expansions", "author_id")
+	params.Set("user.fields", "username,name")
+	switch {
+	case sinceID != "":
+		params.Set("since_id", sinceID)
+	case !startTime.IsZero():
+		params.Set("start_time", startTime.UTC().Format(time.RFC3339))
+	}
+
+	body, err := signedGet(client, cfg, recentSearchEndpoint+"?"+params.Encode())
+	if err != nil {
+		return timelinePage{}, err
+	}
+	return parseTimelinePage(body)
+}
+
+// likeTweet likes tweetID as userID.
+func likeTweet(client *http.Client, cfg config.Config, userID, tweetID string) error {
+	if err := checkAllowed(cfg, actionLike); err != nil {
+		return err
+	}
+	return postUserAction(client, cfg, fmt.Sprintf(likesEndpoint, userID), tweetID)
+}
+
+// retweetTweet retweets tweetID as userID.
+func retweetTweet(client *http.Client, cfg config.Config, userID, tweetID string) error {
+	if err := checkAllowed(cfg, actionRetweet); err != nil {
+		return err
+	}
+	return postUserAction(client, cfg, fmt.Sprintf(retweetsEndpoint, userID), tweetID)
+}
+
+func postUserAction(client *http.Client, cfg config.Config, endpoint, tweetID string) error {
+	body, err := json.Marshal(map[string]string{"tweet_id": tweetID})
+	if err != nil {
+		return err
+	}
+
+	status, respBody, err := sendSigned(client, cfg, http.MethodPost, endpoint, body, "application/json")
+	if err != nil {
+		return fmt.Errorf("performing request: %w", err)
+	}
+	if status >= 300 {
+		return newAPIError(status, respBody)
+	}
+	return nil
+}
+
+// newerID reports whether snowflake ID a is newer than b. An empty b is
+// older than every ID.
+func newerID(a, b string) bool {
+	if len(a) != len(b) {
+		return len(a) > len(b)
+	}
+	return a > b
+}
+
+// tweetURL is the public x.com link of a tweet.
+func tweetURL(username, tweetID string) string {
+	if username == "" {
+		username = "i/web"
+	}
+	return "https://x.com/" + username + "/status/" + tweetID
+}
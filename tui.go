@@ -0,0 +1,474 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/kalikim/x-cli/config"
+	"github.com/spf13/cobra"
+)
+
+const (
+	tuiRefreshInterval = 5 * time.Second
+	tuiQueueRows       = 8
+	tuiHistoryRows     = 5
+)
+
+type tuiMode int
+
+const (
+	tuiModeBrowse tuiMode = iota
+	tuiModeCompose
+	tuiModeConfirm
+	tuiModeScheduleTime
+)
+
+// tuiState holds everything the dashboard renders between key presses.
+type tuiState struct {
+	mode  tuiMode
+	draft []byte
+	// draftID is the saved draft the composer holds, if any.
+	draftID   string
+	timeInput []byte
+	message   string
+	queue     []scheduledTweet
+	history   []historyEntry
+	daemon    string
+}
+
+func newTUICmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "tui",
+		Short: "Interactive dashboard for the schedule queue, history and compose",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runTUI()
+		},
+	}
+}
+
+func runTUI() error {
+	info, err := os.Stdin.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return errors.New("tui requires an interactive terminal")
+	}
+
+	restore, err := enableRawMode()
+	if err != nil {
+		return fmt.Errorf("configuring terminal: %w", err)
+	}
+	defer restore()
+
+	keys := make(chan byte)
+	go func() {
+		buf := make([]byte, 1)
+		for {
+			if _, err := os.Stdin.Read(buf); err != nil {
+				close(keys)
+				return
+			}
+			keys <- buf[0]
+		}
+	}()
+
+	ticker := time.NewTicker(tuiRefreshInterval)
+	defer ticker.Stop()
+
+	state := &tuiState{}
+	state.refresh()
+	state.restoreDraft()
+
+	for {
+		state.render()
+
+		select {
+		case <-ticker.C:
+			state.refresh()
+		case key, ok := <-keys:
+			if !ok {
+				return state.keepDraft()
+			}
+			if quit := state.handleKey(key); quit {
+				fmt.Print("\x1b[2J\x1b[H")
+				return state.keepDraft()
+			}
+		}
+	}
+}
+
+// enableRawMode switches the controlling terminal to raw, no-echo mode via
+// stty and returns a function restoring the previous settings.
+func enableRawMode() (func(), error) {
+	saved, err := sttyOutput("-g")
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := sttyOutput("raw", "-echo"); err != nil {
+		return nil, err
+	}
+
+	return func() {
+		sttyOutput(strings.TrimSpace(saved))
+	}, nil
+}
+
+func sttyOutput(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	return string(out), err
+}
+
+func (s *tuiState) refresh() {
+	if tweets, err := loadScheduledTweets(""); err == nil {
+		s.queue = tweets
+	} else {
+		s.message = decorate("⚠️", "Failed to load queue: "+err.Error())
+	}
+
+	if entries, err := loadHistory(); err == nil {
+		s.history = entries
+	} else {
+		s.message = decorate("⚠️", "Failed to load history: "+err.Error())
+	}
+
+	s.daemon = describeDaemonStatus(time.Now())
+	if state, err := loadSchedulerState(); err == nil && state.Paused {
+		s.daemon += " — queue paused"
+	}
+}
+
+// handleKey applies a single key press and reports whether the TUI should exit.
+func (s *tuiState) handleKey(key byte) bool {
+	const (
+		keyCtrlC     = 3
+		keyEnter     = 13
+		keyEscape    = 27
+		keyBackspace = 127
+	)
+
+	switch s.mode {
+	case tuiModeBrowse:
+		switch key {
+		case 'q', keyCtrlC:
+			return true
+		case 'r':
+			s.refresh()
+			s.message = decorate("🔄", "Refreshed")
+		case 'c':
+			s.mode = tuiModeCompose
+			s.message = ""
+		}
+
+	case tuiModeCompose:
+		switch key {
+		case keyCtrlC, keyEscape:
+			s.mode = tuiModeBrowse
+			s.message = "Draft kept; press c to continue editing"
+		case keyEnter:
+			if strings.TrimSpace(string(s.draft)) == "" {
+				s.message = decorate("⚠️", "Tweet text cannot be empty")
+				return false
+			}
+			s.mode = tuiModeConfirm
+		case keyBackspace, 8:
+			s.draft = deleteLastRune(s.draft)
+		default:
+			s.draft = appendInput(s.draft, key)
+		}
+
+	case tuiModeConfirm:
+		switch key {
+		case 'p':
+			s.postDraft()
+		case 's':
+			s.mode = tuiModeScheduleTime
+			s.timeInput = nil
+		case 'e':
+			s.mode = tuiModeCompose
+		case 'd':
+			s.draft = nil
+			s.mode = tuiModeBrowse
+			s.message = decorate("🗑️", "Draft discarded")
+			s.dropDraft()
+		case keyCtrlC, keyEscape:
+			s.mode = tuiModeCompose
+		}
+
+	case tuiModeScheduleTime:
+		switch key {
+		case keyCtrlC, keyEscape:
+			s.mode = tuiModeConfirm
+		case keyEnter:
+			s.scheduleDraft()
+		case keyBackspace, 8:
+			s.timeInput = deleteLastRune(s.timeInput)
+		default:
+			s.timeInput = appendInput(s.timeInput, key)
+		}
+	}
+
+	return false
+}
+
+// appendInput appends a raw input byte to an editing buffer, ignoring
+// control characters. Multi-byte UTF-8 input arrives one byte at a time and is
+// simply accumulated.
+func appendInput(buf []byte, key byte) []byte {
+	if key < 0x20 {
+		return buf
+	}
+	return append(buf, key)
+}
+
+// deleteLastRune removes the final (possibly multi-byte) character of buf.
+func deleteLastRune(buf []byte) []byte {
+	if len(buf) == 0 {
+		return buf
+	}
+	_, size := utf8.DecodeLastRune(buf)
+	return buf[:len(buf)-size]
+}
+
+func (s *tuiState) postDraft() {
+	text := strings.TrimSpace(string(s.draft))
+
+	cfg := config.LoadConfig()
+	if err := cfg.Validate(); err != nil {
+		s.message = decorate("❌", err.Error())
+		s.mode = tuiModeBrowse
+		return
+	}
+	if err := checkWritable(cfg); err != nil {
+		s.message = decorate("❌", err.Error())
+		s.mode = tuiModeBrowse
+		return
+	}
+	text, err := applyFooter(cfg, text)
+	if err != nil {
+		s.message = decorate("❌", err.Error())
+		s.mode = tuiModeCompose
+		return
+	}
+	if err := validateTweetText(text); err != nil {
+		s.message = decorate("❌", err.Error())
+		s.mode = tuiModeCompose
+		return
+	}
+	if err := checkBannedContent(cfg, text); err != nil {
+		s.message = decorate("❌", err.Error())
+		s.mode = tuiModeCompose
+		return
+	}
+	if _, err := checkContentRules(cfg, []ruleText{{"", text}}, false); err != nil {
+		s.message = decorate("❌", err.Error())
+		s.mode = tuiModeCompose
+		return
+	}
+
+	client := apiClient()
+	if _, err := publishTweet(client, cfg, text, "", mediaOptions{}, tweetExtras{}); err != nil {
+		s.message = decorate("❌", err.Error())
+		s.mode = tuiModeBrowse
+		return
+	}
+
+	s.message = decorate("✅", "Tweet posted successfully!")
+
+	s.draft = nil
+	s.dropDraft()
+	s.mode = tuiModeBrowse
+	s.refresh()
+}
+
+func (s *tuiState) scheduleDraft() {
+	text := strings.TrimSpace(string(s.draft))
+
+	cfg := config.LoadConfig()
+	if err := checkAllowed(cfg, actionSchedule); err != nil {
+		s.message = decorate("❌", err.Error())
+		s.mode = tuiModeCompose
+		return
+	}
+	if err := checkWritable(cfg); err != nil {
+		s.message = decorate("❌", err.Error())
+		s.mode = tuiModeCompose
+		return
+	}
+	text, err := applyFooter(cfg, text)
+	if err != nil {
+		s.message = decorate("❌", err.Error())
+		s.mode = tuiModeCompose
+		return
+	}
+	if err := validateTweetText(text); err != nil {
+		s.message = decorate("❌", err.Error())
+		s.mode = tuiModeCompose
+		return
+	}
+	if err := checkBannedContent(cfg, text); err != nil {
+		s.message = decorate("❌", err.Error())
+		s.mode = tuiModeCompose
+		return
+	}
+	if _, err := checkContentRules(cfg, []ruleText{{"", text}}, false); err != nil {
+		s.message = decorate("❌", err.Error())
+		s.mode = tuiModeCompose
+		return
+	}
+
+	scheduleTime, err := parseScheduleTime(strings.TrimSpace(string(s.timeInput)))
+	if err != nil {
+		s.message = decorate("❌", err.Error())
+		return
+	}
+	if scheduleTime.Before(time.Now()) {
+		s.message = decorate("❌", "schedule time must be in the future")
+		return
+	}
+
+	tweet := scheduledTweet{
+		Text:         text,
+		ScheduleTime: scheduleTime,
+		ID:           generateTweetID(),
+	}
+	if err := checkScheduleConflicts(cfg, "", tweet); err != nil {
+		s.message = decorate("❌", err.Error())
+		return
+	}
+	if err := saveScheduledTweet("", tweet); err != nil {
+		s.message = decorate("❌", "saving scheduled tweet: "+err.Error())
+		return
+	}
+
+	s.message = decorate("✅", fmt.Sprintf("Tweet scheduled for %s (ID: %s)", scheduleTime.Format("2006-01-02 15:04:05"), tweet.ID))
+	s.draft = nil
+	s.dropDraft()
+	s.mode = tuiModeBrowse
+	s.refresh()
+}
+
+// restoreDraft reopens the newest saved draft in the composer.
+func (s *tuiState) restoreDraft() {
+	drafts, err := loadDrafts()
+	if err != nil {
+		s.message = decorate("⚠️", "Failed to load drafts: "+err.Error())
+		return
+	}
+	if len(drafts) == 0 {
+		return
+	}
+	s.draft, s.draftID = []byte(drafts[0].Text), drafts[0].ID
+	s.message = "Draft restored; press c to continue editing"
+}
+
+// keepDraft saves the composer's text on quit, so the next session and
+// 'history search' find it.
+func (s *tuiState) keepDraft() error {
+	text := strings.TrimSpace(string(s.draft))
+	if text == "" {
+		return nil
+	}
+	if s.draftID == "" {
+		s.draftID = generateDraftID()
+	}
+	if err := saveDraft(tweetDraft{ID: s.draftID, Text: text, SavedAt: time.Now()}); err != nil {
+		return fmt.Errorf("saving draft: %w", err)
+	}
+	return nil
+}
+
+// dropDraft deletes the saved draft the composer held once it is posted,
+// scheduled or discarded.
+func (s *tuiState) dropDraft() {
+	if s.draftID == "" {
+		return
+	}
+	if _, err := removeDraft(s.draftID); err != nil {
+		s.message += "  " + decorate("⚠️", "Failed to remove draft: "+err.Error())
+	}
+	s.draftID = ""
+}
+
+func (s *tuiState) render() {
+	var lines []string
+	now := time.Now()
+
+	lines = append(lines, "x-cli dashboard  "+now.Format("2006-01-02 15:04:05"))
+	lines = append(lines, "Daemon: "+s.daemon)
+	lines = append(lines, "")
+
+	lines = append(lines, decorate("📅", fmt.Sprintf("Schedule queue (%d)", len(s.queue))))
+	if len(s.queue) == 0 {
+		lines = append(lines, "  (empty)")
+	}
+	for i, tweet := range s.queue {
+		if i == tuiQueueRows {
+			lines = append(lines, fmt.Sprintf("  … %d more", len(s.queue)-i))
+			break
+		}
+		marker := " "
+		if tweet.ScheduleTime.Before(now) {
+			marker = "!"
+		}
+		lines = append(lines, fmt.Sprintf(" %s%s  %s", marker, tweet.ScheduleTime.Format("01-02 15:04"), isolateBidi(truncateText(tweet.Text, 60))))
+	}
+	lines = append(lines, "")
+
+	lines = append(lines, decorate("🕘", "Recent history"))
+	if len(s.history) == 0 {
+		lines = append(lines, "  (none)")
+	}
+	for i := len(s.history) - 1; i >= 0 && i >= len(s.history)-tuiHistoryRows; i-- {
+		entry := s.history[i]
+		lines = append(lines, fmt.Sprintf("  %s  %s", entry.PostedAt.Format("01-02 15:04"), isolateBidi(truncateText(entry.Text, 60))))
+	}
+	lines = append(lines, "")
+
+	// A character still arriving byte by byte is neither shown nor counted.
+	draft := string(completeRunes(s.draft))
+	count := tweetLength(draft)
+	countLabel := fmt.Sprintf("%d/%d", count, maxTweetLength)
+	if count > maxTweetLength {
+		countLabel += " " + decorate("⚠️", "too long")
+	}
+	lines = append(lines, decorate("✏️", "Compose ["+countLabel+"]"))
+	cursor := ""
+	if s.mode == tuiModeCompose {
+		cursor = "█"
+	}
+	lines = append(lines, "  "+isolateBidi(draft)+cursor)
+	lines = append(lines, "")
+
+	switch s.mode {
+	case tuiModeBrowse:
+		lines = append(lines, "[c] compose  [r] refresh  [q] quit")
+	case tuiModeCompose:
+		lines = append(lines, "Type your tweet  [Enter] done  [Esc] back")
+	case tuiModeConfirm:
+		lines = append(lines, "[p] post now  [s] schedule  [e] edit  [d] discard")
+	case tuiModeScheduleTime:
+		lines = append(lines, "Schedule at (YYYY-MM-DD HH:MM, MM-DD HH:MM or HH:MM): "+string(s.timeInput)+"█")
+	}
+
+	if s.message != "" {
+		lines = append(lines, s.message)
+	}
+
+	// Raw mode disables output post-processing, so lines need explicit CRs.
+	fmt.Print("\x1b[2J\x1b[H" + strings.Join(lines, "\r\n") + "\r\n")
+}
+
+func truncateText(text string, max int) string {
+	text = strings.ReplaceAll(text, "\n", " ")
+	runes := []rune(text)
+	if len(runes) <= max {
+		return text
+	}
+	return string(runes[:max-1]) + "…"
+}
@@ -0,0 +1,372 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	upgradeRepo          = "kalikim/x-cli"
+	checksumsAsset       = "checksums.txt"
+	checksumsSigAsset    = "checksums.txt.sig"
+	updateCheckFile      = "update_check.json"
+	updateCheckInterval  = 24 * time.Hour
+	updateNoticeDisabler = "XCLI_NO_UPDATE_CHECK"
+)
+
+// version is set at build time with -ldflags "-X main.version=v1.2.3".
+// Builds made with "go install ...@vX.Y.Z" fall back to the module version.
+var version = "dev"
+
+// upgradeRan suppresses the update notice after 'x-cli upgrade' itself.
+var upgradeRan bool
+
+func init() {
+	if info, ok := debug.ReadBuildInfo(); ok && version == "dev" && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		version = info.Main.Version
+	}
+}
+
+// releasePublicKey is the base64 Ed25519 key that signs checksums.txt. Builds
+// that set it (-ldflags "-X main.releasePublicKey=...") refuse unsigned
+// upgrades.
+var releasePublicKey = ""
+
+func newUpgradeCmd() *cobra.Command {
+	var checkOnly bool
+	var target string
+
+	upgradeCmd := &cobra.Command{
+		Use:   "upgrade",
+		Short: "Update x-cli to the latest release",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			upgradeRan = true
+			client := apiClientWithTimeout(5 * time.Minute)
+
+			release, err := fetchGitHubRelease(client, upgradeRepo, target)
+			if err != nil {
+				return err
+			}
+			saveUpdateCheck(release.TagName)
+
+			if target == "" && compareVersions(release.TagName, version) <= 0 {
+				say("✅", "x-cli %s is up to date", version)
+				return nil
+			}
+
+			say("⬆️", "x-cli %s is available (installed: %s)", release.TagName, version)
+			if checkOnly {
+				say("💡", "Run 'x-cli upgrade' to install it")
+				return nil
+			}
+
+			return installRelease(client, release)
+		},
+	}
+
+	upgradeCmd.Flags().BoolVar(&checkOnly, "check", false, "Only report whether a newer release exists")
+	upgradeCmd.Flags().StringVar(&target, "version", "", "Install this release tag instead of the latest")
+
+	return upgradeCmd
+}
+
+// releaseAssetName is the binary name published for this platform, matching
+// the names used in the README build instructions.
+func releaseAssetName() string {
+	name := fmt.Sprintf("x-cli-%s-%s", runtime.GOOS, runtime.GOARCH)
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	return name
+}
+
+func installRelease(client *http.Client, release githubRelease) error {
+	assets := map[string]string{}
+	for _, a := range release.Assets {
+		assets[a.Name] = a.DownloadURL
+	}
+
+	binaryName := releaseAssetName()
+	binaryURL, ok := assets[binaryName]
+	if !ok {
+		return fmt.Errorf("release %s has no binary for %s/%s (expected %s)", release.TagName, runtime.GOOS, runtime.GOARCH, binaryName)
+	}
+	checksumsURL, ok := assets[checksumsAsset]
+	if !ok {
+		return fmt.Errorf("release %s has no %s; refusing to install an unverified binary", release.TagName, checksumsAsset)
+	}
+
+	checksums, err := download(client, checksumsURL)
+	if err != nil {
+		return fmt.Errorf("downloading checksums: %w", err)
+	}
+
+	if releasePublicKey != "" {
+		sigURL, ok := assets[checksumsSigAsset]
+		if !ok {
+			return fmt.Errorf("release %s is not signed (%s missing)", release.TagName, checksumsSigAsset)
+		}
+		sig, err := download(client, sigURL)
+		if err != nil {
+			return fmt.Errorf("downloading signature: %w", err)
+		}
+		if err := verifyChecksumsSignature(checksums, sig); err != nil {
+			return err
+		}
+		say("🔏", "Signature verified")
+	}
+
+	want, err := checksumFor(checksums, binaryName)
+	if err != nil {
+		return err
+	}
+
+	say("📥", "Downloading %s...", binaryName)
+	binary, err := download(client, binaryURL)
+	if err != nil {
+		return fmt.Errorf("downloading %s: %w", binaryName, err)
+	}
+
+	sum := sha256.Sum256(binary)
+	if got := hex.EncodeToString(sum[:]); got != want {
+		return fmt.Errorf("checksum mismatch for %s: expected %s, got %s", binaryName, want, got)
+	}
+
+	if err := replaceExecutable(binary); err != nil {
+		return err
+	}
+
+	say("✅", "Upgraded x-cli from %s to %s", version, release.TagName)
+	return nil
+}
+
+func download(client *http.Client, url string) ([]byte, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// checksumFor finds name in a sha256sum-style checksums file.
+func checksumFor(checksums []byte, name string) (string, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(checksums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+	return "", fmt.Errorf("%s does not list %s", checksumsAsset, name)
+}
+
+func verifyChecksumsSignature(checksums, sig []byte) error {
+	key, err := base64.StdEncoding.DecodeString(releasePublicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return errors.New("built-in release public key is invalid")
+	}
+
+	// Accept both raw and base64-encoded signature files.
+	if decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(sig))); err == nil {
+		sig = decoded
+	}
+
+	if !ed25519.Verify(key, checksums, sig) {
+		return fmt.Errorf("%s signature does not match the release key", checksumsAsset)
+	}
+	return nil
+}
+
+// replaceExecutable swaps the running binary for data. The new file is
+// written next to the old one and renamed into place so a failed download
+// never leaves a half-written binary.
+func replaceExecutable(data []byte) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("locating current executable: %w", err)
+	}
+	if exe, err = filepath.EvalSymlinks(exe); err != nil {
+		return fmt.Errorf("locating current executable: %w", err)
+	}
+
+	dir := filepath.Dir(exe)
+	tmp, err := os.CreateTemp(dir, ".x-cli-upgrade-*")
+	if err != nil {
+		return fmt.Errorf("cannot write to %s (try again with sufficient permissions): %w", dir, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing new binary: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writing new binary: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0755); err != nil {
+		return fmt.Errorf("making new binary executable: %w", err)
+	}
+
+	// Windows cannot overwrite a running executable, but can rename it.
+	if runtime.GOOS == "windows" {
+		old := exe + ".old"
+		os.Remove(old)
+		if err := os.Rename(exe, old); err != nil {
+			return fmt.Errorf("moving old binary aside: %w", err)
+		}
+	}
+
+	if err := os.Rename(tmp.Name(), exe); err != nil {
+		return fmt.Errorf("replacing %s: %w", exe, err)
+	}
+	return nil
+}
+
+// compareVersions compares two vMAJOR.MINOR.PATCH tags numerically. "dev"
+// and other unparsable versions sort before every release.
+func compareVersions(a, b string) int {
+	pa, okA := parseVersion(a)
+	pb, okB := parseVersion(b)
+	switch {
+	case !okA && !okB:
+		return 0
+	case !okA:
+		return -1
+	case !okB:
+		return 1
+	}
+
+	for i := range pa {
+		if pa[i] != pb[i] {
+			if pa[i] < pb[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+func parseVersion(v string) ([3]int, bool) {
+	var parts [3]int
+
+	v = strings.TrimPrefix(v, "v")
+	v, _, _ = strings.Cut(v, "-")
+	fields := strings.Split(v, ".")
+	if len(fields) == 0 || len(fields) > 3 {
+		return parts, false
+	}
+
+	for i, f := range fields {
+		n, err := strconv.Atoi(f)
+		if err != nil {
+			return parts, false
+		}
+		parts[i] = n
+	}
+	return parts, true
+}
+
+// updateCheck caches the latest known release so the notice costs no
+// network round trip.
+type updateCheck struct {
+	CheckedAt time.Time `json:"checked_at"`
+	Latest    string    `json:"latest"`
+}
+
+func updateCheckPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".x-cli", updateCheckFile), nil
+}
+
+func loadUpdateCheck() updateCheck {
+	var check updateCheck
+	path, err := updateCheckPath()
+	if err != nil {
+		return check
+	}
+	if data, err := os.ReadFile(path); err == nil {
+		json.Unmarshal(data, &check)
+	}
+	return check
+}
+
+func saveUpdateCheck(latest string) {
+	path, err := updateCheckPath()
+	if err != nil {
+		return
+	}
+	data, err := json.Marshal(updateCheck{CheckedAt: time.Now(), Latest: latest})
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return
+	}
+
+	// Written via rename because the refresh may be cut short by exit.
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err == nil {
+		os.Rename(tmp, path)
+	}
+}
+
+// updateNoticeEnabled reports whether the background check and notice should
+// run: only for release builds, on an interactive terminal, and not offline.
+func updateNoticeEnabled() bool {
+	return version != "dev" && !upgradeRan && !offlineMode && cassetteTransport == nil &&
+		os.Getenv(updateNoticeDisabler) == "" && isTerminal(os.Stderr)
+}
+
+// refreshUpdateCheck looks up the latest release in the background when the
+// cached result is older than a day. It is best effort and never reports
+// errors.
+func refreshUpdateCheck() {
+	if !updateNoticeEnabled() || time.Since(loadUpdateCheck().CheckedAt) < updateCheckInterval {
+		return
+	}
+
+	go func() {
+		client := apiClientWithTimeout(5 * time.Second)
+		if release, err := fetchGitHubRelease(client, upgradeRepo, ""); err == nil {
+			saveUpdateCheck(release.TagName)
+		}
+	}()
+}
+
+// printUpdateNotice prints a one-line hint to stderr when the cached latest
+// release is newer than this build.
+func printUpdateNotice() {
+	if !updateNoticeEnabled() {
+		return
+	}
+	if latest := loadUpdateCheck().Latest; compareVersions(latest, version) > 0 {
+		fmt.Fprintln(os.Stderr, decorate("💡", fmt.Sprintf("x-cli %s is available (you have %s); run 'x-cli upgrade'", latest, version)))
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kalikim/x-cli/config"
+)
+
+// uploadRateFlag is the --upload-rate value; it overrides upload_rate in the
+// config.
+var uploadRateFlag string
+
+// parseUploadRate turns a rate such as "500KB/s", "2MB/s" or "800k" into
+// bytes per second. Units are powers of 1024; "/s" is optional. An empty
+// value or "0" means unlimited.
+func parseUploadRate(s string) (int64, error) {
+	value := strings.TrimSuffix(strings.ToUpper(strings.TrimSpace(s)), "/S")
+	if value == "" || value == "0" {
+		return 0, nil
+	}
+
+	multiplier := int64(1)
+	for _, unit := range []struct {
+		suffixes []string
+		size     int64
+	}{
+		{[]string{"GIB", "GB", "G"}, 1 << 30},
+		{[]string{"MIB", "MB", "M"}, 1 << 20},
+		{[]string{"KIB", "KB", "K"}, 1 << 10},
+		{[]string{"B"}, 1},
+	} {
+		found := false
+		for _, suffix := range unit.suffixes {
+			if strings.HasSuffix(value, suffix) {
+				value = strings.TrimSpace(strings.TrimSuffix(value, suffix))
+				multiplier = unit.size
+				found = true
+				break
+			}
+		}
+		if found {
+			break
+		}
+	}
+
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil || n < 0 {
+		return 0, invalidInput(fmt.Errorf("invalid upload rate %q (use e.g. 500KB/s or 2MB/s)", s))
+	}
+	rate := int64(n * float64(multiplier))
+	if n > 0 && rate < 1024 {
+		return 0, invalidInput(fmt.Errorf("upload rate %q is below the 1KB/s minimum", s))
+	}
+	return rate, nil
+}
+
+// uploadRate returns the media upload limit in bytes per second, 0 for none.
+func uploadRate(cfg config.Config) (int64, error) {
+	if uploadRateFlag != "" {
+		return parseUploadRate(uploadRateFlag)
+	}
+	return parseUploadRate(cfg.UploadRate)
+}
+
+// throttledClient returns a copy of client whose request bodies are sent at
+// no more than rate bytes per second. The timeout grows by the time size
+// bytes need at that rate.
+func throttledClient(client *http.Client, rate, size int64) *http.Client {
+	throttled := *client
+	base := client.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	throttled.Transport = throttledTransport{base: base, rate: rate}
+	if throttled.Timeout > 0 {
+		throttled.Timeout += time.Duration(size/rate+1) * time.Second
+	}
+	return &throttled
+}
+
+type throttledTransport struct {
+	base http.RoundTripper
+	rate int64
+}
+
+func (t throttledTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return t.base.RoundTrip(req)
+	}
+
+	throttled := req.Clone(req.Context())
+	throttled.Body = &throttledReader{r: req.Body, rate: t.rate}
+	return t.base.RoundTrip(throttled)
+}
+
+// throttledReader paces reads so that, on average, no more than rate bytes
+// per second pass through it.
+type throttledReader struct {
+	r     io.ReadCloser
+	rate  int64
+	start time.Time
+	sent  int64
+}
+
+func (r *throttledReader) Read(p []byte) (int, error) {
+	if r.start.IsZero() {
+		r.start = time.Now()
+	}
+
+	// Small chunks keep the pace smooth rather than bursting a second's
+	// worth at a time.
+	if chunk := max(r.rate/10, 1); int64(len(p)) > chunk {
+		p = p[:chunk]
+	}
+
+	n, err := r.r.Read(p)
+	r.sent += int64(n)
+
+	due := time.Duration(float64(r.sent) / float64(r.rate) * float64(time.Second))
+	if wait := due - time.Since(r.start); wait > 0 {
+		time.Sleep(wait)
+	}
+	return n, err
+}
+
+func (r *throttledReader) Close() error {
+	return r.r.Close()
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/kalikim/x-cli/config"
+	"github.com/spf13/cobra"
+)
+
+// Kinds of usage entries.
+const (
+	usageCommand = "command"
+	usageAPI     = "api"
+)
+
+// usageEntry is one line of the local usage log: a command run or an API
+// call. It holds no tweet text, arguments or credentials.
+type usageEntry struct {
+	Time       time.Time `json:"time"`
+	Kind       string    `json:"kind"`
+	Name       string    `json:"name"`
+	DurationMS int64     `json:"duration_ms"`
+	Status     int       `json:"status,omitempty"`
+	ExitCode   int       `json:"exit_code,omitempty"`
+	Failed     bool      `json:"failed,omitempty"`
+}
+
+var (
+	usageEnabled     bool
+	usageEnabledOnce sync.Once
+	usageMu          sync.Mutex
+)
+
+// recordingUsage reports whether usage_stats is on. It is read once, like
+// the User-Agent, since the transport is built before commands load their
+// config.
+func recordingUsage() bool {
+	usageEnabledOnce.Do(func() {
+		usageEnabled = config.Peek().UsageStats
+	})
+	return usageEnabled
+}
+
+func usageLogPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".x-cli", "usage.ndjson"), nil
+}
+
+// recordUsage appends entry to the usage log when usage_stats is enabled.
+// Failures are reported but never affect the command.
+func recordUsage(entry usageEntry) {
+	if !recordingUsage() {
+		return
+	}
+	if err := appendUsage(entry); err != nil {
+		log.Print(decorate("⚠️", fmt.Sprintf("Failed to write usage log: %v", err)))
+	}
+}
+
+func appendUsage(entry usageEntry) error {
+	path, err := usageLogPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	// The daemon's API calls may finish concurrently.
+	usageMu.Lock()
+	defer usageMu.Unlock()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+// recordCommandUsage logs a finished command run.
+func recordCommandUsage(cmd *cobra.Command, started time.Time, err error) {
+	if cmd == nil {
+		return
+	}
+	entry := usageEntry{
+		Time:       started.UTC(),
+		Kind:       usageCommand,
+		Name:       cmd.CommandPath(),
+		DurationMS: time.Since(started).Milliseconds(),
+	}
+	if err != nil {
+		entry.Failed, entry.ExitCode = true, exitCode(err)
+	}
+	recordUsage(entry)
+}
+
+// usageTransport times each request for the usage log.
+type usageTransport struct {
+	base http.RoundTripper
+}
+
+func (t *usageTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !recordingUsage() {
+		return t.base.RoundTrip(req)
+	}
+
+	started := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	entry := usageEntry{
+		Time:       started.UTC(),
+		Kind:       usageAPI,
+		Name:       usageEndpoint(req),
+		DurationMS: time.Since(started).Milliseconds(),
+		Failed:     err != nil,
+	}
+	if resp != nil {
+		entry.Status = resp.StatusCode
+		entry.Failed = entry.Failed || resp.StatusCode >= 400
+	}
+	recordUsage(entry)
+	return resp, err
+}
+
+// usageEndpoint names a request for the usage log: the method and path of
+// X API calls, with IDs replaced by ":id" so calls group by endpoint, and
+// only the host of anything else, as webhook paths embed secrets.
+func usageEndpoint(req *http.Request) string {
+	host := req.URL.Hostname()
+	if host != "twitter.com" && !strings.HasSuffix(host, ".twitter.com") && host != "x.com" && !strings.HasSuffix(host, ".x.com") {
+		return req.Method + " " + host
+	}
+
+	segments := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	for i, s := range segments {
+		// The first segment is the API version, such as "2" or "1.1".
+		if i > 0 && strings.IndexFunc(s, unicode.IsDigit) >= 0 {
+			segments[i] = ":id"
+		}
+	}
+	return req.Method + " /" + strings.Join(segments, "/")
+}
+
+func loadUsage(cutoff time.Time) ([]usageEntry, error) {
+	path, err := usageLogPath()
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []usageEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var entry usageEntry
+		// A line cut short by a crash is skipped, not fatal.
+		if json.Unmarshal(scanner.Bytes(), &entry) != nil || entry.Time.Before(cutoff) {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// writeUsageReport prints, for each command and API endpoint, how often it
+// ran, how often it failed and how long it took. The busiest come first.
+func writeUsageReport(entries []usageEntry, kind, format string) error {
+	type usageKey struct{ kind, name string }
+	durations := map[usageKey][]time.Duration{}
+	failed := map[usageKey]int{}
+	for _, e := range entries {
+		if kind != "" && e.Kind != kind {
+			continue
+		}
+		k := usageKey{e.Kind, e.Name}
+		durations[k] = append(durations[k], time.Duration(e.DurationMS)*time.Millisecond)
+		if e.Failed {
+			failed[k]++
+		}
+	}
+	if len(durations) == 0 {
+		say("📭", "No usage recorded in that period")
+		return nil
+	}
+
+	keys := make([]usageKey, 0, len(durations))
+	for k := range durations {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].kind != keys[j].kind {
+			return keys[i].kind == usageCommand
+		}
+		if n, m := len(durations[keys[i]]), len(durations[keys[j]]); n != m {
+			return n > m
+		}
+		return keys[i].name < keys[j].name
+	})
+
+	rows := make([][]any, len(keys))
+	for i, k := range keys {
+		d := durations[k]
+		rows[i] = []any{k.kind, k.name, len(d), failed[k], formatLateness(percentileDuration(d, 50)), formatLateness(percentileDuration(d, 95)), formatLateness(percentileDuration(d, 100))}
+	}
+	return writeRows(format, []string{"kind", "name", "count", "failed", "median", "p95", "max"}, rows)
+}
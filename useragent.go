@@ -0,0 +1,59 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/kalikim/x-cli/config"
+)
+
+var (
+	userAgentValue string
+	userAgentOnce  sync.Once
+)
+
+// userAgent identifies x-cli to X and to proxies: "x-cli/<version>",
+// followed by the user_agent_suffix setting (or XCLI_USER_AGENT_SUFFIX).
+func userAgent() string {
+	userAgentOnce.Do(func() {
+		suffix := config.Peek().UserAgentSuffix
+		if v, ok := os.LookupEnv("XCLI_USER_AGENT_SUFFIX"); ok {
+			suffix = v
+		}
+		userAgentValue = "x-cli/" + version
+		if suffix = sanitizeUserAgent(suffix); suffix != "" {
+			userAgentValue += " " + suffix
+		}
+	})
+	return userAgentValue
+}
+
+// sanitizeUserAgent keeps the printable ASCII a header value may carry, so
+// a stray newline in the config cannot break every request.
+func sanitizeUserAgent(s string) string {
+	return strings.Join(strings.Fields(strings.Map(func(r rune) rune {
+		if r < 0x20 || r > 0x7e {
+			return ' '
+		}
+		return r
+	}, s)), " ")
+}
+
+// userAgentTransport sets x-cli's User-Agent on requests that do not carry
+// their own, instead of Go's default.
+type userAgentTransport struct {
+	base http.RoundTripper
+}
+
+func (t *userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("User-Agent") != "" {
+		return t.base.RoundTrip(req)
+	}
+
+	// A RoundTripper must not modify the caller's request.
+	req = req.Clone(req.Context())
+	req.Header.Set("User-Agent", userAgent())
+	return t.base.RoundTrip(req)
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"math/rand"
+)
+
+// maxVariants is how many --variant texts a tweet may carry besides its own.
+const maxVariants = 5
+
+// variantLetter names variant i: "a" is the tweet's own text, "b" its first
+// alternative and so on.
+func variantLetter(i int) string {
+	return string(rune('a' + i))
+}
+
+// variantIndex is the inverse of variantLetter; it returns 0 for "" so
+// tweets without variants post their own text.
+func variantIndex(letter string) int {
+	if len(letter) != 1 || letter[0] < 'a' {
+		return 0
+	}
+	return int(letter[0] - 'a')
+}
+
+// pickVariant chooses one of the tweet's texts uniformly at random.
+func pickVariant(tweet scheduledTweet) string {
+	return variantLetter(rand.Intn(len(tweet.Variants) + 1))
+}
+
+// postText returns the text to post: the chosen variant, or the tweet's own
+// text when no variant was chosen.
+func (t scheduledTweet) postText() string {
+	i := variantIndex(t.Variant)
+	if i == 0 || i > len(t.Variants) {
+		return t.Text
+	}
+	return t.Variants[i-1]
+}
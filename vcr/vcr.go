@@ -0,0 +1,252 @@
+// Package vcr records HTTP interactions to a cassette file and replays them
+// later, so code that talks to the X API can be exercised without network
+// access or credentials.
+package vcr
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+)
+
+// Mode selects whether a Transport talks to the network or to its cassette.
+type Mode int
+
+const (
+	// Replay answers requests from the cassette and never hits the network.
+	Replay Mode = iota
+	// Record forwards requests and writes every interaction to the cassette.
+	Record
+)
+
+// Cassette is the on-disk form of a recording.
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is one request and the response it received.
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Request holds the parts of a request used for matching. Headers, which
+// carry credentials, are never stored.
+type Request struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	Body   string `json:"body,omitempty"`
+}
+
+// Response is a recorded response.
+type Response struct {
+	Status int         `json:"status"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body"`
+}
+
+// Transport is an http.RoundTripper that records to or replays from a
+// cassette. Replayed interactions are matched by method and URL in the order
+// they were recorded.
+type Transport struct {
+	mode Mode
+	path string
+	next http.RoundTripper
+
+	mu       sync.Mutex
+	cassette Cassette
+	used     []bool
+}
+
+// New returns a Transport for the cassette at path. In Replay mode the
+// cassette must exist; in Record mode it is replaced. next is the transport
+// used while recording and defaults to http.DefaultTransport.
+func New(path string, mode Mode, next http.RoundTripper) (*Transport, error) {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	t := &Transport{mode: mode, path: path, next: next}
+
+	if mode == Replay {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading cassette: %w", err)
+		}
+		if err := json.Unmarshal(data, &t.cassette); err != nil {
+			return nil, fmt.Errorf("parsing cassette %s: %w", path, err)
+		}
+		t.used = make([]bool, len(t.cassette.Interactions))
+	}
+
+	return t, nil
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		data, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		body = data
+	}
+
+	if t.mode == Replay {
+		return t.replay(req)
+	}
+	return t.record(req, body)
+}
+
+func (t *Transport) replay(req *http.Request) (*http.Response, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for i, in := range t.cassette.Interactions {
+		if t.used[i] || in.Request.Method != req.Method || in.Request.URL != req.URL.String() {
+			continue
+		}
+		t.used[i] = true
+		return in.Response.toHTTP(req), nil
+	}
+
+	return nil, fmt.Errorf("vcr: no recorded interaction for %s %s in %s", req.Method, req.URL, t.path)
+}
+
+func (t *Transport) record(req *http.Request, body []byte) (*http.Response, error) {
+	fwd := req.Clone(req.Context())
+	fwd.Body = io.NopCloser(bytes.NewReader(body))
+	fwd.ContentLength = int64(len(body))
+
+	resp, err := t.next.RoundTrip(fwd)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	recorded := Response{Status: resp.StatusCode, Header: resp.Header.Clone(), Body: string(respBody)}
+	recorded.Header.Del("Set-Cookie")
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.cassette.Interactions = append(t.cassette.Interactions, Interaction{
+		Request:  Request{Method: req.Method, URL: req.URL.String(), Body: RedactBody(req.Header.Get("Content-Type"), body)},
+		Response: recorded,
+	})
+	if err := t.save(); err != nil {
+		return nil, err
+	}
+
+	return recorded.toHTTP(req), nil
+}
+
+// save rewrites the cassette after every interaction so a recording survives
+// the process exiting early.
+func (t *Transport) save() error {
+	data, err := json.MarshalIndent(t.cassette, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(t.path, data, 0644); err != nil {
+		return fmt.Errorf("writing cassette: %w", err)
+	}
+	return nil
+}
+
+// Unused returns the recorded interactions that have not been replayed.
+func (t *Transport) Unused() []Interaction {
+	if t.mode != Replay {
+		return nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var unused []Interaction
+	for i, in := range t.cassette.Interactions {
+		if !t.used[i] {
+			unused = append(unused, in)
+		}
+	}
+	return unused
+}
+
+// ParseMode converts "record" or "replay" to a Mode.
+func ParseMode(name string) (Mode, error) {
+	switch name {
+	case "record":
+		return Record, nil
+	case "replay":
+		return Replay, nil
+	}
+	return Replay, fmt.Errorf("unknown vcr mode %q (use record or replay)", name)
+}
+
+func (r Response) toHTTP(req *http.Request) *http.Response {
+	return &http.Response{
+		StatusCode:    r.Status,
+		Status:        fmt.Sprintf("%d %s", r.Status, http.StatusText(r.Status)),
+		Header:        r.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader([]byte(r.Body))),
+		ContentLength: int64(len(r.Body)),
+		Request:       req,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+	}
+}
+
+// RedactBody replaces uploaded media with its size to keep cassettes and
+// logs small. Multipart bodies are summarized as form values, with file
+// parts reduced to their size.
+func RedactBody(contentType string, body []byte) string {
+	if mediaType, params, err := mime.ParseMediaType(contentType); err == nil && mediaType == "multipart/form-data" {
+		if summary, ok := redactMultipart(body, params["boundary"]); ok {
+			return summary
+		}
+	}
+
+	values, err := url.ParseQuery(string(body))
+	if err != nil || !values.Has("media_data") {
+		return string(body)
+	}
+	values.Set("media_data", fmt.Sprintf("<%d bytes>", len(values.Get("media_data"))))
+	return values.Encode()
+}
+
+func redactMultipart(body []byte, boundary string) (string, bool) {
+	values := url.Values{}
+	reader := multipart.NewReader(bytes.NewReader(body), boundary)
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return values.Encode(), true
+		}
+		if err != nil {
+			return "", false
+		}
+
+		data, err := io.ReadAll(part)
+		if err != nil {
+			return "", false
+		}
+		if part.FileName() != "" {
+			values.Add(part.FormName(), fmt.Sprintf("<%d bytes>", len(data)))
+		} else {
+			values.Add(part.FormName(), string(data))
+		}
+	}
+}
@@ -0,0 +1,37 @@
+// Package vcrtest wires vcr cassettes into Go tests.
+package vcrtest
+
+import (
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/kalikim/x-cli/vcr"
+)
+
+// NewClient returns an HTTP client that replays the cassette at path. Set
+// VCR_RECORD=1 to hit the network once and (re)record it instead. In replay
+// mode the test fails if any recorded interaction was not used.
+func NewClient(tb testing.TB, path string) *http.Client {
+	tb.Helper()
+
+	mode := vcr.Replay
+	if os.Getenv("VCR_RECORD") != "" {
+		mode = vcr.Record
+	}
+
+	transport, err := vcr.New(path, mode, nil)
+	if err != nil {
+		tb.Fatalf("vcrtest: %v", err)
+	}
+
+	if mode == vcr.Replay {
+		tb.Cleanup(func() {
+			for _, in := range transport.Unused() {
+				tb.Errorf("vcrtest: recorded %s %s was never requested", in.Request.Method, in.Request.URL)
+			}
+		})
+	}
+
+	return &http.Client{Transport: transport}
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// X's limits for video in tweets. Anything outside them is accepted by the
+// upload but fails at FINALIZE or processing with an unhelpful message.
+const (
+	videoMinDuration = 500 * time.Millisecond
+	videoMaxDuration = 140 * time.Second
+	videoMaxBitrate  = 25_000_000
+	videoMaxWidth    = 1920
+	videoMaxHeight   = 1200
+	videoMinSide     = 32
+)
+
+var (
+	errNoVideoTrack   = errors.New("no video track found")
+	errMalformedVideo = errors.New("malformed video container")
+)
+
+// videoInfo is what a probe learns about a video file. Zero fields are
+// unknown.
+type videoInfo struct {
+	Duration time.Duration
+	Codec    string
+	Bitrate  int64
+	Width    int
+	Height   int
+}
+
+// checkVideo probes the video at path and reports every way it breaks X's
+// limits in one error.
+func checkVideo(path string) error {
+	info, err := probeVideo(path)
+	if err != nil {
+		return invalidInput(fmt.Errorf("reading video %s: %w", path, err))
+	}
+
+	var problems []string
+	switch {
+	case info.Duration > videoMaxDuration:
+		problems = append(problems, fmt.Sprintf("duration %s exceeds %s limit", formatVideoDuration(info.Duration), formatVideoDuration(videoMaxDuration)))
+	case info.Duration > 0 && info.Duration < videoMinDuration:
+		problems = append(problems, fmt.Sprintf("duration %.1fs is under the %.1fs minimum", info.Duration.Seconds(), videoMinDuration.Seconds()))
+	}
+	if info.Codec != "" && info.Codec != "h264" {
+		problems = append(problems, fmt.Sprintf("codec %s is not supported (X needs H.264; re-encode with ffmpeg -c:v libx264 -c:a aac)", info.Codec))
+	}
+	if info.Bitrate > videoMaxBitrate {
+		problems = append(problems, fmt.Sprintf("bitrate %s exceeds %s limit", formatBitrate(info.Bitrate), formatBitrate(videoMaxBitrate)))
+	}
+	if info.Width > 0 && info.Height > 0 {
+		long, short := max(info.Width, info.Height), min(info.Width, info.Height)
+		if long > videoMaxWidth || short > videoMaxHeight {
+			problems = append(problems, fmt.Sprintf("resolution %dx%d exceeds %dx%d limit", info.Width, info.Height, videoMaxWidth, videoMaxHeight))
+		}
+		if short < videoMinSide {
+			problems = append(problems, fmt.Sprintf("resolution %dx%d is under the %dx%d minimum", info.Width, info.Height, videoMinSide, videoMinSide))
+		}
+	}
+
+	if len(problems) > 0 {
+		return invalidInput(fmt.Errorf("video %s: %s", path, strings.Join(problems, "; ")))
+	}
+	return nil
+}
+
+// probeVideo asks ffprobe about path when it is installed, and otherwise
+// reads the MP4/MOV container itself.
+func probeVideo(path string) (videoInfo, error) {
+	if bin, err := exec.LookPath("ffprobe"); err == nil {
+		if info, err := ffprobeVideo(bin, path); err == nil {
+			return info, nil
+		}
+	}
+	return probeMP4(path)
+}
+
+func ffprobeVideo(bin, path string) (videoInfo, error) {
+	out, err := exec.Command(bin, "-v", "error", "-select_streams", "v:0",
+		"-show_entries", "format=duration,bit_rate:stream=codec_name,width,height",
+		"-of", "json", path).Output()
+	if err != nil {
+		return videoInfo{}, err
+	}
+
+	var probe struct {
+		Streams []struct {
+			CodecName string `json:"codec_name"`
+			Width     int    `json:"width"`
+			Height    int    `json:"height"`
+		} `json:"streams"`
+		Format struct {
+			Duration string `json:"duration"`
+			BitRate  string `json:"bit_rate"`
+		} `json:"format"`
+	}
+	if err := json.Unmarshal(out, &probe); err != nil {
+		return videoInfo{}, fmt.Errorf("decoding ffprobe output: %w", err)
+	}
+	if len(probe.Streams) == 0 {
+		return videoInfo{}, errNoVideoTrack
+	}
+
+	info := videoInfo{
+		Codec:  probe.Streams[0].CodecName,
+		Width:  probe.Streams[0].Width,
+		Height: probe.Streams[0].Height,
+	}
+	if secs, err := strconv.ParseFloat(probe.Format.Duration, 64); err == nil {
+		info.Duration = time.Duration(secs * float64(time.Second))
+	}
+	info.Bitrate, _ = strconv.ParseInt(probe.Format.BitRate, 10, 64)
+	return info, nil
+}
+
+// mp4Codecs maps sample entry types to ffprobe's codec names.
+var mp4Codecs = map[string]string{
+	"avc1": "h264",
+	"avc3": "h264",
+	"hvc1": "hevc",
+	"hev1": "hevc",
+	"mp4v": "mpeg4",
+	"av01": "av1",
+	"vp09": "vp9",
+	"apcn": "prores",
+	"apch": "prores",
+}
+
+// probeMP4 reads the duration from the movie header and the codec and size
+// of the first video track from an MP4/MOV file. Only the moov box is
+// loaded, wherever it sits in the file.
+func probeMP4(path string) (videoInfo, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return videoInfo{}, err
+	}
+	defer f.Close()
+
+	st, err := f.Stat()
+	if err != nil {
+		return videoInfo{}, err
+	}
+
+	var moov []byte
+	for offset := int64(0); offset+8 <= st.Size(); {
+		var header [16]byte
+		if _, err := f.ReadAt(header[:8], offset); err != nil {
+			return videoInfo{}, err
+		}
+		size := int64(binary.BigEndian.Uint32(header[:4]))
+		headerLen := int64(8)
+		switch size {
+		case 0:
+			size = st.Size() - offset
+		case 1:
+			if _, err := f.ReadAt(header[8:16], offset+8); err != nil {
+				return videoInfo{}, err
+			}
+			size = int64(binary.BigEndian.Uint64(header[8:16]))
+			headerLen = 16
+		}
+		if size < headerLen || offset+size > st.Size() {
+			return videoInfo{}, errMalformedVideo
+		}
+
+		if string(header[4:8]) == "moov" {
+			moov = make([]byte, size-headerLen)
+			if _, err := f.ReadAt(moov, offset+headerLen); err != nil && err != io.EOF {
+				return videoInfo{}, err
+			}
+			break
+		}
+		offset += size
+	}
+	if moov == nil {
+		return videoInfo{}, errors.New("no movie header (moov) found")
+	}
+
+	var info videoInfo
+	if mvhd := mp4Child(moov, "mvhd"); len(mvhd) >= 20 {
+		var timescale, duration uint64
+		if mvhd[0] == 1 && len(mvhd) >= 32 {
+			timescale = uint64(binary.BigEndian.Uint32(mvhd[20:]))
+			duration = binary.BigEndian.Uint64(mvhd[24:])
+		} else {
+			timescale = uint64(binary.BigEndian.Uint32(mvhd[12:]))
+			duration = uint64(binary.BigEndian.Uint32(mvhd[16:]))
+		}
+		if timescale > 0 {
+			info.Duration = time.Duration(float64(duration) / float64(timescale) * float64(time.Second))
+		}
+	}
+	if info.Duration > 0 {
+		info.Bitrate = int64(math.Round(float64(st.Size()*8) / info.Duration.Seconds()))
+	}
+
+	found := false
+	for _, trak := range mp4Children(moov, "trak") {
+		mdia := mp4Child(trak, "mdia")
+		if hdlr := mp4Child(mdia, "hdlr"); len(hdlr) < 12 || string(hdlr[8:12]) != "vide" {
+			continue
+		}
+		stsd := mp4Child(mp4Child(mp4Child(mdia, "minf"), "stbl"), "stsd")
+		if len(stsd) < 16 {
+			continue
+		}
+		// stsd: version/flags, entry count, then the first sample entry.
+		entry := stsd[8:]
+		format := string(entry[4:8])
+		info.Codec = mp4Codecs[format]
+		if info.Codec == "" {
+			info.Codec = strings.TrimSpace(format)
+		}
+		// Visual sample entries keep the frame size after 24 bytes of
+		// reserved and predefined fields.
+		if len(entry) >= 36 {
+			info.Width = int(binary.BigEndian.Uint16(entry[32:]))
+			info.Height = int(binary.BigEndian.Uint16(entry[34:]))
+		}
+		found = true
+		break
+	}
+	if !found {
+		return videoInfo{}, errNoVideoTrack
+	}
+
+	return info, nil
+}
+
+// mp4Children returns the payloads of the boxes named typ directly inside
+// data.
+func mp4Children(data []byte, typ string) [][]byte {
+	var boxes [][]byte
+	for len(data) >= 8 {
+		size := uint64(binary.BigEndian.Uint32(data))
+		headerLen := uint64(8)
+		if size == 1 && len(data) >= 16 {
+			size = binary.BigEndian.Uint64(data[8:])
+			headerLen = 16
+		} else if size == 0 {
+			size = uint64(len(data))
+		}
+		if size < headerLen || size > uint64(len(data)) {
+			break
+		}
+		if bytes.Equal(data[4:8], []byte(typ)) {
+			boxes = append(boxes, data[headerLen:size])
+		}
+		data = data[size:]
+	}
+	return boxes
+}
+
+// mp4Child returns the payload of the first box named typ inside data, or
+// nil.
+func mp4Child(data []byte, typ string) []byte {
+	if boxes := mp4Children(data, typ); len(boxes) > 0 {
+		return boxes[0]
+	}
+	return nil
+}
+
+// formatVideoDuration renders d as m:ss, the way players show it.
+func formatVideoDuration(d time.Duration) string {
+	secs := int(d.Round(time.Second) / time.Second)
+	return fmt.Sprintf("%d:%02d", secs/60, secs%60)
+}
+
+func formatBitrate(bps int64) string {
+	switch {
+	case bps >= 1_000_000:
+		return strings.TrimSuffix(fmt.Sprintf("%.1f", float64(bps)/1_000_000), ".0") + " Mbps"
+	case bps >= 1_000:
+		return fmt.Sprintf("%.0f kbps", float64(bps)/1_000)
+	}
+	return fmt.Sprintf("%d bps", bps)
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"os"
+	"strings"
+
+	"github.com/kalikim/x-cli/config"
+)
+
+var watermarkPositions = []string{"top-left", "top-right", "bottom-left", "bottom-right", "center"}
+
+const (
+	defaultWatermarkPosition = "bottom-right"
+	// watermarkMaxWidth is the largest share of the image width a logo
+	// covers; bigger logos are scaled down, smaller ones kept as they are.
+	watermarkMaxWidth = 0.25
+	// watermarkMargin is the gap to the edges, as a share of the image's
+	// shorter side.
+	watermarkMargin = 0.03
+)
+
+// resolveWatermark fills in the defaults of wm and checks that its logo
+// can be read.
+func resolveWatermark(wm config.Watermark) (config.Watermark, error) {
+	wm.Image = expandHome(strings.TrimSpace(wm.Image))
+	if wm.Position == "" {
+		wm.Position = defaultWatermarkPosition
+	}
+	if wm.Opacity == 0 {
+		wm.Opacity = 1
+	}
+
+	valid := false
+	for _, p := range watermarkPositions {
+		valid = valid || wm.Position == p
+	}
+	if !valid {
+		return wm, invalidInput(fmt.Errorf("invalid watermark position %q (use %s)", wm.Position, strings.Join(watermarkPositions, ", ")))
+	}
+	if wm.Opacity < 0 || wm.Opacity > 1 {
+		return wm, invalidInput(fmt.Errorf("watermark opacity must be between 0 and 1, got %g", wm.Opacity))
+	}
+	if _, err := loadWatermarkLogo(wm.Image); err != nil {
+		return wm, invalidInput(err)
+	}
+	return wm, nil
+}
+
+func loadWatermarkLogo(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading watermark: %w", err)
+	}
+	defer f.Close()
+
+	logo, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("reading watermark %s: %w (use a PNG, JPEG or GIF)", path, err)
+	}
+	return logo, nil
+}
+
+// drawWatermark composites the logo of wm onto canvas.
+func drawWatermark(canvas *image.RGBA, wm config.Watermark) error {
+	wm, err := resolveWatermark(wm)
+	if err != nil {
+		return err
+	}
+	logo, err := loadWatermarkLogo(wm.Image)
+	if err != nil {
+		return err
+	}
+
+	b := canvas.Bounds()
+	if limit := int(float64(b.Dx()) * watermarkMaxWidth); logo.Bounds().Dx() > limit && limit > 0 {
+		logo = scaleImage(logo, limit, max(1, logo.Bounds().Dy()*limit/logo.Bounds().Dx()))
+	}
+	margin := int(float64(min(b.Dx(), b.Dy())) * watermarkMargin)
+	target := watermarkRect(b, logo.Bounds().Size(), wm.Position, margin)
+	opacity := image.NewUniform(color.Alpha{A: uint8(wm.Opacity*255 + 0.5)})
+	draw.DrawMask(canvas, target, logo, logo.Bounds().Min, opacity, image.Point{}, draw.Over)
+	return nil
+}
+
+// watermarkRect places a logo of the given size at position within bounds.
+func watermarkRect(bounds image.Rectangle, size image.Point, position string, margin int) image.Rectangle {
+	x := bounds.Min.X + margin
+	y := bounds.Min.Y + margin
+	if strings.HasSuffix(position, "right") {
+		x = bounds.Max.X - margin - size.X
+	}
+	if strings.HasPrefix(position, "bottom") {
+		y = bounds.Max.Y - margin - size.Y
+	}
+	if position == "center" {
+		x = bounds.Min.X + (bounds.Dx()-size.X)/2
+		y = bounds.Min.Y + (bounds.Dy()-size.Y)/2
+	}
+	return image.Rectangle{Min: image.Pt(x, y), Max: image.Pt(x+size.X, y+size.Y)}
+}
+
+// scaleImage shrinks img to w×h, averaging the pixels each target pixel
+// covers so thin lines in a logo survive.
+func scaleImage(img image.Image, w, h int) image.Image {
+	b := img.Bounds()
+	out := image.NewRGBA64(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		y0, y1 := b.Min.Y+y*b.Dy()/h, b.Min.Y+max((y+1)*b.Dy()/h, y*b.Dy()/h+1)
+		for x := 0; x < w; x++ {
+			x0, x1 := b.Min.X+x*b.Dx()/w, b.Min.X+max((x+1)*b.Dx()/w, x*b.Dx()/w+1)
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := img.At(sx, sy).RGBA()
+					r, g, bl, a, n = r+uint64(cr), g+uint64(cg), bl+uint64(cb), a+uint64(ca), n+1
+				}
+			}
+			out.SetRGBA64(x, y, color.RGBA64{R: uint16(r / n), G: uint16(g / n), B: uint16(bl / n), A: uint16(a / n)})
+		}
+	}
+	return out
+}
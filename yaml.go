@@ -0,0 +1,403 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parseYAML reads the block-style subset of YAML that hand-written files
+// such as content plans use: mappings, sequences, plain and quoted scalars,
+// flow sequences like [a, b], | and > block scalars, and comments. Scalars
+// are strings except true, false and null; anchors, tags, flow mappings and
+// multiple documents are rejected. The result holds map[string]any, []any,
+// string, bool and nil values, ready to be re-encoded as JSON.
+func parseYAML(data []byte) (any, error) {
+	p := &yamlParser{}
+	for i, raw := range strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n") {
+		line := yamlLine{num: i + 1, raw: raw}
+		body := strings.TrimLeft(raw, " ")
+		line.indent = len(raw) - len(body)
+		if strings.HasPrefix(body, "\t") {
+			return nil, fmt.Errorf("line %d: indent with spaces, not tabs", line.num)
+		}
+		line.text = strings.TrimRight(stripYAMLComment(body), " \t")
+		p.lines = append(p.lines, line)
+	}
+
+	// A leading document marker is allowed; further documents are not.
+	if i := p.skip(); i < len(p.lines) && p.lines[i].text == "---" {
+		p.pos = i + 1
+	}
+	i := p.skip()
+	if i == len(p.lines) {
+		return nil, nil
+	}
+	value, err := p.node(p.lines[i].indent)
+	if err != nil {
+		return nil, err
+	}
+	if i := p.skip(); i < len(p.lines) {
+		return nil, fmt.Errorf("line %d: unexpected %q (check the indentation)", p.lines[i].num, p.lines[i].text)
+	}
+	return value, nil
+}
+
+type yamlLine struct {
+	num    int
+	indent int
+	raw    string
+	text   string
+}
+
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+// skip returns the index of the next line with content.
+func (p *yamlParser) skip() int {
+	i := p.pos
+	for i < len(p.lines) && p.lines[i].text == "" {
+		i++
+	}
+	return i
+}
+
+func isYAMLSeqItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// node parses the block starting at the next line, which is at indent.
+func (p *yamlParser) node(indent int) (any, error) {
+	if isYAMLSeqItem(p.lines[p.skip()].text) {
+		return p.sequence(indent)
+	}
+	return p.mapping(indent)
+}
+
+func (p *yamlParser) sequence(indent int) (any, error) {
+	items := []any{}
+	for {
+		i := p.skip()
+		if i == len(p.lines) || p.lines[i].indent < indent {
+			return items, nil
+		}
+		line := p.lines[i]
+		if line.indent > indent || !isYAMLSeqItem(line.text) {
+			if line.indent == indent {
+				return items, nil
+			}
+			return nil, fmt.Errorf("line %d: unexpected %q (check the indentation)", line.num, line.text)
+		}
+
+		rest := strings.TrimLeft(strings.TrimPrefix(line.text, "-"), " ")
+		if rest == "" {
+			p.pos = i + 1
+			next := p.skip()
+			if next == len(p.lines) || p.lines[next].indent <= indent {
+				items = append(items, nil)
+				continue
+			}
+			item, err := p.node(p.lines[next].indent)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+			continue
+		}
+
+		if _, _, ok := splitYAMLKey(rest); ok || isYAMLSeqItem(rest) {
+			// "- key: value" starts a mapping (or "- - x" a sequence) whose
+			// lines are indented to where the key begins.
+			p.lines[i].indent = indent + len(line.text) - len(rest)
+			p.lines[i].text = rest
+			p.pos = i
+			item, err := p.node(p.lines[i].indent)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+			continue
+		}
+
+		p.pos = i + 1
+		item, err := p.value(rest, line, indent)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+}
+
+func (p *yamlParser) mapping(indent int) (any, error) {
+	m := map[string]any{}
+	for {
+		i := p.skip()
+		if i == len(p.lines) || p.lines[i].indent < indent {
+			return m, nil
+		}
+		line := p.lines[i]
+		if line.indent > indent {
+			return nil, fmt.Errorf("line %d: unexpected %q (check the indentation)", line.num, line.text)
+		}
+		if isYAMLSeqItem(line.text) {
+			// A sequence at the same indent belongs to the key before it.
+			return nil, fmt.Errorf("line %d: unexpected list item", line.num)
+		}
+		key, rest, ok := splitYAMLKey(line.text)
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected \"key: value\", got %q", line.num, line.text)
+		}
+		if _, dup := m[key]; dup {
+			return nil, fmt.Errorf("line %d: duplicate key %q", line.num, key)
+		}
+		p.pos = i + 1
+
+		if rest != "" {
+			value, err := p.value(rest, line, indent)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = value
+			continue
+		}
+
+		next := p.skip()
+		switch {
+		case next < len(p.lines) && p.lines[next].indent > indent:
+			value, err := p.node(p.lines[next].indent)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = value
+		case next < len(p.lines) && p.lines[next].indent == indent && isYAMLSeqItem(p.lines[next].text):
+			value, err := p.sequence(indent)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = value
+		default:
+			m[key] = nil
+		}
+	}
+}
+
+// value parses the scalar after "key:" or "-" on line, including block
+// scalars, which take the following lines indented beyond parent.
+func (p *yamlParser) value(text string, line yamlLine, parent int) (any, error) {
+	if text[0] == '|' || text[0] == '>' {
+		return p.blockScalar(text, line, parent)
+	}
+	v, err := parseYAMLScalar(text)
+	if err != nil {
+		return nil, fmt.Errorf("line %d: %w", line.num, err)
+	}
+	return v, nil
+}
+
+func (p *yamlParser) blockScalar(header string, line yamlLine, parent int) (any, error) {
+	style, chomp := header[0], strings.TrimSpace(header[1:])
+	if chomp != "" && chomp != "-" && chomp != "+" {
+		return nil, fmt.Errorf("line %d: unsupported block scalar header %q", line.num, header)
+	}
+
+	var lines []string
+	indent := -1
+	for p.pos < len(p.lines) {
+		raw := p.lines[p.pos].raw
+		body := strings.TrimLeft(raw, " ")
+		if body == "" {
+			lines = append(lines, "")
+			p.pos++
+			continue
+		}
+		n := len(raw) - len(body)
+		if n <= parent {
+			break
+		}
+		if indent < 0 {
+			indent = n
+		}
+		if n < indent {
+			return nil, fmt.Errorf("line %d: block text is indented less than its first line", p.lines[p.pos].num)
+		}
+		lines = append(lines, raw[indent:])
+		p.pos++
+	}
+
+	// Trailing blank lines belong to chomping, not the text.
+	end := len(lines)
+	for end > 0 && lines[end-1] == "" {
+		end--
+	}
+	trailing := len(lines) - end
+	lines = lines[:end]
+
+	var text string
+	if style == '|' {
+		text = strings.Join(lines, "\n")
+	} else {
+		var b strings.Builder
+		for i, l := range lines {
+			switch {
+			case i == 0:
+			case l == "" || lines[i-1] == "":
+				b.WriteString("\n")
+			default:
+				b.WriteString(" ")
+			}
+			b.WriteString(l)
+		}
+		text = b.String()
+	}
+
+	switch chomp {
+	case "-":
+	case "+":
+		text += strings.Repeat("\n", trailing+1)
+	default:
+		if text != "" {
+			text += "\n"
+		}
+	}
+	return text, nil
+}
+
+// splitYAMLKey splits "key: value" (or "key:") outside quotes.
+func splitYAMLKey(text string) (string, string, bool) {
+	if text[0] == '"' || text[0] == '\'' {
+		end := closingQuote(text)
+		if end < 0 || end+1 >= len(text) || text[end+1] != ':' || (end+2 < len(text) && text[end+2] != ' ') {
+			return "", "", false
+		}
+		key, err := parseYAMLScalar(text[:end+1])
+		if err != nil {
+			return "", "", false
+		}
+		return key.(string), strings.TrimSpace(text[end+2:]), true
+	}
+	if text[0] == '[' || text[0] == '{' {
+		return "", "", false
+	}
+	if strings.HasSuffix(text, ":") && !strings.Contains(text, ": ") {
+		return text[:len(text)-1], "", true
+	}
+	key, rest, ok := strings.Cut(text, ": ")
+	if !ok {
+		return "", "", false
+	}
+	return strings.TrimSpace(key), strings.TrimSpace(rest), true
+}
+
+// closingQuote returns the index of the quote closing the string text
+// starts with, or -1.
+func closingQuote(text string) int {
+	q := text[0]
+	for i := 1; i < len(text); i++ {
+		switch {
+		case q == '"' && text[i] == '\\':
+			i++
+		case text[i] == q && q == '\'' && i+1 < len(text) && text[i+1] == '\'':
+			i++
+		case text[i] == q:
+			return i
+		}
+	}
+	return -1
+}
+
+func parseYAMLScalar(text string) (any, error) {
+	switch text[0] {
+	case '"':
+		if closingQuote(text) != len(text)-1 {
+			return nil, fmt.Errorf("unterminated or trailing text after string %s", text)
+		}
+		s, err := strconv.Unquote(text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid string %s: %w", text, err)
+		}
+		return s, nil
+	case '\'':
+		if closingQuote(text) != len(text)-1 {
+			return nil, fmt.Errorf("unterminated or trailing text after string %s", text)
+		}
+		return strings.ReplaceAll(text[1:len(text)-1], "''", "'"), nil
+	case '[':
+		return parseYAMLFlowSeq(text)
+	case '{':
+		return nil, fmt.Errorf("flow mappings like %s are not supported; use indented keys", text)
+	case '&', '*', '!':
+		return nil, fmt.Errorf("anchors, aliases and tags are not supported: %s", text)
+	}
+
+	switch text {
+	case "true", "True", "TRUE":
+		return true, nil
+	case "false", "False", "FALSE":
+		return false, nil
+	case "null", "Null", "NULL", "~":
+		return nil, nil
+	}
+	return text, nil
+}
+
+func parseYAMLFlowSeq(text string) (any, error) {
+	if !strings.HasSuffix(text, "]") {
+		return nil, fmt.Errorf("unterminated list %s", text)
+	}
+	inner := strings.TrimSpace(text[1 : len(text)-1])
+	items := []any{}
+	for inner != "" {
+		var item string
+		if inner[0] == '"' || inner[0] == '\'' {
+			end := closingQuote(inner)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated string in list %s", text)
+			}
+			item, inner = inner[:end+1], strings.TrimSpace(inner[end+1:])
+			if inner != "" && inner[0] != ',' {
+				return nil, fmt.Errorf("expected ',' after %s in list %s", item, text)
+			}
+		} else {
+			var ok bool
+			item, inner, ok = strings.Cut(inner, ",")
+			item = strings.TrimSpace(item)
+			if ok {
+				inner = "," + inner
+			}
+		}
+		if item == "" || item[0] == '[' {
+			return nil, fmt.Errorf("empty or nested item in list %s", text)
+		}
+		v, err := parseYAMLScalar(item)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, v)
+		inner = strings.TrimSpace(strings.TrimPrefix(inner, ","))
+	}
+	return items, nil
+}
+
+// stripYAMLComment removes a "#" comment that starts the line or follows
+// whitespace, outside quotes.
+func stripYAMLComment(text string) string {
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote == '"' && c == '\\':
+			i++
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case (c == '"' || c == '\'') && (i == 0 || strings.ContainsRune(" -:[,", rune(text[i-1]))):
+			quote = c
+		case c == '#' && (i == 0 || text[i-1] == ' ' || text[i-1] == '\t'):
+			return text[:i]
+		}
+	}
+	return text
+}